@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// Coverage records which functions of the standard library are called by tests.
+//
+// Calls are counted while the tests run, so a function is covered when
+// the interpreter calls it, either from a test or from another function
+// such as aggregateWindow. Functions that are only called by the
+// compiled row functions of transformations such as map are not counted.
+type Coverage struct {
+	packages  map[string]*PackageCoverage
+	functions map[interface{}][]*FunctionCoverage
+
+	mu sync.Mutex
+}
+
+// PackageCoverage is the coverage of the functions of a single package.
+type PackageCoverage struct {
+	Path      string              `json:"path"`
+	Functions []*FunctionCoverage `json:"functions"`
+}
+
+// FunctionCoverage is the coverage of a single function.
+type FunctionCoverage struct {
+	Name string `json:"name"`
+	// Builtin is true if the function is implemented in Go
+	// and false if it is written in Flux.
+	Builtin bool `json:"builtin"`
+	// Calls is the number of times the tests called the function.
+	Calls int `json:"calls"`
+}
+
+// Covered returns the number of functions of the package that are called.
+func (p *PackageCoverage) Covered() int {
+	n := 0
+	for _, fn := range p.Functions {
+		if fn.Calls > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// NewCoverage creates a Coverage for the packages with the paths.
+// The packages are part of the coverage even if no test imports them,
+// so that packages without tests are reported.
+func NewCoverage(importer interpreter.Importer, paths []string) (*Coverage, error) {
+	c := &Coverage{
+		packages:  make(map[string]*PackageCoverage),
+		functions: make(map[interface{}][]*FunctionCoverage),
+	}
+	for _, path := range paths {
+		if err := c.load(importer, path); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// load adds the functions of the package with the path to the coverage.
+func (c *Coverage) load(importer interpreter.Importer, path string) error {
+	pkg, err := importer.ImportPackageObject(path)
+	if err != nil {
+		return err
+	}
+
+	p := &PackageCoverage{Path: path}
+	pkg.Range(func(name string, v values.Value) {
+		if v.Type().Nature() != semantic.Function {
+			return
+		}
+		_, ok := v.(interpreter.Resolver)
+		fn := &FunctionCoverage{
+			Name:    name,
+			Builtin: !ok,
+		}
+		p.Functions = append(p.Functions, fn)
+		if id, ok := functionID(v.Function()); ok {
+			c.functions[id] = append(c.functions[id], fn)
+		}
+	})
+	sort.Slice(p.Functions, func(i, j int) bool {
+		return p.Functions[i].Name < p.Functions[j].Name
+	})
+	c.packages[path] = p
+	return nil
+}
+
+// Inject returns a context that counts the calls to the functions
+// of the standard library. Tests must run with this context, or one
+// derived from it, to be part of the coverage.
+func (c *Coverage) Inject(ctx context.Context) context.Context {
+	return interpreter.WithCallHook(ctx, c.called)
+}
+
+// called counts a call to the function.
+func (c *Coverage) called(fn values.Function) {
+	id, ok := functionID(fn)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range c.functions[id] {
+		f.Calls++
+	}
+}
+
+// functionID returns the id of the function if it can be used as a map key.
+func functionID(fn values.Function) (interface{}, bool) {
+	id := interpreter.FunctionID(fn)
+	if !reflect.TypeOf(id).Comparable() {
+		return nil, false
+	}
+	return id, true
+}
+
+// Packages returns the coverage of the packages that have functions
+// sorted by their path.
+func (c *Coverage) Packages() []*PackageCoverage {
+	packages := make([]*PackageCoverage, 0, len(c.packages))
+	for _, p := range c.packages {
+		if len(p.Functions) > 0 {
+			packages = append(packages, p)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Path < packages[j].Path
+	})
+	return packages
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// reportFunc writes a report of the tests to w.
+// The coverage is nil unless coverage was requested.
+type reportFunc func(w io.Writer, tests []*Test, elapsed time.Duration, coverage *Coverage) error
+
+var reportFormats = map[string]reportFunc{
+	"junit": writeJUnitReport,
+	"json":  writeJSONReport,
+}
+
+// report is a report that is written to a file once the tests are done.
+type report struct {
+	path  string
+	write reportFunc
+}
+
+// parseReports parses a list of reports in the form format=path.
+func parseReports(specs []string) ([]report, error) {
+	reports := make([]report, 0, len(specs))
+	for _, spec := range specs {
+		format, path, ok := strings.Cut(spec, "=")
+		if !ok || path == "" {
+			return nil, errors.Newf(codes.Invalid, "report %q must be of the form format=path", spec)
+		}
+		fn, ok := reportFormats[format]
+		if !ok {
+			return nil, errors.Newf(codes.Invalid, "unknown report format %q, valid formats are junit and json", format)
+		}
+		reports = append(reports, report{path: path, write: fn})
+	}
+	return reports, nil
+}
+
+func (r report) writeFile(tests []*Test, elapsed time.Duration, coverage *Coverage) error {
+	f, err := os.Create(r.path)
+	if err != nil {
+		return errors.Wrapf(err, codes.Invalid, "could not create report %q", r.path)
+	}
+	if err := r.write(f, tests, elapsed, coverage); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// testStatus returns the status of the test as it is reported.
+func testStatus(test *Test) string {
+	if test.skip {
+		return "skip"
+	} else if test.Error() != nil {
+		return "fail"
+	}
+	return "pass"
+}
+
+type jsonReport struct {
+	Summary  jsonSummary        `json:"summary"`
+	Tests    []jsonTest         `json:"tests"`
+	Coverage []*PackageCoverage `json:"coverage,omitempty"`
+}
+
+type jsonSummary struct {
+	Found    int     `json:"found"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Skipped  int     `json:"skipped"`
+	Duration float64 `json:"duration"`
+}
+
+type jsonTest struct {
+	Name     string   `json:"name"`
+	Package  string   `json:"package"`
+	File     string   `json:"file"`
+	Tags     []string `json:"tags"`
+	Status   string   `json:"status"`
+	Duration float64  `json:"duration"`
+	Failure  string   `json:"failure,omitempty"`
+}
+
+// writeJSONReport writes the tests as a JSON document.
+// Durations are in seconds.
+func writeJSONReport(w io.Writer, tests []*Test, elapsed time.Duration, coverage *Coverage) error {
+	r := jsonReport{
+		Summary: jsonSummary{
+			Found:    len(tests),
+			Duration: elapsed.Seconds(),
+		},
+		Tests: make([]jsonTest, 0, len(tests)),
+	}
+	for _, test := range tests {
+		status := testStatus(test)
+		switch status {
+		case "pass":
+			r.Summary.Passed++
+		case "fail":
+			r.Summary.Failed++
+		case "skip":
+			r.Summary.Skipped++
+		}
+		jt := jsonTest{
+			Name:     test.Name(),
+			Package:  test.PackageName(),
+			File:     test.ast.Files[0].Name,
+			Tags:     test.Tags(),
+			Status:   status,
+			Duration: test.Duration().Seconds(),
+		}
+		if jt.Tags == nil {
+			jt.Tags = []string{}
+		}
+		if err := test.Error(); err != nil {
+			jt.Failure = err.Error()
+		}
+		r.Tests = append(r.Tests, jt)
+	}
+	if coverage != nil {
+		r.Coverage = coverage.Packages()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	File       string           `xml:"file,attr"`
+	Time       float64          `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitFailure    `xml:"failure,omitempty"`
+	Skipped    *struct{}        `xml:"skipped,omitempty"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",cdata"`
+}
+
+// writeJUnitReport writes the tests as a JUnit XML document
+// with a test suite for each package. The tags of a test are
+// written as properties of its test case.
+func writeJUnitReport(w io.Writer, tests []*Test, elapsed time.Duration, _ *Coverage) error {
+	r := junitTestSuites{
+		Tests: len(tests),
+		Time:  elapsed.Seconds(),
+	}
+	suites := make(map[string]int)
+	for _, test := range tests {
+		i, ok := suites[test.PackageName()]
+		if !ok {
+			i = len(r.Suites)
+			suites[test.PackageName()] = i
+			r.Suites = append(r.Suites, junitTestSuite{Name: test.PackageName()})
+		}
+		suite := &r.Suites[i]
+
+		tc := junitTestCase{
+			Name:      test.Name(),
+			Classname: test.PackageName(),
+			File:      test.ast.Files[0].Name,
+			Time:      test.Duration().Seconds(),
+		}
+		if tags := test.Tags(); len(tags) > 0 {
+			tc.Properties = &junitProperties{}
+			for _, tag := range tags {
+				tc.Properties.Properties = append(tc.Properties.Properties, junitProperty{Name: "tag", Value: tag})
+			}
+		}
+		switch testStatus(test) {
+		case "fail":
+			msg := test.Error().Error()
+			if i := strings.IndexByte(msg, '\n'); i >= 0 {
+				msg = msg[:i]
+			}
+			tc.Failure = &junitFailure{
+				Message: msg,
+				Body:    test.Error().Error(),
+			}
+			suite.Failures++
+			r.Failures++
+		case "skip":
+			tc.Skipped = &struct{}{}
+			suite.Skipped++
+			r.Skipped++
+		}
+		suite.Tests++
+		suite.Time += tc.Time
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/csv"
+	"github.com/InfluxCommunity/flux/dependencies/filesystem"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+const (
+	// snapshotYield is the prefix of the results
+	// that testing.snapshot yields.
+	snapshotYield = "_snapshot/"
+	// snapshotDir is the directory next to a test file
+	// that contains the snapshots of its test cases.
+	snapshotDir = "__snapshots__"
+)
+
+// snapshots reads and writes the snapshots of a test.
+//
+// A snapshot is stored as annotated CSV in the file
+// __snapshots__/<testcase>/<name>.csv next to the test file.
+type snapshots struct {
+	fs  filesystem.Service
+	dir string
+	// writable is true if the snapshots are in a directory
+	// and false if they are in an archive.
+	writable bool
+	// update is true if the snapshots are written
+	// instead of compared.
+	update bool
+}
+
+func newSnapshots(fs filesystem.Service, path, testName string) *snapshots {
+	_, writable := fs.(systemfs)
+	return &snapshots{
+		fs:       fs,
+		dir:      filepath.Join(filepath.Dir(path), snapshotDir, testName),
+		writable: writable,
+	}
+}
+
+// isSnapshotFile reports whether the file is the snapshot of a test case.
+func isSnapshotFile(fi os.FileInfo, filename string) bool {
+	return !fi.IsDir() && strings.HasSuffix(filename, ".csv") &&
+		filepath.Base(filepath.Dir(filepath.Dir(filename))) == snapshotDir
+}
+
+// snapshotName returns the name of the snapshot
+// if the result was yielded by testing.snapshot.
+func snapshotName(resultName string) (string, bool) {
+	if !strings.HasPrefix(resultName, snapshotYield) {
+		return "", false
+	}
+	return strings.TrimPrefix(resultName, snapshotYield), true
+}
+
+// Check compares the result with its snapshot and returns their
+// diff or an empty string if they are equal. When the snapshots are
+// updated, the result replaces the snapshot instead.
+func (s *snapshots) Check(name string, result flux.Result) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", errors.Newf(codes.Invalid, "invalid snapshot name %q", name)
+	}
+	path := filepath.Join(s.dir, name+".csv")
+
+	var got bytes.Buffer
+	enc := csv.NewResultEncoder(csv.DefaultEncoderConfig())
+	if _, err := enc.Encode(&got, result); err != nil {
+		return "", err
+	}
+
+	if s.update {
+		if !s.writable {
+			return "", errors.Newf(codes.Invalid, "cannot update snapshot %q of a test in an archive", path)
+		}
+		if err := os.MkdirAll(s.dir, 0755); err != nil {
+			return "", err
+		}
+		return "", os.WriteFile(path, got.Bytes(), 0644)
+	}
+
+	want, err := s.read(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errors.Newf(codes.NotFound, "snapshot %q does not exist, run with --update-snapshots to create it", path)
+	} else if err != nil {
+		return "", err
+	}
+	if bytes.Equal(want, got.Bytes()) {
+		return "", nil
+	}
+
+	wantTables, err := decodeSnapshot(want)
+	if err != nil {
+		return "", errors.Wrapf(err, codes.Invalid, "could not decode snapshot %q", path)
+	}
+	gotTables, err := decodeSnapshot(got.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return table.Diff(wantTables, gotTables), nil
+}
+
+func (s *snapshots) read(path string) ([]byte, error) {
+	f, err := s.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(f)
+}
+
+// decodeSnapshot decodes the tables of a snapshot.
+func decodeSnapshot(data []byte) (flux.TableIterator, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	dec := csv.NewResultDecoder(csv.ResultDecoderConfig{})
+	result, err := dec.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return result.Tables(), nil
+}
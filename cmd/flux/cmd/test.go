@@ -16,6 +16,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/InfluxCommunity/flux"
 	"github.com/InfluxCommunity/flux/ast"
@@ -30,6 +31,7 @@ import (
 	"github.com/InfluxCommunity/flux/fluxinit"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/parser"
+	"github.com/InfluxCommunity/flux/runtime"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -37,15 +39,18 @@ import (
 const errorYield = "errorOutput"
 
 type TestFlags struct {
-	testNames     []string
-	testTags      []string
-	paths         []string
-	skipTestCases []string
-	features      string
-	skipUntagged  bool
-	parallel      bool
-	verbosity     int
-	noinit        bool
+	testNames       []string
+	testTags        []string
+	paths           []string
+	skipTestCases   []string
+	features        string
+	skipUntagged    bool
+	parallel        bool
+	verbosity       int
+	noinit          bool
+	reports         []string
+	coverage        bool
+	updateSnapshots bool
 }
 
 type failedTests struct{}
@@ -94,6 +99,9 @@ error running the tests or at least one test failed.
 	testCommand.Flags().BoolVarP(&flags.parallel, "parallel", "", false, "Enables parallel test execution.")
 	testCommand.Flags().CountVarP(&flags.verbosity, "verbose", "v", "verbose (-v, -vv, or -vvv)")
 	testCommand.Flags().BoolVarP(&flags.noinit, "noinit", "", false, "Disables Flux initialization, used for testing this command.")
+	testCommand.Flags().StringSliceVar(&flags.reports, "report", []string{}, "List of reports to write as format=path. Valid formats are junit and json.")
+	testCommand.Flags().BoolVar(&flags.coverage, "coverage", false, "Report which stdlib functions are called by the tests that ran. Calls are counted while the tests run, including calls made by other stdlib functions.")
+	testCommand.Flags().BoolVar(&flags.updateSnapshots, "update-snapshots", false, "Write the output of testing.snapshot instead of comparing it with the stored snapshots.")
 
 	testCommand.SetOutput(color.Output)
 
@@ -112,7 +120,13 @@ func runFluxTests(out io.Writer, setup TestSetupFunc, flags TestFlags) (bool, er
 		verbosity: flags.verbosity,
 	}
 
+	reports, err := parseReports(flags.reports)
+	if err != nil {
+		return false, err
+	}
+
 	runner := NewTestRunner(reporter)
+	defer runner.Close()
 	if err := runner.Gather(flags.paths); err != nil {
 		return false, err
 	}
@@ -122,26 +136,49 @@ func runFluxTests(out io.Writer, setup TestSetupFunc, flags TestFlags) (bool, er
 	}
 
 	runner.MarkSkipped(flags.testNames, flags.skipTestCases, flags.testTags, flags.skipUntagged)
+	if flags.updateSnapshots {
+		runner.UpdateSnapshots()
+	}
 
-	ctx := context.Background()
-
-	ctx, err := WithFeatureFlags(ctx, flags.features)
+	ctx, err := WithFeatureFlags(context.Background(), flags.features)
 	if err != nil {
 		return false, err
 	}
 
+	var coverage *Coverage
+	if flags.coverage {
+		coverage, err = NewCoverage(runtime.StdLib(), runtime.StdLibPackages())
+		if err != nil {
+			return false, err
+		}
+		ctx = coverage.Inject(ctx)
+	}
+
 	executor, err := setup(ctx)
 	if err != nil {
 		return false, err
 	}
 	defer func() { _ = executor.Close() }()
 
+	start := time.Now()
 	if flags.parallel {
 		runner.RunParallel(executor, flags.verbosity)
 	} else {
 		runner.Run(executor, flags.verbosity)
 	}
-	return runner.Finish(), nil
+	elapsed := time.Since(start)
+	passed := runner.Finish()
+
+	if coverage != nil {
+		reporter.ReportCoverage(coverage)
+	}
+
+	for _, r := range reports {
+		if err := r.writeFile(runner.tests, elapsed, coverage); err != nil {
+			return false, err
+		}
+	}
+	return passed, nil
 }
 
 var defaultCmdFeatureFlags = executetest.TestFlagger{
@@ -170,6 +207,10 @@ type Test struct {
 	// indicates if the test should be skipped
 	skip bool
 	err  error
+	// how long the test took to run
+	duration time.Duration
+	// snapshots of the test case
+	snapshots *snapshots
 }
 
 // NewTest creates a new Test instance from an ast.Package.
@@ -195,6 +236,16 @@ func (t *Test) PackageName() string {
 	return t.pkg
 }
 
+// Tags returns the tags of the test.
+func (t *Test) Tags() []string {
+	return t.tags
+}
+
+// Duration returns how long the test took to run.
+func (t *Test) Duration() time.Duration {
+	return t.duration
+}
+
 // Get the error from the test, if one exists.
 func (t *Test) Error() error {
 	return t.err
@@ -202,8 +253,9 @@ func (t *Test) Error() error {
 
 // Run the test, saving the error to the err property of the struct.
 func (t *Test) Run(executor TestExecutor) {
+	start := time.Now()
 	t.err = executor.Run(t.ast, t.consume)
-
+	t.duration = time.Since(start)
 }
 
 func (t *Test) consume(ctx context.Context, results flux.ResultIterator) error {
@@ -211,7 +263,18 @@ func (t *Test) consume(ctx context.Context, results flux.ResultIterator) error {
 	foundTestError := false
 	for results.More() {
 		result := results.Next()
-		if result.Name() == errorYield {
+		if name, ok := snapshotName(result.Name()); ok {
+			// The result is compared with the snapshot
+			// instead of being part of the output.
+			diff, err := t.snapshots.Check(name, result)
+			if err != nil {
+				return err
+			}
+			if diff != "" {
+				fmt.Fprintf(&output, "SNAPSHOT: %s\n%s\n", name, diff)
+				foundTestError = true
+			}
+		} else if result.Name() == errorYield {
 			lenBeforeError := output.Len()
 			err := result.Tables().Do(func(tbl flux.Table) error {
 				// The data returned here is the result of `testing.diff`, so any result means that
@@ -303,6 +366,9 @@ type TestRunner struct {
 	tests     []*Test
 	validTags []string
 	reporter  TestReporter
+	// filesystems of the tests, which stay open
+	// so that the tests can read their snapshots
+	fss []fs
 }
 
 // NewTestRunner returns a new TestRunner.
@@ -342,7 +408,7 @@ func (t *TestRunner) Gather(roots []string) error {
 		if err != nil {
 			return err
 		}
-		defer func() { _ = fs.Close() }()
+		t.fss = append(t.fss, fs)
 
 		// Gather valid tags from modules
 		for _, m := range mods {
@@ -387,6 +453,7 @@ func (t *TestRunner) Gather(roots []string) error {
 					return errors.Newf(codes.AlreadyExists, "duplicate testcase name %q, found in package %q, at locations %v and %v", tcidens[i].Name, pkg, seen[pkgTest].loc.String(), tcidens[i].Loc.String())
 				}
 				test := NewTest(tcidens[i].Name, astf, tags, pkg)
+				test.snapshots = newSnapshots(fs, file.path, tcidens[i].Name)
 				t.tests = append(t.tests, &test)
 				seen[pkgTest] = testcaseLoc{tcidens[i].Loc}
 			}
@@ -396,6 +463,22 @@ func (t *TestRunner) Gather(roots []string) error {
 	return nil
 }
 
+// UpdateSnapshots makes the tests write their snapshots
+// instead of comparing their output with them.
+func (t *TestRunner) UpdateSnapshots() {
+	for _, test := range t.tests {
+		test.snapshots.update = true
+	}
+}
+
+// Close closes the filesystems of the tests.
+func (t *TestRunner) Close() {
+	for _, fs := range t.fss {
+		_ = fs.Close()
+	}
+	t.fss = nil
+}
+
 // invalidTags returns all tags that are not in the valid set.
 func invalidTags(tags, valid []string) []string {
 	var invalid []string
@@ -554,7 +637,7 @@ func gatherFromTarArchive(filename string) ([]testFile, fs, testcase.TestModules
 		}
 
 		info := hdr.FileInfo()
-		if !isTestFile(info, hdr.Name) {
+		if !isTestFile(info, hdr.Name) && !isSnapshotFile(info, hdr.Name) {
 			if isTestRoot(hdr.Name) {
 				name, tags, err := readTestRoot(archive, nil)
 				if err != nil {
@@ -582,9 +665,11 @@ func gatherFromTarArchive(filename string) ([]testFile, fs, testcase.TestModules
 			data: source,
 			info: info,
 		}
-		files = append(files, testFile{
-			path: hdr.Name,
-		})
+		if isTestFile(info, hdr.Name) {
+			files = append(files, testFile{
+				path: hdr.Name,
+			})
+		}
 	}
 	roots.Assign(files)
 	return files, tfs, modules, nil
@@ -689,7 +774,7 @@ func (z *zipfs) Open(fpath string) (filesystem.File, error) {
 	for _, f := range z.r.File {
 		if filepath.Clean(f.Name) == fpath {
 			fi := f.FileInfo()
-			if !isTestFile(fi, fpath) {
+			if !isTestFile(fi, fpath) && !isSnapshotFile(fi, fpath) {
 				return nil, os.ErrNotExist
 			}
 
@@ -726,7 +811,7 @@ func (s systemfs) Open(fpath string) (filesystem.File, error) {
 	st, err := f.Stat()
 	if err != nil {
 		return nil, err
-	} else if !isTestFile(st, fpath) {
+	} else if !isTestFile(st, fpath) && !isSnapshotFile(st, fpath) {
 		_ = f.Close()
 		return nil, os.ErrNotExist
 	}
@@ -1028,7 +1113,29 @@ func (t *TestReporter) Summarize(tests []*Test) bool {
 	return failures == 0
 }
 
+// ReportCoverage reports the functions of each package that the tests called.
+// The functions that were not called are listed when the verbosity is set.
+func (t *TestReporter) ReportCoverage(coverage *Coverage) {
+	fmt.Fprintf(t.out, "\ncoverage:\n")
+	for _, p := range coverage.Packages() {
+		covered := p.Covered()
+		fmt.Fprintf(t.out, "\t%s: %d/%d functions (%.1f%%)\n", p.Path, covered, len(p.Functions), 100*float64(covered)/float64(len(p.Functions)))
+		if t.verbosity == 0 {
+			continue
+		}
+		for _, fn := range p.Functions {
+			if fn.Calls == 0 {
+				fmt.Fprintf(t.out, "\t\t%s ... %s\n", fn.Name, color.RedString("not called"))
+			}
+		}
+	}
+}
+
 type (
+	// TestSetupFunc creates the TestExecutor that runs the tests.
+	// The executor should run the tests with contexts derived from
+	// the context that is passed to it so that they are part of
+	// the coverage.
 	TestSetupFunc func(ctx context.Context) (TestExecutor, error)
 
 	// TestResultFunc is a function that processes the result of running a test file.
@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/csv"
+	"github.com/InfluxCommunity/flux/encoders"
 	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/lang"
 	"github.com/InfluxCommunity/flux/memory"
 	"github.com/InfluxCommunity/flux/runtime"
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
 )
 
-func executeE(ctx context.Context, script, format string) error {
+func executeE(ctx context.Context, script, format string, extern json.RawMessage) error {
+	var encoder flux.MultiResultEncoder
+	if format != "cli" {
+		var err error
+		if encoder, err = newEncoder(format); err != nil {
+			return err
+		}
+	}
+
 	c := lang.FluxCompiler{
-		Query: script,
+		Extern: extern,
+		Query:  script,
 	}
 	prog, err := c.Compile(ctx, runtime.Default)
 	if err != nil {
@@ -42,14 +56,31 @@ func executeE(ctx context.Context, script, format string) error {
 				return err
 			}
 		}
-	} else if format == "csv" {
-		config := csv.DefaultEncoderConfig()
-		encoder := csv.NewMultiResultEncoder(config)
-		_, err := encoder.Encode(os.Stdout, results)
-		if err != nil {
+	} else {
+		if _, err := encoder.Encode(os.Stdout, results); err != nil {
 			return err
 		}
 	}
 	results.Release()
 	return results.Err()
 }
+
+// newEncoder returns the encoder for an output format other than cli.
+func newEncoder(format string) (flux.MultiResultEncoder, error) {
+	switch format {
+	case "csv":
+		return csv.NewMultiResultEncoder(csv.DefaultEncoderConfig()), nil
+	case "json":
+		return encoders.NewJSONMultiResultEncoder(), nil
+	case "ndjson":
+		return encoders.NewNDJSONMultiResultEncoder(), nil
+	case "arrow":
+		return encoders.NewArrowMultiResultEncoder(arrowmem.DefaultAllocator), nil
+	case "parquet":
+		return encoders.NewParquetMultiResultEncoder(arrowmem.DefaultAllocator), nil
+	case "markdown":
+		return encoders.NewMarkdownMultiResultEncoder(), nil
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown output format %q, must be one of cli, csv, json, ndjson, arrow, parquet or markdown", format)
+	}
+}
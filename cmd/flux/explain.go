@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/InfluxCommunity/flux"
+	fluxcmd "github.com/InfluxCommunity/flux/cmd/flux/cmd"
+	"github.com/InfluxCommunity/flux/fluxinit"
+	"github.com/InfluxCommunity/flux/lang"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/spf13/cobra"
+)
+
+var explainFlags struct {
+	Analyze bool
+}
+
+func explainE(cmd *cobra.Command, args []string) error {
+	script := args[0]
+	if !flags.ExecScript {
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		script = string(content)
+	}
+
+	fluxinit.FluxInit()
+	ctx, span := injectDependencies(context.Background())
+	defer span.Finish()
+
+	ctx, err := fluxcmd.WithFeatureFlags(ctx, flags.Features)
+	if err != nil {
+		return err
+	}
+
+	extern, err := parseParams(flags.Params, flags.ParamFiles)
+	if err != nil {
+		return err
+	}
+
+	mode := lang.ExplainPlan
+	if explainFlags.Analyze {
+		mode = lang.ExplainAnalyze
+	}
+	c := lang.FluxCompiler{
+		Extern:  extern,
+		Query:   script,
+		Explain: mode,
+	}
+	prog, err := c.Compile(ctx, runtime.Default)
+	if err != nil {
+		return err
+	}
+
+	q, err := prog.Start(ctx, &memory.ResourceAllocator{})
+	if err != nil {
+		return err
+	}
+
+	results := flux.NewResultIteratorFromQuery(q)
+	defer results.Release()
+
+	for results.More() {
+		if err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(cr flux.ColReader) error {
+				lines := cr.Strings(0)
+				for i := 0; i < lines.Len(); i++ {
+					fmt.Println(lines.Value(i))
+				}
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+	}
+	results.Release()
+	return results.Err()
+}
@@ -26,6 +26,8 @@ var flags struct {
 	Format            string
 	Features          string
 	EnableSuggestions bool
+	Params            []string
+	ParamFiles        []string
 }
 
 func runE(cmd *cobra.Command, args []string) error {
@@ -60,6 +62,11 @@ func runE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	extern, err := parseParams(flags.Params, flags.ParamFiles)
+	if err != nil {
+		return err
+	}
+
 	var opts []repl.Option
 	if flags.EnableSuggestions {
 		opts = append(opts, repl.EnableSuggestions())
@@ -68,7 +75,7 @@ func runE(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		return replE(ctx, opts...)
 	}
-	return executeE(ctx, script, flags.Format)
+	return executeE(ctx, script, flags.Format, extern)
 }
 
 func configureTracing(ctx context.Context) (context.Context, func(), error) {
@@ -121,7 +128,9 @@ func main() {
 	fluxCmd.Flags().BoolVarP(&flags.ExecScript, "exec", "e", false, "Interpret file argument as a raw flux script")
 	fluxCmd.Flags().BoolVarP(&flags.EnableSuggestions, "enable-suggestions", "", false, "enable suggestions in the repl")
 	fluxCmd.Flags().StringVar(&flags.Trace, "trace", "", "Trace query execution")
-	fluxCmd.Flags().StringVarP(&flags.Format, "format", "", "cli", "Output format one of: cli,csv. Defaults to cli")
+	fluxCmd.Flags().StringVarP(&flags.Format, "format", "", "cli", "Output format one of: cli,csv,json,ndjson,arrow,parquet,markdown. Defaults to cli")
+	fluxCmd.Flags().StringArrayVar(&flags.Params, "param", nil, "Set a value of the params option as name=value or name:type=value, where type is one of string, int, float, bool, duration or time. May be repeated")
+	fluxCmd.Flags().StringArrayVar(&flags.ParamFiles, "param-file", nil, "Read values of the params option from a JSON object in the file. May be repeated and is overridden by --param")
 	fluxCmd.Flag("trace").NoOptDefVal = "jaeger"
 	fluxCmd.Flags().StringVar(&flags.Features, "features", "", "JSON object specifying the features to execute with. See internal/feature/flags.yml for a list of the current features")
 
@@ -136,6 +145,20 @@ func main() {
 	fmtCmd.Flags().BoolVarP(&fmtFlags.AnalyzeCurrentDirectory, "analyze-current-directory", "c", false, "analyze the current <directory | file> and report if file(s) are not formatted")
 	fluxCmd.AddCommand(fmtCmd)
 
+	explainCmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Explain how a Flux script is planned",
+		Long:  "Print the logical and physical plans of a Flux script and the planner rules that were applied (flux explain [--analyze] <file>)",
+		Args:  cobra.ExactArgs(1),
+		RunE:  explainE,
+	}
+	explainCmd.Flags().BoolVar(&explainFlags.Analyze, "analyze", false, "Execute the script and include the rows, memory and time of every operation")
+	explainCmd.Flags().BoolVarP(&flags.ExecScript, "exec", "e", false, "Interpret file argument as a raw flux script")
+	explainCmd.Flags().StringArrayVar(&flags.Params, "param", nil, "Set a value of the params option as name=value or name:type=value. May be repeated")
+	explainCmd.Flags().StringArrayVar(&flags.ParamFiles, "param-file", nil, "Read values of the params option from a JSON object in the file. May be repeated")
+	explainCmd.Flags().StringVar(&flags.Features, "features", "", "JSON object specifying the features to execute with")
+	fluxCmd.AddCommand(explainCmd)
+
 	testCmd := fluxcmd.TestCommand(NewTestExecutor)
 	fluxCmd.AddCommand(testCmd)
 
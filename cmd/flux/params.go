@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux/ast"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/parser"
+)
+
+// paramsOption is the name of the option the parameters are assigned to.
+const paramsOption = "params"
+
+// Parameter types that may be given explicitly with name:type=value.
+const (
+	paramString   = "string"
+	paramInt      = "int"
+	paramFloat    = "float"
+	paramBool     = "bool"
+	paramDuration = "duration"
+	paramTime     = "time"
+)
+
+// parseParams builds the extern that assigns the parameters from the
+// parameter files and the --param flags to the params option.
+// Parameter files are read in order and the --param flags override
+// the values they define. It returns nil when there are no parameters.
+func parseParams(params, files []string) (json.RawMessage, error) {
+	values := make(map[string]ast.Expression)
+	for _, file := range files {
+		if err := readParamFile(file, values); err != nil {
+			return nil, err
+		}
+	}
+	for _, param := range params {
+		eq := strings.Index(param, "=")
+		if eq < 0 {
+			return nil, errors.Newf(codes.Invalid, "invalid param %q, must be of the form name=value or name:type=value", param)
+		}
+		name, typ := splitParamName(param[:eq])
+		v, err := parseParamValue(name, typ, param[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+		values[name] = v
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return paramsExtern(values)
+}
+
+// splitParamName splits a parameter name of the form name:type.
+func splitParamName(s string) (name, typ string) {
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// parseParamValue converts the string value of a parameter to a literal.
+// Without an explicit type the value is an int, duration or time when it
+// parses as one and a string otherwise.
+func parseParamValue(name, typ, value string) (ast.Expression, error) {
+	if err := validateParamName(name); err != nil {
+		return nil, err
+	}
+	if typ == "" {
+		for _, typ := range []string{paramInt, paramDuration, paramTime} {
+			if lit, err := parseLiteral(typ, value); err == nil {
+				return lit, nil
+			}
+		}
+		return &ast.StringLiteral{Value: value}, nil
+	}
+	lit, err := parseLiteral(typ, value)
+	if err != nil {
+		return nil, errors.Wrapf(err, codes.Invalid, "invalid value for param %q", name)
+	}
+	return lit, nil
+}
+
+func parseLiteral(typ, value string) (ast.Expression, error) {
+	switch typ {
+	case paramString:
+		return &ast.StringLiteral{Value: value}, nil
+	case paramInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "cannot parse %q as an int", value)
+		}
+		return &ast.IntegerLiteral{Value: n}, nil
+	case paramFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "cannot parse %q as a float", value)
+		}
+		return &ast.FloatLiteral{Value: f}, nil
+	case paramBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "cannot parse %q as a bool", value)
+		}
+		return &ast.BooleanLiteral{Value: b}, nil
+	case paramDuration:
+		d, err := parser.ParseSignedDuration(value)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "cannot parse %q as a duration", value)
+		}
+		d.BaseNode = ast.BaseNode{}
+		return d, nil
+	case paramTime:
+		t, err := parser.ParseTime(value)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "cannot parse %q as a time", value)
+		}
+		t.BaseNode = ast.BaseNode{}
+		return t, nil
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown param type %q, must be one of %s",
+			typ, strings.Join([]string{paramString, paramInt, paramFloat, paramBool, paramDuration, paramTime}, ", "))
+	}
+}
+
+// validateParamName checks that the name is a valid Flux identifier.
+func validateParamName(name string) error {
+	if name == "" {
+		return errors.New(codes.Invalid, "param name must not be empty")
+	}
+	for i, r := range name {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+		return errors.Newf(codes.Invalid, "invalid param name %q", name)
+	}
+	return nil
+}
+
+// readParamFile reads the parameters from a JSON object in the file.
+// JSON strings, numbers, booleans, arrays and objects are converted to
+// the matching Flux literals. A key of the form name:type parses a
+// string value with that type, for example "start:time".
+func readParamFile(file string, values map[string]ast.Expression) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return errors.Wrapf(err, codes.Invalid, "param file %s must contain a JSON object", file)
+	}
+	for key, v := range obj {
+		name, typ := splitParamName(key)
+		if err := validateParamName(name); err != nil {
+			return err
+		}
+		var lit ast.Expression
+		if typ != "" {
+			s, ok := v.(string)
+			if !ok {
+				return errors.Newf(codes.Invalid, "param %q with type %s must be a string in %s", name, typ, file)
+			}
+			lit, err = parseParamValue(name, typ, s)
+		} else {
+			lit, err = jsonLiteral(v)
+		}
+		if err != nil {
+			return errors.Wrapf(err, codes.Inherit, "invalid param %q in %s", name, file)
+		}
+		values[name] = lit
+	}
+	return nil
+}
+
+// jsonLiteral converts a decoded JSON value to a Flux expression.
+func jsonLiteral(v interface{}) (ast.Expression, error) {
+	switch v := v.(type) {
+	case string:
+		return &ast.StringLiteral{Value: v}, nil
+	case bool:
+		return &ast.BooleanLiteral{Value: v}, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return &ast.IntegerLiteral{Value: n}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "cannot convert number %s", v)
+		}
+		return &ast.FloatLiteral{Value: f}, nil
+	case []interface{}:
+		arr := &ast.ArrayExpression{Elements: make([]ast.Expression, 0, len(v))}
+		for _, elem := range v {
+			e, err := jsonLiteral(elem)
+			if err != nil {
+				return nil, err
+			}
+			arr.Elements = append(arr.Elements, e)
+		}
+		return arr, nil
+	case map[string]interface{}:
+		properties := make(map[string]ast.Expression, len(v))
+		for key, elem := range v {
+			e, err := jsonLiteral(elem)
+			if err != nil {
+				return nil, err
+			}
+			properties[key] = e
+		}
+		return objectExpression(properties), nil
+	case nil:
+		return nil, errors.New(codes.Invalid, "null values are not supported")
+	default:
+		return nil, errors.Newf(codes.Invalid, "unsupported JSON value %v", v)
+	}
+}
+
+// objectExpression returns an object with the properties sorted by key.
+// Keys that are not valid identifiers are written as string literals.
+func objectExpression(properties map[string]ast.Expression) *ast.ObjectExpression {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	obj := &ast.ObjectExpression{Properties: make([]*ast.Property, 0, len(keys))}
+	for _, k := range keys {
+		var key ast.PropertyKey = &ast.Identifier{Name: k}
+		if validateParamName(k) != nil {
+			key = &ast.StringLiteral{Value: k}
+		}
+		obj.Properties = append(obj.Properties, &ast.Property{
+			Key:   key,
+			Value: properties[k],
+		})
+	}
+	return obj
+}
+
+// paramsExtern returns the JSON encoded file that assigns the
+// parameters to the params option.
+func paramsExtern(values map[string]ast.Expression) (json.RawMessage, error) {
+	file := &ast.File{
+		Body: []ast.Statement{
+			&ast.OptionStatement{
+				Assignment: &ast.VariableAssignment{
+					ID:   &ast.Identifier{Name: paramsOption},
+					Init: objectExpression(values),
+				},
+			},
+		},
+	}
+	return json.Marshal(file)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux/ast"
+	"github.com/google/go-cmp/cmp"
+)
+
+// decodeParams returns the object assigned to the params option by the extern.
+func decodeParams(t *testing.T, extern json.RawMessage) *ast.ObjectExpression {
+	t.Helper()
+	var file ast.File
+	if err := json.Unmarshal(extern, &file); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Body) != 1 {
+		t.Fatalf("expected a single statement, got %d", len(file.Body))
+	}
+	stmt, ok := file.Body[0].(*ast.OptionStatement)
+	if !ok {
+		t.Fatalf("expected an option statement, got %T", file.Body[0])
+	}
+	assign := stmt.Assignment.(*ast.VariableAssignment)
+	if want, got := "params", assign.ID.Name; want != got {
+		t.Fatalf("unexpected option name -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	return assign.Init.(*ast.ObjectExpression)
+}
+
+func property(name string, value ast.Expression) *ast.Property {
+	return &ast.Property{Key: &ast.Identifier{Name: name}, Value: value}
+}
+
+func TestParseParams(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "params.json")
+	if err := os.WriteFile(file, []byte(`{
+	"bucket": "telegraf",
+	"limit": 10,
+	"ratio": 0.5,
+	"debug": true,
+	"hosts": ["a", "b"],
+	"tags": {"env": "prod", "rack-id": 7},
+	"start:time": "2023-01-01T00:00:00Z",
+	"every": "1m"
+}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	extern, err := parseParams([]string{
+		"every=5m",
+		"offset=-1h30m",
+		"name=cpu",
+		"count=42",
+		"id:string=42",
+		"threshold:float=1.5",
+		"stop:time=2023-01-02",
+	}, []string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ast.ObjectExpression{
+		Properties: []*ast.Property{
+			property("bucket", &ast.StringLiteral{Value: "telegraf"}),
+			property("count", &ast.IntegerLiteral{Value: 42}),
+			property("debug", &ast.BooleanLiteral{Value: true}),
+			property("every", &ast.DurationLiteral{Values: []ast.Duration{{Magnitude: 5, Unit: "m"}}}),
+			property("hosts", &ast.ArrayExpression{Elements: []ast.Expression{
+				&ast.StringLiteral{Value: "a"},
+				&ast.StringLiteral{Value: "b"},
+			}}),
+			property("id", &ast.StringLiteral{Value: "42"}),
+			property("limit", &ast.IntegerLiteral{Value: 10}),
+			property("name", &ast.StringLiteral{Value: "cpu"}),
+			property("offset", &ast.DurationLiteral{Values: []ast.Duration{
+				{Magnitude: -1, Unit: "h"},
+				{Magnitude: -30, Unit: "m"},
+			}}),
+			property("ratio", &ast.FloatLiteral{Value: 0.5}),
+			property("start", &ast.DateTimeLiteral{Value: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}),
+			property("stop", &ast.DateTimeLiteral{Value: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}),
+			property("tags", &ast.ObjectExpression{Properties: []*ast.Property{
+				property("env", &ast.StringLiteral{Value: "prod"}),
+				{Key: &ast.StringLiteral{Value: "rack-id"}, Value: &ast.IntegerLiteral{Value: 7}},
+			}}),
+			property("threshold", &ast.FloatLiteral{Value: 1.5}),
+		},
+	}
+	if got := decodeParams(t, extern); !cmp.Equal(want, got) {
+		t.Errorf("unexpected params -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestParseParams_None(t *testing.T) {
+	extern, err := parseParams(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extern != nil {
+		t.Errorf("expected no extern, got %s", extern)
+	}
+}
+
+func TestParseParams_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		param string
+		want  string
+	}{
+		{param: "name", want: `invalid param "name", must be of the form name=value or name:type=value`},
+		{param: "=x", want: "param name must not be empty"},
+		{param: "1a=x", want: `invalid param name "1a"`},
+		{param: "n:int=x", want: `invalid value for param "n": cannot parse "x" as an int`},
+		{param: "d:duration=5", want: `invalid value for param "d": cannot parse "5" as a duration`},
+		{param: "n:uint=1", want: `invalid value for param "n": unknown param type "uint", must be one of string, int, float, bool, duration, time`},
+	} {
+		t.Run(tc.param, func(t *testing.T) {
+			_, err := parseParams([]string{tc.param}, nil)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := err.Error(); tc.want != got {
+				t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -18,19 +18,21 @@ import (
 )
 
 func NewTestExecutor(ctx context.Context) (cmd.TestExecutor, error) {
-	return testExecutor{}, nil
+	return testExecutor{ctx: ctx}, nil
 }
 
-type testExecutor struct{}
+type testExecutor struct {
+	ctx context.Context
+}
 
-func (testExecutor) Run(pkg *ast.Package, fn cmd.TestResultFunc) error {
+func (e testExecutor) Run(pkg *ast.Package, fn cmd.TestResultFunc) error {
 	jsonAST, err := json.Marshal(pkg)
 	if err != nil {
 		return err
 	}
 	c := lang.ASTCompiler{AST: jsonAST}
 
-	ctx, span := dependency.Inject(context.Background(),
+	ctx, span := dependency.Inject(e.ctx,
 		executetest.NewTestExecuteDependencies(),
 		testing.FrameworkConfig{},
 	)
@@ -5,6 +5,8 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"io"
 	"io/fs"
@@ -421,3 +423,156 @@ func Test_TestCmd_SkipUntagged(t *testing.T) {
 		}
 	}
 }
+
+func Test_TestCmd_Report(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "out.json")
+	junitPath := filepath.Join(dir, "out.xml")
+	runForPath(t, "./testdata", errors.New("tests failed"),
+		"--tags", "fail",
+		"--report", "json="+jsonPath,
+		"--report", "junit="+junitPath,
+		"--coverage",
+	)
+
+	var report struct {
+		Summary struct {
+			Found   int64
+			Passed  int64
+			Failed  int64
+			Skipped int64
+		}
+		Tests []struct {
+			Name    string
+			Package string
+			Tags    []string
+			Status  string
+			Failure string
+		}
+		Coverage []struct {
+			Path      string
+			Functions []struct {
+				Name  string
+				Calls int
+			}
+		}
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	want := Summary{
+		Found:   9,
+		Passed:  3,
+		Failed:  1,
+		Skipped: 5,
+	}
+	if got := Summary(report.Summary); want != got {
+		t.Errorf("unexpected summary got %+v want %+v", got, want)
+	}
+	for _, test := range report.Tests {
+		if test.Name != "fails" {
+			continue
+		}
+		if test.Package != "test" || test.Status != "fail" || len(test.Tags) != 1 || test.Tags[0] != "fail" {
+			t.Errorf("unexpected test %+v", test)
+		}
+		if !strings.Contains(test.Failure, "_diff") {
+			t.Errorf("expected the failure to contain the diff, got %q", test.Failure)
+		}
+	}
+
+	calls := make(map[string]int)
+	for _, p := range report.Coverage {
+		for _, fn := range p.Functions {
+			calls[p.Path+"."+fn.Name] = fn.Calls
+		}
+	}
+	for name, want := range map[string]int{
+		"array.from":   7,
+		"testing.diff": 3,
+		// experimental.diff is only called by testing.diff.
+		"experimental.diff": 3,
+		"universe.map":      0,
+	} {
+		if got, ok := calls[name]; !ok || want != got {
+			t.Errorf("unexpected calls for %s got %d want %d", name, got, want)
+		}
+	}
+
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"testsuite"`
+	}
+	data, err = os.ReadFile(junitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 9 || suites.Failures != 1 || suites.Skipped != 5 || len(suites.Suites) != 3 {
+		t.Errorf("unexpected junit report %+v", suites)
+	}
+}
+
+func Test_TestCmd_InvalidReport(t *testing.T) {
+	runForPath(t, "./testdata", errors.New(`unknown report format "html", valid formats are junit and json`), "--report", "html=out.html")
+}
+
+func Test_TestCmd_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	src := `package snapshot_test
+
+
+import "array"
+import "testing"
+
+testcase snapshot {
+    array.from(rows: [{_value: 1}])
+        |> testing.snapshot(name: "values")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "snapshot_test.flux"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test fails until the snapshot is created.
+	want := Summary{Found: 1, Failed: 1}
+	if got := runForPath(t, dir, errors.New("tests failed")); want != got {
+		t.Errorf("unexpected summary got %+v want %+v", got, want)
+	}
+
+	want = Summary{Found: 1, Passed: 1}
+	if got := runForPath(t, dir, nil, "--update-snapshots"); want != got {
+		t.Errorf("unexpected summary got %+v want %+v", got, want)
+	}
+	path := filepath.Join(dir, "__snapshots__", "snapshot", "values.csv")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runForPath(t, dir, nil); want != got {
+		t.Errorf("unexpected summary got %+v want %+v", got, want)
+	}
+
+	// The test fails when the output differs from the snapshot.
+	changed := bytes.Replace(data, []byte(",1\r\n"), []byte(",2\r\n"), 1)
+	if bytes.Equal(data, changed) {
+		t.Fatalf("unexpected snapshot:\n%s", data)
+	}
+	if err := os.WriteFile(path, changed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	want = Summary{Found: 1, Failed: 1}
+	if got := runForPath(t, dir, errors.New("tests failed")); want != got {
+		t.Errorf("unexpected summary got %+v want %+v", got, want)
+	}
+}
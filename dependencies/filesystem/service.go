@@ -50,3 +50,36 @@ func Get(ctx context.Context) (Service, error) {
 	}
 	return s.(Service), nil
 }
+
+// TempFile is a temporary file that can be written and read back.
+type TempFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Name() string
+}
+
+// TempFileService is implemented by a Service that can create
+// temporary files, such as the files used to spill data to disk.
+type TempFileService interface {
+	// CreateTemp creates a new temporary file in the directory.
+	// The pattern has the same meaning as in os.CreateTemp.
+	// If dir is empty, the default directory for temporary files is used.
+	CreateTemp(dir, pattern string) (TempFile, error)
+
+	// Remove removes the named file.
+	Remove(name string) error
+}
+
+// GetTempFileService will retrieve the filesystem Service from the
+// context.Context if it can create temporary files.
+func GetTempFileService(ctx context.Context) (TempFileService, error) {
+	fs, err := Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tfs, ok := fs.(TempFileService)
+	if !ok {
+		return nil, errors.New(codes.Unimplemented, "filesystem service does not support temporary files")
+	}
+	return tfs, nil
+}
@@ -17,3 +17,11 @@ func (systemFS) Open(fpath string) (File, error) {
 	}
 	return f, nil
 }
+
+func (systemFS) CreateTemp(dir, pattern string) (TempFile, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+func (systemFS) Remove(name string) error {
+	return os.Remove(name)
+}
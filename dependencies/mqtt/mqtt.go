@@ -74,6 +74,20 @@ type Client interface {
 	io.Closer
 }
 
+// Message is a message that is received from a subscription.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Subscriber is implemented by a Client that can subscribe to topics.
+type Subscriber interface {
+	// Subscribe will call handler with each message that is published to a topic
+	// that matches the topic filter until the returned unsubscribe function is called.
+	// The handler may be called concurrently with the caller.
+	Subscribe(ctx context.Context, topic string, qos byte, handler func(Message)) (unsubscribe func() error, err error)
+}
+
 // DefaultDialer is the default dialer that uses the default mqtt client.
 type DefaultDialer struct{}
 
@@ -141,6 +155,24 @@ func (d *defaultClient) Publish(ctx context.Context, topic string, qos byte, ret
 	return nil
 }
 
+func (d *defaultClient) Subscribe(ctx context.Context, topic string, qos byte, handler func(Message)) (func() error, error) {
+	token := d.client.Subscribe(topic, qos, func(_ mqtt.Client, m mqtt.Message) {
+		handler(Message{Topic: m.Topic(), Payload: m.Payload()})
+	})
+	if !token.WaitTimeout(d.timeout) {
+		return nil, errors.New(codes.Canceled, "mqtt subscribe: timeout reached")
+	} else if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return func() error {
+		token := d.client.Unsubscribe(topic)
+		if !token.WaitTimeout(d.timeout) {
+			return errors.New(codes.Canceled, "mqtt unsubscribe: timeout reached")
+		}
+		return token.Error()
+	}, nil
+}
+
 func (d *defaultClient) Close() error {
 	d.client.Disconnect(250)
 	return nil
@@ -220,6 +252,14 @@ type poolClient struct {
 	pool    *poolDialer
 }
 
+func (c *poolClient) Subscribe(ctx context.Context, topic string, qos byte, handler func(Message)) (func() error, error) {
+	s, ok := c.Client.(Subscriber)
+	if !ok {
+		return nil, errors.New(codes.Unimplemented, "mqtt client does not support subscribing")
+	}
+	return s.Subscribe(ctx, topic, qos, handler)
+}
+
 func (c *poolClient) Close() error {
 	c.pool.mu.Lock()
 	defer c.pool.mu.Unlock()
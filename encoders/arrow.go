@@ -0,0 +1,190 @@
+package encoders
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/iocounter"
+	"github.com/apache/arrow/go/v7/arrow"
+	"github.com/apache/arrow/go/v7/arrow/array"
+	"github.com/apache/arrow/go/v7/arrow/ipc"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+// Schema metadata keys that identify the table of an Arrow IPC stream.
+const (
+	ArrowResultKey   = "flux.result"
+	ArrowTableKey    = "flux.table"
+	ArrowGroupKeyKey = "flux.groupKey"
+)
+
+// ArrowMultiResultEncoder encodes every table as an Arrow IPC stream.
+// The streams of all tables are written one after the other, so readers
+// open a new stream reader on the same input until it is exhausted.
+//
+// The schema metadata of each stream contains the result name, the
+// table index and the JSON encoded list of group key column labels.
+// Time columns are encoded as UTC timestamps with nanosecond precision.
+//
+// The Arrow IPC format cannot represent errors, so errors from the
+// query are always returned.
+type ArrowMultiResultEncoder struct {
+	mem memory.Allocator
+}
+
+// NewArrowMultiResultEncoder creates an encoder for the Arrow IPC stream
+// format that uses mem to allocate the records it writes.
+func NewArrowMultiResultEncoder(mem memory.Allocator) flux.MultiResultEncoder {
+	return &ArrowMultiResultEncoder{mem: mem}
+}
+
+func (e *ArrowMultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	err := encodeTables(wc, results, func(result string, index int, tbl flux.Table) error {
+		schema, err := arrowSchema(tbl.Cols(), arrowTableMetadata(result, index, tbl.Key()))
+		if err != nil {
+			tbl.Done()
+			return err
+		}
+		writer := ipc.NewWriter(wc, ipc.WithSchema(schema), ipc.WithAllocator(e.mem))
+		if err := tbl.Do(func(cr flux.ColReader) error {
+			rec := newArrowRecord(e.mem, schema, cr)
+			defer rec.Release()
+			if err := writer.Write(rec); err != nil {
+				return &encoderError{format: "arrow", err: err}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return &encoderError{format: "arrow", err: err}
+		}
+		return nil
+	}, nil)
+	return wc.Count(), err
+}
+
+// arrowTableMetadata returns the schema metadata that identifies a table.
+func arrowTableMetadata(result string, index int, key flux.GroupKey) arrow.Metadata {
+	labels := make([]string, 0, len(key.Cols()))
+	for _, c := range key.Cols() {
+		labels = append(labels, c.Label)
+	}
+	groupKey, _ := json.Marshal(labels)
+	return arrow.NewMetadata(
+		[]string{ArrowResultKey, ArrowTableKey, ArrowGroupKeyKey},
+		[]string{result, strconv.Itoa(index), string(groupKey)},
+	)
+}
+
+// arrowType returns the Arrow data type used to encode a column type.
+func arrowType(typ flux.ColType) (arrow.DataType, error) {
+	switch typ {
+	case flux.TBool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case flux.TInt:
+		return arrow.PrimitiveTypes.Int64, nil
+	case flux.TUInt:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case flux.TFloat:
+		return arrow.PrimitiveTypes.Float64, nil
+	case flux.TString:
+		return arrow.BinaryTypes.String, nil
+	case flux.TTime:
+		return &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}, nil
+	default:
+		return nil, errors.Newf(codes.Internal, "unsupported column type %s", typ)
+	}
+}
+
+// arrowSchema returns the schema of a table with the given columns.
+// All fields are nullable.
+func arrowSchema(cols []flux.ColMeta, metadata arrow.Metadata) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, len(cols))
+	for j, c := range cols {
+		typ, err := arrowType(c.Type)
+		if err != nil {
+			return nil, err
+		}
+		fields[j] = arrow.Field{Name: c.Label, Type: typ, Nullable: true}
+	}
+	return arrow.NewSchema(fields, &metadata), nil
+}
+
+// newArrowRecord copies the columns of cr into a record with the schema.
+// The columns of the schema must match the columns of cr.
+func newArrowRecord(mem memory.Allocator, schema *arrow.Schema, cr flux.ColReader) arrow.Record {
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	for j := range cr.Cols() {
+		appendColumn(b.Field(j), cr, j)
+	}
+	return b.NewRecord()
+}
+
+// appendColumn appends column j of cr to a builder of the matching type.
+func appendColumn(b array.Builder, cr flux.ColReader, j int) {
+	n := cr.Len()
+	b.Reserve(n)
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		vs := cr.Bools(j)
+		for i := 0; i < n; i++ {
+			if vs.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(vs.Value(i))
+			}
+		}
+	case *array.Int64Builder:
+		vs := cr.Ints(j)
+		for i := 0; i < n; i++ {
+			if vs.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(vs.Value(i))
+			}
+		}
+	case *array.Uint64Builder:
+		vs := cr.UInts(j)
+		for i := 0; i < n; i++ {
+			if vs.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(vs.Value(i))
+			}
+		}
+	case *array.Float64Builder:
+		vs := cr.Floats(j)
+		for i := 0; i < n; i++ {
+			if vs.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(vs.Value(i))
+			}
+		}
+	case *array.StringBuilder:
+		vs := cr.Strings(j)
+		for i := 0; i < n; i++ {
+			if vs.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(vs.Value(i))
+			}
+		}
+	case *array.TimestampBuilder:
+		vs := cr.Times(j)
+		for i := 0; i < n; i++ {
+			if vs.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(arrow.Timestamp(vs.Value(i)))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package encoders provides flux.MultiResultEncoder implementations
+// for formats other than annotated CSV.
+//
+// Every encoder writes the tables of all results in the order they
+// are produced and identifies each table by its result name and its
+// index within that result, like the result and table columns of
+// annotated CSV.
+package encoders
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/iocounter"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// encoderError wraps an error that happened while writing the
+// encoded results, as opposed to an error from the query.
+type encoderError struct {
+	format string
+	err    error
+}
+
+func (e *encoderError) Error() string {
+	return fmt.Sprintf("%s encoder error: %s", e.format, e.err.Error())
+}
+
+func (e *encoderError) Unwrap() error {
+	return e.err
+}
+
+func (e *encoderError) IsEncoderError() bool {
+	return true
+}
+
+func isEncoderError(err error) bool {
+	encErr, ok := err.(flux.EncoderError)
+	return ok && encErr.IsEncoderError()
+}
+
+// tableFunc encodes a single table of the result with the given name.
+// The index of the table starts at zero for every result.
+type tableFunc func(result string, index int, tbl flux.Table) error
+
+// encodeTables calls fn for every table of the results.
+//
+// An error from the results is returned as is when nothing has been
+// written yet. Once data has been written, it is written to the output
+// with encodeError and is only returned if encodeError is nil, which is
+// the case for binary formats that have no way to represent an error.
+// Errors returned by fn must wrap write errors in an encoderError so
+// they are never written to the output.
+func encodeTables(wc *iocounter.Writer, results flux.ResultIterator, fn tableFunc, encodeError func(err error) error) error {
+	handle := func(err error) error {
+		if isEncoderError(err) || wc.Count() == 0 || encodeError == nil {
+			return err
+		}
+		return encodeError(err)
+	}
+
+	for results.More() {
+		res := results.Next()
+		index := 0
+		if err := res.Tables().Do(func(tbl flux.Table) error {
+			defer func() { index++ }()
+			return fn(res.Name(), index, tbl)
+		}); err != nil {
+			return handle(err)
+		}
+	}
+	results.Release()
+
+	if err := results.Err(); err != nil {
+		return handle(err)
+	}
+	return nil
+}
+
+// jsonValue converts a value to the representation used by the JSON
+// encoders. Times are formatted as RFC3339 strings and null values as
+// well as floats that are not finite are encoded as null.
+func jsonValue(v values.Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	switch v.Type().Nature() {
+	case semantic.Bool:
+		return v.Bool()
+	case semantic.Int:
+		return v.Int()
+	case semantic.UInt:
+		return v.UInt()
+	case semantic.Float:
+		if f := v.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+		return nil
+	case semantic.String:
+		return v.Str()
+	case semantic.Time:
+		return v.Time().Time().Format(time.RFC3339Nano)
+	default:
+		return nil
+	}
+}
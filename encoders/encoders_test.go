@@ -0,0 +1,424 @@
+package encoders_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/encoders"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/andreyvit/diff"
+	"github.com/apache/arrow/go/v7/arrow"
+	"github.com/apache/arrow/go/v7/arrow/array"
+	"github.com/apache/arrow/go/v7/arrow/ipc"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/apache/arrow/go/v7/parquet"
+	"github.com/apache/arrow/go/v7/parquet/pqarrow"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	t0 = values.ConvertTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	t1 = values.ConvertTime(time.Date(2023, 1, 1, 0, 0, 1, 500000000, time.UTC))
+)
+
+// testResults returns two results with tables of different schemas.
+func testResults(err error) flux.ResultIterator {
+	return flux.NewSliceResultIterator([]flux.Result{
+		&executetest.Result{
+			Nm: "_result",
+			Tbls: []*executetest.Table{
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{t0, "a|b", 1.5},
+						{t1, "a|b", nil},
+						{nil, "a|b", math.NaN()},
+					},
+				},
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "ok", Type: flux.TBool},
+					},
+					Data: [][]interface{}{
+						{t0, "c", true},
+					},
+				},
+			},
+		},
+		&executetest.Result{
+			Nm: "counts",
+			Tbls: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "n", Type: flux.TInt},
+						{Label: "u", Type: flux.TUInt},
+					},
+					Data: [][]interface{}{
+						{int64(-3), uint64(7)},
+					},
+				},
+			},
+			Err: err,
+		},
+	})
+}
+
+func TestTextEncoders(t *testing.T) {
+	testCases := []struct {
+		name    string
+		encoder flux.MultiResultEncoder
+		err     error
+		want    string
+	}{
+		{
+			name:    "json",
+			encoder: encoders.NewJSONMultiResultEncoder(),
+			want: `[{"result":"_result","table":0,"groupKey":{"host":"a|b"},` +
+				`"columns":[{"label":"_time","type":"time"},{"label":"host","type":"string"},{"label":"_value","type":"float"}],` +
+				`"rows":[{"_time":"2023-01-01T00:00:00Z","host":"a|b","_value":1.5},` +
+				`{"_time":"2023-01-01T00:00:01.5Z","host":"a|b","_value":null},` +
+				`{"_time":null,"host":"a|b","_value":null}]},` +
+				`{"result":"_result","table":1,"groupKey":{"host":"c"},` +
+				`"columns":[{"label":"_time","type":"time"},{"label":"host","type":"string"},{"label":"ok","type":"bool"}],` +
+				`"rows":[{"_time":"2023-01-01T00:00:00Z","host":"c","ok":true}]},` +
+				`{"result":"counts","table":0,"groupKey":{},` +
+				`"columns":[{"label":"n","type":"int"},{"label":"u","type":"uint"}],` +
+				`"rows":[{"n":-3,"u":7}]}]` + "\n",
+		},
+		{
+			name:    "json error",
+			encoder: encoders.NewJSONMultiResultEncoder(),
+			err:     errors.New("query failed"),
+			want: `[{"result":"_result","table":0,"groupKey":{"host":"a|b"},` +
+				`"columns":[{"label":"_time","type":"time"},{"label":"host","type":"string"},{"label":"_value","type":"float"}],` +
+				`"rows":[{"_time":"2023-01-01T00:00:00Z","host":"a|b","_value":1.5},` +
+				`{"_time":"2023-01-01T00:00:01.5Z","host":"a|b","_value":null},` +
+				`{"_time":null,"host":"a|b","_value":null}]},` +
+				`{"result":"_result","table":1,"groupKey":{"host":"c"},` +
+				`"columns":[{"label":"_time","type":"time"},{"label":"host","type":"string"},{"label":"ok","type":"bool"}],` +
+				`"rows":[{"_time":"2023-01-01T00:00:00Z","host":"c","ok":true}]},` +
+				`{"error":"query failed"}]` + "\n",
+		},
+		{
+			name:    "ndjson",
+			encoder: encoders.NewNDJSONMultiResultEncoder(),
+			want: `{"result":"_result","table":0,"_time":"2023-01-01T00:00:00Z","host":"a|b","_value":1.5}
+{"result":"_result","table":0,"_time":"2023-01-01T00:00:01.5Z","host":"a|b","_value":null}
+{"result":"_result","table":0,"_time":null,"host":"a|b","_value":null}
+{"result":"_result","table":1,"_time":"2023-01-01T00:00:00Z","host":"c","ok":true}
+{"result":"counts","table":0,"n":-3,"u":7}
+`,
+		},
+		{
+			name:    "ndjson error",
+			encoder: encoders.NewNDJSONMultiResultEncoder(),
+			err:     errors.New("query failed"),
+			want: `{"result":"_result","table":0,"_time":"2023-01-01T00:00:00Z","host":"a|b","_value":1.5}
+{"result":"_result","table":0,"_time":"2023-01-01T00:00:01.5Z","host":"a|b","_value":null}
+{"result":"_result","table":0,"_time":null,"host":"a|b","_value":null}
+{"result":"_result","table":1,"_time":"2023-01-01T00:00:00Z","host":"c","ok":true}
+{"error":"query failed"}
+`,
+		},
+		{
+			name:    "markdown",
+			encoder: encoders.NewMarkdownMultiResultEncoder(),
+			want: `Result: _result, Table: 0, Group key: host=a\|b
+
+| _time | host | _value |
+| --- | --- | ---: |
+| 2023-01-01T00:00:00Z | a\|b | 1.5 |
+| 2023-01-01T00:00:01.5Z | a\|b |  |
+|  | a\|b | NaN |
+
+Result: _result, Table: 1, Group key: host=c
+
+| _time | host | ok |
+| --- | --- | --- |
+| 2023-01-01T00:00:00Z | c | true |
+
+Result: counts, Table: 0
+
+| n | u |
+| ---: | ---: |
+| -3 | 7 |
+`,
+		},
+		{
+			name:    "markdown error",
+			encoder: encoders.NewMarkdownMultiResultEncoder(),
+			err:     errors.New("query failed"),
+			want: `Result: _result, Table: 0, Group key: host=a\|b
+
+| _time | host | _value |
+| --- | --- | ---: |
+| 2023-01-01T00:00:00Z | a\|b | 1.5 |
+| 2023-01-01T00:00:01.5Z | a\|b |  |
+|  | a\|b | NaN |
+
+Result: _result, Table: 1, Group key: host=c
+
+| _time | host | ok |
+| --- | --- | --- |
+| 2023-01-01T00:00:00Z | c | true |
+
+Error: query failed
+`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var got bytes.Buffer
+			n, err := tc.encoder.Encode(&got, testResults(tc.err))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if g, w := got.String(), tc.want; g != w {
+				t.Errorf("unexpected encoding -want/+got:\n%s", diff.LineDiff(w, g))
+			}
+			if g, w := n, int64(len(tc.want)); g != w {
+				t.Errorf("unexpected encoding count -want/+got:\n%s", cmp.Diff(w, g))
+			}
+		})
+	}
+}
+
+func TestTextEncoders_ErrorBeforeOutput(t *testing.T) {
+	for name, encoder := range map[string]flux.MultiResultEncoder{
+		"json":     encoders.NewJSONMultiResultEncoder(),
+		"ndjson":   encoders.NewNDJSONMultiResultEncoder(),
+		"markdown": encoders.NewMarkdownMultiResultEncoder(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			results := flux.NewSliceResultIterator([]flux.Result{
+				&executetest.Result{Nm: "_result", Err: errors.New("query failed")},
+			})
+			var got bytes.Buffer
+			_, err := encoder.Encode(&got, results)
+			if err == nil || err.Error() != "query failed" {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if got.Len() != 0 {
+				t.Errorf("unexpected output: %s", got.String())
+			}
+		})
+	}
+}
+
+// recordRows returns the values of the record by row.
+func recordRows(t *testing.T, rec arrow.Record) [][]interface{} {
+	t.Helper()
+	rows := make([][]interface{}, rec.NumRows())
+	for i := range rows {
+		rows[i] = make([]interface{}, rec.NumCols())
+		for j, col := range rec.Columns() {
+			if col.IsNull(i) {
+				continue
+			}
+			switch col := col.(type) {
+			case *array.Boolean:
+				rows[i][j] = col.Value(i)
+			case *array.Int64:
+				rows[i][j] = col.Value(i)
+			case *array.Uint64:
+				rows[i][j] = col.Value(i)
+			case *array.Float64:
+				rows[i][j] = col.Value(i)
+			case *array.String:
+				rows[i][j] = col.Value(i)
+			case *array.Timestamp:
+				rows[i][j] = values.Time(col.Value(i))
+			default:
+				t.Fatalf("unexpected column type %s", col.DataType())
+			}
+		}
+	}
+	return rows
+}
+
+// fieldNames returns the names of the fields of the schema.
+func fieldNames(schema *arrow.Schema) []string {
+	names := make([]string, 0, len(schema.Fields()))
+	for _, f := range schema.Fields() {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestArrowEncoder(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	var buf bytes.Buffer
+	if _, err := encoders.NewArrowMultiResultEncoder(mem).Encode(&buf, testResults(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	type stream struct {
+		Metadata map[string]string
+		Fields   []string
+		Rows     [][]interface{}
+	}
+	var got []stream
+	r := bytes.NewReader(buf.Bytes())
+	for r.Len() > 0 {
+		rdr, err := ipc.NewReader(r, ipc.WithAllocator(mem))
+		if err != nil {
+			t.Fatal(err)
+		}
+		md := rdr.Schema().Metadata()
+		s := stream{
+			Metadata: map[string]string{},
+			Fields:   fieldNames(rdr.Schema()),
+		}
+		for i, k := range md.Keys() {
+			s.Metadata[k] = md.Values()[i]
+		}
+		for rdr.Next() {
+			s.Rows = append(s.Rows, recordRows(t, rdr.Record())...)
+		}
+		if err := rdr.Err(); err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		rdr.Release()
+		got = append(got, s)
+	}
+
+	want := []stream{
+		{
+			Metadata: map[string]string{"flux.result": "_result", "flux.table": "0", "flux.groupKey": `["host"]`},
+			Fields:   []string{"_time", "host", "_value"},
+			Rows: [][]interface{}{
+				{t0, "a|b", 1.5},
+				{t1, "a|b", nil},
+				{nil, "a|b", math.NaN()},
+			},
+		},
+		{
+			Metadata: map[string]string{"flux.result": "_result", "flux.table": "1", "flux.groupKey": `["host"]`},
+			Fields:   []string{"_time", "host", "ok"},
+			Rows:     [][]interface{}{{t0, "c", true}},
+		},
+		{
+			Metadata: map[string]string{"flux.result": "counts", "flux.table": "0", "flux.groupKey": `[]`},
+			Fields:   []string{"n", "u"},
+			Rows:     [][]interface{}{{int64(-3), uint64(7)}},
+		},
+	}
+	if !cmp.Equal(want, got, cmp.Comparer(equalNaN)) {
+		t.Errorf("unexpected streams -want/+got:\n%s", cmp.Diff(want, got, cmp.Comparer(equalNaN)))
+	}
+}
+
+func TestArrowEncoder_Error(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := encoders.NewArrowMultiResultEncoder(memory.DefaultAllocator).Encode(&buf, testResults(errors.New("query failed")))
+	if err == nil || err.Error() != "query failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParquetEncoder(t *testing.T) {
+	// The parquet writer and reader do not release all of their
+	// buffers, so the allocations are not checked.
+	mem := memory.DefaultAllocator
+
+	var buf bytes.Buffer
+	if _, err := encoders.NewParquetMultiResultEncoder(mem).Encode(&buf, testResults(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), parquet.NewReaderProperties(mem), pqarrow.ArrowReadProperties{}, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+
+	if want, got := []string{"result", "table", "_time", "host", "_value", "ok", "n", "u"}, fieldNames(tbl.Schema()); !cmp.Equal(want, got) {
+		t.Fatalf("unexpected columns -want/+got:\n%s", cmp.Diff(want, got))
+	}
+	var rows [][]interface{}
+	tr := array.NewTableReader(tbl, -1)
+	defer tr.Release()
+	for tr.Next() {
+		rows = append(rows, recordRows(t, tr.Record())...)
+	}
+	want := [][]interface{}{
+		{"_result", int64(0), t0, "a|b", 1.5, nil, nil, nil},
+		{"_result", int64(0), t1, "a|b", nil, nil, nil, nil},
+		{"_result", int64(0), nil, "a|b", math.NaN(), nil, nil, nil},
+		{"_result", int64(1), t0, "c", nil, true, nil, nil},
+		{"counts", int64(0), nil, nil, nil, nil, int64(-3), uint64(7)},
+	}
+	if !cmp.Equal(want, rows, cmp.Comparer(equalNaN)) {
+		t.Errorf("unexpected rows -want/+got:\n%s", cmp.Diff(want, rows, cmp.Comparer(equalNaN)))
+	}
+}
+
+func TestParquetEncoder_ConflictingTypes(t *testing.T) {
+	results := flux.NewSliceResultIterator([]flux.Result{
+		&executetest.Result{
+			Nm: "_result",
+			Tbls: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{{Label: "_value", Type: flux.TFloat}},
+					Data:    [][]interface{}{{1.0}},
+				},
+				{
+					ColMeta: []flux.ColMeta{{Label: "_value", Type: flux.TInt}},
+					Data:    [][]interface{}{{int64(1)}},
+				},
+			},
+		},
+	})
+	var buf bytes.Buffer
+	_, err := encoders.NewParquetMultiResultEncoder(memory.DefaultAllocator).Encode(&buf, results)
+	if want := `parquet encoder cannot encode column "_value" with types float and int`; err == nil || err.Error() != want {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %v", want, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output of %d bytes", buf.Len())
+	}
+}
+
+func TestParquetEncoder_ReservedColumn(t *testing.T) {
+	results := flux.NewSliceResultIterator([]flux.Result{
+		&executetest.Result{
+			Nm: "_result",
+			Tbls: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{{Label: "table", Type: flux.TString}},
+					Data:    [][]interface{}{{"a"}},
+				},
+			},
+		},
+	})
+	var buf bytes.Buffer
+	_, err := encoders.NewParquetMultiResultEncoder(memory.DefaultAllocator).Encode(&buf, results)
+	if want := `parquet encoder cannot encode column "table" because its name is reserved`; err == nil || err.Error() != want {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %v", want, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output of %d bytes", buf.Len())
+	}
+}
+
+func equalNaN(x, y float64) bool {
+	return x == y || math.IsNaN(x) && math.IsNaN(y)
+}
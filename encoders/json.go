@@ -0,0 +1,214 @@
+package encoders
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/iocounter"
+)
+
+// JSONMultiResultEncoder encodes the results as a JSON array
+// with an object for each table:
+//
+//	[{"result":"_result","table":0,"groupKey":{"host":"a"},
+//	  "columns":[{"label":"_time","type":"time"},...],
+//	  "rows":[{"_time":"2023-01-01T00:00:00Z","host":"a","_value":1.5},...]}]
+//
+// An error from the query that happens after the output has been
+// started is encoded as a final {"error":"..."} element.
+type JSONMultiResultEncoder struct{}
+
+// NewJSONMultiResultEncoder creates an encoder for the JSON format.
+func NewJSONMultiResultEncoder() flux.MultiResultEncoder {
+	return &JSONMultiResultEncoder{}
+}
+
+func (e *JSONMultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	enc := &jsonEncoder{w: wc}
+	err := encodeTables(wc, results, enc.encodeTable, enc.encodeError)
+	if err == nil {
+		err = enc.close()
+	}
+	return wc.Count(), err
+}
+
+// jsonEncoder writes the JSON array element by element so that tables
+// do not have to be buffered.
+type jsonEncoder struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	started bool
+	inRows  bool
+	rows    int
+}
+
+// write writes the buffer to the underlying writer and resets it.
+func (e *jsonEncoder) write() error {
+	if _, err := e.w.Write(e.buf.Bytes()); err != nil {
+		return &encoderError{format: "json", err: err}
+	}
+	e.buf.Reset()
+	return nil
+}
+
+// startElement begins a new element of the array.
+func (e *jsonEncoder) startElement() {
+	if !e.started {
+		e.buf.WriteByte('[')
+		e.started = true
+	} else {
+		e.buf.WriteByte(',')
+	}
+}
+
+func (e *jsonEncoder) encodeTable(result string, index int, tbl flux.Table) error {
+	e.startElement()
+	e.buf.WriteString(`{"result":`)
+	writeJSON(&e.buf, result)
+	e.buf.WriteString(`,"table":`)
+	writeJSON(&e.buf, index)
+
+	key := tbl.Key()
+	e.buf.WriteString(`,"groupKey":{`)
+	for j, c := range key.Cols() {
+		if j > 0 {
+			e.buf.WriteByte(',')
+		}
+		writeJSON(&e.buf, c.Label)
+		e.buf.WriteByte(':')
+		writeJSON(&e.buf, jsonValue(key.Value(j)))
+	}
+	e.buf.WriteString(`},"columns":[`)
+	cols := tbl.Cols()
+	for j, c := range cols {
+		if j > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.buf.WriteString(`{"label":`)
+		writeJSON(&e.buf, c.Label)
+		e.buf.WriteString(`,"type":`)
+		writeJSON(&e.buf, c.Type.String())
+		e.buf.WriteByte('}')
+	}
+	e.buf.WriteString(`],"rows":[`)
+	e.inRows, e.rows = true, 0
+
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		for i := 0; i < cr.Len(); i++ {
+			if e.rows > 0 {
+				e.buf.WriteByte(',')
+			}
+			writeRow(&e.buf, cr, i, nil)
+			e.rows++
+		}
+		return e.write()
+	}); err != nil {
+		return err
+	}
+	e.buf.WriteString("]}")
+	e.inRows = false
+	return e.write()
+}
+
+// encodeError closes the table that is being written, if any,
+// and appends the error as the last element.
+func (e *jsonEncoder) encodeError(err error) error {
+	if e.inRows {
+		e.buf.WriteString("]}")
+		e.inRows = false
+	}
+	e.startElement()
+	e.buf.WriteString(`{"error":`)
+	writeJSON(&e.buf, err.Error())
+	e.buf.WriteByte('}')
+	return e.write()
+}
+
+// close terminates the array.
+func (e *jsonEncoder) close() error {
+	if !e.started {
+		e.buf.WriteByte('[')
+	}
+	e.buf.WriteString("]\n")
+	return e.write()
+}
+
+// NDJSONMultiResultEncoder encodes the results as newline delimited
+// JSON with an object for each row:
+//
+//	{"result":"_result","table":0,"_time":"2023-01-01T00:00:00Z","host":"a","_value":1.5}
+//
+// An error from the query that happens after the output has been
+// started is encoded as a final {"error":"..."} line.
+type NDJSONMultiResultEncoder struct{}
+
+// NewNDJSONMultiResultEncoder creates an encoder for the newline delimited JSON format.
+func NewNDJSONMultiResultEncoder() flux.MultiResultEncoder {
+	return &NDJSONMultiResultEncoder{}
+}
+
+func (e *NDJSONMultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	var buf bytes.Buffer
+	write := func() error {
+		if _, err := wc.Write(buf.Bytes()); err != nil {
+			return &encoderError{format: "ndjson", err: err}
+		}
+		buf.Reset()
+		return nil
+	}
+	err := encodeTables(wc, results, func(result string, index int, tbl flux.Table) error {
+		prefix := func(buf *bytes.Buffer) {
+			buf.WriteString(`"result":`)
+			writeJSON(buf, result)
+			buf.WriteString(`,"table":`)
+			writeJSON(buf, index)
+		}
+		return tbl.Do(func(cr flux.ColReader) error {
+			for i := 0; i < cr.Len(); i++ {
+				writeRow(&buf, cr, i, prefix)
+				buf.WriteByte('\n')
+			}
+			return write()
+		})
+	}, func(err error) error {
+		buf.Reset()
+		buf.WriteString(`{"error":`)
+		writeJSON(&buf, err.Error())
+		buf.WriteString("}\n")
+		return write()
+	})
+	return wc.Count(), err
+}
+
+// writeRow writes row i as a JSON object with the columns in table
+// order. The prefix, if any, writes additional leading properties.
+func writeRow(buf *bytes.Buffer, cr flux.ColReader, i int, prefix func(buf *bytes.Buffer)) {
+	buf.WriteByte('{')
+	if prefix != nil {
+		prefix(buf)
+	}
+	for j, c := range cr.Cols() {
+		if j > 0 || prefix != nil {
+			buf.WriteByte(',')
+		}
+		writeJSON(buf, c.Label)
+		buf.WriteByte(':')
+		writeJSON(buf, jsonValue(execute.ValueForRow(cr, i, j)))
+	}
+	buf.WriteByte('}')
+}
+
+// writeJSON writes the JSON encoding of a string, number, bool or nil.
+// These values cannot fail to encode.
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	// Remove the newline added by Encode.
+	buf.Truncate(buf.Len() - 1)
+}
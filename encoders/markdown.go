@@ -0,0 +1,146 @@
+package encoders
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/iocounter"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// MarkdownMultiResultEncoder encodes every table as a Markdown table
+// preceded by a line with the result name, table index and group key:
+//
+//	Result: _result, Table: 0, Group key: host=a
+//
+//	| _time | host | _value |
+//	| --- | --- | ---: |
+//	| 2023-01-01T00:00:00Z | a | 1.5 |
+//
+// Numeric columns are right aligned and null values are empty cells.
+// An error from the query that happens after the output has been
+// started is encoded as a final "Error: ..." line.
+type MarkdownMultiResultEncoder struct{}
+
+// NewMarkdownMultiResultEncoder creates an encoder for Markdown tables.
+func NewMarkdownMultiResultEncoder() flux.MultiResultEncoder {
+	return &MarkdownMultiResultEncoder{}
+}
+
+func (e *MarkdownMultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	var (
+		buf    bytes.Buffer
+		tables int
+	)
+	write := func() error {
+		if _, err := wc.Write(buf.Bytes()); err != nil {
+			return &encoderError{format: "markdown", err: err}
+		}
+		buf.Reset()
+		return nil
+	}
+	err := encodeTables(wc, results, func(result string, index int, tbl flux.Table) error {
+		if tables > 0 {
+			buf.WriteByte('\n')
+		}
+		tables++
+		buf.WriteString("Result: ")
+		buf.WriteString(markdownEscape(result))
+		buf.WriteString(", Table: ")
+		buf.WriteString(strconv.Itoa(index))
+		key := tbl.Key()
+		if len(key.Cols()) > 0 {
+			buf.WriteString(", Group key: ")
+			for j, c := range key.Cols() {
+				if j > 0 {
+					buf.WriteString(", ")
+				}
+				buf.WriteString(markdownEscape(c.Label))
+				buf.WriteByte('=')
+				buf.WriteString(markdownEscape(markdownValue(key.Value(j))))
+			}
+		}
+		buf.WriteString("\n\n")
+
+		cols := tbl.Cols()
+		for _, c := range cols {
+			buf.WriteString("| ")
+			buf.WriteString(markdownEscape(c.Label))
+			buf.WriteByte(' ')
+		}
+		buf.WriteString("|\n")
+		for _, c := range cols {
+			switch c.Type {
+			case flux.TInt, flux.TUInt, flux.TFloat:
+				buf.WriteString("| ---: ")
+			default:
+				buf.WriteString("| --- ")
+			}
+		}
+		buf.WriteString("|\n")
+
+		if err := tbl.Do(func(cr flux.ColReader) error {
+			for i := 0; i < cr.Len(); i++ {
+				for j := range cols {
+					buf.WriteString("| ")
+					buf.WriteString(markdownEscape(markdownValue(execute.ValueForRow(cr, i, j))))
+					buf.WriteByte(' ')
+				}
+				buf.WriteString("|\n")
+			}
+			return write()
+		}); err != nil {
+			return err
+		}
+		return write()
+	}, func(err error) error {
+		buf.Reset()
+		buf.WriteString("\nError: ")
+		buf.WriteString(markdownEscape(err.Error()))
+		buf.WriteByte('\n')
+		return write()
+	})
+	return wc.Count(), err
+}
+
+// markdownValue formats a value for a table cell.
+func markdownValue(v values.Value) string {
+	if v.IsNull() {
+		return ""
+	}
+	switch v.Type().Nature() {
+	case semantic.Bool:
+		return strconv.FormatBool(v.Bool())
+	case semantic.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case semantic.UInt:
+		return strconv.FormatUint(v.UInt(), 10)
+	case semantic.Float:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case semantic.String:
+		return v.Str()
+	case semantic.Time:
+		return v.Time().Time().Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`|`, `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+)
+
+// markdownEscape escapes the characters that would break a table cell.
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
@@ -0,0 +1,150 @@
+package encoders
+
+import (
+	"io"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/iocounter"
+	"github.com/apache/arrow/go/v7/arrow"
+	"github.com/apache/arrow/go/v7/arrow/array"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/apache/arrow/go/v7/parquet"
+	"github.com/apache/arrow/go/v7/parquet/compress"
+	"github.com/apache/arrow/go/v7/parquet/pqarrow"
+)
+
+// Labels of the columns that identify the table of a row in the
+// Parquet output.
+const (
+	ParquetResultColumn = "result"
+	ParquetTableColumn  = "table"
+)
+
+// ParquetMultiResultEncoder encodes the results as a single snappy
+// compressed Parquet file.
+//
+// A Parquet file has a single schema, so the tables of all results are
+// buffered until the query has finished. The schema starts with the
+// result and table columns followed by the union of the columns of all
+// tables in the order they first appear. Columns that a table does not
+// have are null. A column that has different types in different tables
+// is an error, as is a column named result or table.
+//
+// The Parquet format cannot represent errors, so errors from the
+// query are always returned.
+type ParquetMultiResultEncoder struct {
+	mem memory.Allocator
+}
+
+// NewParquetMultiResultEncoder creates an encoder for the Parquet format
+// that uses mem to allocate the records it writes.
+func NewParquetMultiResultEncoder(mem memory.Allocator) flux.MultiResultEncoder {
+	return &ParquetMultiResultEncoder{mem: mem}
+}
+
+// parquetBuffer is a buffered part of a table.
+type parquetBuffer struct {
+	result string
+	index  int
+	cr     flux.ColReader
+}
+
+func (e *ParquetMultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	var (
+		buffers []parquetBuffer
+		cols    = []flux.ColMeta{
+			{Label: ParquetResultColumn, Type: flux.TString},
+			{Label: ParquetTableColumn, Type: flux.TInt},
+		}
+		colIdx = map[string]int{
+			ParquetResultColumn: 0,
+			ParquetTableColumn:  1,
+		}
+	)
+	defer func() {
+		for _, b := range buffers {
+			b.cr.Release()
+		}
+	}()
+
+	wc := &iocounter.Writer{Writer: w}
+	if err := encodeTables(wc, results, func(result string, index int, tbl flux.Table) error {
+		for _, c := range tbl.Cols() {
+			if c.Label == ParquetResultColumn || c.Label == ParquetTableColumn {
+				tbl.Done()
+				return errors.Newf(codes.Invalid, "parquet encoder cannot encode column %q because its name is reserved", c.Label)
+			}
+			if j, ok := colIdx[c.Label]; !ok {
+				colIdx[c.Label] = len(cols)
+				cols = append(cols, c)
+			} else if cols[j].Type != c.Type {
+				tbl.Done()
+				return errors.Newf(codes.Invalid, "parquet encoder cannot encode column %q with types %s and %s", c.Label, cols[j].Type, c.Type)
+			}
+		}
+		return tbl.Do(func(cr flux.ColReader) error {
+			cr.Retain()
+			buffers = append(buffers, parquetBuffer{result: result, index: index, cr: cr})
+			return nil
+		})
+	}, nil); err != nil {
+		return wc.Count(), err
+	}
+
+	schema, err := arrowSchema(cols, arrow.Metadata{})
+	if err != nil {
+		return wc.Count(), err
+	}
+	props := parquet.NewWriterProperties(
+		parquet.WithAllocator(e.mem),
+		parquet.WithCompression(compress.Codecs.Snappy),
+	)
+	fw, err := pqarrow.NewFileWriter(schema, wc, props, pqarrow.NewArrowWriterProperties(pqarrow.WithAllocator(e.mem)))
+	if err != nil {
+		return wc.Count(), &encoderError{format: "parquet", err: err}
+	}
+	for _, b := range buffers {
+		rec := e.newRecord(schema, colIdx, b)
+		err := fw.Write(rec)
+		rec.Release()
+		if err != nil {
+			return wc.Count(), &encoderError{format: "parquet", err: err}
+		}
+	}
+	if err := fw.Close(); err != nil {
+		return wc.Count(), &encoderError{format: "parquet", err: err}
+	}
+	return wc.Count(), nil
+}
+
+// newRecord copies a buffer into a record with the schema of the file.
+func (e *ParquetMultiResultEncoder) newRecord(schema *arrow.Schema, colIdx map[string]int, buf parquetBuffer) arrow.Record {
+	b := array.NewRecordBuilder(e.mem, schema)
+	defer b.Release()
+
+	n := buf.cr.Len()
+	result := b.Field(0).(*array.StringBuilder)
+	index := b.Field(1).(*array.Int64Builder)
+	for i := 0; i < n; i++ {
+		result.Append(buf.result)
+		index.Append(int64(buf.index))
+	}
+
+	present := make([]bool, len(schema.Fields()))
+	present[0], present[1] = true, true
+	for j, c := range buf.cr.Cols() {
+		k := colIdx[c.Label]
+		appendColumn(b.Field(k), buf.cr, j)
+		present[k] = true
+	}
+	for k, ok := range present {
+		if !ok {
+			for i := 0; i < n; i++ {
+				b.Field(k).AppendNull()
+			}
+		}
+	}
+	return b.NewRecord()
+}
@@ -0,0 +1,262 @@
+// Package admission implements admission control for the executor.
+//
+// A Controller limits the number of queries that run at once and the
+// number of dispatcher workers that they use together. Queries that
+// cannot start wait in a queue that is ordered by their priority and
+// then by their arrival, and give up when the queue timeout passes.
+//
+// Admission control is opt-in. It is enabled by injecting a Controller
+// into the context of the queries that it should apply to.
+package admission
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// Config configures a Controller.
+type Config struct {
+	// MaxConcurrentQueries is the number of queries that may run at once.
+	// If it is zero, the number of queries is not limited.
+	MaxConcurrentQueries int
+
+	// MaxWorkers is the number of dispatcher workers that the running
+	// queries may use together. A query that needs more workers than
+	// this runs only when no other query is running.
+	// If it is zero, the number of workers is not limited.
+	MaxWorkers int
+
+	// MaxQueueLength is the number of queries that may wait to run.
+	// Queries are rejected when the queue is full.
+	// If it is zero, the length of the queue is not limited.
+	MaxQueueLength int
+
+	// QueueTimeout is how long a query waits to run before it is rejected.
+	// If it is zero, a query waits until its context is done.
+	QueueTimeout time.Duration
+}
+
+// Stats are the metrics of a Controller.
+type Stats struct {
+	// Running is the number of queries that are running.
+	Running int
+	// Workers is the number of workers that the running queries use.
+	Workers int
+	// Queued is the number of queries that wait to run.
+	Queued int
+	// Admitted is the number of queries that were admitted.
+	Admitted int64
+	// Rejected is the number of queries that were rejected.
+	Rejected int64
+}
+
+// Controller decides when queries may run.
+type Controller struct {
+	config Config
+
+	mu    sync.Mutex
+	stats Stats
+	queue waitQueue
+	seq   uint64
+}
+
+// NewController returns a Controller with the config.
+func NewController(config Config) *Controller {
+	return &Controller{config: config}
+}
+
+// waiter is a query that waits in the queue.
+type waiter struct {
+	priority int
+	seq      uint64
+	workers  int
+	index    int
+	ready    chan struct{}
+
+	// admitted is protected by the lock of the Controller.
+	admitted bool
+}
+
+// Admit waits until the query may run with the number of workers.
+// It returns a Ticket that must be released when the query is done.
+// The priority of the query is read from the context, see WithPriority.
+//
+// A query that runs within a query that has been admitted, such as
+// one that is executed by a function of the outer query, is admitted
+// right away so that it cannot wait on the query that runs it.
+func (c *Controller) Admit(ctx context.Context, workers int) (*Ticket, error) {
+	if _, ok := ctx.Value(ticketKey).(*Ticket); ok {
+		return &Ticket{}, nil
+	}
+
+	start := time.Now()
+	c.mu.Lock()
+	if c.queue.Len() == 0 && c.fits(workers) {
+		c.admit(workers)
+		c.mu.Unlock()
+		return &Ticket{c: c, workers: workers}, nil
+	}
+	if max := c.config.MaxQueueLength; max > 0 && c.queue.Len() >= max {
+		c.stats.Rejected++
+		c.mu.Unlock()
+		return nil, errors.Newf(codes.ResourceExhausted, "query queue is full: %d queries are waiting to run", c.queue.Len())
+	}
+
+	w := &waiter{
+		priority: GetPriority(ctx),
+		seq:      c.seq,
+		workers:  workers,
+		ready:    make(chan struct{}),
+	}
+	c.seq++
+	heap.Push(&c.queue, w)
+	c.stats.Queued++
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if c.config.QueueTimeout > 0 {
+		timer := time.NewTimer(c.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+	case <-timeout:
+		err = errors.Newf(codes.ResourceExhausted, "query waited longer than %v to run", c.config.QueueTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w.admitted {
+		// The query may have been admitted while giving up.
+		return &Ticket{c: c, workers: workers, wait: time.Since(start)}, nil
+	}
+	heap.Remove(&c.queue, w.index)
+	c.stats.Queued--
+	c.stats.Rejected++
+	// The query may have held up the queries behind it.
+	c.admitQueued()
+	return nil, err
+}
+
+// fits reports whether a query with the number of workers may run.
+// It must be called with the lock held.
+func (c *Controller) fits(workers int) bool {
+	if max := c.config.MaxConcurrentQueries; max > 0 && c.stats.Running >= max {
+		return false
+	}
+	if max := c.config.MaxWorkers; max > 0 && c.stats.Workers+workers > max {
+		// A query that needs more workers than the limit
+		// may run alone so that it is not rejected forever.
+		return c.stats.Running == 0
+	}
+	return true
+}
+
+// admit counts a query as running.
+// It must be called with the lock held.
+func (c *Controller) admit(workers int) {
+	c.stats.Running++
+	c.stats.Workers += workers
+	c.stats.Admitted++
+}
+
+// admitQueued admits the queries in the queue in order
+// until the next query does not fit.
+// It must be called with the lock held.
+func (c *Controller) admitQueued() {
+	for c.queue.Len() > 0 {
+		w := c.queue[0]
+		if !c.fits(w.workers) {
+			return
+		}
+		heap.Pop(&c.queue)
+		c.stats.Queued--
+		c.admit(w.workers)
+		w.admitted = true
+		close(w.ready)
+	}
+}
+
+// release counts a query as done.
+func (c *Controller) release(workers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Running--
+	c.stats.Workers -= workers
+	c.admitQueued()
+}
+
+// Stats returns the metrics of the Controller.
+func (c *Controller) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Ticket is the admission of a query.
+type Ticket struct {
+	c       *Controller
+	workers int
+	wait    time.Duration
+	once    sync.Once
+}
+
+// Wait returns how long the query waited in the queue.
+func (t *Ticket) Wait() time.Duration {
+	return t.wait
+}
+
+// Release lets the queries in the queue use the resources of the query.
+// It must be called when the query is done and may be called more than once.
+func (t *Ticket) Release() {
+	t.once.Do(func() {
+		if t.c != nil {
+			t.c.release(t.workers)
+		}
+	})
+}
+
+// waitQueue is a priority queue of waiters. Waiters with a higher
+// priority come first and waiters with the same priority are in
+// the order that they arrived.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return w
+}
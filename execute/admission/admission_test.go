@@ -0,0 +1,152 @@
+package admission_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute/admission"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// waitQueued waits until the controller has n queries in its queue.
+func waitQueued(c *admission.Controller, n int) {
+	for c.Stats().Queued != n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestController_Priority(t *testing.T) {
+	c := admission.NewController(admission.Config{MaxConcurrentQueries: 1})
+	ctx := context.Background()
+	running, err := c.Admit(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan int, 3)
+	admit := func(priority int) {
+		ticket, err := c.Admit(admission.WithPriority(ctx, priority), 1)
+		if err != nil {
+			t.Error(err)
+			order <- -1
+			return
+		}
+		order <- priority
+		ticket.Release()
+	}
+	go admit(1)
+	waitQueued(c, 1)
+	go admit(1)
+	waitQueued(c, 2)
+	go admit(2)
+	waitQueued(c, 3)
+
+	running.Release()
+	for _, want := range []int{2, 1, 1} {
+		if got := <-order; want != got {
+			t.Fatalf("unexpected priority -want/+got\n\t- %d\n\t+ %d", want, got)
+		}
+	}
+
+	want := admission.Stats{Admitted: 4}
+	if got := c.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestController_MaxWorkers(t *testing.T) {
+	c := admission.NewController(admission.Config{
+		MaxWorkers:   8,
+		QueueTimeout: time.Millisecond,
+	})
+	ctx := context.Background()
+	a, err := c.Admit(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Admit(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Admit(ctx, 1); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+	a.Release()
+	b.Release()
+	b.Release()
+
+	// A query that needs more workers than
+	// the limit runs when it would run alone.
+	big, err := c.Admit(ctx, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	big.Release()
+
+	want := admission.Stats{Admitted: 3, Rejected: 1}
+	if got := c.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestController_MaxQueueLength(t *testing.T) {
+	c := admission.NewController(admission.Config{
+		MaxConcurrentQueries: 1,
+		MaxQueueLength:       1,
+	})
+	running, err := c.Admit(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Admit(ctx, 1)
+		done <- err
+	}()
+	waitQueued(c, 1)
+
+	if _, err := c.Admit(context.Background(), 1); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected error -want/+got\n\t- %v\n\t+ %v", context.Canceled, err)
+	}
+	running.Release()
+
+	want := admission.Stats{Admitted: 1, Rejected: 2}
+	if got := c.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestController_Nested(t *testing.T) {
+	c := admission.NewController(admission.Config{MaxConcurrentQueries: 1})
+	outer, err := c.Admit(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outer.Release()
+
+	// A query that runs within the outer query does not wait for it.
+	ctx := admission.WithTicket(context.Background(), outer)
+	inner, err := c.Admit(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.Release()
+
+	want := admission.Stats{Running: 1, Workers: 1, Admitted: 1}
+	if got := c.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
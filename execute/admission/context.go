@@ -0,0 +1,55 @@
+package admission
+
+import "context"
+
+type key int
+
+const (
+	controllerKey key = iota
+	priorityKey
+	ticketKey
+)
+
+// Dependency will inject the Controller into the dependency chain.
+type Dependency struct {
+	Controller *Controller
+}
+
+// Inject will inject the Controller into the dependency chain.
+func (d Dependency) Inject(ctx context.Context) context.Context {
+	if d.Controller != nil {
+		ctx = Inject(ctx, d.Controller)
+	}
+	return ctx
+}
+
+// Inject will enable admission control with the Controller for the context.
+func Inject(ctx context.Context, c *Controller) context.Context {
+	return context.WithValue(ctx, controllerKey, c)
+}
+
+// Get will retrieve the Controller from the context.Context.
+// It returns false if admission control is not enabled.
+func Get(ctx context.Context) (*Controller, bool) {
+	c, ok := ctx.Value(controllerKey).(*Controller)
+	return c, ok
+}
+
+// WithPriority returns a context for a query with the priority.
+// Queries with a higher priority leave the queue first.
+// The default priority is zero.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey, priority)
+}
+
+// GetPriority returns the priority of the query of the context.
+func GetPriority(ctx context.Context) int {
+	p, _ := ctx.Value(priorityKey).(int)
+	return p
+}
+
+// WithTicket returns a context for the query that the ticket admitted,
+// so that the queries that it runs are admitted right away.
+func WithTicket(ctx context.Context, t *Ticket) context.Context {
+	return context.WithValue(ctx, ticketKey, t)
+}
@@ -74,6 +74,10 @@ func (t *aggregateTransformation) ProcessMessage(m Message) error {
 		return t.processChunk(m.TableChunk())
 	case FlushKeyMsg:
 		return t.flushKey(m.Key())
+	case UpdateWatermarkMsg:
+		return t.d.UpdateWatermark(m.WatermarkTime())
+	case UpdateProcessingTimeMsg:
+		return t.d.UpdateProcessingTime(m.ProcessingTime())
 	case ProcessMsg:
 		panic("unreachable")
 	}
@@ -374,6 +378,11 @@ var DefaultSimpleAggregateConfig = SimpleAggregateConfig{
 	Columns: []string{DefaultValueColLabel},
 }
 
+// Cost estimates that an aggregate produces one row for every table.
+func (c SimpleAggregateConfig) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return plan.AggregateCost(inStats)
+}
+
 func (c SimpleAggregateConfig) Copy() SimpleAggregateConfig {
 	nc := c
 	if c.Columns != nil {
@@ -1,6 +1,8 @@
 package execute
 
 import (
+	"sync/atomic"
+
 	"github.com/InfluxCommunity/flux/memory"
 )
 
@@ -13,6 +15,66 @@ const (
 	timeSize    = 8
 )
 
+// limitedAllocator is an allocator that reports its usage and limit.
+type limitedAllocator interface {
+	Allocated() int64
+	AllocationLimit() (int64, bool)
+}
+
+// profilingAllocator records the number of bytes that an operation
+// allocates from the query allocator for the operator profiler.
+type profilingAllocator struct {
+	memory.Allocator
+	allocated int64
+}
+
+func newProfilingAllocator(mem memory.Allocator) *profilingAllocator {
+	return &profilingAllocator{Allocator: mem}
+}
+
+func (a *profilingAllocator) Allocate(size int) []byte {
+	atomic.AddInt64(&a.allocated, int64(size))
+	return a.Allocator.Allocate(size)
+}
+
+func (a *profilingAllocator) Reallocate(size int, b []byte) []byte {
+	if diff := size - cap(b); diff > 0 {
+		atomic.AddInt64(&a.allocated, int64(diff))
+	}
+	return a.Allocator.Reallocate(size, b)
+}
+
+func (a *profilingAllocator) Account(size int) error {
+	if size > 0 {
+		atomic.AddInt64(&a.allocated, int64(size))
+	}
+	return a.Allocator.Account(size)
+}
+
+// TotalAllocated returns the total number of bytes that were allocated.
+// Memory that was freed and allocated again is counted again.
+func (a *profilingAllocator) TotalAllocated() int64 {
+	return atomic.LoadInt64(&a.allocated)
+}
+
+// Allocated returns the number of bytes currently allocated
+// by the query allocator.
+func (a *profilingAllocator) Allocated() int64 {
+	if la, ok := a.Allocator.(limitedAllocator); ok {
+		return la.Allocated()
+	}
+	return 0
+}
+
+// AllocationLimit forwards the allocation limit of the query allocator
+// so transformations can still decide to spill when they are profiled.
+func (a *profilingAllocator) AllocationLimit() (int64, bool) {
+	if la, ok := a.Allocator.(limitedAllocator); ok {
+		return la.AllocationLimit()
+	}
+	return 0, false
+}
+
 // Allocator is used to track memory allocations for directly allocated structs.
 // Normally, you should use arrow builders and the memory.Allocator by itself to
 // create arrays, but the Allocator is used by older builders that were pre-arrow
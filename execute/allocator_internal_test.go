@@ -0,0 +1,29 @@
+package execute
+
+import (
+	"testing"
+
+	"github.com/InfluxCommunity/flux/memory"
+)
+
+func TestProfilingAllocator_AllocationLimit(t *testing.T) {
+	limit := int64(1024)
+	mem := newProfilingAllocator(&memory.ResourceAllocator{Limit: &limit})
+	b := mem.Allocate(64)
+	mem.Free(b)
+	_ = mem.Allocate(32)
+
+	if want, got := int64(32), mem.Allocated(); want != got {
+		t.Fatalf("unexpected allocated -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := int64(96), mem.TotalAllocated(); want != got {
+		t.Fatalf("unexpected total allocated -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got, ok := mem.AllocationLimit(); !ok || got != limit {
+		t.Fatalf("unexpected allocation limit -want/+got:\n\t- %d\n\t+ %d", limit, got)
+	}
+
+	if _, ok := newProfilingAllocator(memory.DefaultAllocator).AllocationLimit(); ok {
+		t.Fatal("expected no allocation limit")
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/InfluxCommunity/flux"
 	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute/admission"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/internal/feature"
 	"github.com/InfluxCommunity/flux/memory"
@@ -69,15 +70,31 @@ type executionState struct {
 
 	transports []AsyncTransport
 
+	// sourceAllocs record the memory allocated by the
+	// sources when the operator profiler is enabled.
+	sourceAllocs map[Source]*profilingAllocator
+
 	dispatcher *poolDispatcher
 	logger     *zap.Logger
+
+	// ticket is the admission of the query
+	// when admission control is enabled.
+	ticket *admission.Ticket
 }
 
 func (e *executor) Execute(ctx context.Context, p *plan.Spec, a memory.Allocator) (map[string]flux.Result, <-chan flux.Statistics, error) {
+	ctx, ticket, err := admit(ctx, p)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, codes.Inherit, "query was not admitted")
+	}
 	es, err := e.createExecutionState(ctx, p, a)
 	if err != nil {
+		if ticket != nil {
+			ticket.Release()
+		}
 		return nil, nil, errors.Wrap(err, codes.Inherit, "failed to initialize execute state")
 	}
+	es.ticket = ticket
 	es.do()
 	return es.results, es.statsCh, nil
 }
@@ -95,6 +112,9 @@ func (e *executor) createExecutionState(ctx context.Context, p *plan.Spec, a mem
 		dispatcher: newPoolDispatcher(10, e.logger),
 		logger:     e.logger,
 	}
+	if operatorProfilerEnabled(ctx) {
+		es.sourceAllocs = make(map[Source]*profilingAllocator)
+	}
 	v := &createExecutionNodeVisitor{
 		es:    es,
 		nodes: make(map[plan.Node][]Node),
@@ -118,6 +138,16 @@ func (e *executor) createExecutionState(ctx context.Context, p *plan.Spec, a mem
 	return v.es, nil
 }
 
+// operatorProfilerEnabled reports whether the operator
+// profiler is enabled for the execution.
+func operatorProfilerEnabled(ctx context.Context) bool {
+	if !HaveExecutionDependencies(ctx) {
+		return false
+	}
+	opts := GetExecutionDependencies(ctx).ExecutionOptions
+	return opts != nil && opts.OperatorProfiler != nil
+}
+
 // createExecutionNodeVisitor visits each node in a physical query plan
 // and creates a node responsible for executing that physical operation.
 type createExecutionNodeVisitor struct {
@@ -187,6 +217,10 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 	//
 	// 3. Merge instantiation. There is a single copy of the node, but multiple copies of the
 	//    predecessors. These copies merge into the node.
+	//
+	// 4. Partition instantiation. There are multiple copies of the node, but only a
+	//    single copy of the predecessor. Every copy reads from the same predecessor
+	//    and keeps its own partition of the data.
 
 	copies := 1
 	if attr := plan.GetOutputAttribute(ppn, plan.ParallelRunKey); attr != nil {
@@ -209,10 +243,13 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 			streamContext: streamContext,
 			parallelOpts:  ParallelOpts{Group: i, Factor: copies},
 		}
+		if v.es.sourceAllocs != nil {
+			ec[i].alloc = newProfilingAllocator(v.es.alloc)
+		}
 
 		for pi, pred := range nonYieldPredecessors(node) {
 			for j := 0; j < predCopies; j++ {
-				ec[i].parents[pi*predCopies+j] = datasetIDFromNodeID(pred.ID(), i+j)
+				ec[i].parents[pi*predCopies+j] = datasetIDFromNodeID(pred.ID(), v.predecessorCopy(pred, i+j))
 			}
 		}
 	}
@@ -237,6 +274,9 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 
 			source.SetLabel(string(node.ID()))
 			v.es.sources = append(v.es.sources, source)
+			if ec[i].alloc != nil {
+				v.es.sourceAllocs[source] = ec[i].alloc
+			}
 			v.nodes[node][i] = source
 		}
 	} else {
@@ -267,7 +307,7 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 			ds.SetTriggerSpec(ppn.TriggerSpec)
 			v.nodes[node][i] = ds
 
-			for _, p := range nonYieldPredecessors(node) {
+			for pi, p := range nonYieldPredecessors(node) {
 				// In case (1) above, both copies and predCopies are 1. We link
 				// forward from the only copy of the predecessor node.
 				//   i == 0 AND j == 0
@@ -280,10 +320,20 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 				// We link forward from all copies for the node to achieve the
 				// fan-in.
 				//   i == 0 AND ( iterating j )
+				//
+				// In case (4) above, copies is > 1 but the predecessor only
+				// has a single copy. We link forward from that copy to every
+				// copy of the node to achieve the fan-out.
+				//   ( iterating i ) AND j == 0
 				for j := 0; j < predCopies; j++ {
 					// Either i == 0 && j == 0: we are either iterating i, or we are iterating j.
-					executionNode := v.nodes[p][i+j]
-					transport := newConsecutiveTransport(v.es.ctx, v.es.dispatcher, tr, node, v.es.logger, v.es.alloc)
+					executionNode := v.nodes[p][v.predecessorCopy(p, i+j)]
+					transport := newConsecutiveTransport(v.es.ctx, v.es.dispatcher, tr, node, p, v.es.logger, v.es.alloc)
+					// Every transport of a transformation shares its allocator, so the
+					// allocations are only recorded in the profile of the first one.
+					if pi == 0 && j == 0 {
+						transport.alloc = ec[i].alloc
+					}
 					v.es.transports = append(v.es.transports, transport)
 					executionNode.AddTransformation(transport)
 				}
@@ -316,6 +366,16 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 	return nil
 }
 
+// predecessorCopy returns the index of the copy of the predecessor that
+// the given copy of a node reads from. A predecessor that only has
+// a single copy is read by every copy of the node.
+func (v *createExecutionNodeVisitor) predecessorCopy(pred plan.Node, i int) int {
+	if len(v.nodes[pred]) == 1 {
+		return 0
+	}
+	return i
+}
+
 // generateResult will attach a result to the query for the specified node.
 func (v *createExecutionNodeVisitor) generateResult(resultName string, node plan.Node, idx int) error {
 	// if the result name is already present in the result set, that's an error.
@@ -489,6 +549,26 @@ func (es *executionState) chooseDefaultResources(ctx context.Context, p *plan.Sp
 	}
 }
 
+// admit waits until the admission controller in the context,
+// if there is one, lets the query run. The execution state is
+// created from the returned context so that the sources and
+// transformations of the query run with its ticket.
+func admit(ctx context.Context, p *plan.Spec) (context.Context, *admission.Ticket, error) {
+	c, ok := admission.Get(ctx)
+	if !ok {
+		return ctx, nil, nil
+	}
+	workers := p.Resources.ConcurrencyQuota
+	if workers == 0 {
+		workers = computeQueryConcurrencyQuota(ctx, p)
+	}
+	t, err := c.Admit(ctx, workers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return admission.WithTicket(ctx, t), t, nil
+}
+
 func (es *executionState) abort(err error) {
 	for _, r := range es.results {
 		r.(*result).abort(err)
@@ -534,6 +614,9 @@ func (es *executionState) do() {
 			defer es.recover()
 			src.Run(ctx)
 			profileSpan.Finish()
+			if alloc := es.sourceAllocs[src]; alloc != nil {
+				profile.Allocated = alloc.TotalAllocated()
+			}
 
 			updateStats(func(stats *flux.Statistics) {
 				stats.Profiles = append(stats.Profiles, profile)
@@ -578,6 +661,11 @@ func (es *executionState) do() {
 		defer close(es.statsCh)
 		wg.Wait()
 
+		if es.ticket != nil {
+			es.ticket.Release()
+			stats.QueueDuration = es.ticket.Wait()
+		}
+
 		// Merge the transport profiles in with the ones already filled
 		// by the sources.
 		stats.Profiles = append(stats.Profiles, profiles...)
@@ -597,6 +685,9 @@ type executionContext struct {
 	parents       []DatasetID
 	streamContext streamContext
 	parallelOpts  ParallelOpts
+	// alloc records the memory allocated by the
+	// operation when the operator profiler is enabled.
+	alloc *profilingAllocator
 }
 
 func resolveTime(qt flux.Time, now time.Time) Time {
@@ -616,6 +707,9 @@ func (ec executionContext) StreamContext() StreamContext {
 }
 
 func (ec executionContext) Allocator() memory.Allocator {
+	if ec.alloc != nil {
+		return ec.alloc
+	}
 	return ec.es.alloc
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/dependency"
 	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/admission"
 	"github.com/InfluxCommunity/flux/execute/executetest"
 	_ "github.com/InfluxCommunity/flux/fluxinit/static"
 	"github.com/InfluxCommunity/flux/internal/errors"
@@ -26,6 +27,7 @@ import (
 func init() {
 	execute.RegisterSource(executetest.FromTestKind, executetest.CreateFromSource)
 	execute.RegisterSource(executetest.AllocatingFromTestKind, executetest.CreateAllocatingFromSource)
+	execute.RegisterSource(admittedFromTestKind, createAdmittedFromSource)
 	execute.RegisterTransformation(executetest.ToTestKind, executetest.CreateToTransformation)
 	plan.RegisterProcedureSpecWithSideEffect(executetest.ToTestKind, executetest.NewToProcedure, executetest.ToTestKind)
 }
@@ -802,3 +804,78 @@ func TestExecutor_Execute(t *testing.T) {
 		})
 	}
 }
+
+const admittedFromTestKind = "admitted-from-test"
+
+// admittedFromProcedureSpec is a from-test source that is
+// admitted again by the controller when it is created.
+type admittedFromProcedureSpec struct {
+	*executetest.FromProcedureSpec
+	controller *admission.Controller
+}
+
+func (s *admittedFromProcedureSpec) Kind() plan.ProcedureKind {
+	return admittedFromTestKind
+}
+
+func (s *admittedFromProcedureSpec) Copy() plan.ProcedureSpec {
+	return s
+}
+
+func createAdmittedFromSource(spec plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	s := spec.(*admittedFromProcedureSpec)
+	t, err := s.controller.Admit(a.Context(), 1)
+	if err != nil {
+		return nil, err
+	}
+	t.Release()
+	return executetest.CreateFromSource(s.FromProcedureSpec, id, a)
+}
+
+// TestExecutor_Admission checks that the sources of a query are created
+// with the context of the admitted query, so that a query they run is
+// admitted right away instead of waiting for the query to finish.
+func TestExecutor_Admission(t *testing.T) {
+	controller := admission.NewController(admission.Config{
+		MaxConcurrentQueries: 1,
+		QueueTimeout:         10 * time.Millisecond,
+	})
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", &admittedFromProcedureSpec{
+				FromProcedureSpec: executetest.NewFromProcedureSpec(nil),
+				controller:        controller,
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{{0, 1}},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+	defer deps.Finish()
+	ctx = admission.Inject(ctx, controller)
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, stats, err := exe.Execute(ctx, ps, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			tbl.Done()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-stats
+
+	if want, got := int64(1), controller.Stats().Admitted; want != got {
+		t.Fatalf("unexpected admitted count -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+}
@@ -43,6 +43,10 @@ func (n *narrowTransformation) ProcessMessage(m Message) error {
 		return n.t.Process(m.TableChunk(), n.d, n.d.mem)
 	case FlushKeyMsg:
 		return n.d.FlushKey(m.Key())
+	case UpdateWatermarkMsg:
+		return n.d.UpdateWatermark(m.WatermarkTime())
+	case UpdateProcessingTimeMsg:
+		return n.d.UpdateProcessingTime(m.ProcessingTime())
 	case ProcessMsg:
 		panic("unreachable")
 	}
@@ -279,6 +279,48 @@ func TestNarrowTransformation_Finish(t *testing.T) {
 	}
 }
 
+// Ensure that watermarks from a legacy upstream are forwarded
+// to the downstream transformations so that their triggers fire.
+func TestNarrowTransformation_UpdateWatermark(t *testing.T) {
+	tr, d, err := execute.NewNarrowTransformation(
+		executetest.RandomDatasetID(),
+		&mock.NarrowTransformation{},
+		memory.DefaultAllocator,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var watermark, processingTime execute.Time
+	d.AddTransformation(
+		&mock.Transformation{
+			UpdateWatermarkFn: func(id execute.DatasetID, ts execute.Time) error {
+				watermark = ts
+				return nil
+			},
+			UpdateProcessingTimeFn: func(id execute.DatasetID, ts execute.Time) error {
+				processingTime = ts
+				return nil
+			},
+		},
+	)
+
+	id := executetest.RandomDatasetID()
+	if err := tr.UpdateWatermark(id, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.UpdateProcessingTime(id, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := execute.Time(10), watermark; want != got {
+		t.Errorf("unexpected watermark -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if want, got := execute.Time(20), processingTime; want != got {
+		t.Errorf("unexpected processing time -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+}
+
 // Ensure that we report the operation type of the type we wrap
 // and ensure that we don't report ourselves as the operation type.
 //
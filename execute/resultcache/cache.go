@@ -0,0 +1,122 @@
+// Package resultcache caches the results of queries so that a query
+// that is run again, such as the queries of a dashboard that refreshes
+// every few seconds, does not need to read its sources again.
+//
+// Caching is opt-in. It is enabled by injecting a Config into the
+// context or by compiling a program with lang.WithResultCache. Results
+// are stored in the Cache of the Config, such as an in-memory LRU, as
+// Arrow IPC streams under a key that is computed from the physical plan.
+//
+// The key is built from the namespace of the Config, the kinds and
+// procedure specs of the plan nodes and the edges between them. Sources
+// may read data that depends on the context of the query, such as the
+// organization or the credentials of the caller, which the plan does not
+// describe. Queries only share results within a namespace, so an embedder
+// must give every tenant its own namespace. Time bounds are resolved with the now time
+// of the plan, so a relative range() produces a different key whenever
+// now changes. Plans that read state the key cannot describe, such as
+// functions that refer to options or to user-defined closures, are not
+// cached.
+//
+// When every path of the plan is a source followed by a single range()
+// and row-wise operations, such as filter() or drop(), the time bounds
+// are left out of the key and the bounds of the cached results are
+// stored with them. A query whose bounds are within the cached bounds is
+// answered by slicing the cached rows to its bounds. A query whose stop
+// extends past the cached stop by no more than Config.MaxStaleness is
+// answered the same way, which lets refreshing dashboards with a range
+// that is relative to now reuse results. Queries that only partially
+// overlap the cached bounds run in full and replace the cached results.
+//
+// Plans with side effects, such as sql.to() or kafka.to(), are never
+// cached because reading their results from the cache would skip the
+// writes. Plans that read a source that is not limited to a time range,
+// such as sql.from() or http.from(), are only cached if the Config has a
+// TTL, since nothing else would replace their results when the data of
+// the source changes.
+//
+// A query that is not in the cache reads all of its results into memory
+// before the first one is returned so that they can be stored. The
+// results count toward the memory limit of the query.
+package resultcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// Cache stores serialized query results.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored for the key
+	// and whether the key was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores the value for the key. The value expires after the ttl.
+	// A ttl of zero means the value does not expire.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Config configures the result cache.
+type Config struct {
+	// Cache stores the results. It is required.
+	Cache Cache
+
+	// Namespace is added to the key of every result, so that queries
+	// only share results with queries of the same namespace.
+	// It is required and is usually the ID of the tenant of the query.
+	Namespace string
+
+	// TTL is how long results are kept. Zero keeps them until they
+	// are evicted and disables the cache for plans that read sources
+	// that are not limited to a time range.
+	TTL time.Duration
+
+	// MaxStaleness is how far the stop of a query may be past the stop
+	// of the cached results for them to be reused. The rows between the
+	// two stops are missing from the reused results.
+	MaxStaleness time.Duration
+}
+
+// Validate reports whether the Config has a Cache and a Namespace.
+func (c Config) Validate() error {
+	if c.Cache == nil {
+		return errors.New(codes.Invalid, "result cache requires a cache")
+	}
+	if c.Namespace == "" {
+		return errors.New(codes.Invalid, "result cache requires a namespace")
+	}
+	return nil
+}
+
+type key int
+
+const configKey key = iota
+
+// Dependency will inject the result cache Config into the dependency chain.
+type Dependency struct {
+	Config *Config
+}
+
+// Inject will inject the result cache Config into the dependency chain.
+func (d Dependency) Inject(ctx context.Context) context.Context {
+	if d.Config != nil {
+		ctx = Inject(ctx, *d.Config)
+	}
+	return ctx
+}
+
+// Inject will enable the result cache with the Config for the context.
+func Inject(ctx context.Context, c Config) context.Context {
+	return context.WithValue(ctx, configKey, c)
+}
+
+// Get will retrieve the result cache Config from the context.Context.
+// It returns false if the result cache is not enabled.
+func Get(ctx context.Context) (Config, bool) {
+	c, ok := ctx.Value(configKey).(Config)
+	return c, ok
+}
@@ -0,0 +1,442 @@
+package resultcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/arrow"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/values"
+	arrowlib "github.com/apache/arrow/go/v7/arrow"
+	arrowarray "github.com/apache/arrow/go/v7/arrow/array"
+	"github.com/apache/arrow/go/v7/arrow/ipc"
+)
+
+// The results of a query are stored as a header followed by the Arrow IPC
+// streams of the tables in the order that the header lists them. The header
+// starts with entryVersion and is made of unsigned varints, strings that
+// are prefixed with their length and single bytes:
+//
+//	bounds:  0 | 1 start stop
+//	results: count (name tables)...
+//	table:   columns (label type)... key (label type null value)... size
+//
+// The rows of a table are stored as an Arrow IPC stream, with strings
+// stored as binary, and size is the length of that stream.
+const entryVersion = 1
+
+// entryTable is a table of a result in the header of an entry.
+type entryTable struct {
+	Key  []entryKeyValue
+	Cols []flux.ColMeta
+	Size int
+}
+
+// entryKeyValue is a value of a group key. It is stored as a string
+// so that integers and times keep their precision.
+type entryKeyValue struct {
+	Label string
+	Type  flux.ColType
+	Null  bool
+	Value string
+}
+
+// encodeEntry serializes the results. The tables of the results
+// are copied so the results can still be read.
+func encodeEntry(bounds *plan.Bounds, results []*Result, mem memory.Allocator) ([]byte, error) {
+	var (
+		header entryWriter
+		data   bytes.Buffer
+	)
+	header.uvarint(entryVersion)
+	if bounds == nil {
+		header.byte(0)
+	} else {
+		header.byte(1)
+		header.varint(int64(bounds.Start))
+		header.varint(int64(bounds.Stop))
+	}
+	header.uvarint(uint64(len(results)))
+	for _, res := range results {
+		header.string(res.name)
+		header.uvarint(uint64(len(res.tables)))
+		for _, tbl := range res.tables {
+			et, err := encodeTable(tbl.Copy(), &data, mem)
+			if err != nil {
+				return nil, err
+			}
+			header.table(et)
+		}
+	}
+	return append(header.buf.Bytes(), data.Bytes()...), nil
+}
+
+// encodeTable writes the rows of the table to w and
+// returns the header of the table.
+func encodeTable(tbl flux.Table, w *bytes.Buffer, mem memory.Allocator) (entryTable, error) {
+	key := tbl.Key()
+	et := entryTable{
+		Key:  make([]entryKeyValue, len(key.Cols())),
+		Cols: tbl.Cols(),
+	}
+	for j, c := range key.Cols() {
+		et.Key[j] = encodeKeyValue(c, key.Value(j))
+	}
+
+	fields := make([]arrowlib.Field, len(et.Cols))
+	for j, c := range et.Cols {
+		fields[j] = arrowlib.Field{Name: c.Label, Type: arrowType(c.Type), Nullable: true}
+	}
+	schema := arrowlib.NewSchema(fields, nil)
+
+	n := w.Len()
+	iw := ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		if cr.Len() == 0 {
+			return nil
+		}
+		cols := make([]arrowlib.Array, len(et.Cols))
+		defer func() {
+			for _, c := range cols {
+				if c != nil {
+					c.Release()
+				}
+			}
+		}()
+		for j := range et.Cols {
+			cols[j] = toArrow(table.Values(cr, j), mem)
+		}
+		rec := arrowarray.NewRecord(schema, cols, int64(cr.Len()))
+		defer rec.Release()
+		return iw.Write(rec)
+	}); err != nil {
+		return entryTable{}, errors.Wrap(err, codes.Internal, "cannot encode cached table")
+	}
+	if err := iw.Close(); err != nil {
+		return entryTable{}, errors.Wrap(err, codes.Internal, "cannot encode cached table")
+	}
+	et.Size = w.Len() - n
+	return et, nil
+}
+
+// entryWriter writes the header of an entry.
+type entryWriter struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (w *entryWriter) byte(b byte) {
+	w.buf.WriteByte(b)
+}
+
+func (w *entryWriter) uvarint(v uint64) {
+	w.buf.Write(w.tmp[:binary.PutUvarint(w.tmp[:], v)])
+}
+
+func (w *entryWriter) varint(v int64) {
+	w.buf.Write(w.tmp[:binary.PutVarint(w.tmp[:], v)])
+}
+
+func (w *entryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *entryWriter) table(et entryTable) {
+	w.uvarint(uint64(len(et.Cols)))
+	for _, c := range et.Cols {
+		w.string(c.Label)
+		w.byte(byte(c.Type))
+	}
+	w.uvarint(uint64(len(et.Key)))
+	for _, kv := range et.Key {
+		w.string(kv.Label)
+		w.byte(byte(kv.Type))
+		if kv.Null {
+			w.byte(1)
+		} else {
+			w.byte(0)
+		}
+		w.string(kv.Value)
+	}
+	w.uvarint(uint64(et.Size))
+}
+
+// entryReader reads the header of an entry. The first error
+// is kept and every read after it returns a zero value.
+type entryReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (r *entryReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.r.ReadByte()
+	r.err = err
+	return b
+}
+
+func (r *entryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	r.err = err
+	return v
+}
+
+func (r *entryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(r.r)
+	r.err = err
+	return v
+}
+
+// count reads a number of items or bytes that follow it. It is an error
+// if the count is larger than the remaining bytes of the entry.
+func (r *entryReader) count() int {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(r.r.Len()) {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return int(n)
+}
+
+func (r *entryReader) string() string {
+	n := r.count()
+	if r.err != nil || n == 0 {
+		return ""
+	}
+	b := make([]byte, n)
+	_, r.err = io.ReadFull(r.r, b)
+	return string(b)
+}
+
+func (r *entryReader) table() entryTable {
+	et := entryTable{Cols: make([]flux.ColMeta, r.count())}
+	for j := range et.Cols {
+		et.Cols[j] = flux.ColMeta{Label: r.string(), Type: flux.ColType(r.byte())}
+	}
+	et.Key = make([]entryKeyValue, r.count())
+	for j := range et.Key {
+		et.Key[j] = entryKeyValue{
+			Label: r.string(),
+			Type:  flux.ColType(r.byte()),
+			Null:  r.byte() == 1,
+			Value: r.string(),
+		}
+	}
+	et.Size = int(r.uvarint())
+	return et
+}
+
+// arrowType returns the Arrow type that a column is stored as.
+// This is the type of the arrays of the column except for strings,
+// which are stored as binary.
+func arrowType(typ flux.ColType) arrowlib.DataType {
+	switch typ {
+	case flux.TBool:
+		return array.BooleanType
+	case flux.TInt, flux.TTime:
+		return array.IntType
+	case flux.TUInt:
+		return array.UintType
+	case flux.TFloat:
+		return array.FloatType
+	default:
+		return arrowlib.BinaryTypes.Binary
+	}
+}
+
+// toArrow returns the arrow array that stores a column.
+func toArrow(arr array.Array, mem memory.Allocator) arrowlib.Array {
+	vs, ok := arr.(*array.String)
+	if !ok {
+		arr.Retain()
+		return arr.(arrowlib.Array)
+	}
+	b := arrowarray.NewBinaryBuilder(mem, arrowlib.BinaryTypes.Binary)
+	defer b.Release()
+	b.Reserve(vs.Len())
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.Append(vs.ValueBytes(i))
+	}
+	return b.NewArray()
+}
+
+func encodeKeyValue(c flux.ColMeta, v values.Value) entryKeyValue {
+	kv := entryKeyValue{Label: c.Label, Type: c.Type}
+	if v.IsNull() {
+		kv.Null = true
+		return kv
+	}
+	switch c.Type {
+	case flux.TBool:
+		kv.Value = strconv.FormatBool(v.Bool())
+	case flux.TInt:
+		kv.Value = strconv.FormatInt(v.Int(), 10)
+	case flux.TUInt:
+		kv.Value = strconv.FormatUint(v.UInt(), 10)
+	case flux.TFloat:
+		kv.Value = strconv.FormatUint(math.Float64bits(v.Float()), 16)
+	case flux.TString:
+		kv.Value = v.Str()
+	case flux.TTime:
+		kv.Value = strconv.FormatInt(int64(v.Time()), 10)
+	}
+	return kv
+}
+
+func decodeKeyValue(kv entryKeyValue) (values.Value, error) {
+	typ := flux.SemanticType(kv.Type)
+	if kv.Null {
+		return values.NewNull(typ), nil
+	}
+	switch kv.Type {
+	case flux.TBool:
+		v, err := strconv.ParseBool(kv.Value)
+		return values.NewBool(v), err
+	case flux.TInt:
+		v, err := strconv.ParseInt(kv.Value, 10, 64)
+		return values.NewInt(v), err
+	case flux.TUInt:
+		v, err := strconv.ParseUint(kv.Value, 10, 64)
+		return values.NewUInt(v), err
+	case flux.TFloat:
+		v, err := strconv.ParseUint(kv.Value, 16, 64)
+		return values.NewFloat(math.Float64frombits(v)), err
+	case flux.TString:
+		return values.NewString(kv.Value), nil
+	case flux.TTime:
+		v, err := strconv.ParseInt(kv.Value, 10, 64)
+		return values.NewTime(values.Time(v)), err
+	default:
+		return nil, errors.Newf(codes.Internal, "cannot decode group key value of type %s", kv.Type)
+	}
+}
+
+// decodeEntry deserializes results that were stored by encodeEntry.
+func decodeEntry(data []byte, mem memory.Allocator) (*plan.Bounds, []*Result, error) {
+	r := &entryReader{r: bytes.NewReader(data)}
+	if v := r.uvarint(); r.err == nil && v != entryVersion {
+		return nil, nil, errors.Newf(codes.Internal, "cannot decode cached results with version %d", v)
+	}
+	var bounds *plan.Bounds
+	if r.byte() == 1 {
+		bounds = &plan.Bounds{
+			Start: values.Time(r.varint()),
+			Stop:  values.Time(r.varint()),
+		}
+	}
+	type header struct {
+		name   string
+		tables []entryTable
+	}
+	headers := make([]header, r.count())
+	for i := range headers {
+		headers[i].name = r.string()
+		headers[i].tables = make([]entryTable, r.count())
+		for j := range headers[i].tables {
+			headers[i].tables[j] = r.table()
+		}
+	}
+	if r.err != nil {
+		return nil, nil, errors.Wrap(r.err, codes.Internal, "cannot decode cached results")
+	}
+
+	// The streams of the tables follow the header.
+	data = data[len(data)-r.r.Len():]
+	results := make([]*Result, 0, len(headers))
+	release := func() {
+		for _, res := range results {
+			res.Release()
+		}
+	}
+	for _, h := range headers {
+		res := &Result{name: h.name}
+		results = append(results, res)
+		for _, et := range h.tables {
+			if et.Size > len(data) {
+				release()
+				return nil, nil, errors.New(codes.Internal, "cannot decode cached results: unexpected end of data")
+			}
+			tbl, err := decodeTable(et, data[:et.Size], mem)
+			if err != nil {
+				release()
+				return nil, nil, err
+			}
+			data = data[et.Size:]
+			res.tables = append(res.tables, tbl)
+		}
+	}
+	return bounds, results, nil
+}
+
+func decodeTable(et entryTable, data []byte, mem memory.Allocator) (flux.BufferedTable, error) {
+	keyCols := make([]flux.ColMeta, len(et.Key))
+	keyValues := make([]values.Value, len(et.Key))
+	for j, kv := range et.Key {
+		v, err := decodeKeyValue(kv)
+		if err != nil {
+			return nil, errors.Wrap(err, codes.Internal, "cannot decode cached table")
+		}
+		keyCols[j] = flux.ColMeta{Label: kv.Label, Type: kv.Type}
+		keyValues[j] = v
+	}
+	key := execute.NewGroupKey(keyCols, keyValues)
+
+	tbl := &table.BufferedTable{
+		GroupKey: key,
+		Columns:  et.Cols,
+	}
+	if len(data) == 0 {
+		return table.Copy(tbl)
+	}
+
+	r, err := ipc.NewReader(bytes.NewReader(data), ipc.WithAllocator(mem))
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "cannot decode cached table")
+	}
+	defer r.Release()
+	for r.Next() {
+		rec := r.Record()
+		buf := &arrow.TableBuffer{
+			GroupKey: key,
+			Columns:  et.Cols,
+			Values:   make([]array.Array, len(et.Cols)),
+		}
+		for j, col := range rec.Columns() {
+			if bin, ok := col.(*arrowarray.Binary); ok {
+				buf.Values[j] = array.NewStringFromBinaryArray(bin)
+				continue
+			}
+			col.Retain()
+			buf.Values[j] = col.(array.Array)
+		}
+		tbl.Buffers = append(tbl.Buffers, buf)
+	}
+	if err := r.Err(); err != nil && err != io.EOF {
+		tbl.Done()
+		return nil, errors.Wrap(err, codes.Internal, "cannot decode cached table")
+	}
+	return table.Copy(tbl)
+}
@@ -0,0 +1,101 @@
+package resultcache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncodeEntry(t *testing.T) {
+	mem := memory.DefaultAllocator
+	key := execute.NewGroupKey(
+		[]flux.ColMeta{{Label: "host", Type: flux.TString}, {Label: "region", Type: flux.TString}},
+		[]values.Value{values.NewString("a"), values.NewNull(flux.SemanticType(flux.TString))},
+	)
+	b := execute.NewColListTableBuilder(key, mem)
+	for _, c := range []flux.ColMeta{
+		{Label: "host", Type: flux.TString},
+		{Label: "region", Type: flux.TString},
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+	} {
+		if _, err := b.AddCol(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		_ = b.AppendString(0, "a")
+		_ = b.AppendNil(1)
+		_ = b.AppendTime(2, execute.Time(i))
+		_ = b.AppendFloat(3, float64(i)+0.5)
+	}
+	tbl, err := b.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffered, err := table.Copy(tbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := &plan.Bounds{Start: 1, Stop: 10}
+	results := []*Result{{name: "_result", tables: []flux.BufferedTable{buffered}}}
+	data, err := encodeEntry(bounds, results, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotBounds, got, err := decodeEntry(data, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, res := range got {
+			res.Release()
+		}
+	}()
+
+	if !cmp.Equal(bounds, gotBounds) {
+		t.Errorf("unexpected bounds -want/+got:\n%s", cmp.Diff(bounds, gotBounds))
+	}
+	if len(got) != 1 || got[0].name != "_result" || len(got[0].tables) != 1 {
+		t.Fatalf("unexpected results: %v", got)
+	}
+	if want, got := rows(t, buffered.Copy()), rows(t, got[0].tables[0].Copy()); !cmp.Equal(want, got) {
+		t.Errorf("unexpected rows -want/+got:\n%s", cmp.Diff(want, got))
+	}
+	if !got[0].tables[0].Key().Equal(key) {
+		t.Errorf("unexpected group key -want/+got:\n\t- %v\n\t+ %v", key, got[0].tables[0].Key())
+	}
+
+	if _, _, err := decodeEntry(data[:len(data)/2], mem); err == nil {
+		t.Error("expected an error when decoding a truncated entry")
+	}
+}
+
+// rows returns the values of the rows of a table.
+func rows(t *testing.T, tbl flux.Table) [][]interface{} {
+	t.Helper()
+	var rows [][]interface{}
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		for i := 0; i < cr.Len(); i++ {
+			row := make([]interface{}, len(cr.Cols()))
+			for j := range cr.Cols() {
+				if v := execute.ValueForRow(cr, i, j); !v.IsNull() {
+					row[j] = fmt.Sprint(v)
+				}
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
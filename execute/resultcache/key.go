@@ -0,0 +1,406 @@
+package resultcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// maxDepth is the deepest a procedure spec is described before the
+// plan is considered uncacheable. It guards against cyclic specs.
+const maxDepth = 64
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	fluxTimeType      = reflect.TypeOf(flux.Time{})
+	fluxBoundsType    = reflect.TypeOf(flux.Bounds{})
+	regexpType        = reflect.TypeOf((*regexp.Regexp)(nil))
+	resolvedFnType    = reflect.TypeOf(interpreter.ResolvedFunction{})
+	valueType         = reflect.TypeOf((*values.Value)(nil)).Elem()
+	locType           = reflect.TypeOf(semantic.Loc{})
+	monoType          = reflect.TypeOf(semantic.MonoType{})
+	polyType          = reflect.TypeOf(semantic.PolyType{})
+	durationValueType = reflect.TypeOf(values.Duration{})
+)
+
+var errNotCacheable = errors.New(codes.Unimplemented, "plan cannot be cached")
+
+// planKey returns the key of a plan within the namespace. The time bounds
+// in the procedure specs of the nodes in without are left out of the key.
+// It returns false if the plan cannot be described by a key or
+// has side effects, such as writing to an external system, that
+// would be skipped if the results were read from the cache.
+func planKey(namespace string, p *plan.Spec, without map[plan.Node]bool) (string, bool) {
+	w := &keyWriter{
+		h:   sha256.New(),
+		now: p.Now,
+	}
+	w.printf("namespace %q\n", namespace)
+
+	// Nodes are referred to by the order they are visited in
+	// so that the key does not depend on the node IDs.
+	index := make(map[plan.Node]int)
+	if err := p.BottomUpWalk(func(node plan.Node) error {
+		if hasSideEffect(node.ProcedureSpec()) {
+			return errNotCacheable
+		}
+		index[node] = len(index)
+		w.printf("node %d %s(", index[node], node.Kind())
+		for _, pred := range node.Predecessors() {
+			w.printf("%d,", index[pred])
+		}
+		w.printf(")\n")
+		w.withBounds = !without[node]
+		if err := w.write(reflect.ValueOf(node.ProcedureSpec()), 0); err != nil {
+			return err
+		}
+		w.printf("\n")
+		return nil
+	}); err != nil {
+		return "", false
+	}
+	return hex.EncodeToString(w.h.Sum(nil)), true
+}
+
+// hasSideEffect reports whether executing a procedure has side effects.
+// Yields are registered with side effects so that they name a result,
+// but they do not change anything outside of the query.
+func hasSideEffect(spec plan.ProcedureSpec) bool {
+	if _, ok := spec.(plan.YieldProcedureSpec); ok {
+		return false
+	}
+	return plan.HasSideEffect(spec)
+}
+
+// keyWriter writes a description of the procedure specs of a plan to a hash.
+type keyWriter struct {
+	h          hash.Hash
+	now        time.Time
+	withBounds bool
+}
+
+func (w *keyWriter) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(w.h, format, args...)
+}
+
+// write describes a value. Unexported fields are described too,
+// except for source locations and types, which do not change
+// the results of an operation.
+func (w *keyWriter) write(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errNotCacheable
+	}
+
+	switch v.Type() {
+	case locType, monoType, polyType:
+		return nil
+	case fluxTimeType:
+		if !w.withBounds {
+			w.printf("time;")
+			return nil
+		}
+		if v.CanInterface() {
+			t := v.Interface().(flux.Time)
+			w.printf("time(%d);", t.Time(w.now).UnixNano())
+			return nil
+		}
+	case fluxBoundsType:
+		if !w.withBounds {
+			w.printf("bounds;")
+			return nil
+		}
+		if v.CanInterface() {
+			b := v.Interface().(flux.Bounds)
+			now := b.Now
+			if now.IsZero() {
+				now = w.now
+			}
+			w.printf("bounds(%d,%d);", b.Start.Time(now).UnixNano(), b.Stop.Time(now).UnixNano())
+			return nil
+		}
+	case timeType:
+		if v.CanInterface() {
+			w.printf("%d;", v.Interface().(time.Time).UnixNano())
+			return nil
+		}
+	case durationValueType:
+		if v.CanInterface() {
+			w.printf("%s;", v.Interface().(values.Duration))
+			return nil
+		}
+	case regexpType:
+		if v.CanInterface() {
+			if v.IsNil() {
+				w.printf("nil;")
+			} else {
+				w.printf("%q;", v.Interface().(*regexp.Regexp).String())
+			}
+			return nil
+		}
+	case resolvedFnType:
+		if v.CanInterface() {
+			return w.writeFunction(v.Interface().(interpreter.ResolvedFunction), depth)
+		}
+	}
+	if v.Type().Implements(valueType) && v.CanInterface() && !isNil(v) {
+		return w.writeValue(v.Interface().(values.Value), depth)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		w.printf("%t;", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.printf("%d;", v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.printf("%d;", v.Uint())
+	case reflect.Float32, reflect.Float64:
+		w.printf("%x;", math.Float64bits(v.Float()))
+	case reflect.String:
+		w.printf("%q;", v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.printf("nil;")
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			w.printf("%s:", v.Elem().Type())
+		}
+		return w.write(v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		w.printf("[%d:", v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := w.write(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		w.printf("]")
+	case reflect.Map:
+		// Describe every entry on its own so that
+		// the entries can be sorted.
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			ew := &keyWriter{h: sha256.New(), now: w.now, withBounds: w.withBounds}
+			if err := ew.write(iter.Key(), depth+1); err != nil {
+				return err
+			}
+			if err := ew.write(iter.Value(), depth+1); err != nil {
+				return err
+			}
+			entries = append(entries, string(ew.h.Sum(nil)))
+		}
+		sort.Strings(entries)
+		w.printf("{%d:", len(entries))
+		for _, e := range entries {
+			w.printf("%x;", e)
+		}
+		w.printf("}")
+	case reflect.Struct:
+		w.printf("%s{", v.Type())
+		for i := 0; i < v.NumField(); i++ {
+			w.printf("%s=", v.Type().Field(i).Name)
+			if err := w.write(v.Field(i), depth+1); err != nil {
+				return err
+			}
+		}
+		w.printf("}")
+	case reflect.Func, reflect.Chan:
+		if !v.IsNil() {
+			return errNotCacheable
+		}
+		w.printf("nil;")
+	default:
+		return errNotCacheable
+	}
+	return nil
+}
+
+// isNil reports whether the value is a nil pointer or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// writeValue describes a Flux value. Values that are not data,
+// such as functions and streams, cannot be described.
+func (w *keyWriter) writeValue(v values.Value, depth int) error {
+	if depth > maxDepth {
+		return errNotCacheable
+	}
+	if _, ok := v.(*values.Option); ok {
+		return errNotCacheable
+	}
+	if v.IsNull() {
+		w.printf("null(%s);", v.Type())
+		return nil
+	}
+
+	switch v.Type().Nature() {
+	case semantic.Bool:
+		w.printf("%t;", v.Bool())
+	case semantic.Int:
+		w.printf("%d;", v.Int())
+	case semantic.UInt:
+		w.printf("%du;", v.UInt())
+	case semantic.Float:
+		w.printf("%xf;", math.Float64bits(v.Float()))
+	case semantic.String:
+		w.printf("%q;", v.Str())
+	case semantic.Bytes:
+		w.printf("%x;", v.Bytes())
+	case semantic.Time:
+		w.printf("time(%d);", int64(v.Time()))
+	case semantic.Duration:
+		w.printf("%s;", v.Duration())
+	case semantic.Regexp:
+		w.printf("%q;", v.Regexp().String())
+	case semantic.Array:
+		arr := v.Array()
+		w.printf("[%d:", arr.Len())
+		var err error
+		arr.Range(func(i int, v values.Value) {
+			if err == nil {
+				err = w.writeValue(v, depth+1)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		w.printf("]")
+	case semantic.Object:
+		obj := v.Object()
+		labels := make([]string, 0, obj.Len())
+		obj.Range(func(name string, _ values.Value) {
+			labels = append(labels, name)
+		})
+		sort.Strings(labels)
+		w.printf("{")
+		for _, label := range labels {
+			w.printf("%q=", label)
+			prop, _ := obj.Get(label)
+			if err := w.writeValue(prop, depth+1); err != nil {
+				return err
+			}
+		}
+		w.printf("}")
+	case semantic.Dictionary:
+		// Dictionaries are sorted by their keys.
+		w.printf("dict(%s){", v.Type())
+		var err error
+		v.Dict().Range(func(key, value values.Value) {
+			if err == nil {
+				err = w.writeValue(key, depth+1)
+			}
+			if err == nil {
+				err = w.writeValue(value, depth+1)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		w.printf("}")
+	default:
+		return errNotCacheable
+	}
+	return nil
+}
+
+// writeFunction describes a function and the values of the identifiers
+// it refers to that are not defined within the function. Functions that
+// refer to options or to functions that are not from a package cannot be
+// described because what they return may change between queries.
+func (w *keyWriter) writeFunction(fn interpreter.ResolvedFunction, depth int) error {
+	if fn.Fn == nil {
+		w.printf("fn(nil);")
+		return nil
+	}
+	w.printf("fn(")
+	if err := w.write(reflect.ValueOf(fn.Fn), depth+1); err != nil {
+		return err
+	}
+
+	free := freeIdentifiers(fn.Fn)
+	names := make([]string, 0, len(free))
+	for name := range free {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sym := free[name]
+		w.printf("%s.%s=", strconv.Quote(sym.Package), name)
+
+		var (
+			v  values.Value
+			ok bool
+		)
+		if fn.Scope != nil {
+			v, ok = fn.Scope.Lookup(name)
+		}
+		if !ok {
+			if sym.Package == "" {
+				return errNotCacheable
+			}
+			w.printf("global;")
+			continue
+		}
+
+		if _, isOption := v.(*values.Option); isOption {
+			return errNotCacheable
+		} else if pkg, isPackage := v.(values.Package); isPackage {
+			w.printf("package(%q);", pkg.Path())
+			continue
+		} else if v.Type().Nature() == semantic.Function {
+			if sym.Package == "" {
+				return errNotCacheable
+			}
+			w.printf("global;")
+			continue
+		}
+		if err := w.writeValue(v, depth+1); err != nil {
+			return err
+		}
+	}
+	w.printf(");")
+	return nil
+}
+
+// freeIdentifiers returns the identifiers that a function refers to
+// that are not parameters of the function, or of a function within it,
+// or variables that it declares.
+func freeIdentifiers(fn *semantic.FunctionExpression) map[string]semantic.Symbol {
+	declared := make(map[string]bool)
+	used := make(map[string]semantic.Symbol)
+	semantic.Walk(semantic.CreateVisitor(func(node semantic.Node) {
+		switch n := node.(type) {
+		case *semantic.FunctionParameter:
+			declared[n.Key.Name.Name()] = true
+		case *semantic.NativeVariableAssignment:
+			declared[n.Identifier.Name.Name()] = true
+		case *semantic.IdentifierExpression:
+			used[n.Name.Name()] = n.Name
+		}
+	}), fn)
+
+	for name := range declared {
+		delete(used, name)
+	}
+	return used
+}
@@ -0,0 +1,187 @@
+package resultcache
+
+import (
+	"context"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// Result is a result whose tables are buffered in memory
+// so that they can be stored in the cache.
+type Result struct {
+	name   string
+	tables []flux.BufferedTable
+}
+
+// ReadResult reads and buffers the tables of a result.
+func ReadResult(res flux.Result) (*Result, error) {
+	r := &Result{name: res.Name()}
+	if err := res.Tables().Do(func(tbl flux.Table) error {
+		buffered, err := table.Copy(tbl)
+		if err != nil {
+			return err
+		}
+		r.tables = append(r.tables, buffered)
+		return nil
+	}); err != nil {
+		r.Release()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Result) Name() string {
+	return r.name
+}
+
+func (r *Result) Tables() flux.TableIterator {
+	tables := make(table.Iterator, len(r.tables))
+	for i, tbl := range r.tables {
+		tables[i] = tbl
+	}
+	return tables
+}
+
+// Release releases the tables of the result that were not read.
+func (r *Result) Release() {
+	for _, tbl := range r.tables {
+		if tbl != nil {
+			tbl.Done()
+		}
+	}
+}
+
+// Lookup finds and stores the results of a plan in the cache.
+type Lookup struct {
+	config Config
+	key    string
+	slice  *timeSlice
+}
+
+// NewLookup returns the Lookup for the results of a plan.
+// It returns false if the Config is not valid or the results of the
+// plan cannot be cached, such as when the plan reads from an unbounded
+// source or writes to an external system.
+func NewLookup(c Config, p *plan.Spec) (*Lookup, bool) {
+	if c.Validate() != nil {
+		return nil, false
+	}
+	for root := range p.Roots {
+		if plan.IsUnbounded(root) {
+			return nil, false
+		}
+		if c.TTL <= 0 && !timeBounded(root) {
+			return nil, false
+		}
+	}
+
+	l := &Lookup{config: c}
+	var without map[plan.Node]bool
+	if ts, ok := sliceable(p); ok {
+		l.slice = ts
+		without = ts.ranges
+	}
+	key, ok := planKey(c.Namespace, p, without)
+	if !ok {
+		return nil, false
+	}
+	l.key = key
+	return l, true
+}
+
+// Get returns the results of the plan from the cache.
+// It returns false if the cache has no results that can be used.
+func (l *Lookup) Get(ctx context.Context, mem memory.Allocator) ([]*Result, bool, error) {
+	data, ok, err := l.config.Cache.Get(ctx, l.key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	bounds, results, err := decodeEntry(data, mem)
+	if err != nil {
+		return nil, false, err
+	}
+	if l.slice == nil || bounds == nil || *bounds == l.slice.bounds {
+		return results, true, nil
+	}
+
+	maxStaleness := values.ConvertDurationNsecs(l.config.MaxStaleness)
+	if !covers(*bounds, l.slice.bounds, maxStaleness) {
+		for _, res := range results {
+			res.Release()
+		}
+		return nil, false, nil
+	}
+	return l.sliceResults(results, mem)
+}
+
+// sliceResults slices the tables of the results to the bounds of the plan.
+func (l *Lookup) sliceResults(results []*Result, mem memory.Allocator) ([]*Result, bool, error) {
+	sliced := make([]*Result, len(results))
+	release := func() {
+		for _, res := range results {
+			res.Release()
+		}
+		for _, res := range sliced {
+			if res != nil {
+				res.Release()
+			}
+		}
+	}
+	for i, res := range results {
+		sliced[i] = &Result{name: res.name}
+		for j, tbl := range res.tables {
+			res.tables[j] = nil
+			out, ok, err := l.slice.slice(tbl, l.slice.bounds, mem)
+			if err != nil || !ok {
+				release()
+				return nil, false, err
+			} else if out == nil {
+				continue
+			}
+			buffered, err := table.Copy(out)
+			if err != nil {
+				release()
+				return nil, false, err
+			}
+			sliced[i].tables = append(sliced[i].tables, buffered)
+		}
+		res.tables = nil
+	}
+	return sliced, true, nil
+}
+
+// Set stores the results of the plan in the cache.
+// The results can still be read afterwards.
+func (l *Lookup) Set(ctx context.Context, results []*Result, mem memory.Allocator) error {
+	var bounds *plan.Bounds
+	if l.slice != nil {
+		bounds = &l.slice.bounds
+	}
+	data, err := encodeEntry(bounds, results, mem)
+	if err != nil {
+		return err
+	}
+	return l.config.Cache.Set(ctx, l.key, data, l.config.TTL)
+}
+
+// timeBounded reports whether every source that the node reads
+// from is limited to a time range by the node or a predecessor.
+func timeBounded(node plan.Node) bool {
+	if _, ok := node.ProcedureSpec().(plan.BoundsAwareProcedureSpec); ok && node.Bounds() != nil {
+		return true
+	}
+	preds := node.Predecessors()
+	if len(preds) == 0 {
+		return false
+	}
+	for _, pred := range preds {
+		if !timeBounded(pred) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+package resultcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/execute/resultcache"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/plan/plantest"
+	"github.com/InfluxCommunity/flux/stdlib/sql"
+	"github.com/InfluxCommunity/flux/stdlib/universe"
+)
+
+var cols = []flux.ColMeta{
+	{Label: "_start", Type: flux.TTime},
+	{Label: "_stop", Type: flux.TTime},
+	{Label: "_time", Type: flux.TTime},
+	{Label: "_value", Type: flux.TFloat},
+}
+
+// rangePlan returns the plan of a source followed by a range with the
+// bounds and the operation, if one is given.
+func rangePlan(start, stop int64, spec plan.PhysicalProcedureSpec) *plan.Spec {
+	nodes := []plan.Node{
+		plan.CreatePhysicalNode("from", executetest.NewFromProcedureSpec(nil)),
+		plan.CreatePhysicalNode("range", &universe.RangeProcedureSpec{
+			Bounds: flux.Bounds{
+				Start: flux.Time{Absolute: time.Unix(0, start)},
+				Stop:  flux.Time{Absolute: time.Unix(0, stop)},
+			},
+			TimeColumn:  "_time",
+			StartColumn: "_start",
+			StopColumn:  "_stop",
+		}),
+	}
+	edges := [][2]int{{0, 1}}
+	if spec != nil {
+		nodes = append(nodes, plan.CreatePhysicalNode("op", spec))
+		edges = append(edges, [2]int{1, 2})
+	}
+	nodes = append(nodes, plan.CreatePhysicalNode("yield", &universe.YieldProcedureSpec{Name: "_result"}))
+	edges = append(edges, [2]int{len(nodes) - 2, len(nodes) - 1})
+
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: nodes,
+		Edges: edges,
+		Now:   time.Unix(0, 100),
+	})
+	if err := ps.BottomUpWalk(plan.ComputeBounds); err != nil {
+		panic(err)
+	}
+	return ps
+}
+
+func rangeResult(start, stop int64, times ...int64) *executetest.Result {
+	tbl := &executetest.Table{
+		KeyCols:   []string{"_start", "_stop"},
+		KeyValues: []interface{}{execute.Time(start), execute.Time(stop)},
+		ColMeta:   cols,
+	}
+	for _, t := range times {
+		tbl.Data = append(tbl.Data, []interface{}{
+			execute.Time(start), execute.Time(stop), execute.Time(t), float64(t),
+		})
+	}
+	return &executetest.Result{Nm: "_result", Tbls: []*executetest.Table{tbl}}
+}
+
+func store(t *testing.T, c resultcache.Config, ps *plan.Spec, res flux.Result) {
+	t.Helper()
+	l, ok := resultcache.NewLookup(c, ps)
+	if !ok {
+		t.Fatal("expected plan to be cacheable")
+	}
+	r, err := resultcache.ReadResult(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Set(context.Background(), []*resultcache.Result{r}, memory.DefaultAllocator); err != nil {
+		t.Fatal(err)
+	}
+	r.Release()
+}
+
+func TestLookup_SliceByTime(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		start, stop  int64
+		maxStaleness time.Duration
+		want         *executetest.Result
+	}{
+		{
+			name:  "same bounds",
+			start: 10,
+			stop:  20,
+			want:  rangeResult(10, 20, 10, 13, 16, 19),
+		},
+		{
+			name:  "within bounds",
+			start: 12,
+			stop:  17,
+			want:  rangeResult(12, 17, 13, 16),
+		},
+		{
+			name:  "before bounds",
+			start: 5,
+			stop:  15,
+		},
+		{
+			name:  "after bounds",
+			start: 15,
+			stop:  23,
+		},
+		{
+			name:         "after bounds within staleness",
+			start:        15,
+			stop:         23,
+			maxStaleness: 5,
+			want:         rangeResult(15, 23, 16, 19),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := resultcache.Config{
+				Cache:        resultcache.NewLRU(1 << 20),
+				Namespace:    "tenant",
+				MaxStaleness: tc.maxStaleness,
+			}
+			store(t, c, rangePlan(10, 20, nil), rangeResult(10, 20, 10, 13, 16, 19))
+
+			l, ok := resultcache.NewLookup(c, rangePlan(tc.start, tc.stop, nil))
+			if !ok {
+				t.Fatal("expected plan to be cacheable")
+			}
+			results, ok, err := l.Get(context.Background(), memory.DefaultAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.want == nil {
+				if ok {
+					t.Fatal("expected results to not be found")
+				}
+				return
+			} else if !ok {
+				t.Fatal("expected results to be found")
+			}
+			if len(results) != 1 {
+				t.Fatalf("unexpected number of results: %d", len(results))
+			}
+			if err := executetest.EqualResult(tc.want, results[0]); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLookup_Key(t *testing.T) {
+	sum := &universe.SumProcedureSpec{SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig}
+	c := resultcache.Config{Cache: resultcache.NewLRU(1 << 20), Namespace: "tenant"}
+	store(t, c, rangePlan(10, 20, sum), rangeResult(10, 20))
+
+	for _, tc := range []struct {
+		name   string
+		plan   *plan.Spec
+		config resultcache.Config
+		found  bool
+	}{
+		{
+			name:  "same plan",
+			plan:  rangePlan(10, 20, sum),
+			found: true,
+		},
+		{
+			// Aggregates cannot be sliced by time
+			// so the bounds are part of the key.
+			name: "within bounds",
+			plan: rangePlan(12, 17, sum),
+		},
+		{
+			name:   "different namespace",
+			plan:   rangePlan(10, 20, sum),
+			config: resultcache.Config{Cache: c.Cache, Namespace: "other"},
+		},
+		{
+			name: "different operation",
+			plan: rangePlan(10, 20, &universe.CountProcedureSpec{
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			if config.Cache == nil {
+				config = c
+			}
+			l, ok := resultcache.NewLookup(config, tc.plan)
+			if !ok {
+				t.Fatal("expected plan to be cacheable")
+			}
+			results, found, err := l.Get(context.Background(), memory.DefaultAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, res := range results {
+				res.Release()
+			}
+			if found != tc.found {
+				t.Errorf("unexpected lookup -want/+got:\n\t- %v\n\t+ %v", tc.found, found)
+			}
+		})
+	}
+}
+
+// funcProcedureSpec is a procedure spec with a Go function
+// that the key of a plan cannot describe.
+type funcProcedureSpec struct {
+	plan.DefaultCost
+	Fn func() int
+}
+
+func (s *funcProcedureSpec) Kind() plan.ProcedureKind {
+	return "func"
+}
+
+func (s *funcProcedureSpec) Copy() plan.ProcedureSpec {
+	return &funcProcedureSpec{Fn: s.Fn}
+}
+
+func TestLookup_NotCacheable(t *testing.T) {
+	ps := rangePlan(10, 20, &funcProcedureSpec{Fn: func() int { return 1 }})
+	if _, ok := resultcache.NewLookup(testConfig(), ps); ok {
+		t.Error("expected plan to not be cacheable")
+	}
+}
+
+func TestLookup_SideEffect(t *testing.T) {
+	ps := rangePlan(10, 20, &sql.ToSQLProcedureSpec{
+		Spec: &sql.ToSQLOpSpec{
+			DriverName:     "sqlite3",
+			DataSourceName: "file::memory:",
+			Table:          "t",
+		},
+	})
+	if _, ok := resultcache.NewLookup(testConfig(), ps); ok {
+		t.Error("expected plan that writes to sql to not be cacheable")
+	}
+}
+
+func testConfig() resultcache.Config {
+	return resultcache.Config{Cache: resultcache.NewLRU(1 << 20), Namespace: "tenant"}
+}
+
+func TestLookup_InvalidConfig(t *testing.T) {
+	ps := rangePlan(10, 20, nil)
+	for _, c := range []resultcache.Config{
+		{Namespace: "tenant"},
+		{Cache: resultcache.NewLRU(1 << 20)},
+	} {
+		if _, ok := resultcache.NewLookup(c, ps); ok {
+			t.Errorf("expected config %+v to disable the cache", c)
+		}
+	}
+}
+
+func TestLookup_NoTimeRange(t *testing.T) {
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from", executetest.NewFromProcedureSpec(nil)),
+			plan.CreatePhysicalNode("yield", &universe.YieldProcedureSpec{Name: "_result"}),
+		},
+		Edges: [][2]int{{0, 1}},
+		Now:   time.Unix(0, 100),
+	})
+	if err := ps.BottomUpWalk(plan.ComputeBounds); err != nil {
+		t.Fatal(err)
+	}
+
+	c := testConfig()
+	if _, ok := resultcache.NewLookup(c, ps); ok {
+		t.Error("expected plan without a time range to not be cacheable without a TTL")
+	}
+	c.TTL = time.Minute
+	if _, ok := resultcache.NewLookup(c, ps); !ok {
+		t.Error("expected plan without a time range to be cacheable with a TTL")
+	}
+}
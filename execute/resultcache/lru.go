@@ -0,0 +1,99 @@
+package resultcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-memory Cache that holds up to a number of bytes of keys
+// and values and evicts the least recently used values to make room.
+type LRU struct {
+	mu      sync.Mutex
+	size    int64
+	maxSize int64
+	entries map[string]*list.Element
+	order   *list.List
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// NewLRU returns an LRU that holds up to maxSize bytes.
+func NewLRU(maxSize int64) *LRU {
+	return &LRU{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value for the key if it is present and has not expired.
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := elem.Value.(*lruEntry)
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.remove(elem)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return e.value, true, nil
+}
+
+// Set stores the value for the key and evicts the least recently used
+// values until the cache is within its size. Values that are larger
+// than the cache are not stored.
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	e := &lruEntry{key: key, value: value}
+	if e.size() > c.maxSize {
+		return nil
+	}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+	c.entries[key] = c.order.PushFront(e)
+	c.size += e.size()
+
+	for c.size > c.maxSize {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Size returns the number of bytes of keys and values in the cache.
+func (c *LRU) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *LRU) remove(elem *list.Element) {
+	e := c.order.Remove(elem).(*lruEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size()
+}
@@ -0,0 +1,63 @@
+package resultcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	c := NewLRU(16)
+	c.now = func() time.Time { return now }
+
+	get := func(key string) (string, bool) {
+		t.Helper()
+		v, ok, err := c.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(v), ok
+	}
+	set := func(key, value string, ttl time.Duration) {
+		t.Helper()
+		if err := c.Set(ctx, key, []byte(value), ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set("a", "aaaa", 0)
+	set("b", "bbbb", time.Second)
+	set("c", "cccc", 0)
+	if got, want := c.Size(), int64(15); got != want {
+		t.Fatalf("unexpected size -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// Reading a makes b the least recently used value,
+	// so it is evicted to make room for d.
+	if v, ok := get("a"); !ok || v != "aaaa" {
+		t.Fatalf("unexpected value for a: %q", v)
+	}
+	set("d", "dddd", 0)
+	if _, ok := get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := get(key); !ok {
+			t.Errorf("expected %s to be in the cache", key)
+		}
+	}
+
+	set("e", "eeee", time.Second)
+	now = now.Add(time.Second)
+	if _, ok := get("e"); ok {
+		t.Error("expected e to be expired")
+	}
+
+	// Values that are larger than the cache are not stored.
+	set("f", "ffffffffffffffffffff", 0)
+	if _, ok := get("f"); ok {
+		t.Error("expected f to not be stored")
+	}
+}
@@ -0,0 +1,191 @@
+package resultcache
+
+import (
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// RowWiseProcedureSpec is implemented by procedure specs of operations
+// that keep, drop or change every row on its own and do not change the
+// time, start or stop columns. The results of operations like these on
+// the rows of a time range are the same as slicing their results for a
+// wider time range.
+type RowWiseProcedureSpec interface {
+	RowWise() bool
+}
+
+// TimeRangeProcedureSpec is implemented by procedure specs of operations
+// that keep the rows whose time is within the bounds of their node and
+// set the start and stop columns to the bounds.
+type TimeRangeProcedureSpec interface {
+	TimeRangeColumns() (timeCol, startCol, stopCol string, ok bool)
+}
+
+// timeSlice describes the time range of the results of a plan
+// that can be sliced to a narrower time range.
+type timeSlice struct {
+	bounds   plan.Bounds
+	timeCol  string
+	startCol string
+	stopCol  string
+
+	// ranges are the nodes that set the time range.
+	ranges map[plan.Node]bool
+}
+
+// sliceable returns the time range of the results of a plan if every
+// path from a root of the plan to its source has one operation that sets
+// the time range, only row-wise operations after it and only operations
+// that do not depend on the time range before it. The operations must
+// agree on the time range and its columns.
+func sliceable(p *plan.Spec) (*timeSlice, bool) {
+	var ts *timeSlice
+	for root := range p.Roots {
+		node := root
+		for {
+			if _, ok := node.ProcedureSpec().(TimeRangeProcedureSpec); ok {
+				break
+			}
+			preds := node.Predecessors()
+			if !isRowWise(node.ProcedureSpec()) || len(preds) != 1 {
+				return nil, false
+			}
+			node = preds[0]
+		}
+
+		timeCol, startCol, stopCol, ok := node.ProcedureSpec().(TimeRangeProcedureSpec).TimeRangeColumns()
+		bounds := node.Bounds()
+		if !ok || bounds == nil || bounds.IsEmpty() || !unbounded(node.Predecessors()) {
+			return nil, false
+		}
+		if ts == nil {
+			ts = &timeSlice{
+				bounds:   *bounds,
+				timeCol:  timeCol,
+				startCol: startCol,
+				stopCol:  stopCol,
+				ranges:   make(map[plan.Node]bool),
+			}
+		} else if ts.bounds != *bounds || ts.timeCol != timeCol ||
+			ts.startCol != startCol || ts.stopCol != stopCol {
+			return nil, false
+		}
+		ts.ranges[node] = true
+	}
+	return ts, ts != nil
+}
+
+// unbounded reports whether the nodes and all of their
+// predecessors have no time bounds.
+func unbounded(nodes []plan.Node) bool {
+	for _, node := range nodes {
+		if node.Bounds() != nil || !unbounded(node.Predecessors()) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRowWise(spec plan.ProcedureSpec) bool {
+	if _, ok := spec.(plan.YieldProcedureSpec); ok {
+		return true
+	}
+	rw, ok := spec.(RowWiseProcedureSpec)
+	return ok && rw.RowWise()
+}
+
+// covers reports whether results for the cached bounds can be sliced
+// to the requested bounds. The requested stop may be past the cached
+// stop by up to maxStaleness.
+func covers(cached, requested plan.Bounds, maxStaleness values.Duration) bool {
+	if requested.Start < cached.Start || requested.Start >= cached.Stop {
+		return false
+	}
+	return requested.Stop <= cached.Stop.Add(maxStaleness)
+}
+
+// slice returns the rows of the table whose time is within the bounds
+// with the start and stop columns set to the bounds. It returns nil if
+// the table has no rows within the bounds, unless the table had no rows
+// to begin with. It returns false if the table has no time column.
+func (ts *timeSlice) slice(tbl flux.Table, bounds plan.Bounds, mem memory.Allocator) (flux.Table, bool, error) {
+	cols := tbl.Cols()
+	timeIdx := execute.ColIdx(ts.timeCol, cols)
+	if timeIdx < 0 || cols[timeIdx].Type != flux.TTime {
+		tbl.Done()
+		return nil, false, nil
+	}
+	startIdx := execute.ColIdx(ts.startCol, cols)
+	stopIdx := execute.ColIdx(ts.stopCol, cols)
+	if startIdx >= 0 && cols[startIdx].Type != flux.TTime {
+		startIdx = -1
+	}
+	if stopIdx >= 0 && cols[stopIdx].Type != flux.TTime {
+		stopIdx = -1
+	}
+
+	key := sliceKey(tbl.Key(), ts.startCol, ts.stopCol, bounds)
+	b := execute.NewColListTableBuilder(key, mem)
+	if err := execute.AddTableCols(tbl, b); err != nil {
+		tbl.Done()
+		return nil, false, err
+	}
+
+	var n int
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		n += cr.Len()
+		times := cr.Times(timeIdx)
+		for i := 0; i < cr.Len(); i++ {
+			if times.IsNull(i) || !bounds.Contains(values.Time(times.Value(i))) {
+				continue
+			}
+			for j := range cols {
+				var err error
+				switch j {
+				case startIdx:
+					err = b.AppendTime(j, bounds.Start)
+				case stopIdx:
+					err = b.AppendTime(j, bounds.Stop)
+				default:
+					err = b.AppendValue(j, execute.ValueForRow(cr, i, j))
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, false, err
+	}
+
+	if b.NRows() == 0 && n > 0 {
+		b.ClearData()
+		return nil, true, nil
+	}
+	out, err := b.Table()
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// sliceKey returns the group key with the start and stop columns set to the bounds.
+func sliceKey(key flux.GroupKey, startCol, stopCol string, bounds plan.Bounds) flux.GroupKey {
+	cols := key.Cols()
+	vs := make([]values.Value, len(cols))
+	for j, c := range cols {
+		switch {
+		case c.Label == startCol && c.Type == flux.TTime:
+			vs[j] = values.NewTime(bounds.Start)
+		case c.Label == stopCol && c.Type == flux.TTime:
+			vs[j] = values.NewTime(bounds.Stop)
+		default:
+			vs[j] = key.Value(j)
+		}
+	}
+	return execute.NewGroupKey(cols, vs)
+}
@@ -47,6 +47,11 @@ type indexSelectorTransformation struct {
 	selector IndexSelector
 }
 
+// Cost estimates that a selector produces one row for every table.
+func (c SelectorConfig) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return plan.AggregateCost(inStats)
+}
+
 // PassThroughAttribute implements the PassThroughAttributer interface used by
 // the planner. Selector functions preserve collation of their input rows.
 func (c SelectorConfig) PassThroughAttribute(attrKey string) bool {
@@ -0,0 +1,244 @@
+package spill
+
+import (
+	"sync/atomic"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/arrow"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+// Builder is a table.Builder that buffers tables in memory like the
+// table.BufferedBuilder and moves the buffers to a run when the
+// Spiller reports that the allocator is near its limit.
+type Builder struct {
+	buf  *table.BufferedBuilder
+	s    *Spiller
+	runs *RunSet
+
+	// buffered is the size of the buffers in memory.
+	buffered int64
+}
+
+// NewBuilder constructs a new Builder. If the Spiller is nil,
+// the buffers are never spilled.
+func NewBuilder(key flux.GroupKey, s *Spiller, mem memory.Allocator) *Builder {
+	b := &Builder{
+		buf: table.NewBufferedBuilder(key, mem),
+		s:   s,
+	}
+	if s != nil {
+		b.runs = s.NewRunSet(b.mergeRuns)
+	}
+	return b
+}
+
+// AppendTable will append all of the table buffers inside of
+// a table to this Builder.
+func (b *Builder) AppendTable(tbl flux.Table) error {
+	n := len(b.buf.Buffers)
+	if err := b.buf.AppendTable(tbl); err != nil {
+		return err
+	}
+	for _, buf := range b.buf.Buffers[n:] {
+		b.buffered += Size(buf)
+	}
+	return b.maybeSpill()
+}
+
+// AppendBuffer will append a new buffer to this Builder.
+func (b *Builder) AppendBuffer(cr flux.ColReader) error {
+	if err := b.buf.AppendBuffer(cr); err != nil {
+		return err
+	}
+	b.buffered += Size(cr)
+	return b.maybeSpill()
+}
+
+// maybeSpill writes the buffers in memory to a new run
+// if the Spiller reports that they should be spilled.
+func (b *Builder) maybeSpill() error {
+	if len(b.buf.Buffers) == 0 || !b.s.ShouldSpill(b.buffered) {
+		return nil
+	}
+	w, err := b.s.NewRun(b.buf.GroupKey, b.buf.Columns)
+	if err != nil {
+		return err
+	}
+	for _, buf := range b.buf.Buffers {
+		if err := w.Write(buf); err != nil {
+			w.Release()
+			return err
+		}
+	}
+	r, err := w.Finish()
+	if err != nil {
+		return err
+	}
+	for _, buf := range b.buf.Buffers {
+		buf.Release()
+	}
+	b.buf.Buffers, b.buffered = nil, 0
+	return b.runs.Add(r)
+}
+
+// mergeRuns concatenates the runs into a single run with
+// the columns that have been seen so far.
+func (b *Builder) mergeRuns(runs []*RunReader) (*RunReader, error) {
+	w, err := b.s.NewRun(b.buf.GroupKey, b.buf.Columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		for {
+			cr, ok := r.Next()
+			if !ok {
+				break
+			}
+			buf := normalize(cr, b.buf.GroupKey, b.buf.Columns, b.buf.Allocator)
+			err := w.Write(buf)
+			buf.Release()
+			if err != nil {
+				w.Release()
+				return nil, err
+			}
+		}
+		if err := r.Err(); err != nil {
+			w.Release()
+			return nil, err
+		}
+	}
+	return w.Finish()
+}
+
+// Table constructs a table from the spilled runs and the buffers
+// in memory. The spilled runs are read back when the table is read.
+func (b *Builder) Table() (flux.Table, error) {
+	if b.runs.Len() == 0 {
+		return b.buf.Table()
+	}
+	if err := b.runs.Reduce(); err != nil {
+		b.Release()
+		return nil, err
+	}
+	tbl := &spilledTable{
+		key:     b.buf.GroupKey,
+		cols:    b.buf.Columns,
+		runs:    b.runs.Take(),
+		buffers: b.buf.Buffers,
+		mem:     b.buf.Allocator,
+	}
+	b.buf.Buffers, b.buffered = nil, 0
+	return tbl, nil
+}
+
+// Release releases the runs and buffers of the Builder.
+func (b *Builder) Release() {
+	b.runs.Release()
+	for _, buf := range b.buf.Buffers {
+		buf.Release()
+	}
+	b.buf.Buffers, b.buffered = nil, 0
+}
+
+// spilledTable is a table that reads the spilled runs
+// followed by the buffers that are still in memory.
+type spilledTable struct {
+	key     flux.GroupKey
+	cols    []flux.ColMeta
+	runs    []*RunReader
+	buffers []*arrow.TableBuffer
+	mem     memory.Allocator
+	used    int32
+}
+
+func (t *spilledTable) Key() flux.GroupKey   { return t.key }
+func (t *spilledTable) Cols() []flux.ColMeta { return t.cols }
+func (t *spilledTable) Empty() bool          { return false }
+func (t *spilledTable) Done()                { t.release() }
+func (t *spilledTable) Do(f func(flux.ColReader) error) error {
+	if !atomic.CompareAndSwapInt32(&t.used, 0, 1) {
+		return errors.New(codes.Internal, "table already read")
+	}
+	defer t.release()
+
+	for _, r := range t.runs {
+		for {
+			cr, ok := r.Next()
+			if !ok {
+				break
+			}
+			buf := normalize(cr, t.key, t.cols, t.mem)
+			err := f(buf)
+			buf.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if err := r.Err(); err != nil {
+			return err
+		}
+	}
+	for i, buf := range t.buffers {
+		err := f(buf)
+		buf.Release()
+		t.buffers[i] = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalize returns the buffer with the columns and releases the
+// column reader. Runs that were spilled before a column was first
+// seen do not contain it, so it is filled with nulls.
+func normalize(cr flux.ColReader, key flux.GroupKey, cols []flux.ColMeta, mem memory.Allocator) *arrow.TableBuffer {
+	buf := &arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  cols,
+		Values:   make([]array.Array, len(cols)),
+	}
+	for j, c := range cols {
+		if idx := colIdx(c.Label, cr.Cols()); idx >= 0 {
+			buf.Values[j] = table.Values(cr, idx)
+			buf.Values[j].Retain()
+			continue
+		}
+		b := arrow.NewBuilder(c.Type, mem)
+		b.Resize(cr.Len())
+		for i := 0; i < cr.Len(); i++ {
+			b.AppendNull()
+		}
+		buf.Values[j] = b.NewArray()
+		b.Release()
+	}
+	cr.Release()
+	return buf
+}
+
+func (t *spilledTable) release() {
+	for _, r := range t.runs {
+		r.Release()
+	}
+	t.runs = nil
+	for _, buf := range t.buffers {
+		if buf != nil {
+			buf.Release()
+		}
+	}
+	t.buffers = nil
+}
+
+func colIdx(label string, cols []flux.ColMeta) int {
+	for j, c := range cols {
+		if c.Label == label {
+			return j
+		}
+	}
+	return -1
+}
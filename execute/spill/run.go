@@ -0,0 +1,228 @@
+package spill
+
+import (
+	"io"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/arrow"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/dependencies/filesystem"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	arrowlib "github.com/apache/arrow/go/v7/arrow"
+	arrowarray "github.com/apache/arrow/go/v7/arrow/array"
+	"github.com/apache/arrow/go/v7/arrow/ipc"
+)
+
+// filePattern is the pattern of the names of spill files.
+const filePattern = "flux-spill-*.arrow"
+
+// RunWriter writes buffers with the same group key and columns to a
+// temporary file as an Arrow IPC stream.
+type RunWriter struct {
+	s      *Spiller
+	f      filesystem.TempFile
+	w      *ipc.Writer
+	key    flux.GroupKey
+	cols   []flux.ColMeta
+	schema *arrowlib.Schema
+}
+
+// NewRun creates a temporary file for buffers with the group key and columns.
+func (s *Spiller) NewRun(key flux.GroupKey, cols []flux.ColMeta) (*RunWriter, error) {
+	if s == nil {
+		return nil, errors.New(codes.Internal, "spilling is not enabled")
+	}
+	f, err := s.fs.CreateTemp(s.dir, filePattern)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "cannot create spill file")
+	}
+	fields := make([]arrowlib.Field, len(cols))
+	for j, c := range cols {
+		fields[j] = arrowlib.Field{Name: c.Label, Type: arrowType(c.Type), Nullable: true}
+	}
+	schema := arrowlib.NewSchema(fields, nil)
+	return &RunWriter{
+		s:      s,
+		f:      f,
+		w:      ipc.NewWriter(f, ipc.WithSchema(schema), ipc.WithAllocator(s.mem)),
+		key:    key,
+		cols:   cols,
+		schema: schema,
+	}, nil
+}
+
+// arrowType returns the Arrow type that a column is stored as.
+// This is the type of the arrays of the column except for strings,
+// which are stored as binary.
+func arrowType(typ flux.ColType) arrowlib.DataType {
+	switch typ {
+	case flux.TBool:
+		return array.BooleanType
+	case flux.TInt, flux.TTime:
+		return array.IntType
+	case flux.TUInt:
+		return array.UintType
+	case flux.TFloat:
+		return array.FloatType
+	default:
+		return arrowlib.BinaryTypes.Binary
+	}
+}
+
+// Write appends the buffer to the run. The buffer must have the
+// columns of the run in the same order.
+func (w *RunWriter) Write(cr flux.ColReader) error {
+	if cr.Len() == 0 {
+		return nil
+	}
+	cols := make([]arrowlib.Array, len(w.cols))
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	for j := range w.cols {
+		cols[j] = w.toArrow(table.Values(cr, j))
+	}
+	rec := arrowarray.NewRecord(w.schema, cols, int64(cr.Len()))
+	defer rec.Release()
+	if err := w.w.Write(rec); err != nil {
+		return errors.Wrap(err, codes.Internal, "cannot write spill file")
+	}
+	return nil
+}
+
+// toArrow returns the arrow array that stores a column.
+func (w *RunWriter) toArrow(arr array.Array) arrowlib.Array {
+	vs, ok := arr.(*array.String)
+	if !ok {
+		arr.Retain()
+		return arr.(arrowlib.Array)
+	}
+	if !vs.IsConstant() {
+		// Share the buffers of the string array but store it as binary.
+		d := vs.Data()
+		data := arrowarray.NewData(arrowlib.BinaryTypes.Binary, d.Len(), d.Buffers(), nil, d.NullN(), d.Offset())
+		defer data.Release()
+		return arrowarray.NewBinaryData(data)
+	}
+	b := arrowarray.NewBinaryBuilder(w.s.mem, arrowlib.BinaryTypes.Binary)
+	defer b.Release()
+	b.Reserve(vs.Len())
+	for i := 0; i < vs.Len(); i++ {
+		b.Append(vs.ValueBytes(i))
+	}
+	return b.NewArray()
+}
+
+// Finish completes the run and returns a reader for the buffers
+// that were written. The reader owns the file.
+func (w *RunWriter) Finish() (*RunReader, error) {
+	if err := w.w.Close(); err != nil {
+		w.Release()
+		return nil, errors.Wrap(err, codes.Internal, "cannot write spill file")
+	}
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		w.Release()
+		return nil, errors.Wrap(err, codes.Internal, "cannot read spill file")
+	}
+	r := &RunReader{s: w.s, f: w.f, key: w.key, cols: w.cols}
+	w.f = nil
+	return r, nil
+}
+
+// Release removes the file if the run was not finished.
+func (w *RunWriter) Release() {
+	if w.f != nil {
+		w.s.remove(w.f)
+		w.f = nil
+	}
+}
+
+// RunReader reads back the buffers of a run in the order
+// they were written.
+type RunReader struct {
+	s    *Spiller
+	f    filesystem.TempFile
+	r    *ipc.Reader
+	key  flux.GroupKey
+	cols []flux.ColMeta
+	err  error
+}
+
+// Key returns the group key of the buffers in the run.
+func (r *RunReader) Key() flux.GroupKey {
+	return r.key
+}
+
+// Cols returns the columns of the buffers in the run.
+func (r *RunReader) Cols() []flux.ColMeta {
+	return r.cols
+}
+
+// Next returns the next buffer of the run. The caller must release it.
+// It returns false when the run has been read or reading it failed,
+// which is reported by Err.
+func (r *RunReader) Next() (flux.ColReader, bool) {
+	if r.f == nil || r.err != nil {
+		return nil, false
+	}
+	if r.r == nil {
+		rd, err := ipc.NewReader(r.f, ipc.WithAllocator(r.s.mem))
+		if err != nil {
+			r.err = errors.Wrap(err, codes.Internal, "cannot read spill file")
+			return nil, false
+		}
+		r.r = rd
+	}
+	if !r.r.Next() {
+		if err := r.r.Err(); err != nil && err != io.EOF {
+			r.err = errors.Wrap(err, codes.Internal, "cannot read spill file")
+		}
+		return nil, false
+	}
+
+	rec := r.r.Record()
+	buf := &arrow.TableBuffer{
+		GroupKey: r.key,
+		Columns:  r.cols,
+		Values:   make([]array.Array, len(r.cols)),
+	}
+	for j, col := range rec.Columns() {
+		if bin, ok := col.(*arrowarray.Binary); ok {
+			buf.Values[j] = array.NewStringFromBinaryArray(bin)
+			continue
+		}
+		col.Retain()
+		buf.Values[j] = col.(array.Array)
+	}
+	return buf, true
+}
+
+// Err returns the error that stopped reading the run, if any.
+func (r *RunReader) Err() error {
+	return r.err
+}
+
+// Release closes and removes the file of the run.
+func (r *RunReader) Release() {
+	if r.r != nil {
+		r.r.Release()
+		r.r = nil
+	}
+	if r.f != nil {
+		r.s.remove(r.f)
+		r.f = nil
+	}
+}
+
+// remove closes and removes a spill file. Errors are ignored since
+// the data is no longer needed.
+func (s *Spiller) remove(f filesystem.TempFile) {
+	_ = f.Close()
+	_ = s.fs.Remove(f.Name())
+}
@@ -0,0 +1,123 @@
+package spill
+
+// MergeFunc merges runs into a single run. The runs are
+// released by the RunSet after the function returns.
+type MergeFunc func(runs []*RunReader) (*RunReader, error)
+
+// RunSet holds the runs of a table in the order they were written.
+//
+// Each run has a level. A run that is added has level zero. When the
+// most recent runs are the maximum number of runs of the same level,
+// they are merged into a single run of the next level. A merge never
+// reads more than the maximum number of runs, and a row is written
+// once for each level, so the number of times a row is rewritten
+// grows logarithmically with the number of runs.
+//
+// The methods of a nil RunSet are safe to call except Add.
+type RunSet struct {
+	s      *Spiller
+	merge  MergeFunc
+	runs   []*RunReader
+	levels []int
+}
+
+// NewRunSet creates an empty RunSet that combines runs with the merge function.
+func (s *Spiller) NewRunSet(merge MergeFunc) *RunSet {
+	return &RunSet{s: s, merge: merge}
+}
+
+// Len returns the number of runs in the set.
+func (rs *RunSet) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.runs)
+}
+
+// Add adds a run to the set and merges the most
+// recent runs while they fill a level.
+func (rs *RunSet) Add(r *RunReader) error {
+	rs.runs = append(rs.runs, r)
+	rs.levels = append(rs.levels, 0)
+	for {
+		n, max := len(rs.runs), rs.s.maxMergeRuns
+		if n < max {
+			return nil
+		}
+		level := rs.levels[n-1]
+		for _, l := range rs.levels[n-max:] {
+			if l != level {
+				return nil
+			}
+		}
+		if err := rs.mergeLast(max, level+1); err != nil {
+			return err
+		}
+	}
+}
+
+// Reduce merges the most recent runs until no more than
+// the maximum number of runs remain so they can be read
+// at the same time.
+func (rs *RunSet) Reduce() error {
+	if rs == nil {
+		return nil
+	}
+	max := rs.s.maxMergeRuns
+	for n := len(rs.runs); n > max; n = len(rs.runs) {
+		k := n - max + 1
+		if k > max {
+			k = max
+		}
+		level := 0
+		for _, l := range rs.levels[n-k:] {
+			if l >= level {
+				level = l + 1
+			}
+		}
+		if err := rs.mergeLast(k, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeLast merges the last k runs into a single run with the level.
+func (rs *RunSet) mergeLast(k, level int) error {
+	n := len(rs.runs)
+	runs := make([]*RunReader, k)
+	copy(runs, rs.runs[n-k:])
+	for i := n - k; i < n; i++ {
+		rs.runs[i] = nil
+	}
+	rs.runs, rs.levels = rs.runs[:n-k], rs.levels[:n-k]
+
+	r, err := rs.merge(runs)
+	for _, run := range runs {
+		run.Release()
+	}
+	if err != nil {
+		return err
+	}
+	rs.runs = append(rs.runs, r)
+	rs.levels = append(rs.levels, level)
+	return nil
+}
+
+// Take removes the runs from the set and returns them in the
+// order they were written. The caller must release them.
+func (rs *RunSet) Take() []*RunReader {
+	if rs == nil {
+		return nil
+	}
+	runs := rs.runs
+	rs.runs, rs.levels = nil, nil
+	return runs
+}
+
+// Release releases the runs in the set.
+func (rs *RunSet) Release() {
+	for _, r := range rs.Take() {
+		r.Release()
+	}
+}
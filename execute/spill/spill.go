@@ -0,0 +1,208 @@
+// Package spill implements writing buffered table data to temporary
+// files so that memory-heavy transformations can continue with a
+// bounded amount of memory instead of exceeding the allocation limit.
+//
+// Spilling is opt-in. It is enabled by injecting a Config into the
+// context and requires a filesystem service that implements
+// filesystem.TempFileService and an allocator with a limit.
+// Transformations check ShouldSpill with the number of bytes they have
+// buffered and write the buffered data to a run when it reports true.
+// Runs smaller than the minimum run size are not written so that a
+// transformation does not create a file for every buffer once the
+// allocator is near its limit.
+//
+// The runs of a table are kept in a RunSet. The RunSet merges runs in
+// levels and never merges more than the maximum number of runs at once,
+// which bounds the number of files that are open at the same time.
+//
+// The sort transformation merges its buffered data into sorted runs
+// and merges the runs when the table is complete. Transformations that
+// buffer whole tables, such as group when it does not stream, use the
+// Builder. The join.tables transformation spills through the sort
+// transformations that the planner inserts before the merge join.
+//
+// The pivot transformation does not spill. It sets values at random rows
+// and columns of its output tables, so it keeps them in memory and fails
+// with the allocator error when they do not fit. The rows that the merge
+// join holds for a single join key are also kept in memory.
+package spill
+
+import (
+	"context"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/dependencies/filesystem"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/memory"
+)
+
+// DefaultThreshold is the fraction of the allocation limit at which
+// transformations start to spill when the Config does not set one.
+const DefaultThreshold = 0.8
+
+// DefaultMinRunSize is the minimum number of bytes that a transformation
+// buffers before it spills when the Config does not set one.
+const DefaultMinRunSize = 1 << 20
+
+// DefaultMaxMergeRuns is the maximum number of runs that are merged
+// at once when the Config does not set one.
+const DefaultMaxMergeRuns = 16
+
+// Config configures spilling to disk.
+type Config struct {
+	// Dir is the directory for the spill files.
+	// If it is empty, the default directory for temporary files is used.
+	Dir string
+
+	// Threshold is the fraction of the allocation limit at which
+	// transformations start to spill. It must be between 0 and 1.
+	// If it is zero, DefaultThreshold is used.
+	Threshold float64
+
+	// MinRunSize is the minimum number of bytes that a transformation
+	// buffers before it spills them to a run. It is capped at half of
+	// the threshold so that data is spilled before the limit is reached.
+	// If it is zero, DefaultMinRunSize is used.
+	MinRunSize int64
+
+	// MaxMergeRuns is the maximum number of runs that are merged at
+	// once. It must be at least 2. If it is zero, DefaultMaxMergeRuns is used.
+	MaxMergeRuns int
+}
+
+type key int
+
+const configKey key = iota
+
+// Dependency will inject the spill Config into the dependency chain.
+type Dependency struct {
+	Config *Config
+}
+
+// Inject will inject the spill Config into the dependency chain.
+func (d Dependency) Inject(ctx context.Context) context.Context {
+	if d.Config != nil {
+		ctx = Inject(ctx, *d.Config)
+	}
+	return ctx
+}
+
+// Inject will enable spilling with the Config for the context.
+func Inject(ctx context.Context, c Config) context.Context {
+	return context.WithValue(ctx, configKey, c)
+}
+
+// Get will retrieve the spill Config from the context.Context.
+// It returns false if spilling is not enabled.
+func Get(ctx context.Context) (Config, bool) {
+	c, ok := ctx.Value(configKey).(Config)
+	return c, ok
+}
+
+// limitedAllocator is an allocator that reports its usage and limit.
+type limitedAllocator interface {
+	Allocated() int64
+	AllocationLimit() (int64, bool)
+}
+
+var _ limitedAllocator = (*memory.ResourceAllocator)(nil)
+
+// Spiller decides when to spill and creates the runs that data is
+// spilled to.
+type Spiller struct {
+	fs           filesystem.TempFileService
+	mem          memory.Allocator
+	dir          string
+	threshold    float64
+	minRunSize   int64
+	maxMergeRuns int
+}
+
+// New returns a Spiller for the allocator of a transformation.
+// It returns nil if spilling is not enabled in the context, the
+// filesystem service cannot create temporary files or the allocator
+// has no limit. All methods of a nil Spiller are safe to call.
+func New(ctx context.Context, mem memory.Allocator) *Spiller {
+	c, ok := Get(ctx)
+	if !ok {
+		return nil
+	}
+	la, ok := mem.(limitedAllocator)
+	if !ok {
+		return nil
+	} else if _, ok := la.AllocationLimit(); !ok {
+		return nil
+	}
+	fs, err := filesystem.GetTempFileService(ctx)
+	if err != nil {
+		return nil
+	}
+	threshold := c.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+	minRunSize := c.MinRunSize
+	if minRunSize <= 0 {
+		minRunSize = DefaultMinRunSize
+	}
+	maxMergeRuns := c.MaxMergeRuns
+	if maxMergeRuns < 2 {
+		maxMergeRuns = DefaultMaxMergeRuns
+	}
+	return &Spiller{
+		fs:           fs,
+		mem:          mem,
+		dir:          c.Dir,
+		threshold:    threshold,
+		minRunSize:   minRunSize,
+		maxMergeRuns: maxMergeRuns,
+	}
+}
+
+// Enabled reports whether data may be spilled.
+func (s *Spiller) Enabled() bool {
+	return s != nil
+}
+
+// ShouldSpill reports whether the buffered bytes of a transformation
+// should be spilled. This is true when the allocator has reached the
+// threshold of its limit and the transformation has buffered at least
+// the minimum run size.
+func (s *Spiller) ShouldSpill(buffered int64) bool {
+	if s == nil || buffered <= 0 {
+		return false
+	}
+	la := s.mem.(limitedAllocator)
+	limit, ok := la.AllocationLimit()
+	if !ok {
+		return false
+	}
+	threshold := s.threshold * float64(limit)
+	if float64(la.Allocated()) < threshold {
+		return false
+	}
+	minRunSize := float64(s.minRunSize)
+	if minRunSize > threshold/2 {
+		minRunSize = threshold / 2
+	}
+	return float64(buffered) >= minRunSize
+}
+
+// Size returns the number of bytes in the buffers of the columns.
+// Buffers that are shared by several columns are counted for each one.
+func Size(cr flux.ColReader) int64 {
+	var n int64
+	for j := range cr.Cols() {
+		data := table.Values(cr, j).Data()
+		if data == nil {
+			// Constant strings do not have buffers.
+			continue
+		}
+		for _, buf := range data.Buffers() {
+			if buf != nil {
+				n += int64(buf.Len())
+			}
+		}
+	}
+	return n
+}
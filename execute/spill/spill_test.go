@@ -0,0 +1,407 @@
+package spill_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/dependencies/filesystem"
+	"github.com/InfluxCommunity/flux/execute/spill"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/execute/table/static"
+	"github.com/InfluxCommunity/flux/memory"
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/google/go-cmp/cmp"
+)
+
+// spillingAllocator reports a limit of a single byte so
+// the Spiller spills whenever memory is allocated.
+type spillingAllocator struct {
+	*memory.ResourceAllocator
+}
+
+func (a spillingAllocator) AllocationLimit() (int64, bool) {
+	return 1, true
+}
+
+func newSpiller(t *testing.T, c spill.Config) (*spill.Spiller, *arrowmem.CheckedAllocator) {
+	t.Helper()
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	ctx = spill.Inject(ctx, c)
+
+	checked := arrowmem.NewCheckedAllocator(arrowmem.DefaultAllocator)
+	mem := spillingAllocator{ResourceAllocator: memory.NewResourceAllocator(checked)}
+	s := spill.New(ctx, mem)
+	if s == nil {
+		t.Fatal("expected spilling to be enabled")
+	}
+	return s, checked
+}
+
+func assertNoSpillFiles(t *testing.T, dir string) {
+	t.Helper()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		t.Errorf("spill file was not removed: %s", f.Name())
+	}
+}
+
+func TestNew(t *testing.T) {
+	limit := int64(1024)
+	for _, tt := range []struct {
+		name    string
+		ctx     func() context.Context
+		mem     memory.Allocator
+		enabled bool
+	}{
+		{
+			name: "Disabled",
+			ctx: func() context.Context {
+				return filesystem.Inject(context.Background(), filesystem.SystemFS)
+			},
+			mem: &memory.ResourceAllocator{Limit: &limit},
+		},
+		{
+			name: "NoLimit",
+			ctx: func() context.Context {
+				ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+				return spill.Inject(ctx, spill.Config{})
+			},
+			mem: &memory.ResourceAllocator{},
+		},
+		{
+			name: "NoFilesystem",
+			ctx: func() context.Context {
+				return spill.Inject(context.Background(), spill.Config{})
+			},
+			mem: &memory.ResourceAllocator{Limit: &limit},
+		},
+		{
+			name: "Enabled",
+			ctx: func() context.Context {
+				ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+				return spill.Dependency{Config: &spill.Config{}}.Inject(ctx)
+			},
+			mem:     &memory.ResourceAllocator{Limit: &limit},
+			enabled: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := spill.New(tt.ctx(), tt.mem)
+			if want, got := tt.enabled, s.Enabled(); want != got {
+				t.Fatalf("unexpected enabled -want/+got:\n\t- %v\n\t+ %v", want, got)
+			}
+			if s.ShouldSpill(1) {
+				t.Fatal("expected no spill without allocated memory")
+			}
+		})
+	}
+}
+
+func TestShouldSpill(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		minRunSize int64
+		allocated  int
+		buffered   int64
+		want       bool
+	}{
+		{name: "BelowThreshold", allocated: 256, buffered: 256},
+		{name: "AtThreshold", allocated: 512, buffered: 256, want: true},
+		// The default minimum run size is capped at half of the threshold.
+		{name: "BelowMinRunSize", allocated: 512, buffered: 255},
+		{name: "NothingBuffered", allocated: 512},
+		{name: "MinRunSize", minRunSize: 64, allocated: 512, buffered: 64, want: true},
+		{name: "BelowConfiguredMinRunSize", minRunSize: 64, allocated: 512, buffered: 63},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+			ctx = spill.Inject(ctx, spill.Config{Threshold: 0.5, MinRunSize: tt.minRunSize})
+
+			limit := int64(1024)
+			mem := &memory.ResourceAllocator{Limit: &limit}
+			s := spill.New(ctx, mem)
+
+			b := mem.Allocate(tt.allocated)
+			defer mem.Free(b)
+			if want, got := tt.want, s.ShouldSpill(tt.buffered); want != got {
+				t.Fatalf("unexpected spill -want/+got:\n\t- %v\n\t+ %v", want, got)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	s, checked := newSpiller(t, spill.Config{Dir: dir})
+	defer checked.AssertSize(t, 0)
+
+	in := static.Table{
+		static.StringKey("_measurement", "m0"),
+		static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, 30),
+		static.Ints("i", 1, nil, 3, 4),
+		static.Uints("u", 1, 2, nil, 4),
+		static.Floats("f", 1.5, 2.5, 3.5, nil),
+		static.Booleans("b", true, false, nil, true),
+		static.Strings("s", "a", nil, "c", "d"),
+	}
+	tbl := in.Table(memory.DefaultAllocator)
+
+	w, err := s.NewRun(tbl.Key(), tbl.Cols())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write the table twice to produce multiple buffers.
+	for i := 0; i < 2; i++ {
+		if err := in.Table(memory.DefaultAllocator).Do(w.Write); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tbl.Done()
+
+	r, err := w.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := table.NewBufferedBuilder(r.Key(), memory.DefaultAllocator)
+	for {
+		cr, ok := r.Next()
+		if !ok {
+			break
+		}
+		err := b.AppendBuffer(cr)
+		cr.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	r.Release()
+
+	out, err := b.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := static.Table{
+		static.StringKey("_measurement", "m0"),
+		static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, 30, "2020-01-01T00:00:00Z", 10, 20, 30),
+		static.Ints("i", 1, nil, 3, 4, 1, nil, 3, 4),
+		static.Uints("u", 1, 2, nil, 4, 1, 2, nil, 4),
+		static.Floats("f", 1.5, 2.5, 3.5, nil, 1.5, 2.5, 3.5, nil),
+		static.Booleans("b", true, false, nil, true, true, false, nil, true),
+		static.Strings("s", "a", nil, "c", "d", "a", nil, "c", "d"),
+	}
+	if diff := table.Diff(want, table.Iterator{out}); diff != "" {
+		t.Fatalf("unexpected diff -want/+got:\n%s", diff)
+	}
+	assertNoSpillFiles(t, dir)
+}
+
+func TestBuilder(t *testing.T) {
+	dir := t.TempDir()
+	s, checked := newSpiller(t, spill.Config{Dir: dir})
+	defer checked.AssertSize(t, 0)
+
+	in := static.TableGroup{
+		static.StringKey("_measurement", "m0"),
+		static.Table{
+			static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+			static.Floats("f0", 3, 8, 2),
+		},
+		static.Table{
+			static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+			static.Floats("f0", 18, 2, 7),
+			static.Ints("f1", 5, 9, 2),
+		},
+	}
+
+	var b *spill.Builder
+	if err := in.Do(func(tbl flux.Table) error {
+		if b == nil {
+			b = spill.NewBuilder(tbl.Key(), s, checked)
+		}
+		return b.AppendTable(tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := b.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := static.Table{
+		static.StringKey("_measurement", "m0"),
+		static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, "2020-01-01T00:00:00Z", 10, 20),
+		static.Floats("f0", 3, 8, 2, 18, 2, 7),
+		static.Ints("f1", nil, nil, nil, 5, 9, 2),
+	}
+	if diff := table.Diff(want, table.Iterator{out}); diff != "" {
+		t.Fatalf("unexpected diff -want/+got:\n%s", diff)
+	}
+	assertNoSpillFiles(t, dir)
+}
+
+func TestBuilder_Release(t *testing.T) {
+	dir := t.TempDir()
+	s, checked := newSpiller(t, spill.Config{Dir: dir})
+	defer checked.AssertSize(t, 0)
+
+	in := static.Table{
+		static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+		static.Floats("_value", 3, 8, 2),
+	}
+	tbl := in.Table(memory.DefaultAllocator)
+	b := spill.NewBuilder(tbl.Key(), s, checked)
+	if err := b.AppendTable(tbl); err != nil {
+		t.Fatal(err)
+	}
+	b.Release()
+	assertNoSpillFiles(t, dir)
+}
+
+func TestRunSet(t *testing.T) {
+	dir := t.TempDir()
+	s, checked := newSpiller(t, spill.Config{Dir: dir, MaxMergeRuns: 3})
+	defer checked.AssertSize(t, 0)
+
+	// copyRuns writes the buffers of the runs to w in order.
+	copyRuns := func(runs []*spill.RunReader, w func(cr flux.ColReader) error) error {
+		for _, r := range runs {
+			for {
+				cr, ok := r.Next()
+				if !ok {
+					break
+				}
+				err := w(cr)
+				cr.Release()
+				if err != nil {
+					return err
+				}
+			}
+			if err := r.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tbl := static.Table{static.Ints("i", 0)}.Table(memory.DefaultAllocator)
+	key, cols := tbl.Key(), tbl.Cols()
+	tbl.Done()
+
+	var merges []int
+	rs := s.NewRunSet(func(runs []*spill.RunReader) (*spill.RunReader, error) {
+		merges = append(merges, len(runs))
+		w, err := s.NewRun(key, cols)
+		if err != nil {
+			return nil, err
+		}
+		if err := copyRuns(runs, w.Write); err != nil {
+			w.Release()
+			return nil, err
+		}
+		return w.Finish()
+	})
+
+	for i, want := range []int{1, 2, 1, 2, 3, 2, 3, 4} {
+		w, err := s.NewRun(key, cols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := static.Table{static.Ints("i", i)}
+		if err := in.Table(memory.DefaultAllocator).Do(w.Write); err != nil {
+			t.Fatal(err)
+		}
+		r, err := w.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rs.Add(r); err != nil {
+			t.Fatal(err)
+		}
+		if got := rs.Len(); want != got {
+			t.Fatalf("unexpected number of runs after run %d -want/+got:\n\t- %d\n\t+ %d", i, want, got)
+		}
+	}
+
+	if err := rs.Reduce(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 3, rs.Len(); want != got {
+		t.Fatalf("unexpected number of runs after reduce -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if !cmp.Equal([]int{3, 3, 2}, merges) {
+		t.Fatalf("unexpected merges -want/+got:\n%s", cmp.Diff([]int{3, 3, 2}, merges))
+	}
+
+	// The runs are read in the order they were written.
+	b := table.NewBufferedBuilder(key, memory.DefaultAllocator)
+	runs := rs.Take()
+	if err := copyRuns(runs, b.AppendBuffer); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range runs {
+		r.Release()
+	}
+	out, err := b.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := static.Table{static.Ints("i", 0, 1, 2, 3, 4, 5, 6, 7)}
+	if diff := table.Diff(want, table.Iterator{out}); diff != "" {
+		t.Fatalf("unexpected diff -want/+got:\n%s", diff)
+	}
+	assertNoSpillFiles(t, dir)
+}
+
+func TestBuilder_MergeRuns(t *testing.T) {
+	dir := t.TempDir()
+	s, checked := newSpiller(t, spill.Config{Dir: dir, MaxMergeRuns: 2})
+	defer checked.AssertSize(t, 0)
+
+	in := static.TableGroup{
+		static.StringKey("_measurement", "m0"),
+		static.Table{
+			static.Floats("f0", 3, 8),
+		},
+		static.Table{
+			static.Floats("f0", 2),
+			static.Ints("f1", 5),
+		},
+		static.Table{
+			static.Booleans("f2", true, false),
+		},
+	}
+
+	var b *spill.Builder
+	if err := in.Do(func(tbl flux.Table) error {
+		if b == nil {
+			b = spill.NewBuilder(tbl.Key(), s, checked)
+		}
+		return b.AppendTable(tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := b.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := static.Table{
+		static.StringKey("_measurement", "m0"),
+		static.Floats("f0", 3, 8, 2, nil, nil),
+		static.Ints("f1", nil, nil, 5, nil, nil),
+		static.Booleans("f2", nil, nil, nil, true, false),
+	}
+	if diff := table.Diff(want, table.Iterator{out}); diff != "" {
+		t.Fatalf("unexpected diff -want/+got:\n%s", diff)
+	}
+	assertNoSpillFiles(t, dir)
+}
@@ -54,6 +54,12 @@ type consecutiveTransport struct {
 	messages MessageQueue
 	stack    []interpreter.StackEntry
 	profile  flux.TransportProfile
+	// rowsIn counts the rows received by the transport. It is kept
+	// out of the profile because tables may be read concurrently.
+	rowsIn int64
+	// alloc records the memory allocated by the transformation
+	// when the operator profiler is enabled.
+	alloc *profilingAllocator
 
 	finished chan struct{}
 	errMu    sync.Mutex
@@ -67,7 +73,7 @@ type consecutiveTransport struct {
 	span         opentracing.Span
 }
 
-func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Transformation, n plan.Node, logger *zap.Logger, mem memory.Allocator) *consecutiveTransport {
+func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Transformation, n, src plan.Node, logger *zap.Logger, mem memory.Allocator) *consecutiveTransport {
 	return &consecutiveTransport{
 		ctx:        ctx,
 		dispatcher: dispatcher,
@@ -78,6 +84,7 @@ func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Trans
 		profile: flux.TransportProfile{
 			NodeType: OperationType(t),
 			Label:    string(n.ID()),
+			Source:   string(src.ID()),
 		},
 		stack:    n.CallStack(),
 		finished: make(chan struct{}),
@@ -127,7 +134,12 @@ func (t *consecutiveTransport) Finished() <-chan struct{} {
 }
 
 func (t *consecutiveTransport) TransportProfile() flux.TransportProfile {
-	return t.profile
+	profile := t.profile
+	profile.RowsIn = atomic.LoadInt64(&t.rowsIn)
+	if t.alloc != nil {
+		profile.Allocated = t.alloc.TotalAllocated()
+	}
+	return profile
 }
 
 func (t *consecutiveTransport) RetractTable(id DatasetID, key flux.GroupKey) error {
@@ -294,6 +306,10 @@ func (t *consecutiveTransport) processMessage(m Message) (finished bool, err err
 	span := t.profile.StartSpan()
 	defer span.Finish()
 
+	if m.Type() == ProcessChunkType {
+		chunk := m.(ProcessChunkMsg).TableChunk()
+		atomic.AddInt64(&t.rowsIn, int64(chunk.Len()))
+	}
 	if err := t.t.ProcessMessage(m); err != nil {
 		return false, err
 	}
@@ -569,6 +585,7 @@ func (t *consecutiveTransportTable) Do(f func(flux.ColReader) error) error {
 			}
 			logger.Info("Invalid column reader received from predecessor", fields...)
 		}
+		atomic.AddInt64(&t.transport.rowsIn, int64(cr.Len()))
 		return f(cr)
 	})
 }
@@ -785,9 +802,17 @@ func (t *transportTransformationAdapter) OperationType() string {
 func (t *transportTransformationAdapter) RetractTable(_ DatasetID, _ flux.GroupKey) error {
 	return nil
 }
-func (t *transportTransformationAdapter) UpdateWatermark(_ DatasetID, _ Time) error {
-	return nil
+func (t *transportTransformationAdapter) UpdateWatermark(id DatasetID, time Time) error {
+	m := updateWatermarkMsg{
+		srcMessage: srcMessage(id),
+		time:       time,
+	}
+	return t.Transport.ProcessMessage(&m)
 }
-func (t *transportTransformationAdapter) UpdateProcessingTime(_ DatasetID, _ Time) error {
-	return nil
+func (t *transportTransformationAdapter) UpdateProcessingTime(id DatasetID, time Time) error {
+	m := updateProcessingTimeMsg{
+		srcMessage: srcMessage(id),
+		time:       time,
+	}
+	return t.Transport.ProcessMessage(&m)
 }
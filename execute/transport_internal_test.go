@@ -1,8 +1,16 @@
 package execute
 
 import (
+	"context"
+	"sync"
+	"testing"
+
 	"github.com/InfluxCommunity/flux"
 	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/plan/plantest/spec"
+	"go.uber.org/zap"
 )
 
 func NewProcessMsg(tbl flux.Table) ProcessMsg {
@@ -20,3 +28,62 @@ func NewFlushKeyMsg(key flux.GroupKey) FlushKeyMsg {
 func NewFinishMsg(err error) FinishMsg {
 	return &finishMsg{err: err}
 }
+
+type discardTransformation struct{}
+
+func (discardTransformation) RetractTable(id DatasetID, key flux.GroupKey) error { return nil }
+func (discardTransformation) Process(id DatasetID, tbl flux.Table) error         { return nil }
+func (discardTransformation) UpdateWatermark(id DatasetID, t Time) error         { return nil }
+func (discardTransformation) UpdateProcessingTime(id DatasetID, t Time) error    { return nil }
+func (discardTransformation) Finish(id DatasetID, err error)                     {}
+
+// TestConsecutiveTransport_TransportProfile reads the profile while
+// tables are being read so the race detector can check that the
+// row count is not read while it is updated.
+func TestConsecutiveTransport_TransportProfile(t *testing.T) {
+	const (
+		numTables = 8
+		numRows   = 10
+	)
+	node := plan.CreatePhysicalNode("node", spec.MockProcedureSpec{})
+	src := plan.CreatePhysicalNode("src", spec.MockProcedureSpec{})
+	transport := newConsecutiveTransport(context.Background(), nil, discardTransformation{}, node, src, zap.NewNop(), memory.DefaultAllocator)
+
+	tables := make([]flux.Table, numTables)
+	for i := range tables {
+		b := NewColListTableBuilder(NewGroupKey(nil, nil), memory.DefaultAllocator)
+		j, err := b.AddCol(flux.ColMeta{Label: "_value", Type: flux.TInt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k := 0; k < numRows; k++ {
+			if err := b.AppendInt(j, int64(k)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tbl, err := b.Table()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables[i] = newConsecutiveTransportTable(transport, tbl)
+	}
+
+	var wg sync.WaitGroup
+	for _, tbl := range tables {
+		wg.Add(1)
+		go func(tbl flux.Table) {
+			defer wg.Done()
+			if err := tbl.Do(func(flux.ColReader) error { return nil }); err != nil {
+				t.Error(err)
+			}
+		}(tbl)
+	}
+	for i := 0; i < numTables; i++ {
+		_ = transport.TransportProfile()
+	}
+	wg.Wait()
+
+	if want, got := int64(numTables*numRows), transport.TransportProfile().RowsIn; want != got {
+		t.Fatalf("unexpected rows in -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+}
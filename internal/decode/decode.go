@@ -0,0 +1,237 @@
+// Package decode decodes the messages that sources consume from
+// message brokers, such as kafka.from and mqtt.from, into tables.
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/values"
+	protocol "github.com/influxdata/line-protocol"
+)
+
+const (
+	measurementColLabel = "_measurement"
+	fieldColLabel       = "_field"
+)
+
+// Options configure how a message is decoded.
+type Options struct {
+	// Key holds string columns that are part of the group key of every
+	// row of the message, such as the topic that the message was
+	// published to. It may be nil.
+	Key flux.GroupKey
+}
+
+// keyValues returns the labels and values of the columns of the key.
+func (o Options) keyValues() map[string]values.Value {
+	if o.Key == nil {
+		return nil
+	}
+	vs := make(map[string]values.Value, len(o.Key.Cols()))
+	for j, col := range o.Key.Cols() {
+		vs[col.Label] = o.Key.Value(j)
+	}
+	return vs
+}
+
+// JSON decodes the objects of a message that contains JSON into the builder
+// of the key of the options. Each object is a row and each of its properties
+// is a column. Numbers are floats. A _time property is parsed as an RFC3339
+// timestamp and rows without it have the time t. The columns of the key
+// replace properties with the same name.
+func JSON(data []byte, t values.Time, cache execute.TableBuilderCache, opts Options) error {
+	key := opts.Key
+	if key == nil {
+		key = execute.NewGroupKey(nil, nil)
+	}
+	builder, created := cache.TableBuilder(key)
+	if created {
+		if err := execute.AddTableKeyCols(key, builder); err != nil {
+			return err
+		}
+	}
+
+	keyValues := opts.keyValues()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, codes.Invalid, "invalid JSON")
+		}
+
+		row := make(map[string]values.Value, len(obj)+len(keyValues)+1)
+		row[execute.DefaultTimeColLabel] = values.NewTime(t)
+		for k, v := range obj {
+			var err error
+			if row[k], err = jsonValue(k, v); err != nil {
+				return err
+			}
+		}
+		for k, v := range keyValues {
+			row[k] = v
+		}
+		if err := appendRow(builder, row); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonValue converts the value of a JSON property into a Flux value.
+func jsonValue(k string, v interface{}) (values.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return values.Null, nil
+	case string:
+		if k != execute.DefaultTimeColLabel {
+			return values.NewString(v), nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, codes.Invalid, "invalid %s property", k)
+		}
+		return values.NewTime(values.ConvertTime(t)), nil
+	case float64:
+		return values.NewFloat(v), nil
+	case bool:
+		return values.NewBool(v), nil
+	default:
+		return nil, errors.Newf(codes.Invalid, "unsupported value for JSON property %q, must be a string, number, boolean or null", k)
+	}
+}
+
+// appendRow appends the values of a row to the builder.
+// Columns that the builder does not have are added
+// and columns that the row does not have are null.
+func appendRow(builder execute.TableBuilder, row map[string]values.Value) error {
+	labels := make([]string, 0, len(row))
+	for label := range row {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	// Add the columns before appending to the builder,
+	// so new columns are leveled with the previous rows.
+	idxs := make([]int, len(labels))
+	for i, label := range labels {
+		v := row[label]
+		j := execute.ColIdx(label, builder.Cols())
+		if j < 0 && !v.IsNull() {
+			var err error
+			if j, err = builder.AddCol(flux.ColMeta{Label: label, Type: flux.ColumnType(v.Type())}); err != nil {
+				return err
+			}
+		} else if j >= 0 && !v.IsNull() {
+			if typ := builder.Cols()[j].Type; typ != flux.ColumnType(v.Type()) {
+				return errors.Newf(codes.FailedPrecondition, "schema collision detected: column %q is both of type %s and %s", label, typ, flux.ColumnType(v.Type()))
+			}
+		}
+		idxs[i] = j
+	}
+
+	for i, label := range labels {
+		if idxs[i] < 0 {
+			// The type of the column is not known yet.
+			continue
+		}
+		if err := builder.AppendValue(idxs[i], row[label]); err != nil {
+			return err
+		}
+	}
+	return builder.LevelColumns()
+}
+
+// LineProtocol decodes the points of a message that contains line protocol.
+// Each field of a point is a row with the _time, _measurement, _field and
+// _value columns and a column for each tag. The rows are grouped by the
+// columns of the key of the options, the measurement, the tags and the field.
+// Points without a timestamp have the time t.
+func LineProtocol(data []byte, t values.Time, cache execute.TableBuilderCache, opts Options) error {
+	parser := protocol.NewParser(protocol.NewMetricHandler())
+	parser.SetTimeFunc(func() time.Time { return t.Time() })
+	metrics, err := parser.Parse(data)
+	if err != nil {
+		return errors.Wrap(err, codes.Invalid, "invalid line protocol")
+	}
+
+	for _, m := range metrics {
+		for _, field := range m.FieldList() {
+			key := lineProtocolKey(opts, m, field.Key)
+			builder, created := cache.TableBuilder(key)
+			if created {
+				if err := execute.AddTableKeyCols(key, builder); err != nil {
+					return err
+				}
+				if _, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultTimeColLabel, Type: flux.TTime}); err != nil {
+					return err
+				}
+			}
+
+			v := values.New(field.Value)
+			valueIdx := execute.ColIdx(execute.DefaultValueColLabel, builder.Cols())
+			if valueIdx < 0 {
+				if valueIdx, err = builder.AddCol(flux.ColMeta{
+					Label: execute.DefaultValueColLabel,
+					Type:  flux.ColumnType(v.Type()),
+				}); err != nil {
+					return err
+				}
+			} else if typ := builder.Cols()[valueIdx].Type; typ != flux.ColumnType(v.Type()) {
+				return errors.Newf(codes.FailedPrecondition, "schema collision detected: field %q of measurement %q is both of type %s and %s", field.Key, m.Name(), typ, flux.ColumnType(v.Type()))
+			}
+
+			if err := execute.AppendKeyValues(key, builder); err != nil {
+				return err
+			}
+			timeIdx := execute.ColIdx(execute.DefaultTimeColLabel, builder.Cols())
+			if err := builder.AppendTime(timeIdx, values.ConvertTime(m.Time())); err != nil {
+				return err
+			}
+			if err := builder.AppendValue(valueIdx, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lineProtocolKey returns the group key for a field of a point.
+// Tags named like the columns of the key of the options, _measurement
+// or _field are dropped, since these columns have other values.
+func lineProtocolKey(opts Options, m protocol.Metric, field string) flux.GroupKey {
+	vs := make(map[string]string, len(m.TagList())+2)
+	if opts.Key != nil {
+		for j, col := range opts.Key.Cols() {
+			vs[col.Label] = opts.Key.ValueString(j)
+		}
+	}
+	vs[measurementColLabel] = m.Name()
+	vs[fieldColLabel] = field
+	for _, tag := range m.TagList() {
+		if _, ok := vs[tag.Key]; !ok {
+			vs[tag.Key] = tag.Value
+		}
+	}
+	labels := make([]string, 0, len(vs))
+	for label := range vs {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	cols := make([]flux.ColMeta, len(labels))
+	kvs := make([]values.Value, len(labels))
+	for i, label := range labels {
+		cols[i] = flux.ColMeta{Label: label, Type: flux.TString}
+		kvs[i] = values.NewString(vs[label])
+	}
+	return execute.NewGroupKey(cols, kvs)
+}
@@ -0,0 +1,103 @@
+package decode_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/decode"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newCache() execute.TableBuilderCache {
+	cache := execute.NewTableBuilderCache(memory.DefaultAllocator)
+	cache.SetTriggerSpec(plan.DefaultTriggerSpec)
+	return cache
+}
+
+func topicKey(topic string) flux.GroupKey {
+	return execute.NewGroupKey(
+		[]flux.ColMeta{{Label: "topic", Type: flux.TString}},
+		[]values.Value{values.NewString(topic)},
+	)
+}
+
+// tables formats the tables of the cache as a line for the group key
+// and the columns followed by a line for each row.
+func tables(t *testing.T, cache execute.TableBuilderCache) []string {
+	t.Helper()
+	var lines []string
+	if err := cache.ForEachBuilder(func(key flux.GroupKey, builder execute.TableBuilder) error {
+		tbl, err := builder.Table()
+		if err != nil {
+			return err
+		}
+		labels := make([]string, len(tbl.Cols()))
+		for j, col := range tbl.Cols() {
+			labels[j] = col.Label + ":" + col.Type.String()
+		}
+		lines = append(lines, key.String()+" "+strings.Join(labels, ","))
+		return tbl.Do(func(cr flux.ColReader) error {
+			for i := 0; i < cr.Len(); i++ {
+				row := make([]string, len(cr.Cols()))
+				for j := range cr.Cols() {
+					row[j] = fmt.Sprint(execute.ValueForRow(cr, i, j))
+				}
+				lines = append(lines, strings.Join(row, ","))
+			}
+			return nil
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestLineProtocol(t *testing.T) {
+	cache := newCache()
+	data := []byte("cpu,_field=f,_measurement=m,topic=t,host=a usage=1.5 1000000000\ncpu,host=a usage=2.5")
+	opts := decode.Options{Key: topicKey("devices/a")}
+	if err := decode.LineProtocol(data, values.Time(2e9), cache, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"{_field=usage,_measurement=cpu,host=a,topic=devices/a} _field:string,_measurement:string,host:string,topic:string,_time:time,_value:float",
+		"usage,cpu,a,devices/a,1970-01-01T00:00:01.000000000Z,1.5",
+		"usage,cpu,a,devices/a,1970-01-01T00:00:02.000000000Z,2.5",
+	}
+	if got := tables(t, cache); !cmp.Equal(want, got) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestJSON(t *testing.T) {
+	cache := newCache()
+	data := []byte(`{"host": "a", "usage": 1.5, "topic": "other"}{"_time": "1970-01-01T00:00:10Z", "ok": true}`)
+	opts := decode.Options{Key: topicKey("devices/a")}
+	if err := decode.JSON(data, values.Time(1e9), cache, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"{topic=devices/a} topic:string,_time:time,host:string,usage:float,ok:bool",
+		"devices/a,1970-01-01T00:00:01.000000000Z,a,1.5,<nil>",
+		"devices/a,1970-01-01T00:00:10.000000000Z,<nil>,<nil>,true",
+	}
+	if got := tables(t, cache); !cmp.Equal(want, got) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestJSON_Invalid(t *testing.T) {
+	cache := newCache()
+	err := decode.JSON([]byte(`{"values": [1, 2]}`), 0, cache, decode.Options{})
+	if want := `unsupported value for JSON property "values", must be a string, number, boolean or null`; err == nil || err.Error() != want {
+		t.Errorf("unexpected error got %v want %s", err, want)
+	}
+}
@@ -173,6 +173,30 @@ func SalsaDatabase() BoolFlag {
 	return salsaDatabase
 }
 
+var parallelPartitionFactor = feature.MakeIntFlag(
+	"Parallel Partition Factor",
+	"parallelPartitionFactor",
+	"Flux Team",
+	0,
+)
+
+// ParallelPartitionFactor - Number of partitions that a single source is split into so that stateless transformations and mergeable aggregates run in parallel. Values less than two disable partitioning.
+func ParallelPartitionFactor() IntFlag {
+	return parallelPartitionFactor
+}
+
+var parallelPartitionByTime = feature.MakeBoolFlag(
+	"Parallel Partition By Time",
+	"parallelPartitionByTime",
+	"Flux Team",
+	false,
+)
+
+// ParallelPartitionByTime - Partition sources by time range instead of by group key
+func ParallelPartitionByTime() BoolFlag {
+	return parallelPartitionByTime
+}
+
 // Inject will inject the Flagger into the context.
 func Inject(ctx context.Context, flagger Flagger) context.Context {
 	return feature.Inject(ctx, flagger)
@@ -192,6 +216,8 @@ var all = []Flag{
 	strictNullLogicalOps,
 	prettyError,
 	salsaDatabase,
+	parallelPartitionFactor,
+	parallelPartitionByTime,
 }
 
 var byKey = map[string]Flag{
@@ -208,6 +234,8 @@ var byKey = map[string]Flag{
 	"strictNullLogicalOps":             strictNullLogicalOps,
 	"prettyError":                      prettyError,
 	"salsaDatabase":                    salsaDatabase,
+	"parallelPartitionFactor":          parallelPartitionFactor,
+	"parallelPartitionByTime":          parallelPartitionByTime,
 }
 
 // Flags returns all feature flags.
@@ -0,0 +1,217 @@
+// Package promapi decodes the results of the Prometheus HTTP query API
+// into tables and sends write requests to the Prometheus and Loki APIs.
+// Loki uses the same result format for metric queries.
+package promapi
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// Result types returned by the query APIs.
+const (
+	ResultTypeMatrix = "matrix"
+	ResultTypeVector = "vector"
+	ResultTypeScalar = "scalar"
+	ResultTypeString = "string"
+)
+
+// Series is a single series of a matrix or vector result.
+type Series struct {
+	Metric map[string]string `json:"metric"`
+	Values []Sample          `json:"values"`
+	Value  Sample            `json:"value"`
+}
+
+// Sample is a timestamp in fractional seconds and a value
+// encoded as a string. Non-finite floats are encoded as
+// "NaN", "+Inf" and "-Inf" so the value cannot be decoded
+// as a JSON number.
+type Sample struct {
+	Time  json.Number
+	Value string
+}
+
+func (s *Sample) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &[]interface{}{&s.Time, &s.Value})
+}
+
+// ParseTimestamp parses a unix timestamp in fractional seconds
+// without losing precision to floating point conversion.
+func ParseTimestamp(s string) (values.Time, error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, errors.Newf(codes.Internal, "invalid timestamp %q", s)
+		}
+		return values.Time(math.Round(f * 1e9)), nil
+	}
+
+	secs, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		secs, frac = s[:i], s[i+1:]
+	}
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, codes.Internal, "invalid timestamp %q", s)
+	}
+	if len(frac) > 9 {
+		frac = frac[:9]
+	}
+	var nsec int64
+	if frac != "" {
+		nsec, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, codes.Internal, "invalid timestamp %q", s)
+		}
+	}
+	if sec < 0 {
+		nsec = -nsec
+	}
+	return values.Time(sec*1e9 + nsec), nil
+}
+
+// TableSet groups rows into tables by their labels.
+type TableSet struct {
+	// source names the API in error messages.
+	source string
+	cache  execute.TableBuilderCache
+}
+
+// NewTableSet creates an empty table set. The source
+// is used to identify the API in error messages.
+func NewTableSet(source string, mem memory.Allocator) *TableSet {
+	cache := execute.NewTableBuilderCache(mem)
+	cache.SetTriggerSpec(plan.DefaultTriggerSpec)
+	return &TableSet{source: source, cache: cache}
+}
+
+// Builder returns the table builder for the labels. Each label
+// is a string column in the group key followed by the time and
+// value columns.
+func (ts *TableSet) Builder(labels map[string]string, valueType flux.ColType) (execute.TableBuilder, error) {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cols := make([]flux.ColMeta, len(names))
+	vals := make([]values.Value, len(names))
+	for i, name := range names {
+		cols[i] = flux.ColMeta{Label: name, Type: flux.TString}
+		vals[i] = values.NewString(labels[name])
+	}
+	key := execute.NewGroupKey(cols, vals)
+
+	builder, created := ts.cache.TableBuilder(key)
+	if created {
+		if err := execute.AddTableKeyCols(key, builder); err != nil {
+			return nil, err
+		}
+		if _, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultTimeColLabel, Type: flux.TTime}); err != nil {
+			return nil, err
+		}
+		if _, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultValueColLabel, Type: valueType}); err != nil {
+			return nil, err
+		}
+	} else if typ := builder.Cols()[builder.NCols()-1].Type; typ != valueType {
+		return nil, errors.Newf(codes.Internal, "%s series %v returned both %s and %s values", ts.source, key, typ, valueType)
+	}
+	return builder, nil
+}
+
+// AppendRow appends the key columns, time and value to a builder
+// returned by Builder.
+func AppendRow(builder execute.TableBuilder, t values.Time, v values.Value) error {
+	if err := execute.AppendKeyValues(builder.Key(), builder); err != nil {
+		return err
+	}
+	n := builder.NCols()
+	if err := builder.AppendTime(n-2, t); err != nil {
+		return err
+	}
+	return builder.AppendValue(n-1, v)
+}
+
+// AddResult decodes a matrix, vector, scalar or string result
+// and adds it to the tables.
+func (ts *TableSet) AddResult(resultType string, result json.RawMessage) error {
+	switch resultType {
+	case ResultTypeMatrix, ResultTypeVector:
+		var series []Series
+		if err := json.Unmarshal(result, &series); err != nil {
+			return errors.Wrapf(err, codes.Internal, "failed to decode %s %s result", ts.source, resultType)
+		}
+		for _, s := range series {
+			builder, err := ts.Builder(s.Metric, flux.TFloat)
+			if err != nil {
+				return err
+			}
+			samples := s.Values
+			if resultType == ResultTypeVector {
+				samples = []Sample{s.Value}
+			}
+			for _, sample := range samples {
+				if err := ts.appendSample(builder, sample, flux.TFloat); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case ResultTypeScalar, ResultTypeString:
+		var sample Sample
+		if err := json.Unmarshal(result, &sample); err != nil {
+			return errors.Wrapf(err, codes.Internal, "failed to decode %s %s result", ts.source, resultType)
+		}
+		valueType := flux.TFloat
+		if resultType == ResultTypeString {
+			valueType = flux.TString
+		}
+		builder, err := ts.Builder(nil, valueType)
+		if err != nil {
+			return err
+		}
+		return ts.appendSample(builder, sample, valueType)
+	default:
+		return errors.Newf(codes.Internal, "unsupported %s result type %q", ts.source, resultType)
+	}
+}
+
+// appendSample parses the sample and appends it to the builder.
+func (ts *TableSet) appendSample(builder execute.TableBuilder, s Sample, valueType flux.ColType) error {
+	t, err := ParseTimestamp(string(s.Time))
+	if err != nil {
+		return errors.Wrapf(err, codes.Inherit, "invalid %s sample", ts.source)
+	}
+	if valueType == flux.TString {
+		return AppendRow(builder, t, values.NewString(s.Value))
+	}
+	v, err := strconv.ParseFloat(s.Value, 64)
+	if err != nil {
+		return errors.Wrapf(err, codes.Internal, "invalid %s sample value %q", ts.source, s.Value)
+	}
+	return AppendRow(builder, t, values.NewFloat(v))
+}
+
+// Do passes each of the tables to the function.
+func (ts *TableSet) Do(f func(flux.Table) error) error {
+	return ts.cache.ForEachBuilder(func(key flux.GroupKey, builder execute.TableBuilder) error {
+		tbl, err := builder.Table()
+		if err != nil {
+			return err
+		}
+		return f(tbl)
+	})
+}
@@ -0,0 +1,109 @@
+package promapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	for in, want := range map[string]values.Time{
+		"1672531200":            1672531200000000000,
+		"1672531200.5":          1672531200500000000,
+		"1672531200.123456789":  1672531200123456789,
+		"1672531200.1234567891": 1672531200123456789,
+		"-1.5":                  -1500000000,
+		"1.6725312e+09":         1672531200000000000,
+	} {
+		got, err := promapi.ParseTimestamp(in)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("unexpected time for %q -want/+got:\n\t- %d\n\t+ %d", in, want, got)
+		}
+	}
+}
+
+func TestTableSet_AddResult(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		resultType string
+		result     string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "matrix",
+			resultType: promapi.ResultTypeMatrix,
+			result: `[
+				{"metric":{"job":"web"},"values":[[1.5,"2"],[2,"NaN"]]},
+				{"metric":{"job":"api"},"values":[[1,"+Inf"]]}
+			]`,
+			want: `# job=api _time=time,_value=float
+job=api _time=1970-01-01T00:00:01Z,_value=+Inf
+# job=web _time=time,_value=float
+job=web _time=1970-01-01T00:00:01.5Z,_value=2.000
+job=web _time=1970-01-01T00:00:02Z,_value=NaN
+`,
+		},
+		{
+			name:       "vector",
+			resultType: promapi.ResultTypeVector,
+			result:     `[{"metric":{},"value":[1,"3"]}]`,
+			want: `#  _time=time,_value=float
+_time=1970-01-01T00:00:01Z,_value=3.000
+`,
+		},
+		{
+			name:       "string",
+			resultType: promapi.ResultTypeString,
+			result:     `[1,"hello"]`,
+			want: `#  _time=time,_value=string
+_time=1970-01-01T00:00:01Z,_value=hello
+`,
+		},
+		{
+			name:       "invalid value",
+			resultType: promapi.ResultTypeScalar,
+			result:     `[1,"x"]`,
+			wantErr:    `invalid test sample value "x": strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+		{
+			name:       "unsupported",
+			resultType: "streams",
+			result:     `[]`,
+			wantErr:    `unsupported test result type "streams"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := promapi.NewTableSet("test", memory.DefaultAllocator)
+			err := ts.AddResult(tc.resultType, json.RawMessage(tc.result))
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("unexpected error -want/+got:\n\t- %s\n\t+ %v", tc.wantErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			if err := ts.Do(func(tbl flux.Table) error {
+				got += table.Stringify(tbl)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("unexpected tables -want/+got:\n\t- %s\n\t+ %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package promapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	fluxhttp "github.com/InfluxCommunity/flux/dependencies/http"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// Retry settings for write requests that fail with a status of 429 or 5xx.
+// The delay doubles after every attempt up to MaxRetryDelay. A Retry-After
+// header sent by the server replaces the delay but is capped at
+// MaxRetryDelay too.
+var RetryDelay = 500 * time.Millisecond
+
+const MaxRetryDelay = 30 * time.Second
+
+// maxErrorBody is the maximum number of bytes read from
+// the body of a failed response.
+const maxErrorBody = 4096
+
+// Write sends the requests made by newRequest until one succeeds.
+// Requests that fail with a status of 429 or 5xx are retried up to
+// maxRetries times. The name of the operation, such as "logql push",
+// is used in errors.
+func Write(ctx context.Context, client fluxhttp.Client, name string, maxRetries int64, newRequest func() (*http.Request, error)) error {
+	delay := RetryDelay
+	for attempt := int64(0); ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, codes.Unavailable, "%s request failed", name)
+		}
+		msg, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		_ = resp.Body.Close()
+		if resp.StatusCode/100 == 2 {
+			return nil
+		}
+		if err != nil {
+			return errors.Newf(codes.Invalid, "error when reading %s response body: %s", name, err)
+		}
+
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retry || attempt >= maxRetries {
+			code := codes.Invalid
+			if retry {
+				code = codes.Unavailable
+			}
+			text := strings.TrimSpace(string(msg))
+			if text == "" {
+				text = resp.Status
+			}
+			return errors.Newf(code, "%s failed with status %d: %s", name, resp.StatusCode, text)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryWait(resp.Header.Get("Retry-After"), delay)):
+		}
+		if delay *= 2; delay > MaxRetryDelay {
+			delay = MaxRetryDelay
+		}
+	}
+}
+
+// retryWait returns the time to wait before the next attempt. It is the
+// number of seconds of the Retry-After header, if it has one, capped
+// at MaxRetryDelay and otherwise the delay.
+func retryWait(retryAfter string, delay time.Duration) time.Duration {
+	secs, err := strconv.Atoi(retryAfter)
+	if err != nil || secs < 0 {
+		return delay
+	}
+	if secs > int(MaxRetryDelay/time.Second) {
+		return MaxRetryDelay
+	}
+	return time.Duration(secs) * time.Second
+}
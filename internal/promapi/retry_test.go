@@ -0,0 +1,24 @@
+package promapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryWait(t *testing.T) {
+	for _, tt := range []struct {
+		retryAfter string
+		want       time.Duration
+	}{
+		{retryAfter: "", want: time.Second},
+		{retryAfter: "2", want: 2 * time.Second},
+		{retryAfter: "0", want: 0},
+		{retryAfter: "-1", want: time.Second},
+		{retryAfter: "Wed, 21 Oct 2015 07:28:00 GMT", want: time.Second},
+		{retryAfter: "86400", want: MaxRetryDelay},
+	} {
+		if got := retryWait(tt.retryAfter, time.Second); got != tt.want {
+			t.Errorf("unexpected wait for %q -want/+got:\n\t- %s\n\t+ %s", tt.retryAfter, tt.want, got)
+		}
+	}
+}
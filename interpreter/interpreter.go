@@ -787,6 +787,9 @@ func (itrp *Interpreter) doCall(ctx context.Context, call *semantic.CallExpressi
 	}
 
 	f := callee.Function()
+	if hook, ok := ctx.Value(callHookKey).(CallHook); ok {
+		hook(f)
+	}
 
 	// Check if the function is an interpFunction and rebind it.
 	// This is needed so that any side effects produced when
@@ -1779,8 +1782,30 @@ type contextKey int
 
 const (
 	callStackKey contextKey = iota
+	callHookKey
 )
 
+// CallHook is called with each function that the interpreter calls.
+type CallHook func(fn values.Function)
+
+// WithCallHook returns a context that makes the interpreter
+// call the hook before it calls a function.
+// The hook may be called from multiple goroutines.
+func WithCallHook(ctx context.Context, hook CallHook) context.Context {
+	return context.WithValue(ctx, callHookKey, hook)
+}
+
+// FunctionID returns a value that identifies the definition of a function.
+// Functions that are created from the same function expression have the
+// same id, even if they are created by different evaluations of a package.
+// The id of a builtin function is the function itself.
+func FunctionID(fn values.Function) interface{} {
+	if f, ok := fn.(function); ok {
+		return f.e
+	}
+	return fn
+}
+
 // StackEntry describes a single entry in the call stack.
 type StackEntry struct {
 	FunctionName string
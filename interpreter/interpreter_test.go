@@ -902,3 +902,39 @@ func TestStack(t *testing.T) {
 		t.Fatalf("unexpected stack -want/+got:\n%s", cmp.Diff(want, got))
 	}
 }
+
+func TestCallHook(t *testing.T) {
+	src := `from(bucket: "telegraf") |> range(start: -5m) |> aggregateWindow(every: 1m, fn: mean)`
+	ctx, deps := dependency.Inject(context.Background(), dependenciestest.Default())
+	defer deps.Finish()
+
+	prelude := runtime.Prelude()
+	ids := make(map[interface{}]string)
+	for _, name := range []string{"aggregateWindow", "window", "mean"} {
+		v, ok := prelude.Lookup(name)
+		if !ok {
+			t.Fatalf("missing %s in the prelude", name)
+		}
+		ids[interpreter.FunctionID(v.Function())] = name
+	}
+
+	called := make(map[string]int)
+	ctx = interpreter.WithCallHook(ctx, func(fn values.Function) {
+		if name, ok := ids[interpreter.FunctionID(fn)]; ok {
+			called[name]++
+		}
+	})
+	if _, _, err := runtime.Eval(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+
+	// aggregateWindow calls window twice and the function passed as fn once.
+	want := map[string]int{
+		"aggregateWindow": 1,
+		"window":          2,
+		"mean":            1,
+	}
+	if !cmp.Equal(want, called) {
+		t.Fatalf("unexpected calls -want/+got:\n%s", cmp.Diff(want, called))
+	}
+}
@@ -0,0 +1,141 @@
+package lang
+
+import (
+	"context"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/resultcache"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/metadata"
+	"go.uber.org/zap"
+)
+
+// resultCacheMetadataKey is the metadata key that reports
+// whether the results of a query came from the result cache.
+const resultCacheMetadataKey = "flux/result-cache"
+
+// WithResultCache returns a CompileOption that answers the program from
+// the result cache when it has results for the plan of the program and
+// stores the results of the program in the cache otherwise.
+// It takes precedence over a result cache Config in the context.
+// Programs fail to start if the Config has no Cache or Namespace.
+//
+// On a cache miss, Start does not return until the program has finished
+// and all of its results are buffered in memory, so the first result is
+// returned later than without the cache. The buffered results count
+// toward the memory limit of the query, which fails if they exceed it.
+func WithResultCache(c resultcache.Config) CompileOption {
+	return func(o *compileOptions) {
+		o.resultCache = &c
+	}
+}
+
+// resultCache returns the result cache Config of the program, if any.
+func (p *Program) resultCache(ctx context.Context) (resultcache.Config, bool) {
+	if p.opts != nil && p.opts.resultCache != nil {
+		return *p.opts.resultCache, true
+	}
+	return resultcache.Get(ctx)
+}
+
+// startCached starts the program with the result cache. The query reads
+// all of the results of the program before it is returned when they are
+// not in the cache, so that they can be stored. The results are buffered
+// with the allocator of the query so they are bounded by its memory limit.
+func (p *Program) startCached(ctx context.Context, alloc memory.Allocator, c resultcache.Config) (flux.Query, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	lookup, ok := resultcache.NewLookup(c, p.PlanSpec)
+	if !ok {
+		return p.start(ctx, alloc)
+	}
+
+	resourceAlloc, ok := alloc.(*memory.ResourceAllocator)
+	if !ok {
+		resourceAlloc = &memory.ResourceAllocator{
+			Allocator: alloc,
+		}
+	}
+
+	results, ok, err := lookup.Get(ctx, resourceAlloc)
+	if err != nil {
+		// The cache is an optimization so a failure
+		// to read from it does not fail the query.
+		p.logResultCacheError("Failed to read results from the cache", err)
+	}
+	if ok {
+		return p.cachedQuery(ctx, resourceAlloc, results, "hit", flux.Statistics{}), nil
+	}
+
+	q, err := p.start(ctx, resourceAlloc)
+	if err != nil {
+		return nil, err
+	}
+	for res := range q.Results() {
+		r, err := resultcache.ReadResult(res)
+		if err != nil {
+			q.Cancel()
+			q.Done()
+			releaseResults(results)
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	q.Done()
+	if err := q.Err(); err != nil {
+		releaseResults(results)
+		return nil, err
+	}
+
+	if err := lookup.Set(ctx, results, resourceAlloc); err != nil {
+		p.logResultCacheError("Failed to store results in the cache", err)
+	}
+	return p.cachedQuery(ctx, resourceAlloc, results, "miss", q.Statistics()), nil
+}
+
+func (p *Program) logResultCacheError(msg string, err error) {
+	if p.Logger != nil {
+		p.Logger.Warn(msg, zap.Error(err))
+	}
+}
+
+// cachedQuery returns a query that produces results that have been buffered.
+func (p *Program) cachedQuery(ctx context.Context, alloc *memory.ResourceAllocator, results []*resultcache.Result, status string, stats flux.Statistics) flux.Query {
+	ctx, cancel := context.WithCancel(ctx)
+	q := &query{
+		ctx:     ctx,
+		results: make(chan flux.Result, len(results)),
+		alloc:   alloc,
+		cancel:  cancel,
+		stats: flux.Statistics{
+			Metadata: make(metadata.Metadata),
+		},
+	}
+	q.stats.Merge(stats)
+	q.stats.Metadata.Add(resultCacheMetadataKey, status)
+	for _, res := range results {
+		q.results <- res
+	}
+	close(q.results)
+	return &cachedQuery{query: q, buffered: results}
+}
+
+// cachedQuery is a query whose results are buffered.
+// The results that were not read are released when it is done.
+type cachedQuery struct {
+	*query
+	buffered []*resultcache.Result
+}
+
+func (q *cachedQuery) Done() {
+	q.query.Done()
+	releaseResults(q.buffered)
+	q.buffered = nil
+}
+
+func releaseResults(results []*resultcache.Result) {
+	for _, res := range results {
+		res.Release()
+	}
+}
@@ -0,0 +1,80 @@
+package lang_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/dependency"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/execute/resultcache"
+	"github.com/InfluxCommunity/flux/lang"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/parser"
+	"github.com/InfluxCommunity/flux/runtime"
+)
+
+func TestCompileOptions_ResultCache(t *testing.T) {
+	src := `import "array"
+array.from(rows: [{_value: 1}, {_value: 2}, {_value: 3}])
+	|> filter(fn: (r) => r._value > 1)`
+	now := parser.MustParseTime("2018-10-10T00:00:00Z").Value
+	// array.from is not limited to a time range,
+	// so its results are only cached with a TTL.
+	c := resultcache.Config{
+		Cache:     resultcache.NewLRU(1 << 20),
+		Namespace: "tenant",
+		TTL:       time.Minute,
+	}
+
+	run := func() ([]*executetest.Result, string) {
+		ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+		defer deps.Finish()
+
+		program, err := lang.Compile(ctx, src, runtime.Default, now, lang.WithResultCache(c))
+		if err != nil {
+			t.Fatalf("failed to compile script: %v", err)
+		}
+		q, err := program.Start(ctx, &memory.ResourceAllocator{})
+		if err != nil {
+			t.Fatalf("failed to start program: %v", err)
+		}
+
+		var results []*executetest.Result
+		for res := range q.Results() {
+			r := executetest.ConvertResult(res)
+			if r.Err != nil {
+				t.Fatal(r.Err)
+			}
+			r.Normalize()
+			results = append(results, r)
+		}
+		q.Done()
+		if err := q.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		status := q.Statistics().Metadata["flux/result-cache"]
+		if len(status) != 1 {
+			t.Fatalf("expected the result cache status in the metadata, got %v", status)
+		}
+		return results, status[0].(string)
+	}
+
+	want, status := run()
+	if status != "miss" {
+		t.Errorf("unexpected status of the first query -want/+got:\n\t- miss\n\t+ %s", status)
+	}
+	got, status := run()
+	if status != "hit" {
+		t.Errorf("unexpected status of the second query -want/+got:\n\t- hit\n\t+ %s", status)
+	}
+
+	if len(want) != 1 || len(got) != 1 {
+		t.Fatalf("unexpected number of results: %d, %d", len(want), len(got))
+	}
+	if err := executetest.EqualResults([]flux.Result{want[0]}, []flux.Result{got[0]}); err != nil {
+		t.Error(err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/dependency"
 	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/resultcache"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/internal/jaeger"
 	"github.com/InfluxCommunity/flux/internal/operation"
@@ -51,7 +52,9 @@ func AddCompilerMappings(mappings flux.CompilerMappings) error {
 type CompileOption func(*compileOptions)
 
 type compileOptions struct {
-	extern flux.ASTHandle
+	extern      flux.ASTHandle
+	explain     ExplainMode
+	resultCache *resultcache.Config
 
 	planOptions struct {
 		logical  []plan.LogicalOption
@@ -122,17 +125,18 @@ func CompileTableObject(ctx context.Context, to *flux.TableObject, now time.Time
 	if err != nil {
 		return nil, err
 	}
-	ps, err := buildPlan(ctx, s, o)
+	ps, explanation, err := buildPlan(ctx, s, o)
 	if err != nil {
 		return nil, err
 	}
 	return &Program{
-		opts:     o,
-		PlanSpec: ps,
+		opts:        o,
+		PlanSpec:    ps,
+		explanation: explanation,
 	}, nil
 }
 
-func buildPlan(ctx context.Context, spec *operation.Spec, opts *compileOptions) (*plan.Spec, error) {
+func buildPlan(ctx context.Context, spec *operation.Spec, opts *compileOptions) (*plan.Spec, *plan.Explanation, error) {
 	s, _ := opentracing.StartSpanFromContext(ctx, "plan")
 	defer s.Finish()
 
@@ -149,11 +153,17 @@ func buildPlan(ctx context.Context, spec *operation.Spec, opts *compileOptions)
 	pb.AddLogicalOptions(lopts...)
 	pb.AddPhysicalOptions(popts...)
 
+	var explanation *plan.Explanation
+	if opts.explain != ExplainNone {
+		explanation = new(plan.Explanation)
+		ctx = plan.WithExplanation(ctx, explanation)
+	}
+
 	ps, err := pb.Build().Plan(ctx, spec)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return ps, nil
+	return ps, explanation, nil
 }
 
 // FluxCompiler compiles a Flux script into a spec.
@@ -161,6 +171,9 @@ type FluxCompiler struct {
 	Now    time.Time
 	Extern json.RawMessage `json:"extern,omitempty"`
 	Query  string          `json:"query"`
+	// Explain replaces the results of the query with
+	// an explanation of how the query is planned.
+	Explain ExplainMode `json:"explain,omitempty"`
 }
 
 func wrapFileJSONInPkg(bs []byte) []byte {
@@ -182,15 +195,24 @@ func IsNonNullJSON(bs json.RawMessage) bool {
 func (c FluxCompiler) Compile(ctx context.Context, runtime flux.Runtime) (flux.Program, error) {
 	query := c.Query
 
+	var opts []CompileOption
+	switch c.Explain {
+	case ExplainNone:
+	case ExplainPlan, ExplainAnalyze:
+		opts = append(opts, WithExplain(c.Explain))
+	default:
+		return nil, errors.Newf(codes.Invalid, "invalid explain mode %q, must be one of %q or %q", c.Explain, ExplainPlan, ExplainAnalyze)
+	}
+
 	// Ignore context, it will be provided upon Program Start.
 	if IsNonNullJSON(c.Extern) {
 		hdl, err := runtime.JSONToHandle(wrapFileJSONInPkg(c.Extern))
 		if err != nil {
 			return nil, errors.Wrap(err, codes.Inherit, "extern json parse error")
 		}
-		return Compile(ctx, query, runtime, c.Now, WithExtern(hdl))
+		opts = append(opts, WithExtern(hdl))
 	}
-	return Compile(ctx, query, runtime, c.Now)
+	return Compile(ctx, query, runtime, c.Now, opts...)
 }
 
 func (c FluxCompiler) CompilerType() flux.CompilerType {
@@ -261,6 +283,9 @@ type Program struct {
 	Runtime  flux.Runtime
 
 	opts *compileOptions
+	// explanation records what the planner did
+	// when the query is explained.
+	explanation *plan.Explanation
 }
 
 func (p *Program) SetLogger(logger *zap.Logger) {
@@ -268,6 +293,16 @@ func (p *Program) SetLogger(logger *zap.Logger) {
 }
 
 func (p *Program) Start(ctx context.Context, alloc memory.Allocator) (flux.Query, error) {
+	if p.opts != nil && p.opts.explain != ExplainNone {
+		return p.startExplain(ctx, alloc)
+	}
+	if c, ok := p.resultCache(ctx); ok {
+		return p.startCached(ctx, alloc, c)
+	}
+	return p.start(ctx, alloc)
+}
+
+func (p *Program) start(ctx context.Context, alloc memory.Allocator) (flux.Query, error) {
 	ctx, cancel := context.WithCancel(ctx)
 
 	// This span gets closed by the query when it is done.
@@ -494,11 +529,12 @@ func (p *AstProgram) Start(ctx context.Context, alloc memory.Allocator) (flux.Qu
 	if err := p.updateProfilers(ctx, scope); err != nil {
 		return nil, errors.Wrap(err, codes.Inherit, "error in reading profiler settings while starting program")
 	}
-	ps, err := buildPlan(cctx, sp, p.opts)
+	ps, explanation, err := buildPlan(cctx, sp, p.opts)
 	if err != nil {
 		return nil, errors.Wrap(err, codes.Inherit, "error in building plan while starting program")
 	}
 	p.PlanSpec = ps
+	p.explanation = explanation
 	s.Finish()
 
 	// Execution.
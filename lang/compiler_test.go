@@ -124,6 +124,11 @@ func TestFluxCompiler(t *testing.T) {
 			now:  time.Unix(1000, 0),
 			q:    `from(bucket: "foo") |> range(start: -5m)`,
 		},
+		{
+			name:         "invalid explain mode",
+			jsonCompiler: []byte(`{"query": "from(bucket: \"foo\") |> range(start: -5m)", "explain": "analyse"}`),
+			compilerErr:  `invalid explain mode "analyse"`,
+		},
 		{
 			name: "extern that uses null keyword",
 			now:  parser.MustParseTime("2020-03-24T14:24:46.15933241Z").Value,
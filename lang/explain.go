@@ -0,0 +1,234 @@
+package lang
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/metadata"
+	"github.com/InfluxCommunity/flux/plan"
+)
+
+// ExplainMode selects how a query is explained.
+type ExplainMode string
+
+const (
+	// ExplainNone runs the query and returns its results.
+	ExplainNone ExplainMode = ""
+	// ExplainPlan plans the query without running it and returns
+	// the plans and the planner rules that were applied.
+	ExplainPlan ExplainMode = "plan"
+	// ExplainAnalyze runs the query, discards its results and
+	// returns the plans together with the profile of every operation.
+	ExplainAnalyze ExplainMode = "analyze"
+)
+
+// ExplainResultName is the name of the result that holds the explanation.
+const ExplainResultName = "_explain"
+
+// ExplainColumn is the column of the explanation result
+// that holds the lines of the explanation.
+const ExplainColumn = "explain"
+
+// WithExplain returns a CompileOption that replaces the results of the
+// program with a single result that explains how the query was planned.
+func WithExplain(mode ExplainMode) CompileOption {
+	return func(o *compileOptions) {
+		o.explain = mode
+	}
+}
+
+// startExplain starts a query that produces the explanation of the program.
+func (p *Program) startExplain(ctx context.Context, alloc memory.Allocator) (flux.Query, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	resourceAlloc, ok := alloc.(*memory.ResourceAllocator)
+	if !ok {
+		resourceAlloc = &memory.ResourceAllocator{
+			Allocator: alloc,
+		}
+	}
+
+	q := &query{
+		ctx:     ctx,
+		results: make(chan flux.Result, 1),
+		alloc:   resourceAlloc,
+		cancel:  cancel,
+		stats: flux.Statistics{
+			Metadata: make(metadata.Metadata),
+		},
+	}
+
+	var opts []plan.FormatOption
+	if p.opts.explain == ExplainAnalyze {
+		stats, rows, err := p.analyze(ctx, resourceAlloc)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		q.stats.Merge(stats)
+		opts = append(opts, plan.WithAnnotations(analyzeAnnotations(stats, rows)))
+	}
+
+	explanation := p.explanation
+	if explanation == nil {
+		explanation = new(plan.Explanation)
+	}
+	text := explanation.Describe(p.PlanSpec, append([]plan.FormatOption{plan.WithDetails()}, opts...)...)
+
+	tbl, err := explainTable(text, resourceAlloc)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	q.results <- &explainResult{tables: table.Iterator{tbl}}
+	close(q.results)
+	return q, nil
+}
+
+// analyze runs the program with the operator profiler enabled and
+// returns the statistics of the query and the number of rows in every result.
+// The execution options may be shared with other queries, so the
+// operator profiler is removed again when the query has finished.
+func (p *Program) analyze(ctx context.Context, alloc memory.Allocator) (flux.Statistics, map[string]int64, error) {
+	if execute.HaveExecutionDependencies(ctx) {
+		opts := execute.GetExecutionDependencies(ctx).ExecutionOptions
+		if opts != nil && opts.OperatorProfiler == nil {
+			opts.OperatorProfiler = &execute.OperatorProfiler{}
+			defer func() { opts.OperatorProfiler = nil }()
+		}
+	}
+
+	q, err := p.start(ctx, alloc)
+	if err != nil {
+		return flux.Statistics{}, nil, err
+	}
+
+	rows := make(map[string]int64)
+	for res := range q.Results() {
+		name := res.Name()
+		if err := res.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(cr flux.ColReader) error {
+				rows[name] += int64(cr.Len())
+				return nil
+			})
+		}); err != nil {
+			q.Cancel()
+			q.Done()
+			return flux.Statistics{}, nil, err
+		}
+	}
+	q.Done()
+	if err := q.Err(); err != nil {
+		return flux.Statistics{}, nil, err
+	}
+	return q.Statistics(), rows, nil
+}
+
+// analyzeAnnotations returns a function that describes the rows,
+// memory and time that the operation of a plan node used.
+func analyzeAnnotations(stats flux.Statistics, rows map[string]int64) func(node plan.Node) []string {
+	return func(node plan.Node) []string {
+		if y, ok := node.ProcedureSpec().(plan.YieldProcedureSpec); ok {
+			return []string{fmt.Sprintf("Rows: %d", rows[y.YieldName()])}
+		}
+
+		var (
+			found            bool
+			rowsIn, duration int64
+			allocated        int64
+		)
+		for _, profile := range stats.Profiles {
+			if profile.Label != string(node.ID()) {
+				continue
+			}
+			found = true
+			rowsIn += profile.RowsIn
+			duration += profile.Sum
+			allocated += profile.Allocated
+		}
+		if !found {
+			return nil
+		}
+
+		var lines []string
+		rowsOut, ok := analyzeRowsOut(node, stats, rows)
+		switch {
+		case len(node.Predecessors()) == 0 && ok:
+			lines = append(lines, fmt.Sprintf("Rows out: %d", rowsOut))
+		case len(node.Predecessors()) > 0 && ok:
+			lines = append(lines, fmt.Sprintf("Rows in: %d, Rows out: %d", rowsIn, rowsOut))
+		case len(node.Predecessors()) > 0:
+			lines = append(lines, fmt.Sprintf("Rows in: %d", rowsIn))
+		}
+		lines = append(lines,
+			fmt.Sprintf("Allocated: %d bytes", allocated),
+			fmt.Sprintf("Wall time: %v", time.Duration(duration)),
+		)
+		return lines
+	}
+}
+
+// analyzeRowsOut returns the number of rows that a node produced. The rows
+// are counted when they are received by the first successor of the node or,
+// for the nodes at the end of the plan, when they are read from the results.
+func analyzeRowsOut(node plan.Node, stats flux.Statistics, rows map[string]int64) (int64, bool) {
+	if len(node.Successors()) == 0 {
+		name := plan.DefaultYieldName
+		if plan.HasSideEffect(node.ProcedureSpec()) {
+			name = string(node.ID())
+		}
+		n, ok := rows[name]
+		return n, ok
+	}
+
+	succ := node.Successors()[0]
+	if y, ok := succ.ProcedureSpec().(plan.YieldProcedureSpec); ok {
+		n, ok := rows[y.YieldName()]
+		return n, ok
+	}
+
+	var (
+		n     int64
+		found bool
+	)
+	for _, profile := range stats.Profiles {
+		if profile.Label == string(succ.ID()) && profile.Source == string(node.ID()) {
+			n += profile.RowsIn
+			found = true
+		}
+	}
+	return n, found
+}
+
+// explainTable returns a table with a row for every line of the explanation.
+func explainTable(text string, alloc memory.Allocator) (flux.Table, error) {
+	b := execute.NewColListTableBuilder(execute.NewGroupKey(nil, nil), alloc)
+	if _, err := b.AddCol(flux.ColMeta{Label: ExplainColumn, Type: flux.TString}); err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if err := b.AppendString(0, line); err != nil {
+			return nil, err
+		}
+	}
+	return b.Table()
+}
+
+// explainResult is the result that holds the explanation of a query.
+type explainResult struct {
+	tables table.Iterator
+}
+
+func (r *explainResult) Name() string {
+	return ExplainResultName
+}
+
+func (r *explainResult) Tables() flux.TableIterator {
+	return r.tables
+}
@@ -0,0 +1,81 @@
+package lang_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/dependency"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/lang"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/parser"
+	"github.com/InfluxCommunity/flux/runtime"
+)
+
+func TestCompileOptions_Explain(t *testing.T) {
+	src := `import "array"
+array.from(rows: [{_value: 1}, {_value: 2}, {_value: 3}])
+	|> filter(fn: (r) => r._value > 1)`
+	now := parser.MustParseTime("2018-10-10T00:00:00Z").Value
+
+	for _, tc := range []struct {
+		mode lang.ExplainMode
+		want []string
+	}{
+		{
+			mode: lang.ExplainPlan,
+			want: []string{"Logical Plan:", "Physical Plan:", "Rules:", "Pushdowns:"},
+		},
+		{
+			mode: lang.ExplainAnalyze,
+			want: []string{"Physical Plan:", "Rows in: 3, Rows out: 2", "Wall time:"},
+		},
+	} {
+		tc := tc
+		t.Run(string(tc.mode), func(t *testing.T) {
+			ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+			defer deps.Finish()
+
+			program, err := lang.Compile(ctx, src, runtime.Default, now, lang.WithExplain(tc.mode))
+			if err != nil {
+				t.Fatalf("failed to compile script: %v", err)
+			}
+			q, err := program.Start(ctx, &memory.ResourceAllocator{})
+			if err != nil {
+				t.Fatalf("failed to start program: %v", err)
+			}
+			defer q.Done()
+
+			var lines []string
+			for res := range q.Results() {
+				if got, want := res.Name(), lang.ExplainResultName; got != want {
+					t.Fatalf("unexpected result name -want/+got:\n\t- %s\n\t+ %s", want, got)
+				}
+				if err := res.Tables().Do(func(tbl flux.Table) error {
+					return tbl.Do(func(cr flux.ColReader) error {
+						vs := cr.Strings(0)
+						for i := 0; i < vs.Len(); i++ {
+							lines = append(lines, vs.Value(i))
+						}
+						return nil
+					})
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			q.Done()
+			if err := q.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			explanation := strings.Join(lines, "\n")
+			for _, want := range tc.want {
+				if !strings.Contains(explanation, want) {
+					t.Errorf("expected explanation to contain %q:\n%s", want, explanation)
+				}
+			}
+		})
+	}
+}
@@ -141,6 +141,17 @@ func (a *ResourceAllocator) Allocated() int64 {
 	return atomic.LoadInt64(&a.bytesAllocated)
 }
 
+// AllocationLimit reports the current allocation limit, which includes
+// any memory granted by the Manager, and whether the allocator has a limit.
+func (a *ResourceAllocator) AllocationLimit() (int64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.Limit == nil {
+		return 0, false
+	}
+	return *a.Limit, true
+}
+
 // MaxAllocated reports the maximum amount of allocated memory at any point in the query.
 func (a *ResourceAllocator) MaxAllocated() int64 {
 	return atomic.LoadInt64(&a.maxAllocated)
@@ -0,0 +1,428 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+// PoolConfig configures the memory pools of a PoolManager.
+type PoolConfig struct {
+	// Capacity is the number of bytes in the pool of each tenant.
+	Capacity int64
+
+	// InitialReservation is the number of bytes that are reserved
+	// from the pool for a query before it starts.
+	InitialReservation int64
+
+	// MaxQueryMemory is the most memory a single query may use.
+	// If it is zero, a query may use the whole pool.
+	MaxQueryMemory int64
+
+	// BorrowIncrement is the least number of bytes that a query borrows
+	// from the pool when it needs more memory than it has, so that queries
+	// do not need to go to the pool for every allocation. A query borrows
+	// only what it needs when the pool does not have the increment free.
+	BorrowIncrement int64
+
+	// QueueTimeout is how long a query waits for other queries to return
+	// memory to the pool when the pool does not have its initial
+	// reservation. If it is zero, queries do not wait. A query that is
+	// running never waits to borrow memory, because it would hold its
+	// own memory and the lock of its allocator while it waits.
+	QueueTimeout time.Duration
+
+	// Preempt enables cancelling the query that borrowed the most memory
+	// when another query cannot get the memory it needs. Only a query that
+	// uses more memory than the query that is waiting is cancelled.
+	// The memory of the cancelled query returns to the pool when the
+	// query has stopped and released it, so only queries that wait in
+	// the queue, for up to the QueueTimeout, get it.
+	Preempt bool
+}
+
+// PoolStats are the metrics of a memory pool.
+// Flux does not register them with a metrics registry.
+// A service that embeds Flux exports them by reading
+// PoolManager.Stats when its metrics are collected.
+type PoolStats struct {
+	// Capacity is the number of bytes in the pool.
+	Capacity int64
+	// Used is the number of bytes that are reserved by queries.
+	Used int64
+	// Peak is the most bytes that were reserved at once.
+	Peak int64
+	// Queries is the number of queries that hold a reservation.
+	Queries int64
+	// Queued is the number of queries that are waiting for memory.
+	Queued int64
+	// Preempted is the number of queries that were cancelled
+	// to return their memory to the pool.
+	Preempted int64
+	// Rejected is the number of requests for memory that failed.
+	Rejected int64
+}
+
+// PoolManager manages a memory pool for every tenant.
+type PoolManager struct {
+	config PoolConfig
+
+	mu    sync.Mutex
+	pools map[string]*Pool
+}
+
+// NewPoolManager returns a PoolManager that creates the pool
+// of a tenant with the config when it is first used.
+func NewPoolManager(config PoolConfig) *PoolManager {
+	return &PoolManager{
+		config: config,
+		pools:  make(map[string]*Pool),
+	}
+}
+
+// Pool returns the memory pool of the tenant.
+func (m *PoolManager) Pool(tenant string) *Pool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pools[tenant]
+	if !ok {
+		p = NewPool(m.config)
+		m.pools[tenant] = p
+	}
+	return p
+}
+
+// Stats returns the metrics of the pool of every tenant.
+func (m *PoolManager) Stats() map[string]PoolStats {
+	m.mu.Lock()
+	pools := make(map[string]*Pool, len(m.pools))
+	for tenant, p := range m.pools {
+		pools[tenant] = p
+	}
+	m.mu.Unlock()
+
+	stats := make(map[string]PoolStats, len(pools))
+	for tenant, p := range pools {
+		stats[tenant] = p.Stats()
+	}
+	return stats
+}
+
+// Pool is memory that is shared by the queries of a tenant.
+// Every query reserves memory from the pool before it starts
+// and borrows more from it as it needs it.
+type Pool struct {
+	config PoolConfig
+
+	mu           sync.Mutex
+	stats        PoolStats
+	reservations map[*Reservation]struct{}
+	queue        *list.List
+}
+
+// NewPool returns a memory pool.
+func NewPool(config PoolConfig) *Pool {
+	return &Pool{
+		config:       config,
+		stats:        PoolStats{Capacity: config.Capacity},
+		reservations: make(map[*Reservation]struct{}),
+		queue:        list.New(),
+	}
+}
+
+// poolRequest is a request for memory that waits in the queue of the pool.
+type poolRequest struct {
+	r     *Reservation
+	n     int64
+	ready chan struct{}
+
+	// These are protected by the lock of the pool.
+	done    bool
+	granted bool
+}
+
+// Reserve reserves the initial reservation for a query. It waits in the
+// queue for up to the QueueTimeout, or until the context is done, when
+// the pool does not have the memory. The context must be the context of
+// the query, since memory is no longer borrowed for the query after it
+// is done. Cancel is called if the query is preempted and may be nil.
+// The reservation must be released when the query is done.
+func (p *Pool) Reserve(ctx context.Context, cancel func()) (*Reservation, error) {
+	r := &Reservation{
+		p:      p,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if err := p.acquire(ctx, r, p.config.InitialReservation, true); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// acquire takes n bytes from the pool for the reservation.
+// If wait is true and the pool does not have the memory, it waits
+// in the queue for other queries to return memory.
+func (p *Pool) acquire(ctx context.Context, r *Reservation, n int64, wait bool) error {
+	p.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return err
+	} else if max := p.config.MaxQueryMemory; max > 0 && r.reserved+n > max {
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return errors.Newf(codes.ResourceExhausted, "query would use %d bytes which exceeds the limit of %d bytes per query", r.reserved+n, max)
+	} else if r.preempted || r.released {
+		p.mu.Unlock()
+		return errPreempted
+	}
+
+	if p.queue.Len() == 0 && p.tryGrant(r, n) {
+		p.mu.Unlock()
+		return nil
+	}
+	if p.config.Preempt {
+		p.preempt(r, n)
+	}
+	if !wait || p.config.QueueTimeout <= 0 {
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return p.exhausted(n)
+	}
+
+	req := &poolRequest{r: r, n: n, ready: make(chan struct{})}
+	elem := p.queue.PushBack(req)
+	p.stats.Queued++
+	p.mu.Unlock()
+
+	timer := time.NewTimer(p.config.QueueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-req.ready:
+	case <-timer.C:
+		err = p.exhausted(n)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if req.granted {
+		// The memory may have been granted while giving up.
+		return nil
+	}
+	if !req.done {
+		p.queue.Remove(elem)
+		p.stats.Queued--
+		// The request may have held up the requests behind it.
+		p.grantQueued()
+	}
+	if err == nil {
+		err = errPreempted
+	}
+	p.stats.Rejected++
+	return err
+}
+
+var errPreempted = errors.New(codes.ResourceExhausted, "query was preempted to free memory for other queries")
+
+func (p *Pool) exhausted(n int64) error {
+	return errors.Newf(codes.ResourceExhausted, "memory pool exhausted: capacity %d bytes, used: %d, wanted: %d", p.stats.Capacity, p.stats.Used, n)
+}
+
+// tryGrant gives n bytes to the reservation if the pool has them.
+// It must be called with the lock held.
+func (p *Pool) tryGrant(r *Reservation, n int64) bool {
+	if p.stats.Used+n > p.stats.Capacity {
+		return false
+	}
+	p.stats.Used += n
+	if p.stats.Used > p.stats.Peak {
+		p.stats.Peak = p.stats.Used
+	}
+	if _, ok := p.reservations[r]; !ok {
+		p.reservations[r] = struct{}{}
+		p.stats.Queries++
+	}
+	r.reserved += n
+	return true
+}
+
+// grantQueued grants the memory of the requests in the queue in order
+// until the pool does not have the memory for the next request.
+// It must be called with the lock held.
+func (p *Pool) grantQueued() {
+	for p.queue.Len() > 0 {
+		front := p.queue.Front()
+		req := front.Value.(*poolRequest)
+		if req.r.preempted || req.r.released {
+			// The query gave up its memory while it
+			// waited, so it does not get more.
+			req.granted = false
+		} else if p.tryGrant(req.r, req.n) {
+			req.granted = true
+		} else {
+			return
+		}
+		p.queue.Remove(front)
+		p.stats.Queued--
+		req.done = true
+		close(req.ready)
+	}
+}
+
+// preempt cancels the query that uses the most memory if it uses more
+// than the query that needs n bytes will use. The memory of the cancelled
+// query stays in use until the query releases it, since its buffers are
+// live until it has stopped, so requests in the queue wait for it.
+// No query is cancelled when queries that were cancelled before will
+// return enough memory. It must be called with the lock held.
+func (p *Pool) preempt(r *Reservation, n int64) {
+	var (
+		victim  *Reservation
+		pending int64
+	)
+	for other := range p.reservations {
+		if other.preempted {
+			pending += other.reserved
+			continue
+		}
+		if other == r {
+			continue
+		}
+		if victim == nil || other.reserved > victim.reserved {
+			victim = other
+		}
+	}
+	if p.stats.Used-pending+n <= p.stats.Capacity {
+		return
+	}
+	if victim == nil || victim.reserved <= r.reserved+n {
+		return
+	}
+
+	victim.preempted = true
+	p.stats.Preempted++
+	if victim.cancel != nil {
+		// Cancelling the query only signals it to stop, so it is safe
+		// to call with the lock held.
+		victim.cancel()
+	}
+}
+
+// release returns all of the memory of the reservation to the pool.
+// It must be called with the lock held.
+func (p *Pool) release(r *Reservation) {
+	if _, ok := p.reservations[r]; ok {
+		delete(p.reservations, r)
+		p.stats.Queries--
+	}
+	p.stats.Used -= r.reserved
+	r.reserved = 0
+}
+
+// Stats returns the metrics of the pool.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Reservation is the memory that a query holds in a pool.
+// It implements the Manager for the allocator of the query.
+type Reservation struct {
+	p      *Pool
+	ctx    context.Context
+	cancel func()
+
+	// These are protected by the lock of the pool.
+	reserved  int64
+	preempted bool
+	released  bool
+}
+
+var _ Manager = (*Reservation)(nil)
+
+// Allocator returns an allocator that is limited to the memory of the
+// reservation and borrows more from the pool when it needs it.
+func (r *Reservation) Allocator(alloc memory.Allocator) *ResourceAllocator {
+	r.p.mu.Lock()
+	limit := r.reserved
+	r.p.mu.Unlock()
+	return &ResourceAllocator{
+		Limit:     &limit,
+		Manager:   r,
+		Allocator: alloc,
+	}
+}
+
+// Reserved returns the number of bytes that the reservation holds.
+func (r *Reservation) Reserved() int64 {
+	r.p.mu.Lock()
+	defer r.p.mu.Unlock()
+	return r.reserved
+}
+
+// RequestMemory borrows at least want bytes from the pool. It does not
+// wait for memory because it is called while the allocator of the query
+// holds its lock. It fails when the pool does not have the memory, the
+// query would exceed its limit or the context of the query is done.
+func (r *Reservation) RequestMemory(want int64) (got int64, err error) {
+	n := want
+	if inc := r.p.config.BorrowIncrement; inc > n {
+		// Borrow the increment when the pool has it to spare
+		// and fall back to what is needed otherwise.
+		r.p.mu.Lock()
+		max := r.p.config.MaxQueryMemory
+		fits := r.p.queue.Len() == 0 && r.p.stats.Used+inc <= r.p.stats.Capacity &&
+			(max <= 0 || r.reserved+inc <= max)
+		r.p.mu.Unlock()
+		if fits {
+			n = inc
+		}
+	}
+	if err := r.p.acquire(r.ctx, r, n, false); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// FreeMemory returns bytes that the query no longer uses to the pool.
+func (r *Reservation) FreeMemory(bytes int64) {
+	r.p.mu.Lock()
+	defer r.p.mu.Unlock()
+	if bytes > r.reserved {
+		bytes = r.reserved
+	}
+	r.reserved -= bytes
+	r.p.stats.Used -= bytes
+	r.p.grantQueued()
+}
+
+// Release returns all of the memory of the reservation to the pool.
+// It must be called when the query is done.
+func (r *Reservation) Release() {
+	r.p.mu.Lock()
+	defer r.p.mu.Unlock()
+	if r.released {
+		return
+	}
+	r.released = true
+	r.p.release(r)
+	r.p.grantQueued()
+}
+
+// Preempted reports whether the query was cancelled
+// to return its memory to the pool.
+func (r *Reservation) Preempted() bool {
+	r.p.mu.Lock()
+	defer r.p.mu.Unlock()
+	return r.preempted
+}
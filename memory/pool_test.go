@@ -0,0 +1,260 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	arrowmemory "github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+func TestPool_Borrow(t *testing.T) {
+	mem := arrowmemory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	p := memory.NewPool(memory.PoolConfig{
+		Capacity:           256,
+		InitialReservation: 32,
+		BorrowIncrement:    64,
+	})
+	r, err := p.Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allocator := r.Allocator(mem)
+	b := allocator.Allocate(48)
+	if want, got := int64(96), r.Reserved(); want != got {
+		t.Fatalf("unexpected reserved count -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The pool has only 160 bytes left, so the query
+	// borrows what it needs instead of the increment.
+	b2 := allocator.Allocate(208)
+	if want, got := int64(256), r.Reserved(); want != got {
+		t.Fatalf("unexpected reserved count -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+	allocator.Free(b)
+	allocator.Free(b2)
+
+	r.Release()
+	r.Release()
+	want := memory.PoolStats{Capacity: 256, Peak: 256}
+	if got := p.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestPool_MaxQueryMemory(t *testing.T) {
+	p := memory.NewPool(memory.PoolConfig{
+		Capacity:           256,
+		InitialReservation: 32,
+		MaxQueryMemory:     64,
+	})
+	r, err := p.Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+
+	if _, err := r.RequestMemory(48); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := int64(1), p.Stats().Rejected; want != got {
+		t.Fatalf("unexpected rejected count -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+}
+
+func TestPool_Queue(t *testing.T) {
+	p := memory.NewPool(memory.PoolConfig{
+		Capacity:           64,
+		InitialReservation: 48,
+		QueueTimeout:       time.Minute,
+	})
+	r1, err := p.Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		r2, err := p.Reserve(context.Background(), nil)
+		if err == nil {
+			r2.Release()
+		}
+		done <- err
+	}()
+
+	for p.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	r1.Release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := memory.PoolStats{Capacity: 64, Peak: 48}
+	if got := p.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestPool_QueueTimeout(t *testing.T) {
+	p := memory.NewPool(memory.PoolConfig{
+		Capacity:           64,
+		InitialReservation: 48,
+		QueueTimeout:       time.Millisecond,
+	})
+	r, err := p.Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+
+	if _, err := p.Reserve(context.Background(), nil); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+
+	want := memory.PoolStats{Capacity: 64, Used: 48, Peak: 48, Queries: 1, Rejected: 1}
+	if got := p.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestPool_RequestMemory(t *testing.T) {
+	p := memory.NewPool(memory.PoolConfig{
+		Capacity:           64,
+		InitialReservation: 32,
+		QueueTimeout:       time.Minute,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	r1, err := p.Reserve(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Release()
+	r2, err := p.Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Release()
+
+	// A running query does not wait in the queue for memory.
+	if _, err := r2.RequestMemory(16); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+
+	// A query that is done does not borrow memory.
+	r2.Release()
+	cancel()
+	if _, err := r1.RequestMemory(16); err != context.Canceled {
+		t.Fatalf("unexpected error -want/+got\n\t- %s\n\t+ %v", context.Canceled, err)
+	}
+
+	want := memory.PoolStats{Capacity: 64, Used: 32, Peak: 64, Queries: 1, Rejected: 2}
+	if got := p.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestPool_Preempt(t *testing.T) {
+	p := memory.NewPool(memory.PoolConfig{
+		Capacity:           128,
+		InitialReservation: 16,
+		QueueTimeout:       time.Minute,
+		Preempt:            true,
+	})
+	cancelled := make(chan struct{})
+	r1, err := p.Reserve(context.Background(), func() { close(cancelled) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r1.RequestMemory(96); err != nil {
+		t.Fatal(err)
+	}
+	r2, err := p.Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third query cannot get its memory, so the first
+	// query is cancelled because it uses more memory.
+	reserved := make(chan error, 1)
+	go func() {
+		r3, err := p.Reserve(context.Background(), nil)
+		if err == nil {
+			r3.Release()
+		}
+		reserved <- err
+	}()
+	<-cancelled
+	if !r1.Preempted() {
+		t.Fatal("expected the first query to be preempted")
+	}
+	if _, err := r1.RequestMemory(16); err == nil {
+		t.Fatal("expected a preempted query to not get more memory")
+	}
+
+	// The memory of the first query is in use until it is released,
+	// so the third query waits for it instead of overcommitting the pool.
+	if got := p.Stats(); got.Used != 128 {
+		t.Fatalf("unexpected memory in use -want/+got\n\t- 128\n\t+ %d", got.Used)
+	}
+	select {
+	case err := <-reserved:
+		t.Fatalf("expected the third query to wait, got %v", err)
+	default:
+	}
+	r1.Release()
+	if err := <-reserved; err != nil {
+		t.Fatal(err)
+	}
+	r2.Release()
+
+	want := memory.PoolStats{Capacity: 128, Peak: 128, Preempted: 1}
+	if got := p.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestPoolManager(t *testing.T) {
+	m := memory.NewPoolManager(memory.PoolConfig{
+		Capacity:           64,
+		InitialReservation: 48,
+	})
+	a, err := m.Pool("a").Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Release()
+
+	// Every tenant has its own pool.
+	b, err := m.Pool("b").Reserve(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Release()
+
+	want := map[string]memory.PoolStats{
+		"a": {Capacity: 64, Used: 48, Peak: 48, Queries: 1},
+		"b": {Capacity: 64, Peak: 48},
+	}
+	got := m.Stats()
+	if len(want) != len(got) {
+		t.Fatalf("unexpected number of pools -want/+got\n\t- %d\n\t+ %d", len(want), len(got))
+	}
+	for tenant, w := range want {
+		if g := got[tenant]; w != g {
+			t.Errorf("unexpected stats for %s -want/+got\n\t- %+v\n\t+ %+v", tenant, w, g)
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 )
 
 type Transformation struct {
-	ProcessFn func(id execute.DatasetID, tbl flux.Table) error
-	FinishFn  func(id execute.DatasetID, err error)
+	ProcessFn              func(id execute.DatasetID, tbl flux.Table) error
+	UpdateWatermarkFn      func(id execute.DatasetID, ts execute.Time) error
+	UpdateProcessingTimeFn func(id execute.DatasetID, ts execute.Time) error
+	FinishFn               func(id execute.DatasetID, err error)
 }
 
 func (t *Transformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
@@ -21,10 +23,16 @@ func (t *Transformation) Process(id execute.DatasetID, tbl flux.Table) error {
 }
 
 func (t *Transformation) UpdateWatermark(id execute.DatasetID, ts execute.Time) error {
+	if t.UpdateWatermarkFn != nil {
+		return t.UpdateWatermarkFn(id, ts)
+	}
 	return nil
 }
 
 func (t *Transformation) UpdateProcessingTime(id execute.DatasetID, ts execute.Time) error {
+	if t.UpdateProcessingTimeFn != nil {
+		return t.UpdateProcessingTimeFn(id, ts)
+	}
 	return nil
 }
 
@@ -23,6 +23,28 @@ type BoundsAwareProcedureSpec interface {
 	TimeBounds(predecessorBounds *Bounds) *Bounds
 }
 
+// UnboundedProcedureSpec is implemented by the procedures of sources
+// that may produce data until the query is cancelled. Such a source
+// sends watermarks as it produces data so that the transformations
+// that buffer data, such as window, emit it when the watermark passes.
+type UnboundedProcedureSpec interface {
+	Unbounded() bool
+}
+
+// IsUnbounded reports whether the node, or any of its
+// predecessors, reads from an unbounded source.
+func IsUnbounded(node Node) bool {
+	if s, ok := node.ProcedureSpec().(UnboundedProcedureSpec); ok && s.Unbounded() {
+		return true
+	}
+	for _, pred := range node.Predecessors() {
+		if IsUnbounded(pred) {
+			return true
+		}
+	}
+	return false
+}
+
 // ComputeBounds computes the time bounds for a
 // plan node from the bounds of its predecessors.
 func ComputeBounds(node Node) error {
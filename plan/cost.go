@@ -1,7 +1,17 @@
 package plan
 
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Statistics are the estimated statistics of the data produced by a plan node.
+// A zero value means that the statistic is not known.
 type Statistics struct {
-	Cardinality      int64
+	// Cardinality is the estimated number of rows.
+	Cardinality int64
+	// GroupCardinality is the estimated number of tables.
 	GroupCardinality int64
 }
 
@@ -25,9 +35,164 @@ func Add(a Cost, b Cost) Cost {
 	}
 }
 
+func (c Cost) String() string {
+	var b strings.Builder
+	for _, d := range []struct {
+		name  string
+		value int64
+	}{
+		{name: "disk", value: c.Disk},
+		{name: "cpu", value: c.CPU},
+		{name: "gpu", value: c.GPU},
+		{name: "mem", value: c.MEM},
+		{name: "net", value: c.NET},
+	} {
+		if d.value == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		_, _ = fmt.Fprintf(&b, "%s=%d", d.name, d.value)
+	}
+	return "{" + b.String() + "}"
+}
+
+// CostAwareProcedureSpec is any procedure that can estimate
+// its own cost and the statistics of the data it produces
+// from the statistics of its predecessors.
+type CostAwareProcedureSpec interface {
+	Cost(inStats []Statistics) (cost Cost, outStats Statistics)
+}
+
+// DefaultCost estimates that a procedure produces the rows of all
+// of its predecessors and that processing each row costs one unit of CPU.
 type DefaultCost struct {
 }
 
 func (c DefaultCost) Cost(inStats []Statistics) (Cost, Statistics) {
-	return Cost{}, Statistics{}
+	stats := SumStatistics(inStats)
+	return Cost{CPU: stats.Cardinality}, stats
+}
+
+// SumStatistics returns the combined statistics of the inputs.
+// A statistic is only known if it is known for every input.
+func SumStatistics(inStats []Statistics) Statistics {
+	if len(inStats) == 0 {
+		return Statistics{}
+	}
+	var stats Statistics
+	for _, s := range inStats {
+		if s.Cardinality == 0 {
+			stats.Cardinality = 0
+			break
+		}
+		stats.Cardinality += s.Cardinality
+	}
+	for _, s := range inStats {
+		if s.GroupCardinality == 0 {
+			stats.GroupCardinality = 0
+			break
+		}
+		stats.GroupCardinality += s.GroupCardinality
+	}
+	return stats
+}
+
+// AggregateCost estimates the cost of a procedure that produces a single
+// row for every table, such as an aggregate or a selector.
+func AggregateCost(inStats []Statistics) (Cost, Statistics) {
+	stats := SumStatistics(inStats)
+	return Cost{CPU: stats.Cardinality}, Statistics{
+		Cardinality:      stats.GroupCardinality,
+		GroupCardinality: stats.GroupCardinality,
+	}
+}
+
+// LimitStatistics returns the statistics of data when at most
+// n rows of every table are kept.
+func LimitStatistics(stats Statistics, n int64) Statistics {
+	if stats.GroupCardinality > 0 && (stats.Cardinality == 0 || n*stats.GroupCardinality < stats.Cardinality) {
+		stats.Cardinality = n * stats.GroupCardinality
+	}
+	return stats
+}
+
+// SortCost estimates the cost of sorting data with the statistics.
+// The rows are compared n*log(n) times and buffered in memory.
+func SortCost(stats Statistics) Cost {
+	n := stats.Cardinality
+	if n <= 1 {
+		return Cost{CPU: n, MEM: n}
+	}
+	return Cost{
+		CPU: int64(float64(n) * math.Log2(float64(n))),
+		MEM: n,
+	}
+}
+
+// costOf returns the cost of a node and the statistics of its output
+// from the statistics of its predecessors.
+func costOf(node Node, inStats []Statistics) (Cost, Statistics) {
+	if s, ok := node.ProcedureSpec().(CostAwareProcedureSpec); ok {
+		return s.Cost(inStats)
+	}
+	return DefaultCost{}.Cost(inStats)
+}
+
+// ComputeCost computes the statistics and the cumulative cost of
+// a physical plan node from the statistics and costs of its predecessors.
+// It is meant to be used with BottomUpWalk.
+func ComputeCost(node Node) error {
+	ppn, ok := node.(*PhysicalPlanNode)
+	if !ok {
+		return nil
+	}
+
+	var (
+		total   Cost
+		inStats = make([]Statistics, len(node.Predecessors()))
+	)
+	for i, pred := range node.Predecessors() {
+		if pred, ok := pred.(*PhysicalPlanNode); ok {
+			inStats[i] = pred.stats
+			total = Add(total, pred.totalCost)
+		}
+	}
+	cost, stats := ppn.Cost(inStats)
+	ppn.stats = stats
+	ppn.totalCost = Add(total, cost)
+	return nil
+}
+
+// EstimateCost estimates the cumulative cost of the plan rooted
+// at the node and the statistics of the data that it produces.
+// Unlike ComputeCost, it does not modify the plan and can be used
+// for logical and physical nodes while the plan is rewritten.
+// Predecessors that are shared by more than one node are only counted once.
+//
+// The planner does not compare the costs of alternative rewrites.
+// Rules use the estimated statistics where they change a decision,
+// such as the number of partitions that PartitionRule creates.
+func EstimateCost(node Node) (Cost, Statistics) {
+	var (
+		total Cost
+		seen  = make(map[Node]Statistics)
+	)
+	var estimate func(node Node) Statistics
+	estimate = func(node Node) Statistics {
+		if stats, ok := seen[node]; ok {
+			return stats
+		}
+		inStats := make([]Statistics, len(node.Predecessors()))
+		for i, pred := range node.Predecessors() {
+			inStats[i] = estimate(pred)
+		}
+		cost, stats := costOf(node, inStats)
+		total = Add(total, cost)
+		seen[node] = stats
+		return stats
+	}
+	stats := estimate(node)
+	return total, stats
 }
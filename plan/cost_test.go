@@ -0,0 +1,252 @@
+package plan_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/plan/plantest"
+	"github.com/InfluxCommunity/flux/plan/plantest/spec"
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+)
+
+// sourceSpec returns a mock source that reports the statistics.
+func sourceSpec(stats plan.Statistics) spec.MockProcedureSpec {
+	return spec.MockProcedureSpec{
+		CostFn: func(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+			return plan.Cost{Disk: stats.Cardinality}, stats
+		},
+	}
+}
+
+// aggregateSpec returns a mock aggregate that produces one row per table.
+func aggregateSpec() spec.MockProcedureSpec {
+	return spec.MockProcedureSpec{CostFn: plan.AggregateCost}
+}
+
+func TestSumStatistics(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   []plan.Statistics
+		want plan.Statistics
+	}{
+		{
+			name: "NoInputs",
+		},
+		{
+			name: "Known",
+			in: []plan.Statistics{
+				{Cardinality: 10, GroupCardinality: 2},
+				{Cardinality: 5, GroupCardinality: 1},
+			},
+			want: plan.Statistics{Cardinality: 15, GroupCardinality: 3},
+		},
+		{
+			name: "Unknown",
+			in: []plan.Statistics{
+				{Cardinality: 10, GroupCardinality: 2},
+				{GroupCardinality: 1},
+			},
+			want: plan.Statistics{GroupCardinality: 3},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plan.SumStatistics(tt.in); !cmp.Equal(tt.want, got) {
+				t.Fatalf("unexpected statistics -want/+got:\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestLimitStatistics(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   plan.Statistics
+		n    int64
+		want plan.Statistics
+	}{
+		{
+			name: "Limited",
+			in:   plan.Statistics{Cardinality: 100, GroupCardinality: 4},
+			n:    10,
+			want: plan.Statistics{Cardinality: 40, GroupCardinality: 4},
+		},
+		{
+			name: "Smaller",
+			in:   plan.Statistics{Cardinality: 20, GroupCardinality: 4},
+			n:    10,
+			want: plan.Statistics{Cardinality: 20, GroupCardinality: 4},
+		},
+		{
+			name: "UnknownGroups",
+			in:   plan.Statistics{Cardinality: 100},
+			n:    10,
+			want: plan.Statistics{Cardinality: 100},
+		},
+		{
+			name: "UnknownCardinality",
+			in:   plan.Statistics{GroupCardinality: 4},
+			n:    10,
+			want: plan.Statistics{Cardinality: 40, GroupCardinality: 4},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plan.LimitStatistics(tt.in, tt.n); !cmp.Equal(tt.want, got) {
+				t.Fatalf("unexpected statistics -want/+got:\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestPhysicalPlanner_ComputeCost(t *testing.T) {
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plantest.CreatePhysicalNode("source", sourceSpec(plan.Statistics{Cardinality: 100, GroupCardinality: 4})),
+			plantest.CreatePhysicalNode("map", spec.MockProcedureSpec{}),
+			plantest.CreatePhysicalNode("sum", aggregateSpec()),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+		},
+	})
+
+	planner := plan.NewPhysicalPlanner(plan.OnlyPhysicalRules(), plan.DisableValidation())
+	ps, err := planner.Plan(context.Background(), ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		id    plan.NodeID
+		stats plan.Statistics
+		cost  plan.Cost
+	}{
+		{
+			id:    "source",
+			stats: plan.Statistics{Cardinality: 100, GroupCardinality: 4},
+			cost:  plan.Cost{Disk: 100},
+		},
+		{
+			id:    "map",
+			stats: plan.Statistics{Cardinality: 100, GroupCardinality: 4},
+			cost:  plan.Cost{Disk: 100, CPU: 100},
+		},
+		{
+			id:    "sum",
+			stats: plan.Statistics{Cardinality: 4, GroupCardinality: 4},
+			cost:  plan.Cost{Disk: 100, CPU: 200},
+		},
+	} {
+		var node *plan.PhysicalPlanNode
+		_ = ps.BottomUpWalk(func(n plan.Node) error {
+			if n.ID() == tt.id {
+				node = n.(*plan.PhysicalPlanNode)
+			}
+			return nil
+		})
+		if node == nil {
+			t.Fatalf("node %q not found", tt.id)
+		}
+		if got := node.Statistics(); !cmp.Equal(tt.stats, got) {
+			t.Errorf("unexpected statistics for %q -want/+got:\n%s", tt.id, cmp.Diff(tt.stats, got))
+		}
+		if got := node.TotalCost(); !cmp.Equal(tt.cost, got) {
+			t.Errorf("unexpected cost for %q -want/+got:\n%s", tt.id, cmp.Diff(tt.cost, got))
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	// The source is shared by both sides of the union
+	// and must only be counted once.
+	source := plan.CreateLogicalNode("source", sourceSpec(plan.Statistics{Cardinality: 100, GroupCardinality: 4}))
+	left := plan.CreateLogicalNode("left", spec.MockProcedureSpec{})
+	right := plan.CreateLogicalNode("right", aggregateSpec())
+	union := plan.CreateLogicalNode("union", spec.MockProcedureSpec{})
+	source.AddSuccessors(left, right)
+	left.AddPredecessors(source)
+	right.AddPredecessors(source)
+	union.AddPredecessors(left, right)
+
+	cost, stats := plan.EstimateCost(union)
+	if want := (plan.Cost{Disk: 100, CPU: 304}); !cmp.Equal(want, cost) {
+		t.Errorf("unexpected cost -want/+got:\n%s", cmp.Diff(want, cost))
+	}
+	if want := (plan.Statistics{Cardinality: 104, GroupCardinality: 8}); !cmp.Equal(want, stats) {
+		t.Errorf("unexpected statistics -want/+got:\n%s", cmp.Diff(want, stats))
+	}
+}
+
+func TestDefaultCost(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		in        []plan.Statistics
+		wantCost  plan.Cost
+		wantStats plan.Statistics
+	}{
+		{
+			name: "NoInputs",
+		},
+		{
+			name: "Known",
+			in: []plan.Statistics{
+				{Cardinality: 10, GroupCardinality: 2},
+				{Cardinality: 5, GroupCardinality: 1},
+			},
+			wantCost:  plan.Cost{CPU: 15},
+			wantStats: plan.Statistics{Cardinality: 15, GroupCardinality: 3},
+		},
+		{
+			name: "UnknownCardinality",
+			in: []plan.Statistics{
+				{Cardinality: 10, GroupCardinality: 2},
+				{GroupCardinality: 1},
+			},
+			wantStats: plan.Statistics{GroupCardinality: 3},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, stats := plan.DefaultCost{}.Cost(tt.in)
+			if !cmp.Equal(tt.wantCost, cost) {
+				t.Errorf("unexpected cost -want/+got:\n%s", cmp.Diff(tt.wantCost, cost))
+			}
+			if !cmp.Equal(tt.wantStats, stats) {
+				t.Errorf("unexpected statistics -want/+got:\n%s", cmp.Diff(tt.wantStats, stats))
+			}
+		})
+	}
+}
+
+func TestFormatted_Cost(t *testing.T) {
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plantest.CreatePhysicalNode("source", sourceSpec(plan.Statistics{Cardinality: 100, GroupCardinality: 4})),
+			plantest.CreatePhysicalNode("sum", aggregateSpec()),
+		},
+		Edges: [][2]int{
+			{0, 1},
+		},
+	})
+	if err := ps.BottomUpWalk(plan.ComputeCost); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph {
+  "source"
+  // EstimatedCardinality: 100, EstimatedGroupCardinality: 4
+  // EstimatedCost: {disk=100}
+  "sum"
+  // EstimatedCardinality: 4, EstimatedGroupCardinality: 4
+  // EstimatedCost: {disk=100, cpu=100}
+
+  "source" -> "sum"
+}
+`
+	got := fmt.Sprintf("%v", plan.Formatted(ps, plan.WithDetails()))
+	if want != got {
+		t.Fatalf("unexpected output: -want/+got:\n%v", diff.LineDiff(want, got))
+	}
+}
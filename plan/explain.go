@@ -0,0 +1,124 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type explanationKey struct{}
+
+// RuleApplication records that a planner rule rewrote a node.
+type RuleApplication struct {
+	// Phase is the planning phase that applied the rule.
+	// It is one of logical, physical or parallel.
+	Phase string
+	// Rule is the name of the rule.
+	Rule string
+	// Node is the node that was matched by the rule.
+	Node NodeID
+	// Result is the node that the rule produced.
+	Result NodeID
+	// Pushdown is set when the rule merged the node into a source.
+	Pushdown bool
+}
+
+func (r RuleApplication) String() string {
+	if r.Node == r.Result {
+		return fmt.Sprintf("%s %s: %s", r.Phase, r.Rule, r.Node)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", r.Phase, r.Rule, r.Node, r.Result)
+}
+
+// Explanation records what the planner did while it planned a query.
+// The planner records into the Explanation that is attached to the
+// context with WithExplanation.
+type Explanation struct {
+	mu sync.Mutex
+
+	// LogicalPlan is the formatted logical plan
+	// as it was before the physical planning.
+	LogicalPlan string
+	// Rules are the rules that were applied in the order they were applied.
+	Rules []RuleApplication
+}
+
+// WithExplanation returns a context that makes the
+// planner record what it did into the Explanation.
+func WithExplanation(ctx context.Context, e *Explanation) context.Context {
+	return context.WithValue(ctx, explanationKey{}, e)
+}
+
+func explanationFromContext(ctx context.Context) *Explanation {
+	e, _ := ctx.Value(explanationKey{}).(*Explanation)
+	return e
+}
+
+// recordRule records that a rule rewrote a node if the
+// context has an Explanation.
+func recordRule(ctx context.Context, phase string, rule Rule, node, newNode Node) {
+	e := explanationFromContext(ctx)
+	if e == nil {
+		return
+	}
+	// The physical converter rule is applied to every
+	// node and does not tell anything about the plan.
+	if _, ok := rule.(physicalConverterRule); ok {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Rules = append(e.Rules, RuleApplication{
+		Phase:    phase,
+		Rule:     rule.Name(),
+		Node:     node.ID(),
+		Result:   newNode.ID(),
+		Pushdown: len(node.Predecessors()) > 0 && len(newNode.Predecessors()) == 0,
+	})
+}
+
+// recordLogicalPlan records the logical plan if the context has an Explanation.
+func recordLogicalPlan(ctx context.Context, p *Spec) {
+	if e := explanationFromContext(ctx); e != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.LogicalPlan = fmt.Sprint(Formatted(p, AsTree(), WithDetails()))
+	}
+}
+
+// Describe returns a description of the logical plan, the physical
+// plan, the rules that were applied and the pushdowns that happened.
+// The options are used to format the physical plan.
+func (e *Explanation) Describe(p *Spec, opts ...FormatOption) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("Logical Plan:\n")
+	b.WriteString(e.LogicalPlan)
+	b.WriteString("\nPhysical Plan:\n")
+	_, _ = fmt.Fprint(&b, Formatted(p, append([]FormatOption{AsTree()}, opts...)...))
+
+	b.WriteString("\nRules:\n")
+	if len(e.Rules) == 0 {
+		b.WriteString("  none\n")
+	}
+	var pushdowns []RuleApplication
+	for _, r := range e.Rules {
+		_, _ = fmt.Fprintf(&b, "  %v\n", r)
+		if r.Pushdown {
+			pushdowns = append(pushdowns, r)
+		}
+	}
+
+	b.WriteString("\nPushdowns:\n")
+	if len(pushdowns) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, r := range pushdowns {
+		_, _ = fmt.Fprintf(&b, "  %s into %s (%s)\n", r.Node, r.Result, r.Rule)
+	}
+	return b.String()
+}
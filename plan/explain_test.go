@@ -0,0 +1,96 @@
+package plan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/plan/plantest"
+	"github.com/InfluxCommunity/flux/stdlib/influxdata/influxdb"
+	"github.com/InfluxCommunity/flux/stdlib/universe"
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExplanation(t *testing.T) {
+	fromSpec := &influxdb.FromProcedureSpec{
+		Bucket: influxdb.NameOrID{Name: "my-bucket"},
+	}
+	filterSpec := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn: executetest.FunctionExpression(t, `(r) => r._value > 5.0`),
+		},
+	}
+	sumSpec := &universe.SumProcedureSpec{
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	}
+
+	// pushFilter merges the filter into the from.
+	pushFilter := &plantest.FunctionRule{
+		RewriteFn: func(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+			if node.Kind() != universe.FilterKind || node.Predecessors()[0].Kind() != influxdb.FromKind {
+				return node, false, nil
+			}
+			n, err := plan.MergeToLogicalNode(node, node.Predecessors()[0], fromSpec.Copy())
+			if err != nil {
+				return nil, false, err
+			}
+			return n, true, nil
+		},
+	}
+
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreateLogicalNode("from", fromSpec),
+			plan.CreateLogicalNode("filter", filterSpec),
+			plan.CreateLogicalNode("sum", sumSpec),
+		},
+		Edges: [][2]int{{0, 1}, {1, 2}},
+	})
+
+	e := new(plan.Explanation)
+	ctx := plan.WithExplanation(context.Background(), e)
+	lp, err := plan.NewLogicalPlanner(plan.OnlyLogicalRules(pushFilter)).Plan(ctx, ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRules := []plan.RuleApplication{{
+		Phase:    "logical",
+		Rule:     "function",
+		Node:     "filter",
+		Result:   "merged_from_filter",
+		Pushdown: true,
+	}}
+	if !cmp.Equal(wantRules, e.Rules) {
+		t.Errorf("unexpected rules -want/+got:\n%s", cmp.Diff(wantRules, e.Rules))
+	}
+
+	want := `Logical Plan:
+sum
+└── merged_from_filter
+
+Physical Plan:
+sum
+└── merged_from_filter
+    // Explained
+
+Rules:
+  logical function: filter -> merged_from_filter
+
+Pushdowns:
+  filter into merged_from_filter (function)
+`
+	got := e.Describe(lp, plan.WithAnnotations(func(node plan.Node) []string {
+		if len(node.Predecessors()) == 0 {
+			return []string{"Explained"}
+		}
+		return nil
+	}))
+	if want != got {
+		t.Errorf("unexpected explanation -want/+got:\n%s", diff.LineDiff(want, got))
+	}
+}
@@ -3,6 +3,7 @@ package plan
 import (
 	"fmt"
 	"runtime/debug"
+	"sort"
 	"strings"
 )
 
@@ -30,6 +31,22 @@ func WithDetails() FormatOption {
 	}
 }
 
+// AsTree returns a FormatOption that formats the plan as an indented tree
+// that starts at the roots of the plan instead of as a DOT graph.
+func AsTree() FormatOption {
+	return func(f *formatter) {
+		f.asTree = true
+	}
+}
+
+// WithAnnotations returns a FormatOption that adds the lines returned
+// by fn to the details of every node in a formatted plan.
+func WithAnnotations(fn func(node Node) []string) FormatOption {
+	return func(f *formatter) {
+		f.annotate = fn
+	}
+}
+
 // Detailer provides an optional interface that ProcedureSpecs can implement.
 // Implementors of this interface will have their details appear in the
 // formatted output for a plan if the WithDetails() option is set.
@@ -39,6 +56,8 @@ type Detailer interface {
 
 type formatter struct {
 	withDetails bool
+	asTree      bool
+	annotate    func(node Node) []string
 	p           *Spec
 }
 
@@ -48,6 +67,19 @@ func formatAsDOT(id NodeID) string {
 	return fmt.Sprintf("%q", id)
 }
 
+// formatCost returns the details of the estimated statistics
+// and cost of a node if they are known.
+func formatCost(ppn *PhysicalPlanNode) string {
+	var details string
+	if stats := ppn.Statistics(); stats.Cardinality > 0 || stats.GroupCardinality > 0 {
+		details += fmt.Sprintf("EstimatedCardinality: %d, EstimatedGroupCardinality: %d\n", stats.Cardinality, stats.GroupCardinality)
+	}
+	if cost := ppn.TotalCost(); cost != (Cost{}) {
+		details += fmt.Sprintf("EstimatedCost: %v\n", cost)
+	}
+	return details
+}
+
 func (f formatter) Format(fs fmt.State, c rune) {
 	// Panicking while producing debug output is frustrating, so catch any panics and
 	// continue if that happens.
@@ -58,31 +90,17 @@ func (f formatter) Format(fs fmt.State, c rune) {
 		}
 	}()
 
+	if f.asTree {
+		f.formatTree(fs)
+		return
+	}
+
 	_, _ = fmt.Fprintf(fs, "digraph {\n")
 	var edges []string
 	_ = f.p.BottomUpWalk(func(pn Node) error {
 		_, _ = fmt.Fprintf(fs, "  %v\n", formatAsDOT(pn.ID()))
-		if f.withDetails {
-			details := ""
-			if d, ok := pn.ProcedureSpec().(Detailer); ok {
-				details += d.PlanDetails() + "\n"
-			}
-
-			if ppn, ok := pn.(*PhysicalPlanNode); ok {
-				for _, attr := range ppn.outputAttrs() {
-					if d, ok := attr.(Detailer); ok {
-						details += d.PlanDetails() + "\n"
-					}
-				}
-			}
-
-			lines := strings.Split(strings.TrimSpace(details), "\n")
-
-			for _, line := range lines {
-				if len(line) > 0 {
-					_, _ = fmt.Fprintf(fs, "  // %s\n", line)
-				}
-			}
+		for _, line := range f.details(pn) {
+			_, _ = fmt.Fprintf(fs, "  // %s\n", line)
 		}
 		for _, pred := range pn.Predecessors() {
 			edges = append(edges, fmt.Sprintf("  %v -> %v", formatAsDOT(pred.ID()), formatAsDOT(pn.ID())))
@@ -96,3 +114,77 @@ func (f formatter) Format(fs fmt.State, c rune) {
 	}
 	_, _ = fmt.Fprintf(fs, "}\n")
 }
+
+// details returns the lines that describe a node.
+func (f formatter) details(pn Node) []string {
+	details := ""
+	if f.withDetails {
+		if d, ok := pn.ProcedureSpec().(Detailer); ok {
+			details += d.PlanDetails() + "\n"
+		}
+
+		if ppn, ok := pn.(*PhysicalPlanNode); ok {
+			for _, attr := range ppn.outputAttrs() {
+				if d, ok := attr.(Detailer); ok {
+					details += d.PlanDetails() + "\n"
+				}
+			}
+			details += formatCost(ppn)
+		}
+	}
+	if f.annotate != nil {
+		details += strings.Join(f.annotate(pn), "\n")
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(details), "\n") {
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// formatTree writes the plan as a tree with the roots at the top
+// and the predecessors of every node below it. A node that is shared
+// by more than one successor is only written out in full once.
+func (f formatter) formatTree(fs fmt.State) {
+	roots := make([]Node, 0, len(f.p.Roots))
+	for root := range f.p.Roots {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].ID() < roots[j].ID()
+	})
+
+	seen := make(map[Node]bool)
+	for _, root := range roots {
+		f.formatTreeNode(fs, root, "", "", seen)
+	}
+}
+
+func (f formatter) formatTreeNode(fs fmt.State, pn Node, prefix, childPrefix string, seen map[Node]bool) {
+	if seen[pn] {
+		_, _ = fmt.Fprintf(fs, "%s%s (see above)\n", prefix, pn.ID())
+		return
+	}
+	seen[pn] = true
+	_, _ = fmt.Fprintf(fs, "%s%s\n", prefix, pn.ID())
+
+	preds := pn.Predecessors()
+	detailPrefix := childPrefix + "    "
+	if len(preds) > 0 {
+		detailPrefix = childPrefix + "│   "
+	}
+	for _, line := range f.details(pn) {
+		_, _ = fmt.Fprintf(fs, "%s// %s\n", detailPrefix, line)
+	}
+
+	for i, pred := range preds {
+		if i == len(preds)-1 {
+			f.formatTreeNode(fs, pred, childPrefix+"└── ", childPrefix+"    ", seen)
+		} else {
+			f.formatTreeNode(fs, pred, childPrefix+"├── ", childPrefix+"│   ", seen)
+		}
+	}
+}
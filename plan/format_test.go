@@ -105,3 +105,34 @@ func TestFormatted(t *testing.T) {
 		})
 	}
 }
+
+func TestFormattedTree(t *testing.T) {
+	fromSpec := &influxdb.FromProcedureSpec{
+		Bucket: influxdb.NameOrID{Name: "my-bucket"},
+	}
+	filterSpec := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn: executetest.FunctionExpression(t, `(r) => r._value > 5.0`),
+		},
+	}
+
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreateLogicalNode("from", fromSpec),
+			plan.CreateLogicalNode("filter", filterSpec),
+			plan.CreateLogicalNode("union", &universe.UnionProcedureSpec{}),
+		},
+		Edges: [][2]int{{0, 1}, {1, 2}, {0, 2}},
+	})
+
+	want := `union
+├── filter
+│   │   // r._value > 5.000000
+│   └── from
+└── from (see above)
+`
+	got := fmt.Sprintf("%v", plan.Formatted(ps, plan.AsTree(), plan.WithDetails()))
+	if want != got {
+		t.Fatalf("unexpected output: -want/+got:\n%v", diff.LineDiff(want, got))
+	}
+}
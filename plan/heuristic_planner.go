@@ -13,12 +13,15 @@ import (
 // heuristicPlanner applies a set of rules to the nodes in a Spec
 // until a fixed point is reached and no more rules can be applied.
 type heuristicPlanner struct {
+	// phase names the planning phase in an Explanation.
+	phase         string
 	rules         map[ProcedureKind][]Rule
 	disabledRules map[string]bool
 }
 
-func newHeuristicPlanner() *heuristicPlanner {
+func newHeuristicPlanner(phase string) *heuristicPlanner {
 	return &heuristicPlanner{
+		phase:         phase,
 		rules:         make(map[ProcedureKind][]Rule),
 		disabledRules: make(map[string]bool),
 	}
@@ -43,7 +46,7 @@ func (p *heuristicPlanner) clearRules() {
 	p.rules = make(map[ProcedureKind][]Rule)
 }
 
-func applyRule(ctx context.Context, spec *Spec, rule Rule, node Node) (Node, bool, error) {
+func (p *heuristicPlanner) applyRule(ctx context.Context, spec *Spec, rule Rule, node Node) (Node, bool, error) {
 	newNode, changed, err := rule.Rewrite(ctx, node)
 	if err != nil {
 		return nil, false, err
@@ -56,6 +59,7 @@ func applyRule(ctx context.Context, spec *Spec, rule Rule, node Node) (Node, boo
 			)
 		}
 		testing.MarkInvokedPlannerRule(ctx, rule.Name())
+		recordRule(ctx, p.phase, rule, node, newNode)
 		if err := updateSuccessors(spec, node, newNode); err != nil {
 			return node, false, errors.Wrap(
 				err,
@@ -79,7 +83,7 @@ func (p *heuristicPlanner) matchRules(ctx context.Context, spec *Spec, node Node
 			continue
 		}
 		if rule.Pattern().Match(node) {
-			newNode, changed, err := applyRule(ctx, spec, rule, node)
+			newNode, changed, err := p.applyRule(ctx, spec, rule, node)
 			if err != nil {
 				return nil, false, err
 			}
@@ -94,7 +98,7 @@ func (p *heuristicPlanner) matchRules(ctx context.Context, spec *Spec, node Node
 			continue
 		}
 		if rule.Pattern().Match(node) {
-			newNode, changed, err := applyRule(ctx, spec, rule, node)
+			newNode, changed, err := p.applyRule(ctx, spec, rule, node)
 			if err != nil {
 				return nil, false, err
 			}
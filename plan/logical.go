@@ -27,7 +27,7 @@ type LogicalPlanner interface {
 // been registered.
 func NewLogicalPlanner(options ...LogicalOption) LogicalPlanner {
 	thePlanner := &logicalPlanner{
-		heuristicPlanner: newHeuristicPlanner(),
+		heuristicPlanner: newHeuristicPlanner("logical"),
 	}
 
 	rules := make([]Rule, len(ruleNameToLogicalRule))
@@ -112,6 +112,7 @@ func (l *logicalPlanner) Plan(ctx context.Context, logicalPlan *Spec) (*Spec, er
 		}
 	}
 
+	recordLogicalPlan(ctx, newLogicalPlan)
 	return newLogicalPlan, nil
 }
 
@@ -21,8 +21,8 @@ type PhysicalPlanner interface {
 // The new plan will be configured to apply any physical rules that have been registered.
 func NewPhysicalPlanner(options ...PhysicalOption) PhysicalPlanner {
 	pp := &physicalPlanner{
-		heuristicPlannerPhysical: newHeuristicPlanner(),
-		heuristicPlannerParallel: newHeuristicPlanner(),
+		heuristicPlannerPhysical: newHeuristicPlanner("physical"),
+		heuristicPlannerParallel: newHeuristicPlanner("parallel"),
 		defaultMemoryLimit:       math.MaxInt64,
 	}
 
@@ -71,6 +71,11 @@ func (pp *physicalPlanner) Plan(ctx context.Context, spec *Spec) (*Spec, error)
 		return nil, err
 	}
 
+	// Estimate the statistics and cost of the nodes in the plan
+	if err := transformedSpec.BottomUpWalk(ComputeCost); err != nil {
+		return nil, err
+	}
+
 	// Set all default and/or registered trigger specs
 	if err := transformedSpec.TopDownWalk(SetTriggerSpec); err != nil {
 		return nil, err
@@ -239,6 +244,11 @@ type PhysicalPlanNode struct {
 	// The trigger spec defines how and when a transformation
 	// sends its tables to downstream operators
 	TriggerSpec TriggerSpec
+
+	// The estimated statistics of the output and the cumulative
+	// cost of this node and its predecessors. See ComputeCost.
+	stats     Statistics
+	totalCost Cost
 }
 
 // ID returns a human-readable id for this plan node.
@@ -288,6 +298,18 @@ func (ppn *PhysicalPlanNode) Cost(inStats []Statistics) (cost Cost, outStats Sta
 	return ppn.Spec.Cost(inStats)
 }
 
+// Statistics returns the estimated statistics of the data produced
+// by this plan node. It is computed by the physical planner.
+func (ppn *PhysicalPlanNode) Statistics() Statistics {
+	return ppn.stats
+}
+
+// TotalCost returns the estimated cost of this plan node and all of
+// its predecessors. It is computed by the physical planner.
+func (ppn *PhysicalPlanNode) TotalCost() Cost {
+	return ppn.totalCost
+}
+
 var noAttributes = PhysicalAttributes{}
 var noRequiredAttributesSlice = []PhysicalAttributes{
 	noAttributes,
@@ -25,6 +25,14 @@ type MockProcedureSpec struct {
 	PassThroughAttributeFn func(attrKey string) bool
 	RequiredAttributesFn   func() []plan.PhysicalAttributes
 	PlanDetailsFn          func() string
+	CostFn                 func(inStats []plan.Statistics) (plan.Cost, plan.Statistics)
+}
+
+func (s MockProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	if s.CostFn != nil {
+		return s.CostFn(inStats)
+	}
+	return s.DefaultCost.Cost(inStats)
 }
 
 func (s MockProcedureSpec) PlanDetails() string {
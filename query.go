@@ -132,6 +132,17 @@ type TransportProfile struct {
 
 	// Mean is the mean span time of this profile.
 	Mean float64 `json:"mean"`
+
+	// Source holds the label of the plan node that sent data to
+	// this transport. It is empty for sources.
+	Source string `json:"source,omitempty"`
+
+	// RowsIn holds the number of rows received by this transport.
+	RowsIn int64 `json:"rows_in,omitempty"`
+
+	// Allocated holds the number of bytes allocated by the operation.
+	// It is only recorded when the operator profiler is enabled.
+	Allocated int64 `json:"allocated,omitempty"`
 }
 
 // StartSpan will start a profile span to be recorded.
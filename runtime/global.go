@@ -30,6 +30,12 @@ func StdLib() interpreter.Importer {
 	return Default.Stdlib()
 }
 
+// StdLibPackages returns the import paths of the packages
+// of the Flux standard library sorted by path.
+func StdLibPackages() []string {
+	return Default.StdlibPackages()
+}
+
 // Prelude returns a scope object representing the Flux universe block
 func Prelude() values.Scope {
 	return Default.Prelude()
@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"context"
+	"sort"
 
 	"github.com/InfluxCommunity/flux"
 	"github.com/InfluxCommunity/flux/codes"
@@ -161,6 +162,20 @@ func (r *runtime) Stdlib() interpreter.Importer {
 	return &importer{r: r}
 }
 
+// StdlibPackages returns the import paths of the packages
+// of the standard library sorted by path.
+func (r *runtime) StdlibPackages() []string {
+	if !r.finalized {
+		panic("builtins not finalized")
+	}
+	paths := make([]string, 0, len(r.pkgs))
+	for path := range r.pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 func (r *runtime) compilePackages() error {
 	pkgs, err := libflux.SemanticPackages()
 	if err != nil {
@@ -73,6 +73,12 @@ func (s *FromProcedureSpec) Kind() plan.ProcedureKind {
 	return FromKind
 }
 
+// Cost reports the number of rows in the array as a single table.
+func (s *FromProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	n := int64(s.Rows.Len())
+	return plan.Cost{CPU: n, MEM: n}, plan.Statistics{Cardinality: n, GroupCardinality: 1}
+}
+
 func (s *FromProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := new(FromProcedureSpec)
 	*ns = *s
@@ -0,0 +1,335 @@
+package clickhouse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// responseFormat is the ClickHouse output format requested by the source.
+// It carries the column names and ClickHouse types in the first two
+// rows so the Flux schema can be determined before any data is read.
+const responseFormat = "TSVWithNamesAndTypes"
+
+// tsvNull is how ClickHouse encodes a NULL value in the TSV formats.
+const tsvNull = `\N`
+
+// column describes a single column in a ClickHouse response.
+type column struct {
+	Name     string
+	Type     flux.ColType
+	Nullable bool
+	// DBType is the ClickHouse type name as reported by the server.
+	DBType string
+}
+
+// ColMeta returns the Flux column metadata for this column.
+func (c column) ColMeta() flux.ColMeta {
+	return flux.ColMeta{Label: c.Name, Type: c.Type}
+}
+
+// parseColumnType maps a ClickHouse type name onto a Flux column type.
+// Wrapping types such as Nullable and LowCardinality are unwrapped.
+// Types that cannot be represented natively are read as strings.
+func parseColumnType(dbType string) (typ flux.ColType, nullable bool) {
+	t := strings.TrimSpace(dbType)
+	for {
+		if inner, ok := unwrapType(t, "LowCardinality"); ok {
+			t = inner
+			continue
+		}
+		if inner, ok := unwrapType(t, "Nullable"); ok {
+			t, nullable = inner, true
+			continue
+		}
+		break
+	}
+
+	name := t
+	if i := strings.IndexByte(t, '('); i >= 0 {
+		name = t[:i]
+	}
+	switch name {
+	case "Int8", "Int16", "Int32", "Int64":
+		return flux.TInt, nullable
+	case "UInt8", "UInt16", "UInt32", "UInt64":
+		return flux.TUInt, nullable
+	case "Float32", "Float64",
+		"Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		return flux.TFloat, nullable
+	case "Bool", "Boolean":
+		return flux.TBool, nullable
+	case "Date", "Date32", "DateTime", "DateTime32", "DateTime64":
+		return flux.TTime, nullable
+	default:
+		// String, FixedString, UUID, Enum, IPv4, IPv6, (U)Int128/256
+		// and composite types are all returned in their text form.
+		return flux.TString, nullable
+	}
+}
+
+// unwrapType returns the inner type of a parameterized type
+// such as Nullable(T) if the type has the given wrapper name.
+func unwrapType(t, wrapper string) (string, bool) {
+	if !strings.HasPrefix(t, wrapper+"(") || !strings.HasSuffix(t, ")") {
+		return "", false
+	}
+	return t[len(wrapper)+1 : len(t)-1], true
+}
+
+// tsvReader reads rows from a response in the TSVWithNamesAndTypes format.
+// Rows are read one line at a time so the body is never held in memory.
+type tsvReader struct {
+	r       *bufio.Reader
+	columns []column
+	fields  []string
+	err     error
+}
+
+// newTSVReader creates a tsvReader and consumes the
+// header rows containing the column names and types.
+func newTSVReader(r io.Reader) (*tsvReader, error) {
+	tr := &tsvReader{r: bufio.NewReader(r)}
+	names, err := tr.readLine()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New(codes.Internal, "clickhouse response is missing the column names header")
+		}
+		return nil, err
+	}
+	types, err := tr.readLine()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New(codes.Internal, "clickhouse response is missing the column types header")
+		}
+		return nil, err
+	}
+	if len(names) != len(types) {
+		return nil, errors.Newf(codes.Internal, "clickhouse response has %d column names but %d column types", len(names), len(types))
+	}
+
+	tr.columns = make([]column, len(names))
+	for i := range names {
+		typ, nullable := parseColumnType(types[i])
+		tr.columns[i] = column{
+			Name:     names[i],
+			Type:     typ,
+			Nullable: nullable,
+			DBType:   types[i],
+		}
+	}
+	return tr, nil
+}
+
+// Columns returns the columns of the response.
+func (tr *tsvReader) Columns() []column {
+	return tr.columns
+}
+
+// Next reads the next row. It returns false when there are no
+// more rows or an error was encountered.
+func (tr *tsvReader) Next() bool {
+	if tr.err != nil {
+		return false
+	}
+	fields, err := tr.readLine()
+	if err != nil {
+		if err != io.EOF {
+			tr.err = err
+		}
+		return false
+	}
+	if len(fields) != len(tr.columns) {
+		tr.err = errors.Newf(codes.Internal, "clickhouse row has %d fields, expected %d", len(fields), len(tr.columns))
+		return false
+	}
+	tr.fields = fields
+	return true
+}
+
+// Fields returns the raw fields of the current row.
+// The fields have already been unescaped; a NULL
+// value is reported with the tsvNull marker.
+func (tr *tsvReader) Fields() []string {
+	return tr.fields
+}
+
+// Err returns the first error encountered while reading rows.
+func (tr *tsvReader) Err() error {
+	return tr.err
+}
+
+func (tr *tsvReader) readLine() ([]string, error) {
+	line, err := tr.r.ReadString('\n')
+	if err != nil {
+		if err != io.EOF || line == "" {
+			return nil, err
+		}
+	}
+	line = strings.TrimSuffix(line, "\n")
+	fields := strings.Split(line, "\t")
+	for i, f := range fields {
+		if f != tsvNull {
+			fields[i] = unescapeTSV(f)
+		}
+	}
+	return fields, nil
+}
+
+// unescapeTSV reverses the escaping that ClickHouse applies to
+// values in the TSV formats.
+func unescapeTSV(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case '0':
+			sb.WriteByte(0)
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// parseValue converts a raw field into a Flux value of the column type.
+func parseValue(col column, field string) (values.Value, error) {
+	if field == tsvNull {
+		return values.NewNull(flux.SemanticType(col.Type)), nil
+	}
+	switch col.Type {
+	case flux.TInt:
+		v, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, columnError(col, field, err)
+		}
+		return values.NewInt(v), nil
+	case flux.TUInt:
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, columnError(col, field, err)
+		}
+		return values.NewUInt(v), nil
+	case flux.TFloat:
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, columnError(col, field, err)
+		}
+		return values.NewFloat(v), nil
+	case flux.TBool:
+		v, err := strconv.ParseBool(field)
+		if err != nil {
+			return nil, columnError(col, field, err)
+		}
+		return values.NewBool(v), nil
+	case flux.TTime:
+		v, err := parseTime(field)
+		if err != nil {
+			return nil, columnError(col, field, err)
+		}
+		return values.NewTime(values.ConvertTime(v)), nil
+	default:
+		return values.NewString(field), nil
+	}
+}
+
+// appendValue parses the raw field and appends it to the builder.
+// The builder must have been created for the column type.
+func appendValue(b array.Builder, col column, field string) error {
+	if field == tsvNull {
+		b.AppendNull()
+		return nil
+	}
+	switch b := b.(type) {
+	case *array.IntBuilder:
+		var (
+			v   int64
+			err error
+		)
+		if col.Type == flux.TTime {
+			var t time.Time
+			t, err = parseTime(field)
+			v = t.UnixNano()
+		} else {
+			v, err = strconv.ParseInt(field, 10, 64)
+		}
+		if err != nil {
+			return columnError(col, field, err)
+		}
+		b.Append(v)
+	case *array.UintBuilder:
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return columnError(col, field, err)
+		}
+		b.Append(v)
+	case *array.FloatBuilder:
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return columnError(col, field, err)
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, err := strconv.ParseBool(field)
+		if err != nil {
+			return columnError(col, field, err)
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		b.Append(field)
+	default:
+		return errors.Newf(codes.Internal, "unsupported builder %T for column %q", b, col.Name)
+	}
+	return nil
+}
+
+// timeLayouts are the layouts used to parse Date and DateTime values.
+// The source asks ClickHouse for ISO formatted date times, but dates
+// and servers that ignore the setting are also accepted.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02",
+	"2006-01-02 15:04:05.999999999",
+}
+
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func columnError(col column, field string, err error) error {
+	return errors.Wrapf(err, codes.Invalid, "cannot parse %q as %s for column %q of type %s", field, col.Type, col.Name, col.DBType)
+}
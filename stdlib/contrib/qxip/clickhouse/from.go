@@ -0,0 +1,625 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/arrow"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const (
+	pkgName  = "contrib/qxip/clickhouse"
+	FromKind = pkgName + ".from"
+)
+
+// maxErrorBody is the maximum number of bytes read from
+// an error response to construct the error message.
+const maxErrorBody = 4096
+
+type FromOpSpec struct {
+	URL          string   `json:"url"`
+	Query        string   `json:"query,omitempty"`
+	Table        string   `json:"table,omitempty"`
+	Database     string   `json:"database,omitempty"`
+	GroupColumns []string `json:"groupColumns,omitempty"`
+	Columns      []string `json:"columns,omitempty"`
+	MaxBytes     int64    `json:"maxBytes,omitempty"`
+}
+
+func init() {
+	fromSignature := runtime.MustLookupBuiltinType(pkgName, "_from")
+	runtime.RegisterPackageValue(pkgName, "_from", flux.MustValue(flux.FunctionValue(FromKind, createFromOpSpec, fromSignature)))
+	plan.RegisterProcedureSpec(FromKind, newFromProcedure, FromKind)
+	execute.RegisterSource(FromKind, createFromSource)
+	plan.RegisterPhysicalRules(
+		MergeFromRangeRule{},
+		MergeFromFilterRule{},
+		MergeFromLimitRule{},
+	)
+}
+
+func createFromOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(FromOpSpec)
+
+	if u, err := args.GetRequiredString("url"); err != nil {
+		return nil, err
+	} else {
+		spec.URL = u
+	}
+
+	if query, ok, err := args.GetString("query"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Query = query
+	}
+
+	if table, ok, err := args.GetString("table"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Table = table
+	}
+
+	if spec.Query == "" && spec.Table == "" {
+		return nil, errors.New(codes.Invalid, "must specify one of query or table")
+	} else if spec.Query != "" && spec.Table != "" {
+		return nil, errors.New(codes.Invalid, "must specify only one of query or table")
+	}
+
+	if database, ok, err := args.GetString("database"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Database = database
+	}
+
+	if cols, ok, err := args.GetArrayAllowEmpty("groupColumns", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		columns, err := interpreter.ToStringArray(cols)
+		if err != nil {
+			return nil, err
+		}
+		spec.GroupColumns = columns
+	}
+
+	if cols, ok, err := args.GetArrayAllowEmpty("columns", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		columns, err := interpreter.ToStringArray(cols)
+		if err != nil {
+			return nil, err
+		}
+		spec.Columns = columns
+	}
+
+	if maxBytes, ok, err := args.GetInt("maxBytes"); err != nil {
+		return nil, err
+	} else if ok {
+		if maxBytes < 0 {
+			return nil, errors.New(codes.Invalid, "maxBytes must not be negative")
+		}
+		spec.MaxBytes = maxBytes
+	}
+	return spec, nil
+}
+
+func (s *FromOpSpec) Kind() flux.OperationKind {
+	return FromKind
+}
+
+type FromProcedureSpec struct {
+	plan.DefaultCost
+	URL          string
+	Query        string
+	Table        string
+	Database     string
+	GroupColumns []string
+	Columns      []string
+	MaxBytes     int64
+
+	// The following fields are set by the planner rules
+	// when a range, filter or limit is pushed into the query.
+	Bounds      flux.Bounds
+	TimeColumn  string
+	StartColumn string
+	StopColumn  string
+	Predicates  []string
+	Limit       int64
+	Offset      int64
+}
+
+// Cost reports a pushed down limit as the number of rows. The number
+// of rows of a query without a limit is not known until it runs.
+func (s *FromProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	if s.Limit <= 0 {
+		return plan.Cost{}, plan.Statistics{}
+	}
+	return plan.Cost{NET: s.Limit}, plan.Statistics{Cardinality: s.Limit}
+}
+
+func newFromProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &FromProcedureSpec{
+		URL:          spec.URL,
+		Query:        spec.Query,
+		Table:        spec.Table,
+		Database:     spec.Database,
+		GroupColumns: spec.GroupColumns,
+		Columns:      spec.Columns,
+		MaxBytes:     spec.MaxBytes,
+	}, nil
+}
+
+func (s *FromProcedureSpec) Kind() plan.ProcedureKind {
+	return FromKind
+}
+
+func (s *FromProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(FromProcedureSpec)
+	*ns = *s
+	if s.GroupColumns != nil {
+		ns.GroupColumns = make([]string, len(s.GroupColumns))
+		copy(ns.GroupColumns, s.GroupColumns)
+	}
+	if s.Columns != nil {
+		ns.Columns = make([]string, len(s.Columns))
+		copy(ns.Columns, s.Columns)
+	}
+	if s.Predicates != nil {
+		ns.Predicates = make([]string, len(s.Predicates))
+		copy(ns.Predicates, s.Predicates)
+	}
+	return ns
+}
+
+// knownColumns returns the columns that are known to exist in the
+// ClickHouse result. These are the declared columns and the group
+// columns, which the source requires to be present. The start and
+// stop columns of a pushed down range are added by the source.
+func (s *FromProcedureSpec) knownColumns() []string {
+	columns := make([]string, 0, len(s.Columns)+len(s.GroupColumns))
+	for _, cols := range [][]string{s.Columns, s.GroupColumns} {
+		for _, label := range cols {
+			if !s.Bounds.IsEmpty() && (label == s.StartColumn || label == s.StopColumn) {
+				continue
+			}
+			columns = append(columns, label)
+		}
+	}
+	return columns
+}
+
+// TimeBounds implements plan.BoundsAwareProcedureSpec.
+func (s *FromProcedureSpec) TimeBounds(predecessorBounds *plan.Bounds) *plan.Bounds {
+	if s.Bounds.IsEmpty() {
+		return nil
+	}
+	bounds := plan.FromFluxBounds(s.Bounds)
+	return &bounds
+}
+
+// TimeRangeColumns implements resultcache.TimeRangeProcedureSpec.
+// A limit applies to the rows of the whole range so the results
+// cannot be sliced to a narrower range.
+func (s *FromProcedureSpec) TimeRangeColumns() (timeCol, startCol, stopCol string, ok bool) {
+	return s.TimeColumn, s.StartColumn, s.StopColumn, !s.Bounds.IsEmpty() && s.Limit == 0
+}
+
+// BuildQuery returns the query that is sent to ClickHouse.
+// Ranges, filters and limits that were pushed down by the planner
+// are added to the query of the user or the selected table.
+func (s *FromProcedureSpec) BuildQuery() string {
+	var where []string
+	if !s.Bounds.IsEmpty() {
+		col := quoteIdentifier(s.TimeColumn)
+		bounds := plan.FromFluxBounds(s.Bounds)
+		where = append(where,
+			"("+col+" >= "+timeLiteral(bounds.Start)+")",
+			"("+col+" < "+timeLiteral(bounds.Stop)+")",
+		)
+	}
+	where = append(where, s.Predicates...)
+
+	// Rows are ordered by the group columns so the rows of each
+	// table are contiguous and every table can be output as soon
+	// as the next one starts. The outer ORDER BY does not keep the
+	// order of the query, which is documented for groupColumns.
+	var orderBy []string
+	for _, label := range s.GroupColumns {
+		orderBy = append(orderBy, quoteIdentifier(label))
+	}
+	if len(orderBy) > 0 && !s.Bounds.IsEmpty() {
+		orderBy = append(orderBy, quoteIdentifier(s.TimeColumn))
+	}
+
+	if s.Table == "" && len(where) == 0 && len(orderBy) == 0 && s.Limit == 0 {
+		return s.Query
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM ")
+	if s.Table != "" {
+		parts := strings.Split(s.Table, ".")
+		for i, part := range parts {
+			parts[i] = quoteIdentifier(part)
+		}
+		sb.WriteString(strings.Join(parts, "."))
+	} else {
+		sb.WriteString("(")
+		sb.WriteString(s.Query)
+		sb.WriteString(")")
+	}
+	if len(where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(where, " AND "))
+	}
+	if len(orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(orderBy, ", "))
+	}
+	if s.Limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.FormatInt(s.Limit, 10))
+		if s.Offset > 0 {
+			sb.WriteString(" OFFSET ")
+			sb.WriteString(strconv.FormatInt(s.Offset, 10))
+		}
+	}
+	return sb.String()
+}
+
+func createFromSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*FromProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+
+	u, err := url.Parse(spec.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Invalid, "invalid clickhouse url")
+	}
+	deps := flux.GetDependencies(a.Context())
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	if err := validator.Validate(u); err != nil {
+		return nil, err
+	}
+
+	iterator := &sourceIterator{
+		spec: spec,
+		url:  u,
+		mem:  a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*sourceIterator)(nil)
+
+type sourceIterator struct {
+	spec *FromProcedureSpec
+	url  *url.URL
+	mem  memory.Allocator
+}
+
+func (s *sourceIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	req, err := s.newRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := flux.GetDependencies(ctx).HTTPClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	reader, err := newTSVReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	var bounds *execute.Bounds
+	if !s.spec.Bounds.IsEmpty() {
+		b := plan.FromFluxBounds(s.spec.Bounds)
+		bounds = &execute.Bounds{
+			Start: b.Start,
+			Stop:  b.Stop,
+		}
+	}
+	return readTables(reader, readOptions{
+		GroupColumns: s.spec.GroupColumns,
+		Bounds:       bounds,
+		StartColumn:  s.spec.StartColumn,
+		StopColumn:   s.spec.StopColumn,
+	}, s.mem, f)
+}
+
+// readOptions controls how the rows of a response are split into tables.
+type readOptions struct {
+	// GroupColumns are the columns used as the group key.
+	GroupColumns []string
+
+	// Bounds is set when a range was pushed into the query.
+	// The start and stop columns are then added to each
+	// table and to its group key in the same way range does.
+	Bounds      *execute.Bounds
+	StartColumn string
+	StopColumn  string
+}
+
+// newRequest constructs the HTTP request that sends the query
+// to ClickHouse and asks for a typed response format.
+func (s *sourceIterator) newRequest(ctx context.Context) (*http.Request, error) {
+	u := *s.url
+	params := u.Query()
+	params.Set("default_format", responseFormat)
+	params.Set("date_time_output_format", "iso")
+	if s.spec.Database != "" {
+		params.Set("database", s.spec.Database)
+	}
+	if s.spec.MaxBytes > 0 {
+		params.Set("max_result_bytes", strconv.FormatInt(s.spec.MaxBytes, 10))
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(s.spec.BuildQuery()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("X-ClickHouse-Format", responseFormat)
+	return req, nil
+}
+
+// responseError converts an unsuccessful response into an error.
+// ClickHouse returns the exception text as the response body.
+func responseError(resp *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		return errors.Newf(codes.Invalid, "error when reading clickhouse response body: %s", err)
+	}
+	code := codes.Invalid
+	if resp.StatusCode >= 500 {
+		code = codes.Unavailable
+	}
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = resp.Status
+	}
+	return errors.Newf(code, "clickhouse query failed with status %d: %s", resp.StatusCode, msg)
+}
+
+// readTables reads the rows from the reader and splits them into
+// tables using the values of the group columns. The rows of each
+// table must be contiguous, so each table is passed to f as soon
+// as the first row of the next table is read.
+func readTables(reader *tsvReader, opts readOptions, mem memory.Allocator, f func(flux.Table) error) (err error) {
+	columns := reader.Columns()
+	cols := make([]flux.ColMeta, 0, len(columns)+2)
+	keyCols := make([]flux.ColMeta, 0, len(opts.GroupColumns)+2)
+	var constants []values.Value
+	if opts.Bounds != nil {
+		for _, label := range []string{opts.StartColumn, opts.StopColumn} {
+			col := flux.ColMeta{Label: label, Type: flux.TTime}
+			cols = append(cols, col)
+			keyCols = append(keyCols, col)
+		}
+		constants = []values.Value{
+			values.NewTime(opts.Bounds.Start),
+			values.NewTime(opts.Bounds.Stop),
+		}
+	}
+	for _, c := range columns {
+		cols = append(cols, c.ColMeta())
+	}
+
+	keyIdx := make([]int, len(opts.GroupColumns))
+	for i, label := range opts.GroupColumns {
+		idx := -1
+		for j, c := range columns {
+			if c.Name == label {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return errors.Newf(codes.Invalid, "group column %q is not present in the clickhouse response", label)
+		}
+		keyIdx[i] = idx
+		keyCols = append(keyCols, columns[idx].ColMeta())
+	}
+
+	var (
+		builder *tableBuilder
+		lastKey []string
+		seen    = make(map[string]struct{})
+	)
+	defer func() {
+		if builder != nil {
+			builder.Release()
+		}
+	}()
+	emit := func() error {
+		b := builder
+		builder = nil
+		tbl, err := b.Table()
+		if err != nil {
+			b.Release()
+			return err
+		}
+		return f(tbl)
+	}
+
+	for reader.Next() {
+		fields := reader.Fields()
+		if builder == nil || !sameKey(lastKey, fields, keyIdx) {
+			if builder != nil {
+				if err := emit(); err != nil {
+					return err
+				}
+			}
+
+			lastKey = lastKey[:0]
+			for _, idx := range keyIdx {
+				lastKey = append(lastKey, fields[idx])
+			}
+			id := strings.Join(lastKey, "\x00")
+			if _, ok := seen[id]; ok {
+				return errors.Newf(codes.FailedPrecondition, "rows of group %v are not contiguous in the clickhouse response, order the query by the group columns", lastKey)
+			}
+			seen[id] = struct{}{}
+
+			keyValues := make([]values.Value, 0, len(keyCols))
+			keyValues = append(keyValues, constants...)
+			for _, idx := range keyIdx {
+				v, err := parseValue(columns[idx], fields[idx])
+				if err != nil {
+					return err
+				}
+				keyValues = append(keyValues, v)
+			}
+			builder = newTableBuilder(execute.NewGroupKey(keyCols, keyValues), cols, constants, mem)
+		}
+		if err := builder.AppendRow(columns, fields); err != nil {
+			return err
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	// A response without rows still produces an empty table
+	// when the schema does not depend on the group columns.
+	if builder == nil && len(seen) == 0 && len(keyIdx) == 0 {
+		builder = newTableBuilder(execute.NewGroupKey(keyCols, constants), cols, constants, mem)
+	}
+	if builder != nil {
+		return emit()
+	}
+	return nil
+}
+
+// sameKey reports whether the raw group key fields of the
+// row match the group key of the previous row.
+func sameKey(last, fields []string, keyIdx []int) bool {
+	if len(last) != len(keyIdx) {
+		return false
+	}
+	for i, idx := range keyIdx {
+		if last[i] != fields[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// tableBuilder appends rows to arrow builders and moves them
+// into a buffered table every table.BufferSize rows.
+// The leading columns are filled with constant values.
+type tableBuilder struct {
+	buffered  *table.BufferedBuilder
+	cols      []flux.ColMeta
+	constants []values.Value
+	builders  []array.Builder
+	n         int
+}
+
+func newTableBuilder(key flux.GroupKey, cols []flux.ColMeta, constants []values.Value, mem memory.Allocator) *tableBuilder {
+	b := &tableBuilder{
+		buffered:  table.NewBufferedBuilder(key, mem),
+		cols:      cols,
+		constants: constants,
+		builders:  make([]array.Builder, len(cols)),
+	}
+	for i, col := range cols {
+		b.builders[i] = arrow.NewBuilder(col.Type, mem)
+	}
+	return b
+}
+
+func (b *tableBuilder) AppendRow(columns []column, fields []string) error {
+	for i, v := range b.constants {
+		if err := arrow.AppendValue(b.builders[i], v); err != nil {
+			return err
+		}
+	}
+	offset := len(b.constants)
+	for i, builder := range b.builders[offset:] {
+		if err := appendValue(builder, columns[i], fields[i]); err != nil {
+			return err
+		}
+	}
+	b.n++
+	if b.n >= table.BufferSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush moves the appended rows into a buffer of the table.
+// NewArray resets the builders so they are reused for the next rows.
+func (b *tableBuilder) flush() error {
+	buf := arrow.TableBuffer{
+		GroupKey: b.buffered.GroupKey,
+		Columns:  b.cols,
+		Values:   make([]array.Array, len(b.builders)),
+	}
+	for i, builder := range b.builders {
+		buf.Values[i] = builder.NewArray()
+	}
+	defer buf.Release()
+	b.n = 0
+	return b.buffered.AppendBuffer(&buf)
+}
+
+// Table flushes the remaining rows and releases the builders.
+// The tableBuilder must not be used after Table is called.
+func (b *tableBuilder) Table() (flux.Table, error) {
+	if b.n > 0 || len(b.buffered.Buffers) == 0 {
+		if err := b.flush(); err != nil {
+			return nil, err
+		}
+	}
+	b.releaseBuilders()
+	return b.buffered.Table()
+}
+
+func (b *tableBuilder) Release() {
+	b.releaseBuilders()
+	b.buffered.Release()
+}
+
+func (b *tableBuilder) releaseBuilders() {
+	for _, builder := range b.builders {
+		builder.Release()
+	}
+	b.builders = nil
+}
@@ -0,0 +1,230 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseColumnType(t *testing.T) {
+	testCases := []struct {
+		dbType   string
+		want     flux.ColType
+		nullable bool
+	}{
+		{dbType: "Int8", want: flux.TInt},
+		{dbType: "Int64", want: flux.TInt},
+		{dbType: "UInt32", want: flux.TUInt},
+		{dbType: "Float64", want: flux.TFloat},
+		{dbType: "Decimal(9, 2)", want: flux.TFloat},
+		{dbType: "Bool", want: flux.TBool},
+		{dbType: "Date", want: flux.TTime},
+		{dbType: "DateTime('UTC')", want: flux.TTime},
+		{dbType: "DateTime64(3)", want: flux.TTime},
+		{dbType: "String", want: flux.TString},
+		{dbType: "UInt128", want: flux.TString},
+		{dbType: "Array(Int64)", want: flux.TString},
+		{dbType: "Nullable(Int32)", want: flux.TInt, nullable: true},
+		{dbType: "LowCardinality(String)", want: flux.TString},
+		{dbType: "LowCardinality(Nullable(String))", want: flux.TString, nullable: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.dbType, func(t *testing.T) {
+			got, nullable := parseColumnType(tc.dbType)
+			if got != tc.want {
+				t.Errorf("unexpected type -want/+got:\n\t- %s\n\t+ %s", tc.want, got)
+			}
+			if nullable != tc.nullable {
+				t.Errorf("unexpected nullable -want/+got:\n\t- %v\n\t+ %v", tc.nullable, nullable)
+			}
+		})
+	}
+}
+
+func TestUnescapeTSV(t *testing.T) {
+	for in, want := range map[string]string{
+		`plain`:       "plain",
+		`a\tb`:        "a\tb",
+		`line\nbreak`: "line\nbreak",
+		`back\\slash`: `back\slash`,
+		`quote\'d`:    `quote'd`,
+		`trailing\`:   `trailing\`,
+	} {
+		if got := unescapeTSV(in); got != want {
+			t.Errorf("unexpected unescaped value for %q -want/+got:\n\t- %q\n\t+ %q", in, want, got)
+		}
+	}
+}
+
+func TestFrom_Do(t *testing.T) {
+	body := strings.Join([]string{
+		"_time\thost\t_value\tok",
+		"DateTime64(3)\tLowCardinality(String)\tNullable(Float64)\tBool",
+		"2023-01-01T00:00:00.000Z\ta\t1.5\ttrue",
+		"2023-01-01T00:00:02.500Z\ta\t2\ttrue",
+		"2023-01-01T00:00:01.000Z\tb\t\\N\tfalse",
+		"",
+	}, "\n")
+
+	var (
+		got   url.Values
+		query []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		query, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	itr := &sourceIterator{
+		spec: &FromProcedureSpec{
+			URL:          server.URL,
+			Query:        "SELECT * FROM t",
+			Database:     "metrics",
+			GroupColumns: []string{"host"},
+		},
+		url: u,
+		mem: memory.DefaultAllocator,
+	}
+
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	var tables []*executetest.Table
+	if err := itr.Do(ctx, func(tbl flux.Table) error {
+		cpy, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, cpy)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := responseFormat, got.Get("default_format"); want != got {
+		t.Errorf("unexpected format -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := "metrics", got.Get("database"); want != got {
+		t.Errorf("unexpected database -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := "SELECT * FROM (SELECT * FROM t) ORDER BY `host`", string(query); want != got {
+		t.Errorf("unexpected query -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "host", Type: flux.TString},
+		{Label: "_value", Type: flux.TFloat},
+		{Label: "ok", Type: flux.TBool},
+	}
+	want := []*executetest.Table{
+		{
+			KeyCols:   []string{"host"},
+			KeyValues: []interface{}{"a"},
+			ColMeta:   cols,
+			Data: [][]interface{}{
+				{mustParseTime(t, "2023-01-01T00:00:00Z"), "a", 1.5, true},
+				{mustParseTime(t, "2023-01-01T00:00:02.5Z"), "a", 2.0, true},
+			},
+		},
+		{
+			KeyCols:   []string{"host"},
+			KeyValues: []interface{}{"b"},
+			ColMeta:   cols,
+			Data: [][]interface{}{
+				{mustParseTime(t, "2023-01-01T00:00:01Z"), "b", nil, false},
+			},
+		},
+	}
+	executetest.NormalizeTables(want)
+	executetest.NormalizeTables(tables)
+	if !cmp.Equal(want, tables) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+	}
+}
+
+func TestFrom_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Code: 62. DB::Exception: Syntax error"))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	itr := &sourceIterator{
+		spec: &FromProcedureSpec{URL: server.URL, Query: "SELEC 1"},
+		url:  u,
+		mem:  memory.DefaultAllocator,
+	}
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	err := itr.Do(ctx, func(tbl flux.Table) error {
+		tbl.Done()
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := "clickhouse query failed with status 400: Code: 62. DB::Exception: Syntax error"; err.Error() != want {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, err)
+	}
+}
+
+func TestFrom_NotContiguous(t *testing.T) {
+	body := strings.Join([]string{
+		"host\t_value",
+		"String\tFloat64",
+		"a\t1",
+		"b\t2",
+		"a\t3",
+		"",
+	}, "\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	itr := &sourceIterator{
+		spec: &FromProcedureSpec{URL: server.URL, Query: "SELECT 1", GroupColumns: []string{"host"}},
+		url:  u,
+		mem:  memory.DefaultAllocator,
+	}
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	var n int
+	err := itr.Do(ctx, func(tbl flux.Table) error {
+		n++
+		tbl.Done()
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := "rows of group [a] are not contiguous in the clickhouse response, order the query by the group columns"; err.Error() != want {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, err)
+	}
+	// The tables of the groups that were complete have already been output.
+	if n != 2 {
+		t.Errorf("unexpected number of tables -want/+got:\n\t- %d\n\t+ %d", 2, n)
+	}
+}
+
+func mustParseTime(t *testing.T, s string) values.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values.ConvertTime(ts)
+}
@@ -0,0 +1,258 @@
+package clickhouse
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux/ast"
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// comparisonOperators maps the Flux comparison operators that
+// can be expressed in ClickHouse onto their SQL equivalent.
+var comparisonOperators = map[ast.OperatorKind]string{
+	ast.EqualOperator:            "=",
+	ast.NotEqualOperator:         "!=",
+	ast.LessThanOperator:         "<",
+	ast.LessThanEqualOperator:    "<=",
+	ast.GreaterThanOperator:      ">",
+	ast.GreaterThanEqualOperator: ">=",
+}
+
+// reversedOperators contains the operator to use when the
+// operands of a comparison are swapped.
+var reversedOperators = map[ast.OperatorKind]ast.OperatorKind{
+	ast.EqualOperator:            ast.EqualOperator,
+	ast.NotEqualOperator:         ast.NotEqualOperator,
+	ast.LessThanOperator:         ast.GreaterThanOperator,
+	ast.LessThanEqualOperator:    ast.GreaterThanEqualOperator,
+	ast.GreaterThanOperator:      ast.LessThanOperator,
+	ast.GreaterThanEqualOperator: ast.LessThanEqualOperator,
+}
+
+// predicateTranslator converts the body of a filter function
+// into a ClickHouse boolean expression.
+type predicateTranslator struct {
+	fn interpreter.ResolvedFunction
+	// param is the name of the record parameter of the function.
+	param string
+	// columns contains the columns that are known to exist in
+	// the ClickHouse result. Other columns cannot be referenced
+	// because ClickHouse fails the query for unknown columns
+	// while Flux filters the rows out.
+	columns []string
+}
+
+// filterToSQL translates the filter function into a ClickHouse
+// expression that only references the columns. It returns false
+// if any part of the function cannot be translated, in which case
+// the filter must run in Flux.
+func filterToSQL(fn interpreter.ResolvedFunction, columns []string) (string, bool) {
+	if fn.Fn == nil || fn.Fn.Parameters == nil || len(fn.Fn.Parameters.List) != 1 {
+		return "", false
+	}
+	body, ok := fn.Fn.GetFunctionBodyExpression()
+	if !ok {
+		return "", false
+	}
+	t := &predicateTranslator{
+		fn:      fn,
+		param:   fn.Fn.Parameters.List[0].Key.Name.Name(),
+		columns: columns,
+	}
+	return t.expr(body)
+}
+
+func (t *predicateTranslator) expr(e semantic.Expression) (string, bool) {
+	switch e := e.(type) {
+	case *semantic.LogicalExpression:
+		left, ok := t.expr(e.Left)
+		if !ok {
+			return "", false
+		}
+		right, ok := t.expr(e.Right)
+		if !ok {
+			return "", false
+		}
+		switch e.Operator {
+		case ast.AndOperator:
+			return "(" + left + " AND " + right + ")", true
+		case ast.OrOperator:
+			return "(" + left + " OR " + right + ")", true
+		}
+	case *semantic.UnaryExpression:
+		// The not operator is not translated because Flux and
+		// ClickHouse do not agree on how it treats null values.
+		if e.Operator == ast.ExistsOperator {
+			if col, ok := t.column(e.Argument); ok {
+				return "(" + col + " IS NOT NULL)", true
+			}
+		}
+	case *semantic.BinaryExpression:
+		return t.binary(e)
+	case *semantic.BooleanLiteral:
+		if e.Value {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
+
+func (t *predicateTranslator) binary(e *semantic.BinaryExpression) (string, bool) {
+	op, left, right := e.Operator, e.Left, e.Right
+	col, ok := t.column(left)
+	if !ok {
+		// Allow the column to be on the right hand side of a comparison.
+		rop, ok := reversedOperators[op]
+		if !ok {
+			return "", false
+		}
+		if col, ok = t.column(right); !ok {
+			return "", false
+		}
+		op, right = rop, left
+	}
+
+	v, ok := t.value(right)
+	if !ok {
+		return "", false
+	}
+
+	switch op {
+	case ast.RegexpMatchOperator, ast.NotRegexpMatchOperator:
+		if v.Type().Nature() != semantic.Regexp {
+			return "", false
+		}
+		match := "match(" + col + ", " + quoteString(v.Regexp().String()) + ")"
+		if op == ast.NotRegexpMatchOperator {
+			return "(NOT " + match + ")", true
+		}
+		return match, true
+	}
+
+	sqlOp, ok := comparisonOperators[op]
+	if !ok {
+		return "", false
+	}
+	lit, ok := literal(v)
+	if !ok {
+		return "", false
+	}
+	return "(" + col + " " + sqlOp + " " + lit + ")", true
+}
+
+// column returns the quoted column name if the expression
+// is a reference to a property of the record parameter.
+func (t *predicateTranslator) column(e semantic.Expression) (string, bool) {
+	m, ok := e.(*semantic.MemberExpression)
+	if !ok {
+		return "", false
+	}
+	obj, ok := m.Object.(*semantic.IdentifierExpression)
+	if !ok || obj.Name.Name() != t.param {
+		return "", false
+	}
+	label := m.Property.Name()
+	for _, col := range t.columns {
+		if label == col {
+			return quoteIdentifier(label), true
+		}
+	}
+	return "", false
+}
+
+// value resolves an expression into a constant value. Literals
+// and values from the scope of the function are supported.
+func (t *predicateTranslator) value(e semantic.Expression) (values.Value, bool) {
+	switch e := e.(type) {
+	case *semantic.StringLiteral:
+		return values.NewString(e.Value), true
+	case *semantic.IntegerLiteral:
+		return values.NewInt(e.Value), true
+	case *semantic.UnsignedIntegerLiteral:
+		return values.NewUInt(e.Value), true
+	case *semantic.FloatLiteral:
+		return values.NewFloat(e.Value), true
+	case *semantic.BooleanLiteral:
+		return values.NewBool(e.Value), true
+	case *semantic.DateTimeLiteral:
+		return values.NewTime(values.ConvertTime(e.Value)), true
+	case *semantic.RegexpLiteral:
+		return values.NewRegexp(e.Value), true
+	case *semantic.IdentifierExpression:
+		if e.Name.Name() == t.param || t.fn.Scope == nil {
+			return nil, false
+		}
+		return t.fn.Scope.Lookup(e.Name.Name())
+	case *semantic.MemberExpression:
+		obj, ok := t.value(e.Object)
+		if !ok || obj.Type().Nature() != semantic.Object {
+			return nil, false
+		}
+		return obj.Object().Get(e.Property.Name())
+	}
+	return nil, false
+}
+
+// literal formats a constant value as a ClickHouse literal.
+func literal(v values.Value) (string, bool) {
+	if v.IsNull() {
+		return "", false
+	}
+	switch v.Type().Nature() {
+	case semantic.String:
+		return quoteString(v.Str()), true
+	case semantic.Int:
+		return strconv.FormatInt(v.Int(), 10), true
+	case semantic.UInt:
+		return strconv.FormatUint(v.UInt(), 10), true
+	case semantic.Float:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	case semantic.Bool:
+		if v.Bool() {
+			return "true", true
+		}
+		return "false", true
+	case semantic.Time:
+		return timeLiteral(v.Time()), true
+	}
+	return "", false
+}
+
+// timeLiteral formats a time as a nanosecond precision DateTime64.
+func timeLiteral(t values.Time) string {
+	return "fromUnixTimestamp64Nano(toInt64(" + strconv.FormatInt(int64(t), 10) + "), 'UTC')"
+}
+
+// quoteString quotes a string as a ClickHouse string literal.
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '\'':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+// quoteIdentifier quotes a column or table name for ClickHouse.
+func quoteIdentifier(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "`", "\\`")
+	return "`" + s + "`"
+}
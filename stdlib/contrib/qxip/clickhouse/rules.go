@@ -0,0 +1,125 @@
+package clickhouse
+
+import (
+	"context"
+
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/stdlib/universe"
+)
+
+// MergeFromRangeRule pushes a range into the ClickHouse query.
+// The source adds the start and stop columns to its output
+// so the result is the same as if range was run in Flux.
+type MergeFromRangeRule struct{}
+
+func (r MergeFromRangeRule) Name() string {
+	return "contrib/qxip/clickhouse.MergeFromRangeRule"
+}
+
+func (r MergeFromRangeRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(universe.RangeKind, plan.SingleSuccessor(FromKind))
+}
+
+func (r MergeFromRangeRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	fromNode := node.Predecessors()[0]
+	fromSpec := fromNode.ProcedureSpec().(*FromProcedureSpec)
+	if !fromSpec.Bounds.IsEmpty() || fromSpec.Limit > 0 {
+		return node, false, nil
+	}
+
+	rangeSpec := node.ProcedureSpec().(*universe.RangeProcedureSpec)
+	for _, label := range fromSpec.GroupColumns {
+		// Range treats existing start and stop columns
+		// differently so leave it to run in Flux.
+		if label == rangeSpec.StartColumn || label == rangeSpec.StopColumn {
+			return node, false, nil
+		}
+	}
+
+	newFromSpec := fromSpec.Copy().(*FromProcedureSpec)
+	newFromSpec.Bounds = rangeSpec.Bounds
+	newFromSpec.TimeColumn = rangeSpec.TimeColumn
+	newFromSpec.StartColumn = rangeSpec.StartColumn
+	newFromSpec.StopColumn = rangeSpec.StopColumn
+	n, err := plan.MergeToPhysicalNode(node, fromNode, newFromSpec)
+	if err != nil {
+		return nil, false, err
+	}
+	return n, true, nil
+}
+
+// MergeFromFilterRule pushes a filter into the ClickHouse query
+// when the entire predicate can be translated and only references
+// columns that are known to exist in the result.
+type MergeFromFilterRule struct{}
+
+func (r MergeFromFilterRule) Name() string {
+	return "contrib/qxip/clickhouse.MergeFromFilterRule"
+}
+
+func (r MergeFromFilterRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(universe.FilterKind, plan.SingleSuccessor(FromKind))
+}
+
+func (r MergeFromFilterRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	fromNode := node.Predecessors()[0]
+	fromSpec := fromNode.ProcedureSpec().(*FromProcedureSpec)
+	if fromSpec.Limit > 0 {
+		return node, false, nil
+	}
+
+	// Filtering in ClickHouse never produces empty tables
+	// so filters that keep them have to run in Flux.
+	filterSpec := node.ProcedureSpec().(*universe.FilterProcedureSpec)
+	if filterSpec.KeepEmptyTables {
+		return node, false, nil
+	}
+
+	predicate, ok := filterToSQL(filterSpec.Fn, fromSpec.knownColumns())
+	if !ok {
+		return node, false, nil
+	}
+
+	newFromSpec := fromSpec.Copy().(*FromProcedureSpec)
+	newFromSpec.Predicates = append(newFromSpec.Predicates, predicate)
+	n, err := plan.MergeToPhysicalNode(node, fromNode, newFromSpec)
+	if err != nil {
+		return nil, false, err
+	}
+	return n, true, nil
+}
+
+// MergeFromLimitRule pushes a limit into the ClickHouse query.
+// Limit applies to each table so it is only pushed down when
+// the source produces a single table.
+type MergeFromLimitRule struct{}
+
+func (r MergeFromLimitRule) Name() string {
+	return "contrib/qxip/clickhouse.MergeFromLimitRule"
+}
+
+func (r MergeFromLimitRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(universe.LimitKind, plan.SingleSuccessor(FromKind))
+}
+
+func (r MergeFromLimitRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	fromNode := node.Predecessors()[0]
+	fromSpec := fromNode.ProcedureSpec().(*FromProcedureSpec)
+	if fromSpec.Limit > 0 || len(fromSpec.GroupColumns) > 0 {
+		return node, false, nil
+	}
+
+	limitSpec := node.ProcedureSpec().(*universe.LimitProcedureSpec)
+	if limitSpec.N <= 0 || limitSpec.Offset < 0 {
+		return node, false, nil
+	}
+
+	newFromSpec := fromSpec.Copy().(*FromProcedureSpec)
+	newFromSpec.Limit = limitSpec.N
+	newFromSpec.Offset = limitSpec.Offset
+	n, err := plan.MergeToPhysicalNode(node, fromNode, newFromSpec)
+	if err != nil {
+		return nil, false, err
+	}
+	return n, true, nil
+}
@@ -0,0 +1,279 @@
+package clickhouse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/plan/plantest"
+	"github.com/InfluxCommunity/flux/stdlib/contrib/qxip/clickhouse"
+	"github.com/InfluxCommunity/flux/stdlib/universe"
+	"github.com/InfluxCommunity/flux/values/valuestest"
+)
+
+func TestPushDownRules(t *testing.T) {
+	fromSpec := &clickhouse.FromProcedureSpec{
+		URL:     "http://localhost:8123",
+		Table:   "logs.requests",
+		Columns: []string{"status", "host"},
+	}
+	undeclaredFromSpec := &clickhouse.FromProcedureSpec{
+		URL:   "http://localhost:8123",
+		Table: "logs.requests",
+	}
+	groupedFromSpec := &clickhouse.FromProcedureSpec{
+		URL:          "http://localhost:8123",
+		Table:        "logs.requests",
+		GroupColumns: []string{"host"},
+	}
+	bounds := flux.Bounds{
+		Start: flux.Time{IsRelative: true, Relative: -time.Hour},
+		Stop:  flux.Time{IsRelative: true},
+		Now:   time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	rangeSpec := &universe.RangeProcedureSpec{
+		Bounds:      bounds,
+		TimeColumn:  "timestamp",
+		StartColumn: "_start",
+		StopColumn:  "_stop",
+	}
+	filterSpec := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn:    executetest.FunctionExpression(t, `(r) => r.status >= 500 and r.host == "a"`),
+			Scope: valuestest.Scope(),
+		},
+	}
+	keepEmptyFilterSpec := &universe.FilterProcedureSpec{
+		Fn:              filterSpec.Fn,
+		KeepEmptyTables: true,
+	}
+	unsupportedFilterSpec := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn:    executetest.FunctionExpression(t, `(r) => r.status + 1 > 500`),
+			Scope: valuestest.Scope(),
+		},
+	}
+	hostFilterSpec := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn:    executetest.FunctionExpression(t, `(r) => r.host == "a"`),
+			Scope: valuestest.Scope(),
+		},
+	}
+	limitSpec := &universe.LimitProcedureSpec{N: 10}
+
+	rules := []plan.Rule{
+		clickhouse.MergeFromRangeRule{},
+		clickhouse.MergeFromFilterRule{},
+		clickhouse.MergeFromLimitRule{},
+	}
+
+	tcs := []plantest.RuleTestCase{
+		{
+			Name:  "range filter limit",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", fromSpec),
+					plan.CreateLogicalNode("range", rangeSpec),
+					plan.CreateLogicalNode("filter", filterSpec),
+					plan.CreateLogicalNode("limit", limitSpec),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("merged_from_range_filter_limit", &clickhouse.FromProcedureSpec{
+						URL:         "http://localhost:8123",
+						Table:       "logs.requests",
+						Columns:     []string{"status", "host"},
+						Bounds:      bounds,
+						TimeColumn:  "timestamp",
+						StartColumn: "_start",
+						StopColumn:  "_stop",
+						Predicates:  []string{"((`status` >= 500) AND (`host` = 'a'))"},
+						Limit:       10,
+					}),
+				},
+			},
+		},
+		{
+			Name:  "limit before filter",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", fromSpec),
+					plan.CreateLogicalNode("limit", limitSpec),
+					plan.CreateLogicalNode("filter", filterSpec),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("merged_from_limit", &clickhouse.FromProcedureSpec{
+						URL:     "http://localhost:8123",
+						Table:   "logs.requests",
+						Columns: []string{"status", "host"},
+						Limit:   10,
+					}),
+					plan.CreatePhysicalNode("filter", filterSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+		},
+		{
+			Name:  "grouped limit",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", groupedFromSpec),
+					plan.CreateLogicalNode("limit", limitSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			NoChange: true,
+		},
+		{
+			Name:  "filter keeps empty tables",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", fromSpec),
+					plan.CreateLogicalNode("filter", keepEmptyFilterSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			NoChange: true,
+		},
+		{
+			Name:  "filter on undeclared column",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", undeclaredFromSpec),
+					plan.CreateLogicalNode("filter", filterSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			NoChange: true,
+		},
+		{
+			Name:  "filter on group column",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", groupedFromSpec),
+					plan.CreateLogicalNode("filter", hostFilterSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("merged_from_filter", &clickhouse.FromProcedureSpec{
+						URL:          "http://localhost:8123",
+						Table:        "logs.requests",
+						GroupColumns: []string{"host"},
+						Predicates:   []string{"(`host` = 'a')"},
+					}),
+				},
+			},
+		},
+		{
+			Name:  "unsupported filter",
+			Rules: rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from", fromSpec),
+					plan.CreateLogicalNode("filter", unsupportedFilterSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			NoChange: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
+
+func TestFromProcedureSpec_BuildQuery(t *testing.T) {
+	now := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
+	bounds := flux.Bounds{
+		Start: flux.Time{IsRelative: true, Relative: -time.Hour},
+		Stop:  flux.Time{IsRelative: true},
+		Now:   now,
+	}
+	for _, tc := range []struct {
+		name string
+		spec clickhouse.FromProcedureSpec
+		want string
+	}{
+		{
+			name: "query",
+			spec: clickhouse.FromProcedureSpec{Query: "SELECT 1"},
+			want: "SELECT 1",
+		},
+		{
+			name: "table",
+			spec: clickhouse.FromProcedureSpec{Table: "logs.requests"},
+			want: "SELECT * FROM `logs`.`requests`",
+		},
+		{
+			name: "query with limit",
+			spec: clickhouse.FromProcedureSpec{Query: "SELECT 1", Limit: 5, Offset: 2},
+			want: "SELECT * FROM (SELECT 1) LIMIT 5 OFFSET 2",
+		},
+		{
+			name: "query with group columns",
+			spec: clickhouse.FromProcedureSpec{Query: "SELECT 1", GroupColumns: []string{"host", "region"}},
+			want: "SELECT * FROM (SELECT 1) ORDER BY `host`, `region`",
+		},
+		{
+			name: "table with range and group columns",
+			spec: clickhouse.FromProcedureSpec{
+				Table:        "requests",
+				Bounds:       bounds,
+				TimeColumn:   "_time",
+				GroupColumns: []string{"host"},
+			},
+			want: "SELECT * FROM `requests` WHERE " +
+				"(`_time` >= fromUnixTimestamp64Nano(toInt64(1672531200000000000), 'UTC')) AND " +
+				"(`_time` < fromUnixTimestamp64Nano(toInt64(1672534800000000000), 'UTC')) " +
+				"ORDER BY `host`, `_time`",
+		},
+		{
+			name: "table with range and predicates",
+			spec: clickhouse.FromProcedureSpec{
+				Table:      "requests",
+				Bounds:     bounds,
+				TimeColumn: "_time",
+				Predicates: []string{"(`host` = 'a')"},
+			},
+			want: "SELECT * FROM `requests` WHERE " +
+				"(`_time` >= fromUnixTimestamp64Nano(toInt64(1672531200000000000), 'UTC')) AND " +
+				"(`_time` < fromUnixTimestamp64Nano(toInt64(1672534800000000000), 'UTC')) AND " +
+				"(`host` = 'a')",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.spec.BuildQuery(); got != tc.want {
+				t.Errorf("unexpected query -want/+got:\n\t- %s\n\t+ %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFromProcedureSpec_Cost(t *testing.T) {
+	if _, stats := (&clickhouse.FromProcedureSpec{}).Cost(nil); stats.Cardinality != 0 {
+		t.Errorf("unexpected cardinality without a limit: %d", stats.Cardinality)
+	}
+	if _, stats := (&clickhouse.FromProcedureSpec{Limit: 100}).Cost(nil); stats.Cardinality != 100 {
+		t.Errorf("unexpected cardinality -want/+got:\n\t- 100\n\t+ %d", stats.Cardinality)
+	}
+}
@@ -6,11 +6,18 @@ import (
 	_md5 "crypto/md5"
 	_sha1 "crypto/sha1"
 	_sha256 "crypto/sha256"
+	_sha512 "crypto/sha512"
 	_b64 "encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
-	"github.com/cespare/xxhash/v2"
+	_hash "hash"
+	_crc32 "hash/crc32"
 	"strconv"
 
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/xxh3"
+
+	"github.com/InfluxCommunity/flux"
 	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/interpreter"
@@ -21,12 +28,23 @@ import (
 
 const (
 	conversionArg = "v"
+	encodingArg   = "encoding"
 	pkgName       = "contrib/qxip/hash"
 )
 
+// Output encodings of a hash.
+const (
+	encodingHex    = "hex"
+	encodingBase64 = "base64"
+	encodingUint   = "uint"
+)
+
 // function is a function definition
 type function func(args interpreter.Arguments) (values.Value, error)
 
+// contextFunction is a function definition that requires the context.
+type contextFunction func(ctx context.Context, args interpreter.Arguments) (values.Value, error)
+
 // makeFunction constructs a values.Function from a function definition.
 func makeFunction(name string, fn function) values.Function {
 	mt := runtime.MustLookupBuiltinType(pkgName, name)
@@ -35,72 +53,130 @@ func makeFunction(name string, fn function) values.Function {
 	}, false)
 }
 
+// makeContextFunction constructs a values.Function from a function
+// definition that requires the context.
+func makeContextFunction(name string, fn contextFunction) values.Function {
+	mt := runtime.MustLookupBuiltinType(pkgName, name)
+	return values.NewFunction(name, mt, func(ctx context.Context, args values.Object) (values.Value, error) {
+		return interpreter.DoFunctionCallContext(fn, ctx, args)
+	}, false)
+}
+
 func init() {
 	runtime.RegisterPackageValue(pkgName, "sha256", makeFunction("sha256", sha256))
 	runtime.RegisterPackageValue(pkgName, "sha1", makeFunction("sha1", sha1))
+	runtime.RegisterPackageValue(pkgName, "sha512", makeFunction("sha512", sha512))
 	runtime.RegisterPackageValue(pkgName, "xxhash64", makeFunction("xxhash64", xxhash64))
+	runtime.RegisterPackageValue(pkgName, "xxh3", makeFunction("xxh3", xxh3_64))
 	runtime.RegisterPackageValue(pkgName, "cityhash64", makeFunction("cityhash64", cityhash64))
+	runtime.RegisterPackageValue(pkgName, "murmur3_32", makeFunction("murmur3_32", murmur3_32))
+	runtime.RegisterPackageValue(pkgName, "murmur3_128", makeFunction("murmur3_128", murmur3_128))
+	runtime.RegisterPackageValue(pkgName, "crc32", makeFunction("crc32", crc32))
 	runtime.RegisterPackageValue(pkgName, "md5", makeFunction("md5", md5))
 	runtime.RegisterPackageValue(pkgName, "b64", makeFunction("b64", b64))
-	runtime.RegisterPackageValue(pkgName, "hmac", makeFunction("hmac", hmac))
+	runtime.RegisterPackageValue(pkgName, "hmac", makeContextFunction("hmac", hmac))
 }
 
 var errMissingArg = errors.Newf(codes.Invalid, "missing argument %q", conversionArg)
 
-func sha256(args interpreter.Arguments) (values.Value, error) {
-	v, ok := args.Get(conversionArg)
-	if !ok {
-		return nil, errMissingArg
-	} else if v.IsNull() {
-		return values.Null, nil
+// getEncoding reads the optional encoding argument.
+func getEncoding(args interpreter.Arguments, defaultEncoding string) (string, error) {
+	encoding, ok, err := args.GetString(encodingArg)
+	if err != nil {
+		return "", err
+	} else if !ok {
+		return defaultEncoding, nil
 	}
-	switch v.Type().Nature() {
-	case semantic.String:
-		s := v.Str()
-		hash := _sha256.Sum256([]byte(s))
-		str := hex.EncodeToString(hash[:])
-		return values.NewString(str), nil
+	switch encoding {
+	case encodingHex, encodingBase64, encodingUint:
+		return encoding, nil
 	default:
-		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to sha256", v.Type())
+		return "", errors.Newf(codes.Invalid, "unknown encoding %q, must be one of %q, %q or %q", encoding, encodingHex, encodingBase64, encodingUint)
 	}
 }
 
-func sha1(args interpreter.Arguments) (values.Value, error) {
-	v, ok := args.Get(conversionArg)
-	if !ok {
-		return nil, errMissingArg
-	} else if v.IsNull() {
-		return values.Null, nil
-	}
-	switch v.Type().Nature() {
-	case semantic.String:
-		s := v.Str()
-		hash := _sha1.Sum([]byte(s))
-		str := hex.EncodeToString(hash[:])
-		return values.NewString(str), nil
+// encode formats the digest of a hash with the encoding. The uint
+// encoding formats the big-endian digest as a decimal and is only
+// supported by hashes of at most 64 bits.
+func encode(name string, digest []byte, encoding string) (values.Value, error) {
+	switch encoding {
+	case encodingBase64:
+		return values.NewString(_b64.StdEncoding.EncodeToString(digest)), nil
+	case encodingUint:
+		if len(digest) > 8 {
+			return nil, errors.Newf(codes.Invalid, "%s does not support the %q encoding", name, encodingUint)
+		}
+		var n uint64
+		for _, b := range digest {
+			n = n<<8 | uint64(b)
+		}
+		return values.NewString(strconv.FormatUint(n, 10)), nil
 	default:
-		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to sha1", v.Type())
+		return values.NewString(hex.EncodeToString(digest)), nil
 	}
 }
 
-func md5(args interpreter.Arguments) (values.Value, error) {
+// sum hashes the string argument with fn and encodes the digest.
+func sum(args interpreter.Arguments, name, defaultEncoding string, fn func(b []byte) []byte) (values.Value, error) {
 	v, ok := args.Get(conversionArg)
 	if !ok {
 		return nil, errMissingArg
-	} else if v.IsNull() {
+	}
+	encoding, err := getEncoding(args, defaultEncoding)
+	if err != nil {
+		return nil, err
+	}
+	if v.IsNull() {
 		return values.Null, nil
 	}
 	switch v.Type().Nature() {
 	case semantic.String:
-		s := v.Str()
-		hash := _md5.Sum([]byte(s))
-		str := hex.EncodeToString(hash[:])
-		return values.NewString(str), nil
+		return encode(name, fn([]byte(v.Str())), encoding)
 	default:
-		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to md5", v.Type())
+		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to %s", v.Type(), name)
 	}
 }
 
+func uint32Bytes(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+func uint64Bytes(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+func sha256(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "sha256", encodingHex, func(b []byte) []byte {
+		hash := _sha256.Sum256(b)
+		return hash[:]
+	})
+}
+
+func sha1(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "sha1", encodingHex, func(b []byte) []byte {
+		hash := _sha1.Sum(b)
+		return hash[:]
+	})
+}
+
+func sha512(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "sha512", encodingHex, func(b []byte) []byte {
+		hash := _sha512.Sum512(b)
+		return hash[:]
+	})
+}
+
+func md5(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "md5", encodingHex, func(b []byte) []byte {
+		hash := _md5.Sum(b)
+		return hash[:]
+	})
+}
+
 func b64(args interpreter.Arguments) (values.Value, error) {
 	v, ok := args.Get(conversionArg)
 	if !ok {
@@ -119,62 +195,112 @@ func b64(args interpreter.Arguments) (values.Value, error) {
 }
 
 func xxhash64(args interpreter.Arguments) (values.Value, error) {
-	v, ok := args.Get(conversionArg)
-	if !ok {
-		return nil, errMissingArg
-	} else if v.IsNull() {
-		return values.Null, nil
-	}
-	switch v.Type().Nature() {
-	case semantic.String:
-		s := v.Str()
-		hash := xxhash.Sum64([]byte(s))
-		str := strconv.FormatUint(hash, 10)
-		return values.NewString(str), nil
-	default:
-		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to sha256", v.Type())
-	}
+	return sum(args, "xxhash64", encodingUint, func(b []byte) []byte {
+		return uint64Bytes(xxhash.Sum64(b))
+	})
+}
+
+func xxh3_64(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "xxh3", encodingUint, func(b []byte) []byte {
+		return uint64Bytes(xxh3.Hash(b))
+	})
 }
 
 func cityhash64(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "cityhash64", encodingUint, func(b []byte) []byte {
+		return uint64Bytes(CityHash64(b, uint32(len(b))))
+	})
+}
+
+func murmur3_32(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "murmur3_32", encodingUint, func(b []byte) []byte {
+		return uint32Bytes(Murmur3Sum32(b, 0))
+	})
+}
+
+// murmur3_128 encodes the digest as the little-endian halves of
+// the hash, which is the byte order used by ClickHouse.
+func murmur3_128(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "murmur3_128", encodingHex, func(b []byte) []byte {
+		h1, h2 := Murmur3Sum128(b, 0)
+		digest := make([]byte, 16)
+		binary.LittleEndian.PutUint64(digest, h1)
+		binary.LittleEndian.PutUint64(digest[8:], h2)
+		return digest
+	})
+}
+
+func crc32(args interpreter.Arguments) (values.Value, error) {
+	return sum(args, "crc32", encodingUint, func(b []byte) []byte {
+		return uint32Bytes(_crc32.ChecksumIEEE(b))
+	})
+}
+
+// hmacAlgorithms are the hash algorithms supported by hmac.
+var hmacAlgorithms = map[string]func() _hash.Hash{
+	"md5":    _md5.New,
+	"sha1":   _sha1.New,
+	"sha256": _sha256.New,
+	"sha512": _sha512.New,
+}
+
+// hmac signs the string argument with either the key in k or
+// the secret named by key, which is read from the secret service.
+func hmac(ctx context.Context, args interpreter.Arguments) (values.Value, error) {
 	v, ok := args.Get(conversionArg)
 	if !ok {
 		return nil, errMissingArg
-	} else if v.IsNull() {
-		return values.Null, nil
 	}
-	switch v.Type().Nature() {
-	case semantic.String:
-		s := v.Str()
-		hash := CityHash64([]byte(s), uint32(len(s)))
-		str := strconv.FormatUint(hash, 10)
-		return values.NewString(str), nil
-	default:
-		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to sha256", v.Type())
+	k, kok := args.Get("k")
+	secretKey, sok, err := args.GetString("key")
+	if err != nil {
+		return nil, err
+	}
+	if kok == sok {
+		return nil, errors.New(codes.Invalid, "hmac requires exactly one of k or key")
 	}
-}
 
-func hmac(args interpreter.Arguments) (values.Value, error) {
-	v, ok := args.Get(conversionArg)
+	algo, ok, err := args.GetString("algo")
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		algo = "sha1"
+	}
+	newHash, ok := hmacAlgorithms[algo]
 	if !ok {
-		return nil, errMissingArg
-	} else if v.IsNull() {
-		return values.Null, nil
+		return nil, errors.Newf(codes.Invalid, "unsupported hmac algorithm %q", algo)
 	}
-	k, kok := args.Get("k")
-	if !kok {
-		return nil, errMissingArg
-	} else if k.IsNull() {
+
+	encoding, err := getEncoding(args, encodingBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.IsNull() || (kok && k.IsNull()) {
 		return values.Null, nil
 	}
-	switch v.Type().Nature() {
-	case semantic.String:
-		key_for_sign := []byte(k.Str())
-		h := _hmac.New(_sha1.New, key_for_sign)
-		h.Write([]byte(v.Str()))
-		str := _b64.StdEncoding.EncodeToString(h.Sum(nil))
-		return values.NewString(str), nil
-	default:
+	if v.Type().Nature() != semantic.String {
 		return nil, errors.Newf(codes.Invalid, "hash cannot convert %v to hmac", v.Type())
 	}
+
+	var key []byte
+	if sok {
+		ss, err := flux.GetDependencies(ctx).SecretService()
+		if err != nil {
+			return nil, errors.Wrapf(err, codes.Inherit, "cannot retrieve secret %q", secretKey)
+		}
+		s, err := ss.LoadSecret(ctx, secretKey)
+		if err != nil {
+			return nil, err
+		}
+		key = []byte(s)
+	} else if k.Type().Nature() == semantic.String {
+		key = []byte(k.Str())
+	} else {
+		return nil, errors.Newf(codes.Invalid, "hmac key must be a string, got %v", k.Type())
+	}
+
+	h := _hmac.New(newHash, key)
+	h.Write([]byte(v.Str()))
+	return encode("hmac", h.Sum(nil), encoding)
 }
@@ -1,10 +1,13 @@
 package hash
 
 import (
-	//	"errors"
+	"context"
 	"testing"
 
+	"github.com/InfluxCommunity/flux/dependencies/dependenciestest"
+	"github.com/InfluxCommunity/flux/dependency"
 	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/mock"
 	"github.com/InfluxCommunity/flux/values"
 )
 
@@ -289,7 +292,7 @@ func Test_HMac(t *testing.T) {
 				"k": values.New(tc.k),
 			}
 			args := interpreter.NewArguments(values.NewObjectWithValues(myMap))
-			got, err := hmac(args)
+			got, err := hmac(context.Background(), args)
 			if err != nil {
 				if tc.expectErr == nil {
 					t.Errorf("unexpected error - want: <nil>, got: %s", err.Error())
@@ -311,3 +314,181 @@ func Test_HMac(t *testing.T) {
 		})
 	}
 }
+
+func Test_Encodings(t *testing.T) {
+	testCases := []struct {
+		name      string
+		fn        function
+		v         string
+		encoding  string
+		want      string
+		expectErr string
+	}{
+		{
+			name: "sha512(v:string)",
+			fn:   sha512,
+			v:    "Hello, world!",
+			want: "c1527cd893c124773d811911970c8fe6e857d6df5dc9226bd8a160614c0cd963a4ddea2b94bb7d36021ef9d865d5cea294a82dd49a0bb269f51f6e7a57f79421",
+		},
+		{
+			name:     "sha256(v:string, encoding:base64)",
+			fn:       sha256,
+			v:        "Hello, world!",
+			encoding: "base64",
+			want:     "MV9b23bQeMQ7isAGTkoBZGErH853yGk0W/yUx1iU7dM=",
+		},
+		{
+			name:      "sha256(v:string, encoding:uint)",
+			fn:        sha256,
+			v:         "Hello, world!",
+			encoding:  "uint",
+			expectErr: `sha256 does not support the "uint" encoding`,
+		},
+		{
+			name:      "md5(v:string, encoding:unknown)",
+			fn:        md5,
+			v:         "Hello, world!",
+			encoding:  "decimal",
+			expectErr: `unknown encoding "decimal", must be one of "hex", "base64" or "uint"`,
+		},
+		{
+			name:     "xxhash64(v:string, encoding:hex)",
+			fn:       xxhash64,
+			v:        "Hello, world!",
+			encoding: "hex",
+			want:     "f58336a78b6f9476",
+		},
+		{
+			name: "xxh3(v:string)",
+			fn:   xxh3_64,
+			v:    "",
+			want: "3244421341483603138",
+		},
+		{
+			name: "murmur3_32(v:string)",
+			fn:   murmur3_32,
+			v:    "The quick brown fox jumps over the lazy dog",
+			want: "776992547",
+		},
+		{
+			name:     "murmur3_32(v:string, encoding:hex)",
+			fn:       murmur3_32,
+			v:        "The quick brown fox jumps over the lazy dog",
+			encoding: "hex",
+			want:     "2e4ff723",
+		},
+		{
+			name: "murmur3_128(v:string)",
+			fn:   murmur3_128,
+			v:    "The quick brown fox jumps over the lazy dog",
+			want: "6c1b07bc7bbc4be347939ac4a93c437a",
+		},
+		{
+			name: "murmur3_128(v:empty)",
+			fn:   murmur3_128,
+			v:    "",
+			want: "00000000000000000000000000000000",
+		},
+		{
+			name: "crc32(v:string)",
+			fn:   crc32,
+			v:    "Hello, world!",
+			want: "3957769958",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			myMap := map[string]values.Value{
+				"v": values.New(tc.v),
+			}
+			if tc.encoding != "" {
+				myMap["encoding"] = values.New(tc.encoding)
+			}
+			args := interpreter.NewArguments(values.NewObjectWithValues(myMap))
+			got, err := tc.fn(args)
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Errorf("unexpected error - want: <nil>, got: %s", err.Error())
+				} else if want, got := tc.expectErr, err.Error(); got != want {
+					t.Errorf("unexpected error - want: %s, got: %s", want, got)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %s, got none", tc.expectErr)
+			}
+			if want := values.NewString(tc.want); !got.Equal(want) {
+				t.Errorf("Wanted: %s, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_HMacSecret(t *testing.T) {
+	testCases := []struct {
+		name      string
+		args      map[string]values.Value
+		want      string
+		expectErr string
+	}{
+		{
+			name: "hmac(v:string, key:secret, algo:sha256, encoding:hex)",
+			args: map[string]values.Value{
+				"v":        values.NewString("helloworld"),
+				"key":      values.NewString("signing_key"),
+				"algo":     values.NewString("sha256"),
+				"encoding": values.NewString("hex"),
+			},
+			want: "362cce899dd7d48a28f92d4cce8e5429ceda327506b643ee71aea65b11904ec0",
+		},
+		{
+			name: "hmac(v:string, key:missing)",
+			args: map[string]values.Value{
+				"v":   values.NewString("helloworld"),
+				"key": values.NewString("missing"),
+			},
+			expectErr: `secret key "missing" not found`,
+		},
+		{
+			name: "hmac(v:string, k:key, key:secret)",
+			args: map[string]values.Value{
+				"v":   values.NewString("helloworld"),
+				"k":   values.NewString("123456"),
+				"key": values.NewString("signing_key"),
+			},
+			expectErr: "hmac requires exactly one of k or key",
+		},
+		{
+			name: "hmac(v:string, k:key, algo:unknown)",
+			args: map[string]values.Value{
+				"v":    values.NewString("helloworld"),
+				"k":    values.NewString("123456"),
+				"algo": values.NewString("sha3"),
+			},
+			expectErr: `unsupported hmac algorithm "sha3"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deps := dependenciestest.Default()
+			deps.Deps.Deps.SecretService = mock.SecretService{"signing_key": "123456"}
+			ctx, span := dependency.Inject(context.Background(), deps)
+			defer span.Finish()
+
+			args := interpreter.NewArguments(values.NewObjectWithValues(tc.args))
+			got, err := hmac(ctx, args)
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Errorf("unexpected error - want: <nil>, got: %s", err.Error())
+				} else if want, got := tc.expectErr, err.Error(); got != want {
+					t.Errorf("unexpected error - want: %s, got: %s", want, got)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %s, got none", tc.expectErr)
+			}
+			if want := values.NewString(tc.want); !got.Equal(want) {
+				t.Errorf("Wanted: %s, got: %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Go implementation of MurmurHash3 (public domain)
+// https://github.com/aappleby/smhasher/blob/master/src/MurmurHash3.cpp
+
+const (
+	murmur3C1_32 uint32 = 0xcc9e2d51
+	murmur3C2_32 uint32 = 0x1b873593
+
+	murmur3C1_128 uint64 = 0x87c37b91114253d5
+	murmur3C2_128 uint64 = 0x4cf5ad432745937f
+)
+
+// Murmur3Sum32 returns the 32-bit x86 variant of MurmurHash3.
+func Murmur3Sum32(data []byte, seed uint32) uint32 {
+	h := seed
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= murmur3C1_32
+		k = bits.RotateLeft32(k, 15)
+		k *= murmur3C2_32
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[nblocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= murmur3C1_32
+		k = bits.RotateLeft32(k, 15)
+		k *= murmur3C2_32
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	return fmix32(h)
+}
+
+// Murmur3Sum128 returns the 128-bit x64 variant of MurmurHash3
+// as the two 64-bit halves of the hash.
+func Murmur3Sum128(data []byte, seed uint64) (uint64, uint64) {
+	h1, h2 := seed, seed
+	nblocks := len(data) / 16
+	for i := 0; i < nblocks; i++ {
+		k1 := binary.LittleEndian.Uint64(data[i*16:])
+		k2 := binary.LittleEndian.Uint64(data[i*16+8:])
+
+		k1 *= murmur3C1_128
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmur3C2_128
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= murmur3C2_128
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmur3C1_128
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := data[nblocks*16:]
+	var k1, k2 uint64
+	switch len(tail) {
+	case 15:
+		k2 ^= uint64(tail[14]) << 48
+		fallthrough
+	case 14:
+		k2 ^= uint64(tail[13]) << 40
+		fallthrough
+	case 13:
+		k2 ^= uint64(tail[12]) << 32
+		fallthrough
+	case 12:
+		k2 ^= uint64(tail[11]) << 24
+		fallthrough
+	case 11:
+		k2 ^= uint64(tail[10]) << 16
+		fallthrough
+	case 10:
+		k2 ^= uint64(tail[9]) << 8
+		fallthrough
+	case 9:
+		k2 ^= uint64(tail[8])
+		k2 *= murmur3C2_128
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmur3C1_128
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= uint64(tail[7]) << 56
+		fallthrough
+	case 7:
+		k1 ^= uint64(tail[6]) << 48
+		fallthrough
+	case 6:
+		k1 ^= uint64(tail[5]) << 40
+		fallthrough
+	case 5:
+		k1 ^= uint64(tail[4]) << 32
+		fallthrough
+	case 4:
+		k1 ^= uint64(tail[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= uint64(tail[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint64(tail[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint64(tail[0])
+		k1 *= murmur3C1_128
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmur3C2_128
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(len(data))
+	h2 ^= uint64(len(data))
+
+	h1 += h2
+	h2 += h1
+
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func fmix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
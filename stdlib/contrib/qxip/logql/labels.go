@@ -0,0 +1,175 @@
+package logql
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const LabelsKind = pkgName + ".labels"
+
+type LabelsOpSpec struct {
+	Config
+	Path string
+	// Label is the name of the label to list the values of.
+	// When it is empty the label names are listed instead.
+	Label string
+	Start values.Time
+	End   values.Time
+}
+
+func init() {
+	labelsSignature := runtime.MustLookupBuiltinType(pkgName, "_labels")
+	runtime.RegisterPackageValue(pkgName, "_labels", flux.MustValue(flux.FunctionValue(LabelsKind, createLabelsOpSpec, labelsSignature)))
+	plan.RegisterProcedureSpec(LabelsKind, newLabelsProcedure, LabelsKind)
+	execute.RegisterSource(LabelsKind, createLabelsSource)
+}
+
+// getTimeRange reads the required start and end arguments.
+func getTimeRange(args flux.Arguments) (start, end values.Time, err error) {
+	if start, err = getTime(args, "start"); err != nil {
+		return 0, 0, err
+	}
+	if end, err = getTime(args, "end"); err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, errors.New(codes.Invalid, "end must not be before start")
+	}
+	return start, end, nil
+}
+
+func createLabelsOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(LabelsOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	if path, err := args.GetRequiredString("path"); err != nil {
+		return nil, err
+	} else {
+		spec.Path = path
+	}
+
+	if label, ok, err := args.GetString("label"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Label = label
+	}
+
+	if spec.Start, spec.End, err = getTimeRange(args); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *LabelsOpSpec) Kind() flux.OperationKind {
+	return LabelsKind
+}
+
+type LabelsProcedureSpec struct {
+	plan.DefaultCost
+	Config
+	Path  string
+	Label string
+	Start values.Time
+	End   values.Time
+}
+
+func newLabelsProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*LabelsOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &LabelsProcedureSpec{
+		Config: spec.Config,
+		Path:   spec.Path,
+		Label:  spec.Label,
+		Start:  spec.Start,
+		End:    spec.End,
+	}, nil
+}
+
+func (s *LabelsProcedureSpec) Kind() plan.ProcedureKind {
+	return LabelsKind
+}
+
+func (s *LabelsProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(LabelsProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func createLabelsSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*LabelsProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	c, err := newClient(a.Context(), spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	iterator := &labelsIterator{
+		spec:   spec,
+		client: c,
+		mem:    a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*labelsIterator)(nil)
+
+type labelsIterator struct {
+	spec   *LabelsProcedureSpec
+	client *client
+	mem    memory.Allocator
+}
+
+// Do produces a single table with the label names or
+// values in the _value column.
+func (l *labelsIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	path := l.spec.Path
+	if l.spec.Label != "" {
+		path += "/" + url.PathEscape(l.spec.Label) + "/values"
+	}
+	params := url.Values{
+		"start": []string{strconv.FormatInt(int64(l.spec.Start), 10)},
+		"end":   []string{strconv.FormatInt(int64(l.spec.End), 10)},
+	}
+	var resp metadataResponse
+	if err := l.client.get(ctx, path, params, &resp); err != nil {
+		return err
+	}
+	var names []string
+	if err := resp.decodeData(&names); err != nil {
+		return err
+	}
+
+	builder := execute.NewColListTableBuilder(execute.NewGroupKey(nil, nil), l.mem)
+	if _, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultValueColLabel, Type: flux.TString}); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := builder.AppendString(0, name); err != nil {
+			return err
+		}
+	}
+	tbl, err := builder.Table()
+	if err != nil {
+		return err
+	}
+	return f(tbl)
+}
@@ -0,0 +1,243 @@
+package logql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	fluxhttp "github.com/InfluxCommunity/flux/dependencies/http"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const pkgName = "contrib/qxip/logql"
+
+// resultTypeStreams is the result type of log queries. Metric
+// queries return the result types of the Prometheus query API.
+const resultTypeStreams = "streams"
+
+// maxErrorBody is the maximum number of bytes read from
+// an error response to construct the error message.
+const maxErrorBody = 4096
+
+// Config contains the options that are common to
+// every request made to a Loki compatible API.
+type Config struct {
+	URL   string
+	OrgID string
+}
+
+// client sends requests to a Loki compatible API.
+type client struct {
+	config Config
+	base   *url.URL
+	http   fluxhttp.Client
+}
+
+// newClient validates the configured url and constructs a client
+// using the http client from the flux dependencies.
+func newClient(ctx context.Context, config Config) (*client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Invalid, "invalid logql url")
+	}
+	deps := flux.GetDependencies(ctx)
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	if err := validator.Validate(u); err != nil {
+		return nil, err
+	}
+	hc, err := deps.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return &client{config: config, base: u, http: hc}, nil
+}
+
+// get sends a GET request to the path with the parameters
+// and decodes the JSON response into v. The path must already
+// be escaped.
+func (c *client) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	u := *c.base
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + path
+	p, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return errors.Newf(codes.Invalid, "invalid logql path %q: %s", path, err)
+	}
+	u.Path = p
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.config.OrgID != "" {
+		req.Header.Set("X-Scope-OrgID", c.config.OrgID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if err != nil {
+			return errors.Newf(codes.Invalid, "error when reading logql response body: %s", err)
+		}
+		code := codes.Invalid
+		if resp.StatusCode >= 500 {
+			code = codes.Unavailable
+		}
+		msg := strings.TrimSpace(string(body))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return errors.Newf(code, "logql request failed with status %d: %s", resp.StatusCode, msg)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return errors.Wrap(err, codes.Internal, "failed to decode logql response")
+	}
+	return nil
+}
+
+// queryResponse is the response of the query and query_range APIs.
+type queryResponse struct {
+	Status string    `json:"status"`
+	Error  string    `json:"error"`
+	Data   queryData `json:"data"`
+}
+
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// metadataResponse is the response of the labels, label values and series APIs.
+type metadataResponse struct {
+	Status string          `json:"status"`
+	Error  string          `json:"error"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// decodeData decodes the data of a successful response.
+func (r *metadataResponse) decodeData(v interface{}) error {
+	if r.Status != "success" {
+		return errors.Newf(codes.Invalid, "logql request failed: %s", r.Error)
+	}
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		return errors.Wrap(err, codes.Internal, "failed to decode logql response data")
+	}
+	return nil
+}
+
+// stream is a single log stream of a streams result.
+type stream struct {
+	Labels map[string]string `json:"stream"`
+	// Values contains the timestamp in nanoseconds and
+	// the log line. Additional elements are ignored.
+	Values [][]json.RawMessage `json:"values"`
+}
+
+// entry is a single log line.
+type entry struct {
+	Time values.Time
+	Line string
+}
+
+// checkStatus returns an error if the response reports a failure.
+func (r *queryResponse) checkStatus() error {
+	if r.Status != "success" {
+		return errors.Newf(codes.Invalid, "logql query failed: %s", r.Error)
+	}
+	return nil
+}
+
+// decodeResult decodes the result of a successful response.
+func (r *queryResponse) decodeResult(v interface{}) error {
+	if err := r.checkStatus(); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(r.Data.Result, v); err != nil {
+		return errors.Wrapf(err, codes.Internal, "failed to decode logql %s result", r.Data.ResultType)
+	}
+	return nil
+}
+
+// entries decodes the values of the stream.
+func (s *stream) entries() ([]entry, error) {
+	entries := make([]entry, 0, len(s.Values))
+	for _, v := range s.Values {
+		if len(v) < 2 {
+			return nil, errors.New(codes.Internal, "logql stream value is missing the timestamp or line")
+		}
+		var ts, line string
+		if err := json.Unmarshal(v[0], &ts); err != nil {
+			return nil, errors.Wrap(err, codes.Internal, "invalid logql stream timestamp")
+		}
+		if err := json.Unmarshal(v[1], &line); err != nil {
+			return nil, errors.Wrap(err, codes.Internal, "invalid logql stream line")
+		}
+		ns, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, codes.Internal, "invalid logql stream timestamp")
+		}
+		entries = append(entries, entry{Time: values.Time(ns), Line: line})
+	}
+	return entries, nil
+}
+
+// tableSet groups rows into tables by their labels.
+type tableSet struct {
+	*promapi.TableSet
+}
+
+func newTableSet(mem memory.Allocator) *tableSet {
+	return &tableSet{TableSet: promapi.NewTableSet("logql", mem)}
+}
+
+// addStreams adds the entries of each stream to its table.
+// Entries for which skip returns true are not added.
+func (ts *tableSet) addStreams(streams []stream, skip func(labels map[string]string, e entry) bool) error {
+	for i := range streams {
+		entries, err := streams[i].entries()
+		if err != nil {
+			return err
+		}
+		builder, err := ts.Builder(streams[i].Labels, flux.TString)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if skip != nil && skip(streams[i].Labels, e) {
+				continue
+			}
+			if err := promapi.AppendRow(builder, e.Time, values.NewString(e.Line)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addResult adds a matrix, vector or scalar result.
+func (ts *tableSet) addResult(resp *queryResponse) error {
+	if err := resp.checkStatus(); err != nil {
+		return err
+	}
+	return ts.AddResult(resp.Data.ResultType, resp.Data.Result)
+}
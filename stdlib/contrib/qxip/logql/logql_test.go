@@ -0,0 +1,213 @@
+package logql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newTestServer returns a server that responds to each path
+// with the body in the map and records the request.
+func newTestServer(t *testing.T, bodies map[string]string, req **http.Request) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*req = r
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runIterator runs the source iterator and converts the resulting tables.
+func runIterator(t *testing.T, itr execute.SourceIterator) []*executetest.Table {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	var tables []*executetest.Table
+	if err := itr.Do(ctx, func(tbl flux.Table) error {
+		cpy, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, cpy)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(tables)
+	return tables
+}
+
+func mustNewClient(t *testing.T, config Config) *client {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	c, err := newClient(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestQuery_Vector(t *testing.T) {
+	var req *http.Request
+	server := newTestServer(t, map[string]string{
+		"/loki/api/v1/query": `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"job":"api"},"value":[1672531200.25,"3"]},
+			{"metric":{"job":"web"},"value":[1672531200.25,"4.5"]}
+		]}}`,
+	}, &req)
+
+	config := Config{URL: server.URL, OrgID: "tenant"}
+	tables := runIterator(t, &queryIterator{
+		spec: &QueryProcedureSpec{
+			Config: config,
+			Path:   "/loki/api/v1/query",
+			Query:  `sum by (job) (rate({job=~".+"}[1m]))`,
+			Limit:  100,
+			Time:   values.Time(1672531200250000000),
+		},
+		client: mustNewClient(t, config),
+		mem:    memory.DefaultAllocator,
+	})
+
+	if want, got := "1672531200250000000", req.URL.Query().Get("time"); want != got {
+		t.Errorf("unexpected time -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := "tenant", req.Header.Get("X-Scope-OrgID"); want != got {
+		t.Errorf("unexpected org id -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+
+	cols := []flux.ColMeta{
+		{Label: "job", Type: flux.TString},
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+	}
+	want := []*executetest.Table{
+		{
+			KeyCols:   []string{"job"},
+			KeyValues: []interface{}{"api"},
+			ColMeta:   cols,
+			Data:      [][]interface{}{{"api", values.Time(1672531200250000000), 3.0}},
+		},
+		{
+			KeyCols:   []string{"job"},
+			KeyValues: []interface{}{"web"},
+			ColMeta:   cols,
+			Data:      [][]interface{}{{"web", values.Time(1672531200250000000), 4.5}},
+		},
+	}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+	}
+}
+
+func TestLabels(t *testing.T) {
+	var req *http.Request
+	server := newTestServer(t, map[string]string{
+		"/loki/api/v1/labels":           `{"status":"success","data":["job","level"]}`,
+		"/loki/api/v1/label/job/values": `{"status":"success","data":["api","web"]}`,
+		"/loki/api/v1/label/a/b/values": `{"status":"success","data":["c"]}`,
+	}, &req)
+	config := Config{URL: server.URL}
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		label    string
+		wantPath string
+		want     []string
+	}{
+		{name: "names", path: "/loki/api/v1/labels", wantPath: "/loki/api/v1/labels", want: []string{"job", "level"}},
+		{name: "values", path: "/loki/api/v1/label", label: "job", wantPath: "/loki/api/v1/label/job/values", want: []string{"api", "web"}},
+		{name: "escaped values", path: "/loki/api/v1/label", label: "a/b", wantPath: "/loki/api/v1/label/a%2Fb/values", want: []string{"c"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tables := runIterator(t, &labelsIterator{
+				spec: &LabelsProcedureSpec{
+					Config: config,
+					Path:   tc.path,
+					Label:  tc.label,
+					Start:  1000,
+					End:    2000,
+				},
+				client: mustNewClient(t, config),
+				mem:    memory.DefaultAllocator,
+			})
+
+			if want, got := tc.wantPath, req.URL.EscapedPath(); want != got {
+				t.Errorf("unexpected path -want/+got:\n\t- %s\n\t+ %s", want, got)
+			}
+			if want, got := "1000", req.URL.Query().Get("start"); want != got {
+				t.Errorf("unexpected start -want/+got:\n\t- %s\n\t+ %s", want, got)
+			}
+
+			data := make([][]interface{}, len(tc.want))
+			for i, v := range tc.want {
+				data[i] = []interface{}{v}
+			}
+			want := []*executetest.Table{{
+				ColMeta: []flux.ColMeta{{Label: "_value", Type: flux.TString}},
+				Data:    data,
+			}}
+			executetest.NormalizeTables(want)
+			if !cmp.Equal(want, tables) {
+				t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+			}
+		})
+	}
+}
+
+func TestSeries(t *testing.T) {
+	var req *http.Request
+	server := newTestServer(t, map[string]string{
+		"/loki/api/v1/series": `{"status":"success","data":[
+			{"job":"api","level":"info"},
+			{"job":"web"}
+		]}`,
+	}, &req)
+	config := Config{URL: server.URL}
+
+	tables := runIterator(t, &seriesIterator{
+		spec: &SeriesProcedureSpec{
+			Config: config,
+			Path:   "/loki/api/v1/series",
+			Match:  []string{`{job="api"}`, `{job="web"}`},
+			Start:  1000,
+			End:    2000,
+		},
+		client: mustNewClient(t, config),
+		mem:    memory.DefaultAllocator,
+	})
+
+	if want, got := []string{`{job="api"}`, `{job="web"}`}, req.URL.Query()["match[]"]; !cmp.Equal(want, got) {
+		t.Errorf("unexpected match -want/+got:\n%s", cmp.Diff(want, got))
+	}
+
+	want := []*executetest.Table{{
+		ColMeta: []flux.ColMeta{
+			{Label: "job", Type: flux.TString},
+			{Label: "level", Type: flux.TString},
+		},
+		Data: [][]interface{}{
+			{"api", "info"},
+			{"web", nil},
+		},
+	}}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+	}
+}
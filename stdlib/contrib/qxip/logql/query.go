@@ -0,0 +1,161 @@
+package logql
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const QueryKind = pkgName + ".query"
+
+type QueryOpSpec struct {
+	Config
+	Path  string
+	Query string
+	Limit int64
+	Time  values.Time
+}
+
+func init() {
+	querySignature := runtime.MustLookupBuiltinType(pkgName, "_query")
+	runtime.RegisterPackageValue(pkgName, "_query", flux.MustValue(flux.FunctionValue(QueryKind, createQueryOpSpec, querySignature)))
+	plan.RegisterProcedureSpec(QueryKind, newQueryProcedure, QueryKind)
+	execute.RegisterSource(QueryKind, createQuerySource)
+}
+
+func createQueryOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(QueryOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	if path, err := args.GetRequiredString("path"); err != nil {
+		return nil, err
+	} else {
+		spec.Path = path
+	}
+
+	if query, err := args.GetRequiredString("query"); err != nil {
+		return nil, err
+	} else {
+		spec.Query = query
+	}
+
+	if limit, err := args.GetRequiredInt("limit"); err != nil {
+		return nil, err
+	} else if limit <= 0 {
+		return nil, errors.New(codes.Invalid, "limit must be greater than zero")
+	} else {
+		spec.Limit = limit
+	}
+
+	if spec.Time, err = getTime(args, "time"); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *QueryOpSpec) Kind() flux.OperationKind {
+	return QueryKind
+}
+
+type QueryProcedureSpec struct {
+	plan.DefaultCost
+	Config
+	Path  string
+	Query string
+	Limit int64
+	Time  values.Time
+}
+
+func newQueryProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*QueryOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &QueryProcedureSpec{
+		Config: spec.Config,
+		Path:   spec.Path,
+		Query:  spec.Query,
+		Limit:  spec.Limit,
+		Time:   spec.Time,
+	}, nil
+}
+
+func (s *QueryProcedureSpec) Kind() plan.ProcedureKind {
+	return QueryKind
+}
+
+func (s *QueryProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(QueryProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func createQuerySource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*QueryProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	c, err := newClient(a.Context(), spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	iterator := &queryIterator{
+		spec:   spec,
+		client: c,
+		mem:    a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*queryIterator)(nil)
+
+type queryIterator struct {
+	spec   *QueryProcedureSpec
+	client *client
+	mem    memory.Allocator
+}
+
+func (q *queryIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	params := url.Values{
+		"query":     []string{q.spec.Query},
+		"limit":     []string{strconv.FormatInt(q.spec.Limit, 10)},
+		"time":      []string{strconv.FormatInt(int64(q.spec.Time), 10)},
+		"direction": []string{"forward"},
+	}
+	var resp queryResponse
+	if err := q.client.get(ctx, q.spec.Path, params, &resp); err != nil {
+		return err
+	}
+	if err := resp.checkStatus(); err != nil {
+		return err
+	}
+
+	tables := newTableSet(q.mem)
+	if resp.Data.ResultType == resultTypeStreams {
+		var streams []stream
+		if err := resp.decodeResult(&streams); err != nil {
+			return err
+		}
+		if err := tables.addStreams(streams, nil); err != nil {
+			return err
+		}
+	} else if err := tables.addResult(&resp); err != nil {
+		return err
+	}
+	return tables.Do(f)
+}
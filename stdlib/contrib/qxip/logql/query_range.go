@@ -0,0 +1,291 @@
+package logql
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const QueryRangeKind = pkgName + ".queryRange"
+
+type QueryRangeOpSpec struct {
+	Config
+	Path  string
+	Query string
+	Limit int64
+	Step  int64
+	Start values.Time
+	End   values.Time
+}
+
+func init() {
+	queryRangeSignature := runtime.MustLookupBuiltinType(pkgName, "_queryRange")
+	runtime.RegisterPackageValue(pkgName, "_queryRange", flux.MustValue(flux.FunctionValue(QueryRangeKind, createQueryRangeOpSpec, queryRangeSignature)))
+	plan.RegisterProcedureSpec(QueryRangeKind, newQueryRangeProcedure, QueryRangeKind)
+	execute.RegisterSource(QueryRangeKind, createQueryRangeSource)
+}
+
+// getConfig reads the arguments that are shared by every function.
+func getConfig(args flux.Arguments) (Config, error) {
+	var config Config
+	if u, err := args.GetRequiredString("url"); err != nil {
+		return config, err
+	} else {
+		config.URL = u
+	}
+	if orgID, ok, err := args.GetString("orgid"); err != nil {
+		return config, err
+	} else if ok {
+		config.OrgID = orgID
+	}
+	return config, nil
+}
+
+// getTime reads a required time argument.
+func getTime(args flux.Arguments, name string) (values.Time, error) {
+	v, err := args.GetRequired(name)
+	if err != nil {
+		return 0, err
+	}
+	if v.IsNull() {
+		return 0, errors.Newf(codes.Invalid, "%s must not be null", name)
+	}
+	return v.Time(), nil
+}
+
+func createQueryRangeOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(QueryRangeOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	if path, err := args.GetRequiredString("path"); err != nil {
+		return nil, err
+	} else {
+		spec.Path = path
+	}
+
+	if query, err := args.GetRequiredString("query"); err != nil {
+		return nil, err
+	} else {
+		spec.Query = query
+	}
+
+	if limit, err := args.GetRequiredInt("limit"); err != nil {
+		return nil, err
+	} else if limit <= 0 {
+		return nil, errors.New(codes.Invalid, "limit must be greater than zero")
+	} else {
+		spec.Limit = limit
+	}
+
+	if step, err := args.GetRequiredInt("step"); err != nil {
+		return nil, err
+	} else if step <= 0 {
+		return nil, errors.New(codes.Invalid, "step must be greater than zero")
+	} else {
+		spec.Step = step
+	}
+
+	if spec.Start, err = getTime(args, "start"); err != nil {
+		return nil, err
+	}
+	if spec.End, err = getTime(args, "end"); err != nil {
+		return nil, err
+	}
+	if spec.End < spec.Start {
+		return nil, errors.New(codes.Invalid, "end must not be before start")
+	}
+	return spec, nil
+}
+
+func (s *QueryRangeOpSpec) Kind() flux.OperationKind {
+	return QueryRangeKind
+}
+
+type QueryRangeProcedureSpec struct {
+	plan.DefaultCost
+	Config
+	Path  string
+	Query string
+	Limit int64
+	Step  int64
+	Start values.Time
+	End   values.Time
+}
+
+func newQueryRangeProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*QueryRangeOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &QueryRangeProcedureSpec{
+		Config: spec.Config,
+		Path:   spec.Path,
+		Query:  spec.Query,
+		Limit:  spec.Limit,
+		Step:   spec.Step,
+		Start:  spec.Start,
+		End:    spec.End,
+	}, nil
+}
+
+func (s *QueryRangeProcedureSpec) Kind() plan.ProcedureKind {
+	return QueryRangeKind
+}
+
+func (s *QueryRangeProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(QueryRangeProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func createQueryRangeSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*QueryRangeProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	c, err := newClient(a.Context(), spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	iterator := &queryRangeIterator{
+		spec:   spec,
+		client: c,
+		mem:    a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*queryRangeIterator)(nil)
+
+type queryRangeIterator struct {
+	spec   *QueryRangeProcedureSpec
+	client *client
+	mem    memory.Allocator
+}
+
+func (q *queryRangeIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	tables := newTableSet(q.mem)
+	if err := q.read(ctx, tables); err != nil {
+		return err
+	}
+	return tables.Do(f)
+}
+
+// read requests the time range and adds the results to the tables.
+//
+// A log query returns at most limit entries for each request.
+// When a request returns limit entries, another request is made
+// that starts at the timestamp of the last entry until the entire
+// time range has been read.
+func (q *queryRangeIterator) read(ctx context.Context, tables *tableSet) error {
+	start := q.spec.Start
+	// seen contains the entries that were already read
+	// with a timestamp equal to the start of the next request.
+	var seen map[string]struct{}
+	for {
+		params := url.Values{
+			"query":     []string{q.spec.Query},
+			"limit":     []string{strconv.FormatInt(q.spec.Limit, 10)},
+			"start":     []string{strconv.FormatInt(int64(start), 10)},
+			"end":       []string{strconv.FormatInt(int64(q.spec.End), 10)},
+			"step":      []string{strconv.FormatInt(q.spec.Step, 10)},
+			"direction": []string{"forward"},
+		}
+		var resp queryResponse
+		if err := q.client.get(ctx, q.spec.Path, params, &resp); err != nil {
+			return err
+		}
+		if err := resp.checkStatus(); err != nil {
+			return err
+		}
+		if resp.Data.ResultType != resultTypeStreams {
+			return tables.addResult(&resp)
+		}
+
+		var streams []stream
+		if err := resp.decodeResult(&streams); err != nil {
+			return err
+		}
+
+		n, last := 0, start
+		for i := range streams {
+			entries, err := streams[i].entries()
+			if err != nil {
+				return err
+			}
+			n += len(entries)
+			for _, e := range entries {
+				if e.Time > last {
+					last = e.Time
+				}
+			}
+		}
+
+		prev := seen
+		if err := tables.addStreams(streams, func(labels map[string]string, e entry) bool {
+			if e.Time != start || prev == nil {
+				return false
+			}
+			_, ok := prev[entryID(labels, e)]
+			return ok
+		}); err != nil {
+			return err
+		}
+
+		if int64(n) < q.spec.Limit || last >= q.spec.End {
+			return nil
+		}
+		if last == start {
+			return errors.Newf(codes.Invalid, "more than %d logql entries share the timestamp %s; increase the limit", q.spec.Limit, start)
+		}
+
+		// Remember the entries at the last timestamp so they
+		// are not added again by the next request.
+		seen = make(map[string]struct{})
+		for i := range streams {
+			entries, _ := streams[i].entries()
+			for _, e := range entries {
+				if e.Time == last {
+					seen[entryID(streams[i].Labels, e)] = struct{}{}
+				}
+			}
+		}
+		start = last
+	}
+}
+
+// entryID identifies a log entry by its labels and line.
+func entryID(labels map[string]string, e entry) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(strconv.Quote(name))
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(labels[name]))
+		sb.WriteByte(',')
+	}
+	sb.WriteString(strconv.Quote(e.Line))
+	return sb.String()
+}
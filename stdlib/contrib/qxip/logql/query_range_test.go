@@ -0,0 +1,230 @@
+package logql
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// readTables runs the iterator and converts the resulting tables.
+func readTables(t *testing.T, itr *queryRangeIterator) ([]*executetest.Table, error) {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	c, err := newClient(ctx, itr.spec.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itr.client = c
+
+	var tables []*executetest.Table
+	if err := itr.Do(ctx, func(tbl flux.Table) error {
+		cpy, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, cpy)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	executetest.NormalizeTables(tables)
+	return tables, nil
+}
+
+func TestQueryRange_Streams(t *testing.T) {
+	// The server returns two pages. The second page starts at the
+	// last timestamp of the first page and repeats its entry.
+	pages := map[string]string{
+		"1000": `{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"job":"api","level":"info"},"values":[["1000","a"],["2000","b"]]},
+			{"stream":{"job":"api","level":"error"},"values":[["3000","c"]]}
+		]}}`,
+		"3000": `{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"job":"api","level":"error"},"values":[["3000","c"],["4000","d"]]}
+		]}}`,
+	}
+	var starts []string
+	var orgID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		starts = append(starts, q.Get("start"))
+		orgID = r.Header.Get("X-Scope-OrgID")
+		_, _ = w.Write([]byte(pages[q.Get("start")]))
+	}))
+	defer server.Close()
+
+	tables, err := readTables(t, &queryRangeIterator{
+		spec: &QueryRangeProcedureSpec{
+			Config: Config{URL: server.URL, OrgID: "tenant"},
+			Path:   "/loki/api/v1/query_range",
+			Query:  `{job="api"}`,
+			Limit:  3,
+			Step:   10,
+			Start:  1000,
+			End:    10000,
+		},
+		mem: memory.DefaultAllocator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"1000", "3000"}; !cmp.Equal(want, starts) {
+		t.Errorf("unexpected requests -want/+got:\n%s", cmp.Diff(want, starts))
+	}
+	if want := "tenant"; orgID != want {
+		t.Errorf("unexpected org id -want/+got:\n\t- %s\n\t+ %s", want, orgID)
+	}
+
+	cols := []flux.ColMeta{
+		{Label: "job", Type: flux.TString},
+		{Label: "level", Type: flux.TString},
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TString},
+	}
+	want := []*executetest.Table{
+		{
+			KeyCols:   []string{"job", "level"},
+			KeyValues: []interface{}{"api", "error"},
+			ColMeta:   cols,
+			Data: [][]interface{}{
+				{"api", "error", values.Time(3000), "c"},
+				{"api", "error", values.Time(4000), "d"},
+			},
+		},
+		{
+			KeyCols:   []string{"job", "level"},
+			KeyValues: []interface{}{"api", "info"},
+			ColMeta:   cols,
+			Data: [][]interface{}{
+				{"api", "info", values.Time(1000), "a"},
+				{"api", "info", values.Time(2000), "b"},
+			},
+		},
+	}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+	}
+}
+
+func TestQueryRange_Matrix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"job":"api"},"values":[[1672531200,"1.5"],[1672531210.5,"2"],[1672531220,"NaN"]]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	tables, err := readTables(t, &queryRangeIterator{
+		spec: &QueryRangeProcedureSpec{
+			Config: Config{URL: server.URL},
+			Path:   "/loki/api/v1/query_range",
+			Query:  `rate({job="api"}[1m])`,
+			Limit:  1,
+			Step:   10,
+			End:    values.Time(1672534800000000000),
+		},
+		mem: memory.DefaultAllocator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*executetest.Table{
+		{
+			KeyCols:   []string{"job"},
+			KeyValues: []interface{}{"api"},
+			ColMeta: []flux.ColMeta{
+				{Label: "job", Type: flux.TString},
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{"api", values.Time(1672531200000000000), 1.5},
+				{"api", values.Time(1672531210500000000), 2.0},
+				{"api", values.Time(1672531220000000000), math.NaN()},
+			},
+		},
+	}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables, cmpopts.EquateNaNs()) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables, cmpopts.EquateNaNs()))
+	}
+}
+
+func TestQueryRange_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		body   string
+		limit  int64
+		want   string
+	}{
+		{
+			name:   "status",
+			status: http.StatusBadRequest,
+			body:   "parse error at line 1, col 1: syntax error\n",
+			limit:  100,
+			want:   "logql request failed with status 400: parse error at line 1, col 1: syntax error",
+		},
+		{
+			name:   "failed query",
+			status: http.StatusOK,
+			body:   `{"status":"error","error":"query timed out"}`,
+			limit:  100,
+			want:   "logql query failed: query timed out",
+		},
+		{
+			name:   "no progress",
+			status: http.StatusOK,
+			body: `{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"job":"api"},"values":[["1000","a"],["1000","b"]]}
+			]}}`,
+			limit: 2,
+			want:  "increase the limit",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			_, err := readTables(t, &queryRangeIterator{
+				spec: &QueryRangeProcedureSpec{
+					Config: Config{URL: server.URL},
+					Path:   "/loki/api/v1/query_range",
+					Query:  `{job="api"}`,
+					Limit:  tc.limit,
+					Step:   10,
+					Start:  1000,
+					End:    10000,
+				},
+				mem: memory.DefaultAllocator,
+			})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", tc.want, err)
+			}
+		})
+	}
+}
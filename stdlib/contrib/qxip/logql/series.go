@@ -0,0 +1,190 @@
+package logql
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const SeriesKind = pkgName + ".series"
+
+type SeriesOpSpec struct {
+	Config
+	Path  string
+	Match []string
+	Start values.Time
+	End   values.Time
+}
+
+func init() {
+	seriesSignature := runtime.MustLookupBuiltinType(pkgName, "_series")
+	runtime.RegisterPackageValue(pkgName, "_series", flux.MustValue(flux.FunctionValue(SeriesKind, createSeriesOpSpec, seriesSignature)))
+	plan.RegisterProcedureSpec(SeriesKind, newSeriesProcedure, SeriesKind)
+	execute.RegisterSource(SeriesKind, createSeriesSource)
+}
+
+func createSeriesOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(SeriesOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	if path, err := args.GetRequiredString("path"); err != nil {
+		return nil, err
+	} else {
+		spec.Path = path
+	}
+
+	match, err := args.GetRequiredArray("match", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	spec.Match = make([]string, match.Len())
+	match.Range(func(i int, v values.Value) {
+		spec.Match[i] = v.Str()
+	})
+
+	if spec.Start, spec.End, err = getTimeRange(args); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *SeriesOpSpec) Kind() flux.OperationKind {
+	return SeriesKind
+}
+
+type SeriesProcedureSpec struct {
+	plan.DefaultCost
+	Config
+	Path  string
+	Match []string
+	Start values.Time
+	End   values.Time
+}
+
+func newSeriesProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*SeriesOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &SeriesProcedureSpec{
+		Config: spec.Config,
+		Path:   spec.Path,
+		Match:  spec.Match,
+		Start:  spec.Start,
+		End:    spec.End,
+	}, nil
+}
+
+func (s *SeriesProcedureSpec) Kind() plan.ProcedureKind {
+	return SeriesKind
+}
+
+func (s *SeriesProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(SeriesProcedureSpec)
+	*ns = *s
+	ns.Match = make([]string, len(s.Match))
+	copy(ns.Match, s.Match)
+	return ns
+}
+
+func createSeriesSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*SeriesProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	c, err := newClient(a.Context(), spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	iterator := &seriesIterator{
+		spec:   spec,
+		client: c,
+		mem:    a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*seriesIterator)(nil)
+
+type seriesIterator struct {
+	spec   *SeriesProcedureSpec
+	client *client
+	mem    memory.Allocator
+}
+
+// Do produces a single table with one row for each series.
+// Each label is a string column that is null for the series
+// that do not have the label.
+func (s *seriesIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	params := url.Values{
+		"match[]": s.spec.Match,
+		"start":   []string{strconv.FormatInt(int64(s.spec.Start), 10)},
+		"end":     []string{strconv.FormatInt(int64(s.spec.End), 10)},
+	}
+	var resp metadataResponse
+	if err := s.client.get(ctx, s.spec.Path, params, &resp); err != nil {
+		return err
+	}
+	var result []map[string]string
+	if err := resp.decodeData(&result); err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, labels := range result {
+		for name := range labels {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	builder := execute.NewColListTableBuilder(execute.NewGroupKey(nil, nil), s.mem)
+	for _, name := range names {
+		if _, err := builder.AddCol(flux.ColMeta{Label: name, Type: flux.TString}); err != nil {
+			return err
+		}
+	}
+	for _, labels := range result {
+		for j, name := range names {
+			v, ok := labels[name]
+			if !ok {
+				if err := builder.AppendNil(j); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := builder.AppendString(j, v); err != nil {
+				return err
+			}
+		}
+	}
+	tbl, err := builder.Table()
+	if err != nil {
+		return err
+	}
+	return f(tbl)
+}
@@ -0,0 +1,413 @@
+package logql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const ToKind = pkgName + ".to"
+
+// Payload formats of the push API.
+const (
+	formatJSON     = "json"
+	formatProtobuf = "protobuf"
+)
+
+type ToOpSpec struct {
+	Config
+	Path         string
+	LabelColumns []string
+	LineColumn   string
+	TimeColumn   string
+	Format       string
+	BatchSize    int64
+	MaxRetries   int64
+}
+
+func init() {
+	toSignature := runtime.MustLookupBuiltinType(pkgName, "_to")
+	runtime.RegisterPackageValue(pkgName, "_to", flux.MustValue(flux.FunctionValueWithSideEffect(ToKind, createToOpSpec, toSignature)))
+	plan.RegisterProcedureSpecWithSideEffect(ToKind, newToProcedure, ToKind)
+	execute.RegisterTransformation(ToKind, createToTransformation)
+}
+
+func createToOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := new(ToOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	if spec.Path, err = args.GetRequiredString("path"); err != nil {
+		return nil, err
+	}
+
+	labelColumns, err := args.GetRequiredArrayAllowEmpty("labelColumns", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	spec.LabelColumns = make([]string, 0, labelColumns.Len())
+	labelColumns.Range(func(i int, v values.Value) {
+		spec.LabelColumns = append(spec.LabelColumns, v.Str())
+	})
+
+	if spec.LineColumn, err = args.GetRequiredString("lineColumn"); err != nil {
+		return nil, err
+	}
+	if spec.TimeColumn, err = args.GetRequiredString("timeColumn"); err != nil {
+		return nil, err
+	}
+
+	if spec.Format, err = args.GetRequiredString("format"); err != nil {
+		return nil, err
+	} else if spec.Format != formatJSON && spec.Format != formatProtobuf {
+		return nil, errors.Newf(codes.Invalid, "format must be %q or %q, got %q", formatJSON, formatProtobuf, spec.Format)
+	}
+
+	if spec.BatchSize, err = args.GetRequiredInt("batchSize"); err != nil {
+		return nil, err
+	} else if spec.BatchSize <= 0 {
+		return nil, errors.New(codes.Invalid, "batchSize must be greater than zero")
+	}
+
+	if spec.MaxRetries, err = args.GetRequiredInt("maxRetries"); err != nil {
+		return nil, err
+	} else if spec.MaxRetries < 0 {
+		return nil, errors.New(codes.Invalid, "maxRetries must not be negative")
+	}
+	return spec, nil
+}
+
+func (s *ToOpSpec) Kind() flux.OperationKind {
+	return ToKind
+}
+
+type ToProcedureSpec struct {
+	plan.DefaultCost
+	Spec *ToOpSpec
+}
+
+func newToProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ToOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ToProcedureSpec{Spec: spec}, nil
+}
+
+func (s *ToProcedureSpec) Kind() plan.ProcedureKind {
+	return ToKind
+}
+
+func (s *ToProcedureSpec) Copy() plan.ProcedureSpec {
+	spec := *s.Spec
+	spec.LabelColumns = append([]string(nil), s.Spec.LabelColumns...)
+	return &ToProcedureSpec{Spec: &spec}
+}
+
+func createToTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ToProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return newToTransformation(a.Context(), id, s.Spec, a.Allocator())
+}
+
+// toTransformation pushes the rows of every table to the push API
+// and passes the tables through unchanged.
+type toTransformation struct {
+	ctx    context.Context
+	spec   *ToOpSpec
+	client *client
+	batch  *pushBatch
+}
+
+func newToTransformation(ctx context.Context, id execute.DatasetID, spec *ToOpSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	c, err := newClient(ctx, spec.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &toTransformation{
+		ctx:    ctx,
+		spec:   spec,
+		client: c,
+		batch:  newPushBatch(),
+	}
+	return execute.NewNarrowTransformation(id, t, mem)
+}
+
+func (t *toTransformation) Process(chunk table.Chunk, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	if err := t.writeChunk(chunk); err != nil {
+		return err
+	}
+	chunk.Retain()
+	return d.Process(chunk)
+}
+
+// writeChunk adds the rows of the chunk to the batch and pushes
+// the batch every time it reaches the batch size. Rows with a
+// null time or line are skipped.
+func (t *toTransformation) writeChunk(chunk table.Chunk) error {
+	cols := chunk.Cols()
+	timeIdx := execute.ColIdx(t.spec.TimeColumn, cols)
+	if timeIdx < 0 {
+		return errors.Newf(codes.Invalid, "no column with label %s exists", t.spec.TimeColumn)
+	} else if cols[timeIdx].Type != flux.TTime {
+		return errors.Newf(codes.Invalid, "column %s of type %s is not of type %s", t.spec.TimeColumn, cols[timeIdx].Type, flux.TTime)
+	}
+	lineIdx := execute.ColIdx(t.spec.LineColumn, cols)
+	if lineIdx < 0 {
+		return errors.Newf(codes.Invalid, "no column with label %s exists", t.spec.LineColumn)
+	}
+	labelIdxs := t.labelColumns(chunk.Key(), cols)
+
+	buf := chunk.Buffer()
+	for i := 0; i < chunk.Len(); i++ {
+		ts := execute.ValueForRow(&buf, i, timeIdx)
+		line := execute.ValueForRow(&buf, i, lineIdx)
+		if ts.IsNull() || line.IsNull() {
+			continue
+		}
+		lineStr, err := values.Stringify(line)
+		if err != nil {
+			return errors.Newf(codes.Invalid, "column %s of type %s cannot be used as a log line", t.spec.LineColumn, cols[lineIdx].Type)
+		}
+
+		labels := make(map[string]string, len(labelIdxs))
+		for _, j := range labelIdxs {
+			v := execute.ValueForRow(&buf, i, j)
+			if v.IsNull() {
+				continue
+			}
+			s, err := values.Stringify(v)
+			if err != nil {
+				return errors.Newf(codes.Invalid, "column %s of type %s cannot be used as a label", cols[j].Label, cols[j].Type)
+			}
+			if s.Str() != "" {
+				labels[cols[j].Label] = s.Str()
+			}
+		}
+
+		t.batch.add(labels, ts.Time(), lineStr.Str())
+		if int64(t.batch.size) >= t.spec.BatchSize {
+			if err := t.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// labelColumns returns the indexes of the columns that are used as labels.
+// When no label columns are configured, the string columns of the group key
+// are used. Configured columns that are not in the table are ignored.
+func (t *toTransformation) labelColumns(key flux.GroupKey, cols []flux.ColMeta) []int {
+	var idxs []int
+	if len(t.spec.LabelColumns) == 0 {
+		for _, c := range key.Cols() {
+			if c.Type == flux.TString && c.Label != t.spec.LineColumn {
+				idxs = append(idxs, execute.ColIdx(c.Label, cols))
+			}
+		}
+		return idxs
+	}
+	for _, label := range t.spec.LabelColumns {
+		if j := execute.ColIdx(label, cols); j >= 0 {
+			idxs = append(idxs, j)
+		}
+	}
+	return idxs
+}
+
+// flush pushes the pending entries and resets the batch.
+func (t *toTransformation) flush() error {
+	if t.batch.size == 0 {
+		return nil
+	}
+	var (
+		body        []byte
+		contentType string
+	)
+	switch t.spec.Format {
+	case formatProtobuf:
+		body, contentType = snappy.Encode(nil, t.batch.protobuf()), "application/x-protobuf"
+	default:
+		b, err := t.batch.json()
+		if err != nil {
+			return err
+		}
+		body, contentType = b, "application/json"
+	}
+	t.batch.reset()
+	return t.client.push(t.ctx, t.spec.Path, contentType, body, int(t.spec.MaxRetries))
+}
+
+// Close pushes the entries that remain in the batch.
+func (t *toTransformation) Close() error {
+	return t.flush()
+}
+
+// pushStream is a stream of log entries that share the same labels.
+type pushStream struct {
+	labels  map[string]string
+	entries []pushEntry
+}
+
+type pushEntry struct {
+	ts   values.Time
+	line string
+}
+
+// pushBatch collects entries by stream in the order the streams are first seen.
+type pushBatch struct {
+	streams []*pushStream
+	index   map[string]*pushStream
+	size    int
+}
+
+func newPushBatch() *pushBatch {
+	return &pushBatch{index: make(map[string]*pushStream)}
+}
+
+func (b *pushBatch) add(labels map[string]string, ts values.Time, line string) {
+	key := formatLabels(labels)
+	s, ok := b.index[key]
+	if !ok {
+		s = &pushStream{labels: labels}
+		b.index[key] = s
+		b.streams = append(b.streams, s)
+	}
+	s.entries = append(s.entries, pushEntry{ts: ts, line: line})
+	b.size++
+}
+
+func (b *pushBatch) reset() {
+	b.streams = b.streams[:0]
+	b.index = make(map[string]*pushStream)
+	b.size = 0
+}
+
+// json encodes the batch as the JSON body of the push API.
+func (b *pushBatch) json() ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	req := struct {
+		Streams []stream `json:"streams"`
+	}{Streams: make([]stream, 0, len(b.streams))}
+	for _, s := range b.streams {
+		vs := make([][2]string, 0, len(s.entries))
+		for _, e := range s.entries {
+			vs = append(vs, [2]string{strconv.FormatInt(int64(e.ts), 10), e.line})
+		}
+		req.Streams = append(req.Streams, stream{Stream: s.labels, Values: vs})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "failed to encode logql push request")
+	}
+	return body, nil
+}
+
+// protobuf encodes the batch as a logproto.PushRequest.
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func (b *pushBatch) protobuf() []byte {
+	var req []byte
+	for _, s := range b.streams {
+		var stream []byte
+		stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+		stream = protowire.AppendString(stream, formatLabels(s.labels))
+		for _, e := range s.entries {
+			var ts []byte
+			if secs := int64(e.ts) / int64(time.Second); secs != 0 {
+				ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+				ts = protowire.AppendVarint(ts, uint64(secs))
+			}
+			if nanos := int64(e.ts) % int64(time.Second); nanos != 0 {
+				ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+				ts = protowire.AppendVarint(ts, uint64(nanos))
+			}
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, ts)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendString(entry, e.line)
+
+			stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+			stream = protowire.AppendBytes(stream, entry)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, stream)
+	}
+	return req
+}
+
+// formatLabels formats labels as a stream selector with the labels sorted by name.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(labels[name]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// push sends a POST request with the body to the path. Requests that
+// fail with a status of 429 or 5xx are retried up to maxRetries times.
+func (c *client) push(ctx context.Context, path, contentType string, body []byte, maxRetries int) error {
+	u := *c.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+
+	return promapi.Write(ctx, c.http, "logql push", int64(maxRetries), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		if c.config.OrgID != "" {
+			req.Header.Set("X-Scope-OrgID", c.config.OrgID)
+		}
+		return req, nil
+	})
+}
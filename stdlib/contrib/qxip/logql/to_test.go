@@ -0,0 +1,256 @@
+package logql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/golang/snappy"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// pushRequest is a request received by the push test server.
+type pushRequest struct {
+	contentType string
+	orgID       string
+	body        []byte
+}
+
+// newPushServer returns a server that records every request
+// and responds with the next status in the list or 204.
+func newPushServer(t *testing.T, statuses ...int) (*httptest.Server, *[]pushRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []pushRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/loki/api/v1/push" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		reqs = append(reqs, pushRequest{
+			contentType: r.Header.Get("Content-Type"),
+			orgID:       r.Header.Get("X-Scope-OrgID"),
+			body:        body,
+		})
+		status := http.StatusNoContent
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &reqs
+}
+
+func newToTestSpec(url string) *ToOpSpec {
+	return &ToOpSpec{
+		Config:       Config{URL: url, OrgID: "tenant"},
+		Path:         "/loki/api/v1/push",
+		LabelColumns: []string{},
+		LineColumn:   "_value",
+		TimeColumn:   "_time",
+		Format:       formatJSON,
+		BatchSize:    1000,
+		MaxRetries:   3,
+	}
+}
+
+// toTestTable returns the input table of the tests.
+func toTestTable() *executetest.Table {
+	return &executetest.Table{
+		KeyCols: []string{"job"},
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "job", Type: flux.TString},
+			{Label: "level", Type: flux.TString},
+			{Label: "_value", Type: flux.TString},
+		},
+		Data: [][]interface{}{
+			{execute.Time(1672531200000000000), "api", "info", "started"},
+			{execute.Time(1672531201000000000), "api", "error", "failed"},
+			{execute.Time(1672531202500000000), "api", "info", "stopped"},
+			{nil, "api", "info", "skipped"},
+		},
+	}
+}
+
+func runTo(t *testing.T, spec *ToOpSpec, wantErr error) {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	executetest.ProcessTestHelper2(
+		t,
+		[]flux.Table{toTestTable()},
+		[]*executetest.Table{toTestTable()},
+		wantErr,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := newToTransformation(ctx, id, spec, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+}
+
+func TestTo_JSON(t *testing.T) {
+	server, reqs := newPushServer(t)
+	spec := newToTestSpec(server.URL)
+	spec.LabelColumns = []string{"job", "level", "missing"}
+	spec.BatchSize = 2
+	runTo(t, spec, nil)
+
+	want := []pushRequest{
+		{
+			contentType: "application/json",
+			orgID:       "tenant",
+			body: []byte(`{"streams":[` +
+				`{"stream":{"job":"api","level":"info"},"values":[["1672531200000000000","started"]]},` +
+				`{"stream":{"job":"api","level":"error"},"values":[["1672531201000000000","failed"]]}]}`),
+		},
+		{
+			contentType: "application/json",
+			orgID:       "tenant",
+			body:        []byte(`{"streams":[{"stream":{"job":"api","level":"info"},"values":[["1672531202500000000","stopped"]]}]}`),
+		},
+	}
+	if !cmp.Equal(want, *reqs, cmp.AllowUnexported(pushRequest{})) {
+		t.Errorf("unexpected requests -want/+got:\n%s", cmp.Diff(want, *reqs, cmp.AllowUnexported(pushRequest{})))
+	}
+}
+
+// protoEntry is a decoded logproto entry.
+type protoEntry struct {
+	Labels string
+	Secs   int64
+	Nanos  int64
+	Line   string
+}
+
+// decodePushRequest decodes the fields of a logproto.PushRequest used by the tests.
+func decodePushRequest(t *testing.T, b []byte) []protoEntry {
+	t.Helper()
+	fields := func(b []byte, f func(num protowire.Number, v []byte, n uint64)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				f(num, v, 0)
+				b = b[n:]
+			case protowire.VarintType:
+				v, n := protowire.ConsumeVarint(b)
+				f(num, nil, v)
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", typ)
+			}
+		}
+	}
+
+	var entries []protoEntry
+	fields(b, func(_ protowire.Number, stream []byte, _ uint64) {
+		var labels string
+		fields(stream, func(num protowire.Number, v []byte, _ uint64) {
+			if num == 1 {
+				labels = string(v)
+				return
+			}
+			e := protoEntry{Labels: labels}
+			fields(v, func(num protowire.Number, v []byte, _ uint64) {
+				if num == 2 {
+					e.Line = string(v)
+					return
+				}
+				fields(v, func(num protowire.Number, _ []byte, n uint64) {
+					if num == 1 {
+						e.Secs = int64(n)
+					} else {
+						e.Nanos = int64(n)
+					}
+				})
+			})
+			entries = append(entries, e)
+		})
+	})
+	return entries
+}
+
+func TestTo_Protobuf(t *testing.T) {
+	server, reqs := newPushServer(t)
+	spec := newToTestSpec(server.URL)
+	spec.Format = formatProtobuf
+	runTo(t, spec, nil)
+
+	if len(*reqs) != 1 {
+		t.Fatalf("unexpected number of requests: %d", len(*reqs))
+	}
+	req := (*reqs)[0]
+	if want, got := "application/x-protobuf", req.contentType; want != got {
+		t.Errorf("unexpected content type -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	body, err := snappy.Decode(nil, req.body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without label columns the string columns of the group key are used.
+	want := []protoEntry{
+		{Labels: `{job="api"}`, Secs: 1672531200, Line: "started"},
+		{Labels: `{job="api"}`, Secs: 1672531201, Line: "failed"},
+		{Labels: `{job="api"}`, Secs: 1672531202, Nanos: 500000000, Line: "stopped"},
+	}
+	if got := decodePushRequest(t, body); !cmp.Equal(want, got) {
+		t.Errorf("unexpected entries -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestTo_Retry(t *testing.T) {
+	defer func(d time.Duration) { promapi.RetryDelay = d }(promapi.RetryDelay)
+	promapi.RetryDelay = time.Millisecond
+
+	t.Run("succeeds after retry", func(t *testing.T) {
+		server, reqs := newPushServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+		runTo(t, newToTestSpec(server.URL), nil)
+		if want, got := 3, len(*reqs); want != got {
+			t.Errorf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+	})
+
+	t.Run("fails after max retries", func(t *testing.T) {
+		server, reqs := newPushServer(t, http.StatusBadGateway, http.StatusBadGateway)
+		spec := newToTestSpec(server.URL)
+		spec.MaxRetries = 1
+		runTo(t, spec, errors.New("logql push failed with status 502: 502 Bad Gateway"))
+		if want, got := 2, len(*reqs); want != got {
+			t.Errorf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, reqs := newPushServer(t, http.StatusBadRequest)
+		runTo(t, newToTestSpec(server.URL), errors.New("logql push failed with status 400: 400 Bad Request"))
+		if want, got := 1, len(*reqs); want != got {
+			t.Errorf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const SearchKind = pkgName + ".search"
+
+const searchPath = "/api/search"
+
+type SearchOpSpec struct {
+	Config
+	Query       string
+	Tags        string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Limit       int64
+	Start       values.Time
+	End         values.Time
+}
+
+func init() {
+	searchSignature := runtime.MustLookupBuiltinType(pkgName, "_search")
+	runtime.RegisterPackageValue(pkgName, "_search", flux.MustValue(flux.FunctionValue(SearchKind, createSearchOpSpec, searchSignature)))
+	plan.RegisterProcedureSpec(SearchKind, newSearchProcedure, SearchKind)
+	execute.RegisterSource(SearchKind, createSearchSource)
+}
+
+// getDuration reads an optional duration argument.
+func getDuration(args flux.Arguments, name string) (time.Duration, error) {
+	v, ok := args.Get(name)
+	if !ok || v.IsNull() {
+		return 0, nil
+	}
+	d := v.Duration()
+	if d.IsNegative() || !d.NanoOnly() {
+		return 0, errors.Newf(codes.Invalid, "%s must be a positive duration without months or years", name)
+	}
+	return d.Duration(), nil
+}
+
+// getTime reads a required time argument.
+func getTime(args flux.Arguments, name string) (values.Time, error) {
+	v, err := args.GetRequired(name)
+	if err != nil {
+		return 0, err
+	}
+	if v.IsNull() {
+		return 0, errors.Newf(codes.Invalid, "%s must not be null", name)
+	}
+	return v.Time(), nil
+}
+
+func createSearchOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(SearchOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	if q, ok, err := args.GetString("q"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Query = q
+	}
+
+	if tags, ok, err := args.GetString("tags"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Tags = tags
+	}
+
+	if spec.MinDuration, err = getDuration(args, "minDuration"); err != nil {
+		return nil, err
+	}
+	if spec.MaxDuration, err = getDuration(args, "maxDuration"); err != nil {
+		return nil, err
+	}
+
+	if limit, err := args.GetRequiredInt("limit"); err != nil {
+		return nil, err
+	} else if limit <= 0 {
+		return nil, errors.New(codes.Invalid, "limit must be greater than zero")
+	} else {
+		spec.Limit = limit
+	}
+
+	if spec.Start, err = getTime(args, "start"); err != nil {
+		return nil, err
+	}
+	if spec.End, err = getTime(args, "end"); err != nil {
+		return nil, err
+	}
+	if spec.End < spec.Start {
+		return nil, errors.New(codes.Invalid, "end must not be before start")
+	}
+	return spec, nil
+}
+
+func (s *SearchOpSpec) Kind() flux.OperationKind {
+	return SearchKind
+}
+
+type SearchProcedureSpec struct {
+	plan.DefaultCost
+	Config
+	Query       string
+	Tags        string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Limit       int64
+	Start       values.Time
+	End         values.Time
+}
+
+func newSearchProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*SearchOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &SearchProcedureSpec{
+		Config:      spec.Config,
+		Query:       spec.Query,
+		Tags:        spec.Tags,
+		MinDuration: spec.MinDuration,
+		MaxDuration: spec.MaxDuration,
+		Limit:       spec.Limit,
+		Start:       spec.Start,
+		End:         spec.End,
+	}, nil
+}
+
+func (s *SearchProcedureSpec) Kind() plan.ProcedureKind {
+	return SearchKind
+}
+
+func (s *SearchProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(SearchProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func createSearchSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*SearchProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	c, err := newClient(a.Context(), spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	iterator := &searchIterator{
+		spec:   spec,
+		client: c,
+		mem:    a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*searchIterator)(nil)
+
+type searchIterator struct {
+	spec   *SearchProcedureSpec
+	client *client
+	mem    memory.Allocator
+}
+
+// searchResponse is the response of the search API.
+type searchResponse struct {
+	Traces []struct {
+		TraceID           string      `json:"traceID"`
+		RootServiceName   string      `json:"rootServiceName"`
+		RootTraceName     string      `json:"rootTraceName"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		DurationMs        json.Number `json:"durationMs"`
+	} `json:"traces"`
+}
+
+// searchColumns are the columns of the search table.
+var searchColumns = []flux.ColMeta{
+	{Label: traceIDCol, Type: flux.TString},
+	{Label: serviceCol, Type: flux.TString},
+	{Label: operationCol, Type: flux.TString},
+	{Label: execute.DefaultTimeColLabel, Type: flux.TTime},
+	{Label: durationCol, Type: flux.TInt},
+}
+
+// Do produces a single table with a row for each trace that matches the search.
+func (s *searchIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	params := url.Values{
+		"limit": []string{strconv.FormatInt(s.spec.Limit, 10)},
+		// The search API expects unix seconds.
+		"start": []string{strconv.FormatInt(s.spec.Start.Time().Unix(), 10)},
+		"end":   []string{strconv.FormatInt(ceilSeconds(s.spec.End), 10)},
+	}
+	if s.spec.Query != "" {
+		params.Set("q", s.spec.Query)
+	}
+	if s.spec.Tags != "" {
+		params.Set("tags", s.spec.Tags)
+	}
+	if s.spec.MinDuration > 0 {
+		params.Set("minDuration", s.spec.MinDuration.String())
+	}
+	if s.spec.MaxDuration > 0 {
+		params.Set("maxDuration", s.spec.MaxDuration.String())
+	}
+
+	var resp searchResponse
+	if err := s.client.get(ctx, searchPath, params, &resp); err != nil {
+		return err
+	}
+
+	rows := make([]row, 0, len(resp.Traces))
+	for _, t := range resp.Traces {
+		r := row{values: map[string]values.Value{
+			traceIDCol:   values.NewString(normalizeID(t.TraceID, 16)),
+			serviceCol:   values.NewString(t.RootServiceName),
+			operationCol: values.NewString(t.RootTraceName),
+		}}
+		if t.StartTimeUnixNano != "" {
+			ns, err := strconv.ParseInt(t.StartTimeUnixNano, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, codes.Internal, "invalid tempo trace start time %q", t.StartTimeUnixNano)
+			}
+			r.values[execute.DefaultTimeColLabel] = values.NewTime(values.Time(ns))
+		}
+		if t.DurationMs != "" {
+			ms, err := t.DurationMs.Int64()
+			if err != nil {
+				return errors.Wrapf(err, codes.Internal, "invalid tempo trace duration %q", t.DurationMs)
+			}
+			r.values[durationCol] = values.NewInt(ms * int64(time.Millisecond))
+		}
+		rows = append(rows, r)
+	}
+
+	tbl, err := buildTable(s.mem, searchColumns, rows)
+	if err != nil {
+		return err
+	}
+	return f(tbl)
+}
+
+// ceilSeconds rounds a time up to the next unix second
+// so the end of the time range is included in the search.
+func ceilSeconds(t values.Time) int64 {
+	secs := int64(t) / int64(time.Second)
+	if int64(t)%int64(time.Second) > 0 {
+		secs++
+	}
+	return secs
+}
@@ -0,0 +1,281 @@
+package tempo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	fluxhttp "github.com/InfluxCommunity/flux/dependencies/http"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const pkgName = "contrib/qxip/tempo"
+
+// Column labels of the span and trace tables.
+const (
+	traceIDCol      = "trace_id"
+	spanIDCol       = "span_id"
+	parentSpanIDCol = "parent_span_id"
+	serviceCol      = "service"
+	operationCol    = "operation"
+	kindCol         = "kind"
+	statusCol       = "status"
+	durationCol     = "duration"
+)
+
+// maxErrorBody is the maximum number of bytes read from
+// an error response to construct the error message.
+const maxErrorBody = 4096
+
+// Config contains the options that are common to
+// every request made to a Tempo compatible API.
+type Config struct {
+	URL   string
+	OrgID string
+}
+
+// getConfig reads the arguments that are shared by every function.
+func getConfig(args flux.Arguments) (Config, error) {
+	var config Config
+	if u, err := args.GetRequiredString("url"); err != nil {
+		return config, err
+	} else {
+		config.URL = u
+	}
+	if orgID, ok, err := args.GetString("orgid"); err != nil {
+		return config, err
+	} else if ok {
+		config.OrgID = orgID
+	}
+	return config, nil
+}
+
+// client sends requests to a Tempo compatible API.
+type client struct {
+	config Config
+	base   *url.URL
+	http   fluxhttp.Client
+}
+
+// newClient validates the configured url and constructs a client
+// using the http client from the flux dependencies.
+func newClient(ctx context.Context, config Config) (*client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Invalid, "invalid tempo url")
+	}
+	deps := flux.GetDependencies(ctx)
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	if err := validator.Validate(u); err != nil {
+		return nil, err
+	}
+	hc, err := deps.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return &client{config: config, base: u, http: hc}, nil
+}
+
+// get sends a GET request to the path with the parameters
+// and decodes the JSON response into v.
+func (c *client) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	u := *c.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.config.OrgID != "" {
+		req.Header.Set("X-Scope-OrgID", c.config.OrgID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if err != nil {
+			return errors.Newf(codes.Invalid, "error when reading tempo response body: %s", err)
+		}
+		code := codes.Invalid
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			code = codes.NotFound
+		case resp.StatusCode >= 500:
+			code = codes.Unavailable
+		}
+		msg := strings.TrimSpace(string(body))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return errors.Newf(code, "tempo request failed with status %d: %s", resp.StatusCode, msg)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrap(err, codes.Internal, "failed to decode tempo response")
+	}
+	return nil
+}
+
+// normalizeID returns the lower case hex encoding of a trace or span id.
+// Tempo encodes ids as hex without leading zeros in search results
+// and as base64 in OTLP JSON.
+func normalizeID(id string, size int) string {
+	if id == "" {
+		return ""
+	}
+	if len(id) <= size*2 {
+		if _, err := hex.DecodeString(padHex(id)); err == nil {
+			return strings.ToLower(strings.Repeat("0", size*2-len(id)) + id)
+		}
+	}
+	if b, err := base64.StdEncoding.DecodeString(id); err == nil && len(b) == size {
+		return hex.EncodeToString(b)
+	}
+	return strings.ToLower(id)
+}
+
+// padHex pads an odd length hex string so it can be decoded.
+func padHex(s string) string {
+	if len(s)%2 == 1 {
+		return "0" + s
+	}
+	return s
+}
+
+// keyValue is an OTLP attribute.
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue is an OTLP attribute value. Exactly one of the fields is set.
+type anyValue struct {
+	StringValue *string          `json:"stringValue"`
+	BoolValue   *bool            `json:"boolValue"`
+	IntValue    *json.Number     `json:"intValue"`
+	DoubleValue *json.Number     `json:"doubleValue"`
+	ArrayValue  *json.RawMessage `json:"arrayValue"`
+	KvlistValue *json.RawMessage `json:"kvlistValue"`
+	BytesValue  *string          `json:"bytesValue"`
+}
+
+// String formats the attribute value as a string.
+func (v anyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return v.IntValue.String()
+	case v.DoubleValue != nil:
+		return v.DoubleValue.String()
+	case v.ArrayValue != nil:
+		return string(*v.ArrayValue)
+	case v.KvlistValue != nil:
+		return string(*v.KvlistValue)
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	}
+	return ""
+}
+
+// attributes converts a list of OTLP attributes into a map.
+func attributes(kvs []keyValue, into map[string]string) {
+	for _, kv := range kvs {
+		into[kv.Key] = kv.Value.String()
+	}
+}
+
+// row is a single row of an output table. Fixed columns are
+// stored by label and attributes are stored separately so
+// they can be added as columns after all rows are known.
+type row struct {
+	values     map[string]values.Value
+	attributes map[string]string
+}
+
+// buildTable constructs a single table without a group key.
+// The fixed columns come first in order followed by a string
+// column for each attribute sorted by name. Attributes that
+// conflict with a fixed column are dropped.
+func buildTable(mem memory.Allocator, fixed []flux.ColMeta, rows []row) (flux.Table, error) {
+	reserved := make(map[string]bool, len(fixed))
+	for _, c := range fixed {
+		reserved[c.Label] = true
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range rows {
+		for name := range r.attributes {
+			if !reserved[name] && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	builder := execute.NewColListTableBuilder(execute.NewGroupKey(nil, nil), mem)
+	for _, c := range fixed {
+		if _, err := builder.AddCol(c); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		if _, err := builder.AddCol(flux.ColMeta{Label: name, Type: flux.TString}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range rows {
+		for j, c := range fixed {
+			v, ok := r.values[c.Label]
+			if !ok {
+				if err := builder.AppendNil(j); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := builder.AppendValue(j, v); err != nil {
+				return nil, err
+			}
+		}
+		for j, name := range names {
+			j += len(fixed)
+			v, ok := r.attributes[name]
+			if !ok {
+				if err := builder.AppendNil(j); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := builder.AppendString(j, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return builder.Table()
+}
@@ -0,0 +1,187 @@
+package tempo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeID(t *testing.T) {
+	for _, tc := range []struct {
+		id   string
+		size int
+		want string
+	}{
+		{id: "2f3e0cee77ae5dc9c17ade3689eb2e54", size: 16, want: "2f3e0cee77ae5dc9c17ade3689eb2e54"},
+		{id: "F3E0CEE77AE5DC9C17ADE3689EB2E54", size: 16, want: "0f3e0cee77ae5dc9c17ade3689eb2e54"},
+		{id: "Lz4M7neuXcnBet42iesuVA==", size: 16, want: "2f3e0cee77ae5dc9c17ade3689eb2e54"},
+		{id: "wXreNonrLlQ=", size: 8, want: "c17ade3689eb2e54"},
+	} {
+		if got := normalizeID(tc.id, tc.size); got != tc.want {
+			t.Errorf("unexpected id for %q -want/+got:\n\t- %s\n\t+ %s", tc.id, tc.want, got)
+		}
+	}
+}
+
+func runIterator(t *testing.T, config Config, itr func(c *client) execute.SourceIterator) []*executetest.Table {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	c, err := newClient(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []*executetest.Table
+	if err := itr(c).Do(ctx, func(tbl flux.Table) error {
+		cpy, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, cpy)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(tables)
+	return tables
+}
+
+func TestSearch(t *testing.T) {
+	var req *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		_, _ = w.Write([]byte(`{"traces":[
+			{"traceID":"2f3e0cee77ae5dc9c17ade3689eb2e54","rootServiceName":"api","rootTraceName":"GET /users","startTimeUnixNano":"1672531200000000000","durationMs":120},
+			{"traceID":"f3e0cee77ae5dc9","rootServiceName":"web","rootTraceName":"render","startTimeUnixNano":"1672531201000000000"}
+		]}`))
+	}))
+	defer server.Close()
+
+	config := Config{URL: server.URL, OrgID: "tenant"}
+	tables := runIterator(t, config, func(c *client) execute.SourceIterator {
+		return &searchIterator{
+			spec: &SearchProcedureSpec{
+				Config: config,
+				Query:  `{ span.http.status_code >= 500 }`,
+				Limit:  20,
+				Start:  values.Time(1672531200000000000),
+				End:    values.Time(1672534800500000000),
+			},
+			client: c,
+			mem:    memory.DefaultAllocator,
+		}
+	})
+
+	if want, got := searchPath, req.URL.Path; want != got {
+		t.Errorf("unexpected path -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	q := req.URL.Query()
+	for name, want := range map[string]string{
+		"q":     `{ span.http.status_code >= 500 }`,
+		"limit": "20",
+		"start": "1672531200",
+		"end":   "1672534801",
+	} {
+		if got := q.Get(name); got != want {
+			t.Errorf("unexpected %s -want/+got:\n\t- %s\n\t+ %s", name, want, got)
+		}
+	}
+	if want, got := "tenant", req.Header.Get("X-Scope-OrgID"); want != got {
+		t.Errorf("unexpected org id -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+
+	want := []*executetest.Table{{
+		ColMeta: append([]flux.ColMeta{}, searchColumns...),
+		Data: [][]interface{}{
+			{"2f3e0cee77ae5dc9c17ade3689eb2e54", "api", "GET /users", values.Time(1672531200000000000), int64(120000000)},
+			{"00000000000000000f3e0cee77ae5dc9", "web", "render", values.Time(1672531201000000000), nil},
+		},
+	}}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+	}
+}
+
+func TestTraces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tracePath+"2f3e0cee77ae5dc9c17ade3689eb2e54" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"batches":[{
+			"resource":{"attributes":[
+				{"key":"service.name","value":{"stringValue":"api"}},
+				{"key":"host","value":{"stringValue":"a"}}
+			]},
+			"scopeSpans":[{"spans":[
+				{
+					"traceId":"Lz4M7neuXcnBet42iesuVA==",
+					"spanId":"wXreNonrLlQ=",
+					"name":"GET /users",
+					"kind":"SPAN_KIND_SERVER",
+					"startTimeUnixNano":"1672531200000000000",
+					"endTimeUnixNano":"1672531200120000000",
+					"attributes":[{"key":"http.status_code","value":{"intValue":"500"}}],
+					"status":{"code":"STATUS_CODE_ERROR"}
+				},
+				{
+					"traceId":"Lz4M7neuXcnBet42iesuVA==",
+					"spanId":"AAAAAAAAAAE=",
+					"parentSpanId":"wXreNonrLlQ=",
+					"name":"SELECT",
+					"kind":3,
+					"startTimeUnixNano":"1672531200010000000",
+					"endTimeUnixNano":"1672531200100000000",
+					"attributes":[{"key":"host","value":{"stringValue":"db"}}],
+					"status":{}
+				}
+			]}]
+		}]}`))
+	}))
+	defer server.Close()
+
+	config := Config{URL: server.URL}
+	tables := runIterator(t, config, func(c *client) execute.SourceIterator {
+		return &tracesIterator{
+			spec: &TracesProcedureSpec{
+				Config: config,
+				IDs:    []string{"2f3e0cee77ae5dc9c17ade3689eb2e54"},
+			},
+			client: c,
+			mem:    memory.DefaultAllocator,
+		}
+	})
+
+	cols := append(append([]flux.ColMeta{}, spanColumns...),
+		flux.ColMeta{Label: "host", Type: flux.TString},
+		flux.ColMeta{Label: "http.status_code", Type: flux.TString},
+		flux.ColMeta{Label: "service.name", Type: flux.TString},
+	)
+	want := []*executetest.Table{{
+		ColMeta: cols,
+		Data: [][]interface{}{
+			{
+				"2f3e0cee77ae5dc9c17ade3689eb2e54", "c17ade3689eb2e54", nil, "api", "GET /users", "server", "error",
+				values.Time(1672531200000000000), int64(120000000),
+				"a", "500", "api",
+			},
+			{
+				"2f3e0cee77ae5dc9c17ade3689eb2e54", "0000000000000001", "c17ade3689eb2e54", "api", "SELECT", "client", "unset",
+				values.Time(1672531200010000000), int64(90000000),
+				"db", nil, "api",
+			},
+		},
+	}}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables))
+	}
+}
@@ -0,0 +1,265 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const TracesKind = pkgName + ".traces"
+
+const tracePath = "/api/traces/"
+
+type TracesOpSpec struct {
+	Config
+	IDs []string
+}
+
+func init() {
+	tracesSignature := runtime.MustLookupBuiltinType(pkgName, "_traces")
+	runtime.RegisterPackageValue(pkgName, "_traces", flux.MustValue(flux.FunctionValue(TracesKind, createTracesOpSpec, tracesSignature)))
+	plan.RegisterProcedureSpec(TracesKind, newTracesProcedure, TracesKind)
+	execute.RegisterSource(TracesKind, createTracesSource)
+}
+
+func createTracesOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(TracesOpSpec)
+
+	config, err := getConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.Config = config
+
+	ids, err := args.GetRequiredArrayAllowEmpty("ids", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	spec.IDs = make([]string, 0, ids.Len())
+	ids.Range(func(i int, v values.Value) {
+		if !v.IsNull() && v.Str() != "" {
+			spec.IDs = append(spec.IDs, v.Str())
+		}
+	})
+	return spec, nil
+}
+
+func (s *TracesOpSpec) Kind() flux.OperationKind {
+	return TracesKind
+}
+
+type TracesProcedureSpec struct {
+	plan.DefaultCost
+	Config
+	IDs []string
+}
+
+func newTracesProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*TracesOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &TracesProcedureSpec{
+		Config: spec.Config,
+		IDs:    spec.IDs,
+	}, nil
+}
+
+func (s *TracesProcedureSpec) Kind() plan.ProcedureKind {
+	return TracesKind
+}
+
+func (s *TracesProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(TracesProcedureSpec)
+	*ns = *s
+	ns.IDs = make([]string, len(s.IDs))
+	copy(ns.IDs, s.IDs)
+	return ns
+}
+
+func createTracesSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*TracesProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	c, err := newClient(a.Context(), spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	iterator := &tracesIterator{
+		spec:   spec,
+		client: c,
+		mem:    a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+var _ execute.SourceIterator = (*tracesIterator)(nil)
+
+type tracesIterator struct {
+	spec   *TracesProcedureSpec
+	client *client
+	mem    memory.Allocator
+}
+
+// traceResponse is the OTLP JSON response of the trace by id API.
+// Older versions of Tempo use instrumentationLibrarySpans
+// and the OTLP specification uses resourceSpans.
+type traceResponse struct {
+	Batches       []resourceSpans `json:"batches"`
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource struct {
+		Attributes []keyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans                  []scopeSpans `json:"scopeSpans"`
+	InstrumentationLibrarySpans []scopeSpans `json:"instrumentationLibrarySpans"`
+}
+
+type scopeSpans struct {
+	Spans []span `json:"spans"`
+}
+
+type span struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	Kind              json.RawMessage `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []keyValue      `json:"attributes"`
+	Status            struct {
+		Code json.RawMessage `json:"code"`
+	} `json:"status"`
+}
+
+// spanColumns are the columns of the spans table.
+var spanColumns = []flux.ColMeta{
+	{Label: traceIDCol, Type: flux.TString},
+	{Label: spanIDCol, Type: flux.TString},
+	{Label: parentSpanIDCol, Type: flux.TString},
+	{Label: serviceCol, Type: flux.TString},
+	{Label: operationCol, Type: flux.TString},
+	{Label: kindCol, Type: flux.TString},
+	{Label: statusCol, Type: flux.TString},
+	{Label: execute.DefaultTimeColLabel, Type: flux.TTime},
+	{Label: durationCol, Type: flux.TInt},
+}
+
+// spanKinds and statusCodes contain the names of the
+// OTLP enums when they are encoded as integers.
+var (
+	spanKinds   = []string{"unspecified", "internal", "server", "client", "producer", "consumer"}
+	statusCodes = []string{"unset", "ok", "error"}
+)
+
+// Do produces a single table with a row for each span of the traces.
+func (t *tracesIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	var rows []row
+	for _, id := range t.spec.IDs {
+		var resp traceResponse
+		if err := t.client.get(ctx, tracePath+url.PathEscape(id), nil, &resp); err != nil {
+			return err
+		}
+		for _, rs := range append(resp.Batches, resp.ResourceSpans...) {
+			resource := make(map[string]string, len(rs.Resource.Attributes))
+			attributes(rs.Resource.Attributes, resource)
+			for _, ss := range append(rs.ScopeSpans, rs.InstrumentationLibrarySpans...) {
+				for _, s := range ss.Spans {
+					r, err := spanRow(s, resource)
+					if err != nil {
+						return err
+					}
+					rows = append(rows, r)
+				}
+			}
+		}
+	}
+
+	tbl, err := buildTable(t.mem, spanColumns, rows)
+	if err != nil {
+		return err
+	}
+	return f(tbl)
+}
+
+// spanRow flattens a span into a row. Span attributes take
+// precedence over the attributes of the resource.
+func spanRow(s span, resource map[string]string) (row, error) {
+	attrs := make(map[string]string, len(resource)+len(s.Attributes))
+	for k, v := range resource {
+		attrs[k] = v
+	}
+	attributes(s.Attributes, attrs)
+
+	r := row{
+		values: map[string]values.Value{
+			traceIDCol:   values.NewString(normalizeID(s.TraceID, 16)),
+			spanIDCol:    values.NewString(normalizeID(s.SpanID, 8)),
+			serviceCol:   values.NewString(resource["service.name"]),
+			operationCol: values.NewString(s.Name),
+			kindCol:      values.NewString(enumName(s.Kind, "SPAN_KIND_", spanKinds)),
+			statusCol:    values.NewString(enumName(s.Status.Code, "STATUS_CODE_", statusCodes)),
+		},
+		attributes: attrs,
+	}
+	if s.ParentSpanID != "" {
+		r.values[parentSpanIDCol] = values.NewString(normalizeID(s.ParentSpanID, 8))
+	}
+
+	start, err := parseUnixNano(s.StartTimeUnixNano)
+	if err != nil {
+		return row{}, err
+	}
+	end, err := parseUnixNano(s.EndTimeUnixNano)
+	if err != nil {
+		return row{}, err
+	}
+	r.values[execute.DefaultTimeColLabel] = values.NewTime(values.Time(start))
+	r.values[durationCol] = values.NewInt(end - start)
+	return r, nil
+}
+
+// enumName returns the lower case name of an OTLP enum value
+// that may be encoded either as its name or as an integer.
+func enumName(raw json.RawMessage, prefix string, names []string) string {
+	if len(raw) == 0 {
+		return names[0]
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return strings.ToLower(strings.TrimPrefix(name, prefix))
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil && n >= 0 && n < len(names) {
+		return names[n]
+	}
+	return string(raw)
+}
+
+func parseUnixNano(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	ns, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, codes.Internal, "invalid tempo span timestamp %q", s)
+	}
+	return ns, nil
+}
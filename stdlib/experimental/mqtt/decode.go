@@ -0,0 +1,63 @@
+package mqtt
+
+import (
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/dependencies/mqtt"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/decode"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// payloadDecoder decodes the payload of a message that was received at t.
+type payloadDecoder func(msg mqtt.Message, t values.Time) error
+
+// newPayloadDecoder returns a payloadDecoder that decodes
+// payloads with the decoder into the builders of the cache.
+// The topic of a message is a group key column of its rows.
+func newPayloadDecoder(decoder string, cache execute.TableBuilderCache) payloadDecoder {
+	switch decoder {
+	case DecoderJSON:
+		return func(msg mqtt.Message, t values.Time) error {
+			return decode.JSON(msg.Payload, t, cache, decode.Options{Key: topicKey(msg.Topic)})
+		}
+	case DecoderLineProtocol:
+		return func(msg mqtt.Message, t values.Time) error {
+			return decode.LineProtocol(msg.Payload, t, cache, decode.Options{Key: topicKey(msg.Topic)})
+		}
+	default:
+		return func(msg mqtt.Message, t values.Time) error {
+			return decodeRaw(msg, t, cache)
+		}
+	}
+}
+
+func topicKey(topic string) flux.GroupKey {
+	return execute.NewGroupKey(
+		[]flux.ColMeta{{Label: TopicColLabel, Type: flux.TString}},
+		[]values.Value{values.NewString(topic)},
+	)
+}
+
+// decodeRaw decodes the payload as a string in the _value column.
+func decodeRaw(msg mqtt.Message, t values.Time, cache execute.TableBuilderCache) error {
+	key := topicKey(msg.Topic)
+	builder, created := cache.TableBuilder(key)
+	if created {
+		if err := execute.AddTableKeyCols(key, builder); err != nil {
+			return err
+		}
+		if _, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultTimeColLabel, Type: flux.TTime}); err != nil {
+			return err
+		}
+		if _, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultValueColLabel, Type: flux.TString}); err != nil {
+			return err
+		}
+	}
+	if err := execute.AppendKeyValues(key, builder); err != nil {
+		return err
+	}
+	if err := builder.AppendTime(1, t); err != nil {
+		return err
+	}
+	return builder.AppendString(2, string(msg.Payload))
+}
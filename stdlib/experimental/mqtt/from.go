@@ -0,0 +1,255 @@
+package mqtt
+
+import (
+	"context"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/dependencies/mqtt"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/line"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const (
+	FromMQTTKind = "fromMQTT"
+
+	// DefaultSubscribeDuration is how long mqtt.from
+	// subscribes to the topic if no duration is given.
+	DefaultSubscribeDuration = 10 * time.Second
+
+	// TopicColLabel is the label of the group key column
+	// with the topic of the messages.
+	TopicColLabel = "topic"
+)
+
+// Decoders of the payloads of the messages that mqtt.from receives.
+const (
+	DecoderRaw          = "raw"
+	DecoderJSON         = "json"
+	DecoderLineProtocol = "lineprotocol"
+)
+
+var decoders = []string{DecoderRaw, DecoderJSON, DecoderLineProtocol}
+
+func init() {
+	fromMQTTSignature := runtime.MustLookupBuiltinType("experimental/mqtt", "from")
+
+	runtime.RegisterPackageValue("experimental/mqtt", "from", flux.MustValue(flux.FunctionValue(FromMQTTKind, createFromMQTTOpSpec, fromMQTTSignature)))
+	plan.RegisterProcedureSpec(FromMQTTKind, newFromMQTTProcedure, FromMQTTKind)
+	execute.RegisterSource(FromMQTTKind, createFromMQTTSource)
+}
+
+type FromMQTTOpSpec struct {
+	CommonMQTTOpSpec
+	Topic       string        `json:"topic"`
+	Duration    time.Duration `json:"duration"`
+	MaxMessages int64         `json:"maxMessages"`
+	Decoder     string        `json:"decoder"`
+}
+
+// ReadArgs loads a flux.Arguments into FromMQTTOpSpec.
+// The subscription lasts DefaultSubscribeDuration if no duration is given
+// and the number of messages is not limited if maxMessages is not given.
+func (o *FromMQTTOpSpec) ReadArgs(args flux.Arguments) error {
+	if err := o.CommonMQTTOpSpec.ReadArgs(args); err != nil {
+		return err
+	}
+
+	topic, err := args.GetRequiredString("topic")
+	if err != nil {
+		return err
+	}
+	if topic == "" {
+		return errors.New(codes.Invalid, "empty topic")
+	}
+	o.Topic = topic
+
+	if duration, ok, err := args.GetDuration("duration"); err != nil {
+		return err
+	} else if ok {
+		if !duration.IsPositive() || !duration.NanoOnly() {
+			return errors.New(codes.Invalid, "duration must be a positive duration without months")
+		}
+		o.Duration = duration.Duration()
+	} else {
+		o.Duration = DefaultSubscribeDuration
+	}
+
+	if maxMessages, ok, err := args.GetInt("maxMessages"); err != nil {
+		return err
+	} else if ok {
+		if maxMessages <= 0 {
+			return errors.New(codes.Invalid, "maxMessages must be positive")
+		}
+		o.MaxMessages = maxMessages
+	}
+
+	if decoder, ok, err := args.GetString("decoder"); err != nil {
+		return err
+	} else if ok {
+		o.Decoder = decoder
+	} else {
+		o.Decoder = DecoderRaw
+	}
+	for _, d := range decoders {
+		if o.Decoder == d {
+			return nil
+		}
+	}
+	return errors.Newf(codes.Invalid, "invalid decoder %s, must be one of %v", o.Decoder, decoders)
+}
+
+func createFromMQTTOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	s := new(FromMQTTOpSpec)
+	if err := s.ReadArgs(args); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (FromMQTTOpSpec) Kind() flux.OperationKind {
+	return FromMQTTKind
+}
+
+type FromMQTTProcedureSpec struct {
+	plan.DefaultCost
+	Spec *FromMQTTOpSpec
+}
+
+func (o *FromMQTTProcedureSpec) Kind() plan.ProcedureKind {
+	return FromMQTTKind
+}
+
+func (o *FromMQTTProcedureSpec) Copy() plan.ProcedureSpec {
+	s := *o.Spec
+	return &FromMQTTProcedureSpec{Spec: &s}
+}
+
+func newFromMQTTProcedure(qs flux.OperationSpec, a plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromMQTTOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &FromMQTTProcedureSpec{Spec: spec}, nil
+}
+
+// nowTimeProvider provides wall clock time.
+type nowTimeProvider struct{}
+
+func (nowTimeProvider) CurrentTime() values.Time {
+	return values.ConvertTime(time.Now())
+}
+
+func createFromMQTTSource(s plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := s.(*FromMQTTProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", s)
+	}
+	return NewMQTTSource(spec.Spec, mqtt.GetDialer(a.Context()), nowTimeProvider{}, dsid, a.Allocator())
+}
+
+// NewMQTTSource creates a source that subscribes to the topic of the spec with a client
+// of the dialer. Messages are timestamped with the time provider when they are received.
+func NewMQTTSource(spec *FromMQTTOpSpec, dialer mqtt.Dialer, tp line.TimeProvider, dsid execute.DatasetID, alloc memory.Allocator) (execute.Source, error) {
+	return execute.CreateSourceFromIterator(&mqttIterator{
+		spec:   spec,
+		dialer: dialer,
+		tp:     tp,
+		alloc:  alloc,
+	}, dsid)
+}
+
+// mqttIterator subscribes to a topic until the duration has passed or
+// the maximum number of messages has been received and outputs the
+// tables that the messages decode into.
+type mqttIterator struct {
+	spec   *FromMQTTOpSpec
+	dialer mqtt.Dialer
+	tp     line.TimeProvider
+	alloc  memory.Allocator
+}
+
+func (m *mqttIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	client, err := m.dialer.Dial(ctx, []string{m.spec.Broker}, mqtt.Options{
+		ClientID: m.spec.ClientID,
+		Username: m.spec.Username,
+		Password: m.spec.Password,
+		Timeout:  m.spec.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	subscriber, ok := client.(mqtt.Subscriber)
+	if !ok {
+		return errors.New(codes.Unimplemented, "mqtt client does not support subscribing")
+	}
+
+	cache := execute.NewTableBuilderCache(m.alloc)
+	cache.SetTriggerSpec(plan.DefaultTriggerSpec)
+	if err := m.receive(ctx, subscriber, newPayloadDecoder(m.spec.Decoder, cache)); err != nil {
+		return err
+	}
+
+	return cache.ForEachBuilder(func(key flux.GroupKey, builder execute.TableBuilder) error {
+		tbl, err := builder.Table()
+		if err != nil {
+			return err
+		}
+		return f(tbl)
+	})
+}
+
+// receive decodes the messages of the subscription until it ends.
+func (m *mqttIterator) receive(ctx context.Context, subscriber mqtt.Subscriber, decode payloadDecoder) (err error) {
+	type received struct {
+		msg  mqtt.Message
+		time values.Time
+	}
+	var (
+		messages = make(chan received, 100)
+		done     = make(chan struct{})
+	)
+
+	unsubscribe, err := subscriber.Subscribe(ctx, m.spec.Topic, byte(m.spec.QoS), func(msg mqtt.Message) {
+		select {
+		case messages <- received{msg: msg, time: m.tp.CurrentTime()}:
+		case <-done:
+		}
+	})
+	if err != nil {
+		close(done)
+		return err
+	}
+	defer func() {
+		// The handler is released before unsubscribing, since the
+		// client may handle the reply only after the handler returns.
+		close(done)
+		if uerr := unsubscribe(); uerr != nil && err == nil {
+			err = uerr
+		}
+	}()
+
+	timer := time.NewTimer(m.spec.Duration)
+	defer timer.Stop()
+	for n := int64(0); m.spec.MaxMessages <= 0 || n < m.spec.MaxMessages; n++ {
+		select {
+		case r := <-messages:
+			if err := decode(r.msg, r.time); err != nil {
+				return errors.Wrapf(err, codes.Inherit, "could not decode message of topic %s", r.msg.Topic)
+			}
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package mqtt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	fmqtt "github.com/InfluxCommunity/flux/dependencies/mqtt"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/internal/operation"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/mock"
+	"github.com/InfluxCommunity/flux/querytest"
+	"github.com/InfluxCommunity/flux/stdlib/experimental/mqtt"
+)
+
+func TestFromMQTT_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name:    "from no topic",
+			Raw:     `import "experimental/mqtt" mqtt.from(broker: "tcp://localhost:1883", topic: "")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from invalid decoder",
+			Raw:     `import "experimental/mqtt" mqtt.from(broker: "tcp://localhost:1883", topic: "devices", decoder: "xml")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from zero maxMessages",
+			Raw:     `import "experimental/mqtt" mqtt.from(broker: "tcp://localhost:1883", topic: "devices", maxMessages: 0)`,
+			WantErr: true,
+		},
+		{
+			Name: "from defaults",
+			Raw:  `import "experimental/mqtt" mqtt.from(broker: "tcp://localhost:1883", topic: "devices/#")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromMQTT0",
+						Spec: &mqtt.FromMQTTOpSpec{
+							CommonMQTTOpSpec: mqtt.CommonMQTTOpSpec{
+								Broker:   "tcp://localhost:1883",
+								ClientID: mqtt.DefaultClientID,
+								Timeout:  mqtt.DefaultConnectMQTTTimeout,
+							},
+							Topic:    "devices/#",
+							Duration: mqtt.DefaultSubscribeDuration,
+							Decoder:  mqtt.DecoderRaw,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "from bounded",
+			Raw: `import "experimental/mqtt"
+mqtt.from(broker: "tcp://localhost:1883", topic: "devices/+/readings", qos: 1, duration: 1m, maxMessages: 100, decoder: "json")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromMQTT0",
+						Spec: &mqtt.FromMQTTOpSpec{
+							CommonMQTTOpSpec: mqtt.CommonMQTTOpSpec{
+								Broker:   "tcp://localhost:1883",
+								ClientID: mqtt.DefaultClientID,
+								QoS:      1,
+								Timeout:  mqtt.DefaultConnectMQTTTimeout,
+							},
+							Topic:       "devices/+/readings",
+							Duration:    time.Minute,
+							MaxMessages: 100,
+							Decoder:     mqtt.DecoderJSON,
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+// brokerMock returns a dialer of clients that send the messages
+// to the handler of each subscription. Like a client that handles
+// the messages in order, unsubscribing waits for the handler to return.
+func brokerMock(msgs []fmqtt.Message) mock.MqttDialer {
+	return mock.MqttDialer{
+		DialFn: func(ctx context.Context, brokers []string, options fmqtt.Options) (fmqtt.Client, error) {
+			return mock.MqttClient{
+				SubscribeFn: func(ctx context.Context, topic string, qos byte, handler func(fmqtt.Message)) (func() error, error) {
+					handled := make(chan struct{})
+					go func() {
+						defer close(handled)
+						for _, msg := range msgs {
+							handler(msg)
+						}
+					}()
+					return func() error {
+						<-handled
+						return nil
+					}, nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestFromMQTTSource_Run(t *testing.T) {
+	testCases := []struct {
+		name    string
+		msgs    []fmqtt.Message
+		spec    *mqtt.FromMQTTOpSpec
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "raw",
+			msgs: []fmqtt.Message{
+				{Topic: "devices/a", Payload: []byte("on")},
+				{Topic: "devices/b", Payload: []byte("off")},
+				{Topic: "devices/a", Payload: []byte("off")},
+			},
+			spec: &mqtt.FromMQTTOpSpec{MaxMessages: 3, Decoder: mqtt.DecoderRaw},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"topic"},
+					ColMeta: []flux.ColMeta{
+						{Label: "topic", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"devices/a", execute.Time(0), "on"},
+						{"devices/a", execute.Time(2), "off"},
+					},
+				},
+				{
+					KeyCols: []string{"topic"},
+					ColMeta: []flux.ColMeta{
+						{Label: "topic", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"devices/b", execute.Time(1), "off"},
+					},
+				},
+			},
+		},
+		{
+			name: "max messages",
+			msgs: []fmqtt.Message{
+				{Topic: "devices/a", Payload: []byte("on")},
+				{Topic: "devices/a", Payload: []byte("off")},
+			},
+			spec: &mqtt.FromMQTTOpSpec{MaxMessages: 1, Decoder: mqtt.DecoderRaw},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"topic"},
+					ColMeta: []flux.ColMeta{
+						{Label: "topic", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"devices/a", execute.Time(0), "on"},
+					},
+				},
+			},
+		},
+		{
+			name: "max messages with pending messages",
+			msgs: func() []fmqtt.Message {
+				msgs := make([]fmqtt.Message, 1000)
+				for i := range msgs {
+					msgs[i] = fmqtt.Message{Topic: "devices/a", Payload: []byte("on")}
+				}
+				return msgs
+			}(),
+			spec: &mqtt.FromMQTTOpSpec{MaxMessages: 1, Decoder: mqtt.DecoderRaw},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"topic"},
+					ColMeta: []flux.ColMeta{
+						{Label: "topic", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"devices/a", execute.Time(0), "on"},
+					},
+				},
+			},
+		},
+		{
+			name: "json",
+			msgs: []fmqtt.Message{
+				{Topic: "devices/a", Payload: []byte(`{"temp": 21.5}`)},
+				{Topic: "devices/a", Payload: []byte(`{"ok": true}{"_time": "1970-01-01T00:00:10Z", "temp": null}`)},
+			},
+			spec: &mqtt.FromMQTTOpSpec{MaxMessages: 2, Decoder: mqtt.DecoderJSON},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"topic"},
+					ColMeta: []flux.ColMeta{
+						{Label: "topic", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "temp", Type: flux.TFloat},
+						{Label: "ok", Type: flux.TBool},
+					},
+					Data: [][]interface{}{
+						{"devices/a", execute.Time(0), 21.5, nil},
+						{"devices/a", execute.Time(1), nil, true},
+						{"devices/a", execute.Time(10e9), nil, nil},
+					},
+				},
+			},
+		},
+		{
+			name: "line protocol",
+			msgs: []fmqtt.Message{
+				{Topic: "telegraf", Payload: []byte("cpu,host=a usage=1.5 1000000000\ncpu,host=a usage=2.5")},
+			},
+			spec: &mqtt.FromMQTTOpSpec{MaxMessages: 1, Decoder: mqtt.DecoderLineProtocol},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"_field", "_measurement", "host", "topic"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "topic", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"usage", "cpu", "a", "telegraf", execute.Time(1e9), 1.5},
+						{"usage", "cpu", "a", "telegraf", execute.Time(0), 2.5},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Topic = "#"
+			tc.spec.Duration = time.Minute
+			executetest.RunSourceHelper(t, context.Background(), tc.want, tc.wantErr, func(id execute.DatasetID) execute.Source {
+				src, err := mqtt.NewMQTTSource(tc.spec, brokerMock(tc.msgs), &mock.AscendingTimeProvider{}, id, memory.DefaultAllocator)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return src
+			})
+		})
+	}
+}
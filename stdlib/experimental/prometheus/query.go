@@ -0,0 +1,390 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+const (
+	QueryPrometheusKind      = "queryPrometheus"
+	QueryRangePrometheusKind = "queryRangePrometheus"
+)
+
+const (
+	queryPath      = "/api/v1/query"
+	queryRangePath = "/api/v1/query_range"
+
+	// maxErrorBody is the maximum number of bytes read from
+	// an error response to construct the error message.
+	maxErrorBody = 4096
+)
+
+// QueryConfig contains the options that are common
+// to instant and range queries.
+type QueryConfig struct {
+	URL     string
+	Query   string
+	OrgID   string
+	Headers map[string]string
+}
+
+type QueryPrometheusOpSpec struct {
+	QueryConfig
+	Time values.Time
+}
+
+type QueryRangePrometheusOpSpec struct {
+	QueryConfig
+	Start values.Time
+	End   values.Time
+	Step  time.Duration
+}
+
+func init() {
+	querySignature := runtime.MustLookupBuiltinType("experimental/prometheus", "_query")
+	runtime.RegisterPackageValue("experimental/prometheus", "_query", flux.MustValue(flux.FunctionValue(QueryPrometheusKind, createQueryPrometheusOpSpec, querySignature)))
+	plan.RegisterProcedureSpec(QueryPrometheusKind, newQueryPrometheusProcedure, QueryPrometheusKind)
+	execute.RegisterSource(QueryPrometheusKind, createQueryPrometheusSource)
+
+	queryRangeSignature := runtime.MustLookupBuiltinType("experimental/prometheus", "_queryRange")
+	runtime.RegisterPackageValue("experimental/prometheus", "_queryRange", flux.MustValue(flux.FunctionValue(QueryRangePrometheusKind, createQueryRangePrometheusOpSpec, queryRangeSignature)))
+	plan.RegisterProcedureSpec(QueryRangePrometheusKind, newQueryRangePrometheusProcedure, QueryRangePrometheusKind)
+	execute.RegisterSource(QueryRangePrometheusKind, createQueryRangePrometheusSource)
+}
+
+// getQueryConfig reads the arguments shared by instant and range queries.
+func getQueryConfig(args flux.Arguments) (QueryConfig, error) {
+	var config QueryConfig
+	if u, err := args.GetRequiredString("url"); err != nil {
+		return config, err
+	} else {
+		config.URL = u
+	}
+
+	if query, err := args.GetRequiredString("query"); err != nil {
+		return config, err
+	} else {
+		config.Query = query
+	}
+
+	if orgID, ok, err := args.GetString("orgid"); err != nil {
+		return config, err
+	} else if ok {
+		config.OrgID = orgID
+	}
+
+	if headers, ok, err := args.GetDictionary("headers"); err != nil {
+		return config, err
+	} else if ok && headers.Len() > 0 {
+		config.Headers = make(map[string]string, headers.Len())
+		headers.Range(func(k, v values.Value) {
+			config.Headers[k.Str()] = v.Str()
+		})
+	}
+	return config, nil
+}
+
+// getTime reads a required time argument.
+func getTime(args flux.Arguments, name string) (values.Time, error) {
+	v, err := args.GetRequired(name)
+	if err != nil {
+		return 0, err
+	}
+	if v.IsNull() {
+		return 0, errors.Newf(codes.Invalid, "%s must not be null", name)
+	}
+	return v.Time(), nil
+}
+
+func (c QueryConfig) copy() QueryConfig {
+	if c.Headers != nil {
+		headers := make(map[string]string, len(c.Headers))
+		for k, v := range c.Headers {
+			headers[k] = v
+		}
+		c.Headers = headers
+	}
+	return c
+}
+
+func createQueryPrometheusOpSpec(args flux.Arguments, administration *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(QueryPrometheusOpSpec)
+
+	config, err := getQueryConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.QueryConfig = config
+
+	if spec.Time, err = getTime(args, "time"); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *QueryPrometheusOpSpec) Kind() flux.OperationKind {
+	return QueryPrometheusKind
+}
+
+func createQueryRangePrometheusOpSpec(args flux.Arguments, administration *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(QueryRangePrometheusOpSpec)
+
+	config, err := getQueryConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	spec.QueryConfig = config
+
+	if spec.Start, err = getTime(args, "start"); err != nil {
+		return nil, err
+	}
+	if spec.End, err = getTime(args, "end"); err != nil {
+		return nil, err
+	}
+	if spec.End < spec.Start {
+		return nil, errors.New(codes.Invalid, "end must not be before start")
+	}
+
+	step, err := args.GetRequired("step")
+	if err != nil {
+		return nil, err
+	}
+	if d := step.Duration(); !d.IsPositive() || !d.NanoOnly() {
+		return nil, errors.New(codes.Invalid, "step must be a positive duration without months or years")
+	} else {
+		spec.Step = d.Duration()
+	}
+	return spec, nil
+}
+
+func (s *QueryRangePrometheusOpSpec) Kind() flux.OperationKind {
+	return QueryRangePrometheusKind
+}
+
+type QueryPrometheusProcedureSpec struct {
+	plan.DefaultCost
+	QueryConfig
+	Time values.Time
+}
+
+func newQueryPrometheusProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*QueryPrometheusOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Invalid, "invalid spec type %T", qs)
+	}
+
+	return &QueryPrometheusProcedureSpec{
+		QueryConfig: spec.QueryConfig,
+		Time:        spec.Time,
+	}, nil
+}
+
+func (s *QueryPrometheusProcedureSpec) Kind() plan.ProcedureKind {
+	return QueryPrometheusKind
+}
+
+func (s *QueryPrometheusProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(QueryPrometheusProcedureSpec)
+	*ns = *s
+	ns.QueryConfig = s.QueryConfig.copy()
+	return ns
+}
+
+type QueryRangePrometheusProcedureSpec struct {
+	plan.DefaultCost
+	QueryConfig
+	Start values.Time
+	End   values.Time
+	Step  time.Duration
+}
+
+func newQueryRangePrometheusProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*QueryRangePrometheusOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Invalid, "invalid spec type %T", qs)
+	}
+
+	return &QueryRangePrometheusProcedureSpec{
+		QueryConfig: spec.QueryConfig,
+		Start:       spec.Start,
+		End:         spec.End,
+		Step:        spec.Step,
+	}, nil
+}
+
+func (s *QueryRangePrometheusProcedureSpec) Kind() plan.ProcedureKind {
+	return QueryRangePrometheusKind
+}
+
+func (s *QueryRangePrometheusProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(QueryRangePrometheusProcedureSpec)
+	*ns = *s
+	ns.QueryConfig = s.QueryConfig.copy()
+	return ns
+}
+
+func createQueryPrometheusSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*QueryPrometheusProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Invalid, "invalid spec type %T", prSpec)
+	}
+	iterator := &queryIterator{
+		config: spec.QueryConfig,
+		path:   queryPath,
+		params: url.Values{
+			"time": []string{formatTime(spec.Time)},
+		},
+		mem: a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+func createQueryRangePrometheusSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*QueryRangePrometheusProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Invalid, "invalid spec type %T", prSpec)
+	}
+	iterator := &queryIterator{
+		config: spec.QueryConfig,
+		path:   queryRangePath,
+		params: url.Values{
+			"start": []string{formatTime(spec.Start)},
+			"end":   []string{formatTime(spec.End)},
+			"step":  []string{strconv.FormatFloat(spec.Step.Seconds(), 'f', -1, 64)},
+		},
+		mem: a.Allocator(),
+	}
+	return execute.CreateSourceFromIterator(iterator, dsid)
+}
+
+// formatTime formats a time in the RFC3339 format accepted by the query APIs.
+func formatTime(t values.Time) string {
+	return t.Time().UTC().Format(time.RFC3339Nano)
+}
+
+var _ execute.SourceIterator = (*queryIterator)(nil)
+
+// queryIterator sends a query to the Prometheus HTTP API
+// and produces a table for each series in the result.
+type queryIterator struct {
+	config QueryConfig
+	path   string
+	params url.Values
+	mem    memory.Allocator
+}
+
+// queryResponse is the response of the query and query_range APIs.
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (q *queryIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	resp, err := q.query(ctx)
+	if err != nil {
+		return err
+	}
+
+	tables := promapi.NewTableSet("prometheus", q.mem)
+	if err := tables.AddResult(resp.Data.ResultType, resp.Data.Result); err != nil {
+		return err
+	}
+	return tables.Do(f)
+}
+
+// query sends the request and decodes the response envelope.
+func (q *queryIterator) query(ctx context.Context) (*queryResponse, error) {
+	u, err := url.Parse(q.config.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Invalid, "invalid prometheus url")
+	}
+	deps := flux.GetDependencies(ctx)
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	if err := validator.Validate(u); err != nil {
+		return nil, err
+	}
+	client, err := deps.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + q.path
+	form := url.Values{"query": []string{q.config.Query}}
+	for k, v := range q.params {
+		form[k] = v
+	}
+
+	// The form is sent in the body so long queries do not exceed url length limits.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range q.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if q.config.OrgID != "" {
+		req.Header.Set("X-Scope-OrgID", q.config.OrgID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var qr queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&qr); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, statusError(resp)
+		}
+		return nil, errors.Wrap(err, codes.Internal, "failed to decode prometheus response")
+	}
+	if qr.Status != "success" {
+		code := codes.Invalid
+		if resp.StatusCode >= 500 {
+			code = codes.Unavailable
+		}
+		return nil, errors.Newf(code, "prometheus query failed with status %d: %s: %s", resp.StatusCode, qr.ErrorType, qr.Error)
+	}
+	return &qr, nil
+}
+
+// statusError constructs an error for a response
+// that does not contain an API error.
+func statusError(resp *http.Response) error {
+	code := codes.Invalid
+	if resp.StatusCode >= 500 {
+		code = codes.Unavailable
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = resp.Status
+	}
+	return errors.Newf(code, "prometheus query failed with status %d: %s", resp.StatusCode, msg)
+}
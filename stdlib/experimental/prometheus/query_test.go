@@ -0,0 +1,213 @@
+package prometheus
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	flux "github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func runQueryIterator(t *testing.T, itr *queryIterator) ([]*executetest.Table, error) {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	var tables []*executetest.Table
+	if err := itr.Do(ctx, func(tbl flux.Table) error {
+		cpy, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, cpy)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	executetest.NormalizeTables(tables)
+	return tables, nil
+}
+
+// TestQueryRange will make sure that a matrix result produces a table for each series.
+func TestQueryRange(t *testing.T) {
+	var req *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		req = r
+		if r.URL.Path != queryRangePath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"up","job":"api"},"values":[[1672531200,"1"],[1672531260.5,"0"]]},
+			{"metric":{"__name__":"up","job":"web"},"values":[[1672531200,"NaN"]]}
+		]}}`))
+	}))
+	defer ts.Close()
+
+	start := values.ConvertTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	tables, err := runQueryIterator(t, &queryIterator{
+		config: QueryConfig{
+			URL:     ts.URL,
+			Query:   "up",
+			OrgID:   "tenant",
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		},
+		path: queryRangePath,
+		params: map[string][]string{
+			"start": {formatTime(start)},
+			"end":   {formatTime(start.Add(values.ConvertDurationNsecs(time.Hour)))},
+			"step":  {"60"},
+		},
+		mem: memory.DefaultAllocator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "up", req.PostForm.Get("query"); want != got {
+		t.Errorf("unexpected query -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := "2023-01-01T00:00:00Z", req.PostForm.Get("start"); want != got {
+		t.Errorf("unexpected start -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := "tenant", req.Header.Get("X-Scope-OrgID"); want != got {
+		t.Errorf("unexpected org id -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := "Bearer token", req.Header.Get("Authorization"); want != got {
+		t.Errorf("unexpected authorization -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+
+	cols := []flux.ColMeta{
+		{Label: "__name__", Type: flux.TString},
+		{Label: "job", Type: flux.TString},
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+	}
+	want := []*executetest.Table{
+		{
+			KeyCols:   []string{"__name__", "job"},
+			KeyValues: []interface{}{"up", "api"},
+			ColMeta:   cols,
+			Data: [][]interface{}{
+				{"up", "api", values.Time(1672531200000000000), 1.0},
+				{"up", "api", values.Time(1672531260500000000), 0.0},
+			},
+		},
+		{
+			KeyCols:   []string{"__name__", "job"},
+			KeyValues: []interface{}{"up", "web"},
+			ColMeta:   cols,
+			Data: [][]interface{}{
+				{"up", "web", values.Time(1672531200000000000), math.NaN()},
+			},
+		},
+	}
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, tables, cmpopts.EquateNaNs()) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, tables, cmpopts.EquateNaNs()))
+	}
+}
+
+// TestQuery will make sure that vector and scalar results are decoded.
+func TestQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		want []*executetest.Table
+	}{
+		{
+			name: "vector",
+			body: `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"job":"api"},"value":[1672531200.123,"42"]}
+			]}}`,
+			want: []*executetest.Table{{
+				KeyCols:   []string{"job"},
+				KeyValues: []interface{}{"api"},
+				ColMeta: []flux.ColMeta{
+					{Label: "job", Type: flux.TString},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"api", values.Time(1672531200123000000), 42.0},
+				},
+			}},
+		},
+		{
+			name: "scalar",
+			body: `{"status":"success","data":{"resultType":"scalar","result":[1672531200,"+Inf"]}}`,
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{values.Time(1672531200000000000), math.Inf(1)},
+				},
+			}},
+		},
+		{
+			name: "string",
+			body: `{"status":"success","data":{"resultType":"string","result":[1672531200,"hello"]}}`,
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{values.Time(1672531200000000000), "hello"},
+				},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer ts.Close()
+
+			tables, err := runQueryIterator(t, &queryIterator{
+				config: QueryConfig{URL: ts.URL, Query: "q"},
+				path:   queryPath,
+				mem:    memory.DefaultAllocator,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			executetest.NormalizeTables(tc.want)
+			if !cmp.Equal(tc.want, tables, cmpopts.EquateNaNs()) {
+				t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(tc.want, tables, cmpopts.EquateNaNs()))
+			}
+		})
+	}
+}
+
+// TestQueryError will make sure that API errors are reported.
+func TestQueryError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error at char 3"}`))
+	}))
+	defer ts.Close()
+
+	_, err := runQueryIterator(t, &queryIterator{
+		config: QueryConfig{URL: ts.URL, Query: "up{"},
+		path:   queryPath,
+		mem:    memory.DefaultAllocator,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := "prometheus query failed with status 400: bad_data: parse error at char 3"; err.Error() != want {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, err)
+	}
+}
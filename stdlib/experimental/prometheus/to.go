@@ -0,0 +1,439 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	fluxhttp "github.com/InfluxCommunity/flux/dependencies/http"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const ToPrometheusKind = "toPrometheus"
+
+// metricNameLabel is the label that contains the name of a series.
+const metricNameLabel = "__name__"
+
+type ToPrometheusOpSpec struct {
+	URL          string
+	Name         string
+	NameColumn   string
+	LabelColumns []string
+	ValueColumn  string
+	TimeColumn   string
+	BatchSize    int64
+	MaxRetries   int64
+	OrgID        string
+	Headers      map[string]string
+}
+
+func init() {
+	toSignature := runtime.MustLookupBuiltinType("experimental/prometheus", "_to")
+	runtime.RegisterPackageValue("experimental/prometheus", "_to", flux.MustValue(flux.FunctionValueWithSideEffect(ToPrometheusKind, createToPrometheusOpSpec, toSignature)))
+	plan.RegisterProcedureSpecWithSideEffect(ToPrometheusKind, newToPrometheusProcedure, ToPrometheusKind)
+	execute.RegisterTransformation(ToPrometheusKind, createToPrometheusTransformation)
+}
+
+func createToPrometheusOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := new(ToPrometheusOpSpec)
+
+	var err error
+	if spec.URL, err = args.GetRequiredString("url"); err != nil {
+		return nil, err
+	}
+
+	if name, ok, err := args.GetString("name"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Name = name
+	}
+	if spec.NameColumn, err = args.GetRequiredString("nameColumn"); err != nil {
+		return nil, err
+	}
+
+	labelColumns, err := args.GetRequiredArrayAllowEmpty("labelColumns", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	spec.LabelColumns = make([]string, 0, labelColumns.Len())
+	labelColumns.Range(func(i int, v values.Value) {
+		spec.LabelColumns = append(spec.LabelColumns, v.Str())
+	})
+
+	if spec.ValueColumn, err = args.GetRequiredString("valueColumn"); err != nil {
+		return nil, err
+	}
+	if spec.TimeColumn, err = args.GetRequiredString("timeColumn"); err != nil {
+		return nil, err
+	}
+
+	if spec.BatchSize, err = args.GetRequiredInt("batchSize"); err != nil {
+		return nil, err
+	} else if spec.BatchSize <= 0 {
+		return nil, errors.New(codes.Invalid, "batchSize must be greater than zero")
+	}
+
+	if spec.MaxRetries, err = args.GetRequiredInt("maxRetries"); err != nil {
+		return nil, err
+	} else if spec.MaxRetries < 0 {
+		return nil, errors.New(codes.Invalid, "maxRetries must not be negative")
+	}
+
+	if orgID, ok, err := args.GetString("orgid"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.OrgID = orgID
+	}
+
+	if headers, ok, err := args.GetDictionary("headers"); err != nil {
+		return nil, err
+	} else if ok && headers.Len() > 0 {
+		spec.Headers = make(map[string]string, headers.Len())
+		headers.Range(func(k, v values.Value) {
+			spec.Headers[k.Str()] = v.Str()
+		})
+	}
+	return spec, nil
+}
+
+func (s *ToPrometheusOpSpec) Kind() flux.OperationKind {
+	return ToPrometheusKind
+}
+
+type ToPrometheusProcedureSpec struct {
+	plan.DefaultCost
+	Spec *ToPrometheusOpSpec
+}
+
+func newToPrometheusProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ToPrometheusOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ToPrometheusProcedureSpec{Spec: spec}, nil
+}
+
+func (s *ToPrometheusProcedureSpec) Kind() plan.ProcedureKind {
+	return ToPrometheusKind
+}
+
+func (s *ToPrometheusProcedureSpec) Copy() plan.ProcedureSpec {
+	spec := *s.Spec
+	spec.LabelColumns = append([]string(nil), s.Spec.LabelColumns...)
+	if s.Spec.Headers != nil {
+		spec.Headers = make(map[string]string, len(s.Spec.Headers))
+		for k, v := range s.Spec.Headers {
+			spec.Headers[k] = v
+		}
+	}
+	return &ToPrometheusProcedureSpec{Spec: &spec}
+}
+
+func createToPrometheusTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ToPrometheusProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return newToPrometheusTransformation(a.Context(), id, s.Spec, a.Allocator())
+}
+
+// toPrometheusTransformation sends the rows of every table to a remote
+// write endpoint and passes the tables through unchanged.
+type toPrometheusTransformation struct {
+	ctx    context.Context
+	spec   *ToPrometheusOpSpec
+	url    string
+	client fluxhttp.Client
+	batch  *writeBatch
+}
+
+func newToPrometheusTransformation(ctx context.Context, id execute.DatasetID, spec *ToPrometheusOpSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	u, err := url.Parse(spec.URL)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, codes.Invalid, "invalid prometheus url")
+	}
+	deps := flux.GetDependencies(ctx)
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validator.Validate(u); err != nil {
+		return nil, nil, err
+	}
+	client, err := deps.HTTPClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &toPrometheusTransformation{
+		ctx:    ctx,
+		spec:   spec,
+		url:    u.String(),
+		client: client,
+		batch:  newWriteBatch(),
+	}
+	return execute.NewNarrowTransformation(id, t, mem)
+}
+
+func (t *toPrometheusTransformation) Process(chunk table.Chunk, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	if err := t.writeChunk(chunk); err != nil {
+		return err
+	}
+	chunk.Retain()
+	return d.Process(chunk)
+}
+
+// writeChunk adds the rows of the chunk to the batch and sends the
+// batch every time it reaches the batch size. Rows with a null time
+// or value are skipped.
+func (t *toPrometheusTransformation) writeChunk(chunk table.Chunk) error {
+	cols := chunk.Cols()
+	timeIdx := execute.ColIdx(t.spec.TimeColumn, cols)
+	if timeIdx < 0 {
+		return errors.Newf(codes.Invalid, "no column with label %s exists", t.spec.TimeColumn)
+	} else if cols[timeIdx].Type != flux.TTime {
+		return errors.Newf(codes.Invalid, "column %s of type %s is not of type %s", t.spec.TimeColumn, cols[timeIdx].Type, flux.TTime)
+	}
+	valueIdx := execute.ColIdx(t.spec.ValueColumn, cols)
+	if valueIdx < 0 {
+		return errors.Newf(codes.Invalid, "no column with label %s exists", t.spec.ValueColumn)
+	}
+	switch typ := cols[valueIdx].Type; typ {
+	case flux.TFloat, flux.TInt, flux.TUInt:
+	default:
+		return errors.Newf(codes.Invalid, "column %s of type %s is not numeric", t.spec.ValueColumn, typ)
+	}
+	nameIdx := -1
+	if t.spec.Name == "" {
+		if nameIdx = execute.ColIdx(t.spec.NameColumn, cols); nameIdx < 0 {
+			return errors.Newf(codes.Invalid, "no column with label %s exists", t.spec.NameColumn)
+		} else if cols[nameIdx].Type != flux.TString {
+			return errors.Newf(codes.Invalid, "column %s of type %s is not of type %s", t.spec.NameColumn, cols[nameIdx].Type, flux.TString)
+		}
+	}
+	labelIdxs := t.labelColumns(chunk.Key(), cols)
+
+	buf := chunk.Buffer()
+	for i := 0; i < chunk.Len(); i++ {
+		ts := execute.ValueForRow(&buf, i, timeIdx)
+		v := execute.ValueForRow(&buf, i, valueIdx)
+		if ts.IsNull() || v.IsNull() {
+			continue
+		}
+
+		name := t.spec.Name
+		if nameIdx >= 0 {
+			if nv := execute.ValueForRow(&buf, i, nameIdx); !nv.IsNull() {
+				name = nv.Str()
+			}
+		}
+		if name == "" {
+			return errors.New(codes.Invalid, "metric name must not be empty")
+		}
+
+		labels := []label{{name: metricNameLabel, value: name}}
+		for _, j := range labelIdxs {
+			lv := execute.ValueForRow(&buf, i, j)
+			if lv.IsNull() {
+				continue
+			}
+			s, err := values.Stringify(lv)
+			if err != nil {
+				return errors.Newf(codes.Invalid, "column %s of type %s cannot be used as a label", cols[j].Label, cols[j].Type)
+			}
+			if s.Str() != "" {
+				labels = append(labels, label{name: cols[j].Label, value: s.Str()})
+			}
+		}
+
+		var f float64
+		switch v.Type().Nature() {
+		case semantic.Float:
+			f = v.Float()
+		case semantic.Int:
+			f = float64(v.Int())
+		case semantic.UInt:
+			f = float64(v.UInt())
+		}
+
+		t.batch.add(labels, ts.Time(), f)
+		if int64(t.batch.size) >= t.spec.BatchSize {
+			if err := t.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// labelColumns returns the indexes of the columns that are used as labels.
+// When no label columns are configured, the string columns of the group key
+// other than the name column and _measurement are used. Configured columns
+// that are not in the table are ignored.
+func (t *toPrometheusTransformation) labelColumns(key flux.GroupKey, cols []flux.ColMeta) []int {
+	var idxs []int
+	if len(t.spec.LabelColumns) == 0 {
+		for _, c := range key.Cols() {
+			switch c.Label {
+			case t.spec.NameColumn, t.spec.ValueColumn, "_measurement":
+				continue
+			}
+			if c.Type == flux.TString {
+				idxs = append(idxs, execute.ColIdx(c.Label, cols))
+			}
+		}
+		return idxs
+	}
+	for _, l := range t.spec.LabelColumns {
+		if j := execute.ColIdx(l, cols); j >= 0 {
+			idxs = append(idxs, j)
+		}
+	}
+	return idxs
+}
+
+// flush sends the pending samples and resets the batch.
+func (t *toPrometheusTransformation) flush() error {
+	if t.batch.size == 0 {
+		return nil
+	}
+	body := snappy.Encode(nil, t.batch.protobuf())
+	t.batch.reset()
+	return t.write(body)
+}
+
+// write sends the compressed write request. Requests that fail with
+// a status of 429 or 5xx are retried up to the maximum number of retries.
+func (t *toPrometheusTransformation) write(body []byte) error {
+	return promapi.Write(t.ctx, t.client, "prometheus remote write", t.spec.MaxRetries, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range t.spec.Headers {
+			req.Header.Set(k, v)
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		if t.spec.OrgID != "" {
+			req.Header.Set("X-Scope-OrgID", t.spec.OrgID)
+		}
+		return req, nil
+	})
+}
+
+// Close sends the samples that remain in the batch.
+func (t *toPrometheusTransformation) Close() error {
+	return t.flush()
+}
+
+type label struct {
+	name, value string
+}
+
+type writeSample struct {
+	ts    values.Time
+	value float64
+}
+
+// writeSeries is the samples of a single series.
+type writeSeries struct {
+	labels  []label
+	samples []writeSample
+}
+
+// writeBatch collects samples by series in the order the series are first seen.
+type writeBatch struct {
+	series []*writeSeries
+	index  map[string]*writeSeries
+	size   int
+}
+
+func newWriteBatch() *writeBatch {
+	return &writeBatch{index: make(map[string]*writeSeries)}
+}
+
+// add adds a sample to the series with the labels.
+// Remote write requires the labels to be sorted by name.
+func (b *writeBatch) add(labels []label, ts values.Time, v float64) {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	var key strings.Builder
+	for _, l := range labels {
+		key.WriteString(l.name)
+		key.WriteByte(0xff)
+		key.WriteString(l.value)
+		key.WriteByte(0xff)
+	}
+	s, ok := b.index[key.String()]
+	if !ok {
+		s = &writeSeries{labels: labels}
+		b.index[key.String()] = s
+		b.series = append(b.series, s)
+	}
+	s.samples = append(s.samples, writeSample{ts: ts, value: v})
+	b.size++
+}
+
+func (b *writeBatch) reset() {
+	b.series = b.series[:0]
+	b.index = make(map[string]*writeSeries)
+	b.size = 0
+}
+
+// protobuf encodes the batch as a prometheus.WriteRequest.
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func (b *writeBatch) protobuf() []byte {
+	var req []byte
+	for _, s := range b.series {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+		for _, smp := range s.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(smp.value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(int64(smp.ts)/int64(time.Millisecond)))
+
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sb)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return req
+}
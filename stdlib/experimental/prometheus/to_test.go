@@ -0,0 +1,283 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	flux "github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/internal/promapi"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/golang/snappy"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testSeries is a decoded remote write time series.
+type testSeries struct {
+	Labels  []string
+	Samples []testSample
+}
+
+type testSample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// consumeFields calls f with every field of the message.
+func consumeFields(t *testing.T, b []byte, f func(num protowire.Number, typ protowire.Type, v []byte, n uint64)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			f(num, typ, v, 0)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			f(num, typ, nil, v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			f(num, typ, nil, v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+}
+
+// decodeWriteRequest decodes a snappy compressed remote write request.
+func decodeWriteRequest(t *testing.T, body []byte) []testSeries {
+	t.Helper()
+	b, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var series []testSeries
+	consumeFields(t, b, func(_ protowire.Number, _ protowire.Type, ts []byte, _ uint64) {
+		var s testSeries
+		consumeFields(t, ts, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+			if num == 1 {
+				var name, value string
+				consumeFields(t, v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				s.Labels = append(s.Labels, name+"="+value)
+				return
+			}
+			var smp testSample
+			consumeFields(t, v, func(num protowire.Number, _ protowire.Type, _ []byte, n uint64) {
+				if num == 1 {
+					smp.Value = math.Float64frombits(n)
+				} else {
+					smp.Timestamp = int64(n)
+				}
+			})
+			s.Samples = append(s.Samples, smp)
+		})
+		series = append(series, s)
+	})
+	return series
+}
+
+// writeRequest is a request received by the remote write test server.
+type writeRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newWriteServer returns a server that records every request
+// and responds with the next status in the list or 204.
+func newWriteServer(t *testing.T, statuses ...int) (*httptest.Server, *[]writeRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []writeRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		reqs = append(reqs, writeRequest{header: r.Header, body: body})
+		status := http.StatusNoContent
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &reqs
+}
+
+func newToTestSpec(url string) *ToPrometheusOpSpec {
+	return &ToPrometheusOpSpec{
+		URL:          url + "/api/v1/write",
+		NameColumn:   "_field",
+		LabelColumns: []string{},
+		ValueColumn:  "_value",
+		TimeColumn:   "_time",
+		BatchSize:    1000,
+		MaxRetries:   3,
+	}
+}
+
+// toTestTables returns the input tables of the tests.
+func toTestTables() []*executetest.Table {
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_measurement", Type: flux.TString},
+		{Label: "_field", Type: flux.TString},
+		{Label: "job", Type: flux.TString},
+		{Label: "_value", Type: flux.TFloat},
+	}
+	return []*executetest.Table{
+		{
+			KeyCols: []string{"_measurement", "_field", "job"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(1672531200000000000), "prometheus", "up", "api", 1.0},
+				{execute.Time(1672531215000000000), "prometheus", "up", "api", 0.0},
+				{execute.Time(1672531230000000000), "prometheus", "up", "api", nil},
+			},
+		},
+		{
+			KeyCols: []string{"_measurement", "_field", "job"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(1672531200000000000), "prometheus", "up", "db", 1.0},
+			},
+		},
+	}
+}
+
+func runTo(t *testing.T, spec *ToPrometheusOpSpec, wantErr error) {
+	t.Helper()
+	ctx := flux.NewDefaultDependencies().Inject(context.Background())
+	var input []flux.Table
+	for _, tbl := range toTestTables() {
+		input = append(input, tbl)
+	}
+	executetest.ProcessTestHelper2(
+		t,
+		input,
+		toTestTables(),
+		wantErr,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := newToPrometheusTransformation(ctx, id, spec, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+}
+
+func TestTo(t *testing.T) {
+	server, reqs := newWriteServer(t)
+	spec := newToTestSpec(server.URL)
+	spec.OrgID = "tenant"
+	spec.Headers = map[string]string{"Authorization": "Bearer token"}
+	spec.BatchSize = 2
+	runTo(t, spec, nil)
+
+	if want, got := 2, len(*reqs); want != got {
+		t.Fatalf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+		"X-Scope-Orgid":                     "tenant",
+		"Authorization":                     "Bearer token",
+	} {
+		if got := (*reqs)[0].header.Get(name); want != got {
+			t.Errorf("unexpected %s header -want/+got:\n\t- %s\n\t+ %s", name, want, got)
+		}
+	}
+
+	want := [][]testSeries{
+		{{
+			Labels: []string{"__name__=up", "job=api"},
+			Samples: []testSample{
+				{Value: 1, Timestamp: 1672531200000},
+				{Value: 0, Timestamp: 1672531215000},
+			},
+		}},
+		{{
+			Labels:  []string{"__name__=up", "job=db"},
+			Samples: []testSample{{Value: 1, Timestamp: 1672531200000}},
+		}},
+	}
+	for i, req := range *reqs {
+		if got := decodeWriteRequest(t, req.body); !cmp.Equal(want[i], got) {
+			t.Errorf("unexpected series in request %d -want/+got:\n%s", i, cmp.Diff(want[i], got))
+		}
+	}
+}
+
+func TestTo_NameAndLabels(t *testing.T) {
+	server, reqs := newWriteServer(t)
+	spec := newToTestSpec(server.URL)
+	spec.Name = "service_up"
+	spec.LabelColumns = []string{"_measurement", "job"}
+	runTo(t, spec, nil)
+
+	if want, got := 1, len(*reqs); want != got {
+		t.Fatalf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	want := []testSeries{
+		{
+			Labels: []string{"__name__=service_up", "_measurement=prometheus", "job=api"},
+			Samples: []testSample{
+				{Value: 1, Timestamp: 1672531200000},
+				{Value: 0, Timestamp: 1672531215000},
+			},
+		},
+		{
+			Labels:  []string{"__name__=service_up", "_measurement=prometheus", "job=db"},
+			Samples: []testSample{{Value: 1, Timestamp: 1672531200000}},
+		},
+	}
+	if got := decodeWriteRequest(t, (*reqs)[0].body); !cmp.Equal(want, got) {
+		t.Errorf("unexpected series -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestTo_Retry(t *testing.T) {
+	defer func(d time.Duration) { promapi.RetryDelay = d }(promapi.RetryDelay)
+	promapi.RetryDelay = time.Millisecond
+
+	t.Run("succeeds after retry", func(t *testing.T) {
+		server, reqs := newWriteServer(t, http.StatusTooManyRequests, http.StatusInternalServerError)
+		runTo(t, newToTestSpec(server.URL), nil)
+		if want, got := 3, len(*reqs); want != got {
+			t.Errorf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, reqs := newWriteServer(t, http.StatusBadRequest)
+		runTo(t, newToTestSpec(server.URL), errors.New("prometheus remote write failed with status 400: 400 Bad Request"))
+		if want, got := 1, len(*reqs); want != got {
+			t.Errorf("unexpected number of requests -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+	})
+}
@@ -98,6 +98,11 @@ func (s *FromGeneratorProcedureSpec) Kind() plan.ProcedureKind {
 	return FromGeneratorKind
 }
 
+// Cost reports the number of generated rows as a single table.
+func (s *FromGeneratorProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return plan.Cost{CPU: s.Count}, plan.Statistics{Cardinality: s.Count, GroupCardinality: 1}
+}
+
 func (s *FromGeneratorProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := new(FromGeneratorProcedureSpec)
 
@@ -0,0 +1,611 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	fluxhttp "github.com/InfluxCommunity/flux/dependencies/http"
+	fluxurl "github.com/InfluxCommunity/flux/dependencies/url"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// MaxResponseSize is the maximum number of bytes that http.from reads
+// from the responses of all pages together. The rows of all pages are
+// held in memory until the last page is read, so the limit bounds the
+// size of the table and not only the size of a single page.
+var MaxResponseSize int64 = 100 * 1024 * 1024
+
+const (
+	FromHTTPKind = "fromHTTP"
+
+	// DefaultJSONPath selects the whole response document.
+	DefaultJSONPath = "$"
+
+	// DefaultMaxPages is the number of pages that http.from
+	// follows if no maxPages is given.
+	DefaultMaxPages = 100
+
+	// maxErrorBody is the maximum number of bytes of the body
+	// of an unsuccessful response that is reported in the error.
+	maxErrorBody = 1024
+)
+
+// columnTypes are the names of the types that columns can have.
+var columnTypes = map[string]flux.ColType{
+	"string": flux.TString,
+	"int":    flux.TInt,
+	"uint":   flux.TUInt,
+	"float":  flux.TFloat,
+	"bool":   flux.TBool,
+	"time":   flux.TTime,
+}
+
+func init() {
+	fromHTTPSignature := runtime.MustLookupBuiltinType("http", "from")
+
+	runtime.RegisterPackageValue("http", "from", flux.MustValue(flux.FunctionValue(FromHTTPKind, createFromHTTPOpSpec, fromHTTPSignature)))
+	plan.RegisterProcedureSpec(FromHTTPKind, newFromHTTPProcedure, FromHTTPKind)
+	execute.RegisterSource(FromHTTPKind, createFromHTTPSource)
+}
+
+type FromHTTPOpSpec struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	JSONPath    string            `json:"jsonPath"`
+	Columns     map[string]string `json:"columns,omitempty"`
+	CursorPath  string            `json:"cursorPath,omitempty"`
+	CursorParam string            `json:"cursorParam,omitempty"`
+	MaxPages    int64             `json:"maxPages"`
+}
+
+// ReadArgs loads a flux.Arguments into FromHTTPOpSpec.
+// The paths and the column types are validated before the query runs.
+func (o *FromHTTPOpSpec) ReadArgs(args flux.Arguments) error {
+	var err error
+	if o.URL, err = args.GetRequiredString("url"); err != nil {
+		return err
+	}
+	if _, err := url.Parse(o.URL); err != nil {
+		return errors.Wrap(err, codes.Invalid, "invalid url")
+	}
+
+	if method, ok, err := args.GetString("method"); err != nil {
+		return err
+	} else if ok {
+		o.Method = method
+	} else {
+		o.Method = http.MethodGet
+	}
+	switch o.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		return errors.Newf(codes.Invalid, "invalid HTTP method %q, must be GET or POST", o.Method)
+	}
+
+	if headers, ok, err := args.GetDictionary("headers"); err != nil {
+		return err
+	} else if ok {
+		o.Headers = make(map[string]string, headers.Len())
+		headers.Range(func(k, v values.Value) {
+			o.Headers[k.Str()] = v.Str()
+		})
+	}
+
+	if body, ok := args.Get("body"); ok {
+		o.Body = body.Bytes()
+	}
+
+	if jsonPath, ok, err := args.GetString("jsonPath"); err != nil {
+		return err
+	} else if ok {
+		o.JSONPath = jsonPath
+	} else {
+		o.JSONPath = DefaultJSONPath
+	}
+	if _, err := parseJSONPath(o.JSONPath); err != nil {
+		return err
+	}
+
+	if columns, ok, err := args.GetDictionary("columns"); err != nil {
+		return err
+	} else if ok {
+		o.Columns = make(map[string]string, columns.Len())
+		columns.Range(func(k, v values.Value) {
+			o.Columns[k.Str()] = v.Str()
+		})
+		for label, typ := range o.Columns {
+			if _, ok := columnTypes[typ]; !ok {
+				return errors.Newf(codes.Invalid, "invalid type %q of column %q, must be one of string, int, uint, float, bool or time", typ, label)
+			}
+		}
+	}
+
+	if o.CursorPath, _, err = args.GetString("cursorPath"); err != nil {
+		return err
+	}
+	if o.CursorParam, _, err = args.GetString("cursorParam"); err != nil {
+		return err
+	}
+	if (o.CursorPath == "") != (o.CursorParam == "") {
+		return errors.New(codes.Invalid, "cursorPath and cursorParam must be given together")
+	}
+	if o.CursorPath != "" {
+		if _, err := parseJSONPath(o.CursorPath); err != nil {
+			return err
+		}
+	}
+
+	if maxPages, ok, err := args.GetInt("maxPages"); err != nil {
+		return err
+	} else if ok {
+		if maxPages <= 0 {
+			return errors.New(codes.Invalid, "maxPages must be positive")
+		}
+		o.MaxPages = maxPages
+	} else {
+		o.MaxPages = DefaultMaxPages
+	}
+	return nil
+}
+
+func createFromHTTPOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	s := new(FromHTTPOpSpec)
+	if err := s.ReadArgs(args); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (FromHTTPOpSpec) Kind() flux.OperationKind {
+	return FromHTTPKind
+}
+
+type FromHTTPProcedureSpec struct {
+	plan.DefaultCost
+	Spec *FromHTTPOpSpec
+}
+
+func (o *FromHTTPProcedureSpec) Kind() plan.ProcedureKind {
+	return FromHTTPKind
+}
+
+func (o *FromHTTPProcedureSpec) Copy() plan.ProcedureSpec {
+	s := *o.Spec
+	return &FromHTTPProcedureSpec{Spec: &s}
+}
+
+func newFromHTTPProcedure(qs flux.OperationSpec, a plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromHTTPOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &FromHTTPProcedureSpec{Spec: spec}, nil
+}
+
+func createFromHTTPSource(s plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := s.(*FromHTTPProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", s)
+	}
+	deps := flux.GetDependencies(a.Context())
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	client, err := deps.HTTPClient()
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Aborted, "missing client in http.from")
+	}
+	return NewHTTPSource(spec.Spec, client, validator, dsid, a.Allocator())
+}
+
+// NewHTTPSource creates a source that requests the pages of the url of the spec
+// with the client. The url of each page is validated with the validator before it
+// is requested, so redirects of the pagination cannot reach urls that are not allowed.
+func NewHTTPSource(spec *FromHTTPOpSpec, client fluxhttp.Client, validator fluxurl.Validator, dsid execute.DatasetID, alloc memory.Allocator) (execute.Source, error) {
+	path, err := parseJSONPath(spec.JSONPath)
+	if err != nil {
+		return nil, err
+	}
+	var cursorPath jsonPath
+	if spec.CursorPath != "" {
+		if cursorPath, err = parseJSONPath(spec.CursorPath); err != nil {
+			return nil, err
+		}
+	}
+	return execute.CreateSourceFromIterator(&httpIterator{
+		spec:       spec,
+		client:     client,
+		validator:  validator,
+		path:       path,
+		cursorPath: cursorPath,
+		alloc:      alloc,
+	}, dsid)
+}
+
+// httpIterator requests pages of JSON or newline delimited JSON documents
+// and outputs a single table with a row for each value that the JSONPath
+// selects from the documents.
+type httpIterator struct {
+	spec       *FromHTTPOpSpec
+	client     fluxhttp.Client
+	validator  fluxurl.Validator
+	path       jsonPath
+	cursorPath jsonPath
+	alloc      memory.Allocator
+
+	// read is the number of bytes read from the responses.
+	read int64
+}
+
+func (h *httpIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	u, err := url.Parse(h.spec.URL)
+	if err != nil {
+		return errors.Wrap(err, codes.Invalid, "invalid url")
+	}
+
+	builder := execute.NewColListTableBuilder(execute.NewGroupKey(nil, nil), h.alloc)
+	if h.spec.Columns != nil {
+		labels := make([]string, 0, len(h.spec.Columns))
+		for label := range h.spec.Columns {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			if _, err := builder.AddCol(flux.ColMeta{Label: label, Type: columnTypes[h.spec.Columns[label]]}); err != nil {
+				return err
+			}
+		}
+	}
+
+	origin := *u
+	for page := int64(0); u != nil; page++ {
+		if page == h.spec.MaxPages {
+			return errors.Newf(codes.Invalid, "http.from requested %d pages but the response has more pages, increase maxPages to read them", h.spec.MaxPages)
+		}
+		if u, err = h.fetch(ctx, u, &origin, builder); err != nil {
+			return err
+		}
+	}
+
+	tbl, err := builder.Table()
+	if err != nil {
+		return err
+	}
+	return f(tbl)
+}
+
+// fetch requests a page, appends the rows of its documents to the builder
+// and returns the url of the next page or nil if it is the last page.
+// The headers of the spec are only sent to the origin of the first url
+// so that credentials do not leak to other hosts that a page links to.
+func (h *httpIterator) fetch(ctx context.Context, u, origin *url.URL, builder execute.TableBuilder) (*url.URL, error) {
+	if err := h.validator.Validate(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, h.spec.Method, u.String(), bytes.NewReader(h.spec.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, application/x-ndjson")
+	if sameOrigin(u, origin) {
+		for k, v := range h.spec.Headers {
+			req.Header.Set(k, v)
+		}
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "error when reading http.from response body: %s", err)
+		}
+		code := codes.Invalid
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			code = codes.NotFound
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			code = codes.PermissionDenied
+		case resp.StatusCode >= 500:
+			code = codes.Unavailable
+		}
+		msg := strings.TrimSpace(string(body))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return nil, errors.Newf(code, "http.from request failed with status %d: %s", resp.StatusCode, msg)
+	}
+
+	// Newline delimited JSON is a sequence of documents,
+	// so each document of the body is decoded in turn.
+	var cursor string
+	dec := json.NewDecoder(&countingReader{r: resp.Body, n: &h.read})
+	dec.UseNumber()
+	for {
+		var doc interface{}
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if h.read > MaxResponseSize {
+			return nil, errors.Newf(codes.ResourceExhausted, "http.from responses are larger than the limit of %d bytes", MaxResponseSize)
+		} else if err != nil {
+			return nil, errors.Wrap(err, codes.Invalid, "invalid JSON response")
+		}
+		for _, v := range h.path.selectValues(doc) {
+			if vs, ok := v.([]interface{}); ok {
+				for _, v := range vs {
+					if err := h.appendRow(builder, v); err != nil {
+						return nil, err
+					}
+				}
+			} else if err := h.appendRow(builder, v); err != nil {
+				return nil, err
+			}
+		}
+		if h.cursorPath != nil {
+			if c, ok := cursorValue(h.cursorPath.selectValues(doc)); ok {
+				cursor = c
+			}
+		}
+	}
+
+	next := nextLink(resp.Header, u)
+	if next == nil && cursor != "" {
+		nu := *u
+		q := nu.Query()
+		q.Set(h.spec.CursorParam, cursor)
+		nu.RawQuery = q.Encode()
+		next = &nu
+	}
+	if next != nil && next.String() == u.String() {
+		// The page refers to itself, so it is the last page.
+		return nil, nil
+	}
+	return next, nil
+}
+
+// appendRow appends a row for a selected value to the builder.
+// The properties of an object are its columns and any other
+// value is the _value column of the row.
+func (h *httpIterator) appendRow(builder execute.TableBuilder, v interface{}) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		obj = map[string]interface{}{execute.DefaultValueColLabel: v}
+	}
+
+	if h.spec.Columns != nil {
+		for j, col := range builder.Cols() {
+			cv, err := convertValue(col, obj[col.Label])
+			if err != nil {
+				return err
+			}
+			if err := builder.AppendValue(j, cv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	labels := make([]string, 0, len(obj))
+	for label := range obj {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	// Add the columns before appending to the builder,
+	// so new columns are leveled with the previous rows.
+	row := make([]values.Value, len(labels))
+	idxs := make([]int, len(labels))
+	for i, label := range labels {
+		cv, err := inferValue(obj[label])
+		if err != nil {
+			return err
+		}
+		j := execute.ColIdx(label, builder.Cols())
+		if j < 0 && !cv.IsNull() {
+			if j, err = builder.AddCol(flux.ColMeta{Label: label, Type: flux.ColumnType(cv.Type())}); err != nil {
+				return err
+			}
+		} else if j >= 0 && !cv.IsNull() {
+			if typ := builder.Cols()[j].Type; typ != flux.ColumnType(cv.Type()) {
+				return errors.Newf(codes.FailedPrecondition, "schema collision detected: column %q is both of type %s and %s", label, typ, flux.ColumnType(cv.Type()))
+			}
+		}
+		row[i], idxs[i] = cv, j
+	}
+
+	for i, j := range idxs {
+		if j < 0 {
+			// The type of the column is not known yet.
+			continue
+		}
+		if err := builder.AppendValue(j, row[i]); err != nil {
+			return err
+		}
+	}
+	return builder.LevelColumns()
+}
+
+// countingReader adds the number of bytes that it reads to n.
+// It stops reading once n is larger than MaxResponseSize.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if *c.n > MaxResponseSize {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// inferValue converts a decoded JSON value into a Flux value.
+// Numbers are floats and objects and arrays are JSON strings.
+func inferValue(v interface{}) (values.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return values.Null, nil
+	case string:
+		return values.NewString(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, errors.Wrapf(err, codes.Invalid, "invalid number %s", v)
+		}
+		return values.NewFloat(f), nil
+	case bool:
+		return values.NewBool(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewString(string(b)), nil
+	}
+}
+
+// convertValue converts a decoded JSON value into a Flux value of the type of the column.
+// Strings are parsed as values of the type. Times are RFC3339 strings or integer
+// nanoseconds since the Unix epoch.
+func convertValue(col flux.ColMeta, v interface{}) (values.Value, error) {
+	if v == nil {
+		return values.NewNull(flux.SemanticType(col.Type)), nil
+	}
+
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case bool:
+		s = strconv.FormatBool(v)
+	default:
+		if col.Type != flux.TString {
+			return nil, errors.Newf(codes.Invalid, "cannot convert JSON %T of column %q to %s", v, col.Label, col.Type)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		s = string(b)
+	}
+
+	var (
+		cv  values.Value
+		err error
+	)
+	switch col.Type {
+	case flux.TString:
+		cv = values.NewString(s)
+	case flux.TInt:
+		var i int64
+		i, err = strconv.ParseInt(s, 10, 64)
+		cv = values.NewInt(i)
+	case flux.TUInt:
+		var u uint64
+		u, err = strconv.ParseUint(s, 10, 64)
+		cv = values.NewUInt(u)
+	case flux.TFloat:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		cv = values.NewFloat(f)
+	case flux.TBool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		cv = values.NewBool(b)
+	case flux.TTime:
+		var t values.Time
+		if _, ok := v.(json.Number); ok {
+			var ns int64
+			ns, err = strconv.ParseInt(s, 10, 64)
+			t = values.Time(ns)
+		} else {
+			var tt time.Time
+			tt, err = time.Parse(time.RFC3339Nano, s)
+			t = values.ConvertTime(tt)
+		}
+		cv = values.NewTime(t)
+	default:
+		return nil, errors.Newf(codes.Internal, "unsupported type %s of column %q", col.Type, col.Label)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, codes.Invalid, "cannot convert %q of column %q to %s", s, col.Label, col.Type)
+	}
+	return cv, nil
+}
+
+// cursorValue returns the cursor of the next page from the values
+// that the cursor path selects. A missing, null or empty cursor
+// means that there is no next page.
+func cursorValue(vs []interface{}) (string, bool) {
+	if len(vs) == 0 {
+		return "", false
+	}
+	switch v := vs[0].(type) {
+	case string:
+		return v, v != ""
+	case json.Number:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// sameOrigin reports whether the urls have the same scheme and host.
+func sameOrigin(u, v *url.URL) bool {
+	return strings.EqualFold(u.Scheme, v.Scheme) && strings.EqualFold(u.Host, v.Host)
+}
+
+// nextLink returns the url of the link of the header with the next relation.
+// Relative urls are resolved against the url of the page.
+func nextLink(header http.Header, u *url.URL) *url.URL {
+	for _, link := range header.Values("Link") {
+		for _, l := range strings.Split(link, ",") {
+			parts := strings.Split(l, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(k, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(v, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						if next, err := u.Parse(target[1 : len(target)-1]); err == nil {
+							return next
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
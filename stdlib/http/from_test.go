@@ -0,0 +1,320 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/dependencies/url"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/operation"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/querytest"
+	fhttp "github.com/InfluxCommunity/flux/stdlib/http"
+)
+
+func TestFromHTTP_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name:    "from invalid method",
+			Raw:     `import "http" http.from(url: "http://localhost:8080", method: "DELETE")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from invalid JSONPath",
+			Raw:     `import "http" http.from(url: "http://localhost:8080", jsonPath: "$..items")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from invalid column type",
+			Raw:     `import "http" http.from(url: "http://localhost:8080", columns: ["a": "duration"])`,
+			WantErr: true,
+		},
+		{
+			Name:    "from cursorPath without cursorParam",
+			Raw:     `import "http" http.from(url: "http://localhost:8080", cursorPath: "$.next")`,
+			WantErr: true,
+		},
+		{
+			Name: "from defaults",
+			Raw:  `import "http" http.from(url: "http://localhost:8080")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromHTTP0",
+						Spec: &fhttp.FromHTTPOpSpec{
+							URL:      "http://localhost:8080",
+							Method:   "GET",
+							JSONPath: fhttp.DefaultJSONPath,
+							MaxPages: fhttp.DefaultMaxPages,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "from paginated",
+			Raw: `import "http"
+http.from(
+    url: "http://localhost:8080",
+    method: "POST",
+    headers: ["Authorization": "Bearer t"],
+    body: bytes(v: "{}"),
+    jsonPath: "$.data[*]",
+    columns: ["id": "int"],
+    cursorPath: "$.next",
+    cursorParam: "cursor",
+    maxPages: 5,
+)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromHTTP0",
+						Spec: &fhttp.FromHTTPOpSpec{
+							URL:         "http://localhost:8080",
+							Method:      "POST",
+							Headers:     map[string]string{"Authorization": "Bearer t"},
+							Body:        []byte("{}"),
+							JSONPath:    "$.data[*]",
+							Columns:     map[string]string{"id": "int"},
+							CursorPath:  "$.next",
+							CursorParam: "cursor",
+							MaxPages:    5,
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+// denyValidator rejects all urls.
+type denyValidator struct {
+	url.PassValidator
+}
+
+func (denyValidator) Validate(*neturl.URL) error {
+	return errors.New(codes.Invalid, "no such host")
+}
+
+func TestFromHTTPSource_Run(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[%q]`, r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `</auth?page=2>; rel="next"`)
+			} else {
+				w.Header().Set("Link", `<`+other.URL+`/auth>; rel="next"`)
+			}
+			fmt.Fprintf(w, `[%q]`, r.Header.Get("Authorization"))
+		case "/items":
+			switch r.URL.Query().Get("cursor") {
+			case "":
+				fmt.Fprint(w, `{"data": [{"id": 1, "name": "a"}, {"id": 2, "tags": ["x"]}], "next": "c1"}`)
+			case "c1":
+				fmt.Fprint(w, `{"data": [{"id": 3, "name": "c", "ok": true}], "next": null}`)
+			}
+		case "/linked":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `</linked?page=2>; rel="next", </linked>; rel="first"`)
+				fmt.Fprint(w, `[1, 2]`)
+				return
+			}
+			fmt.Fprint(w, `[3]`)
+		case "/events":
+			fmt.Fprint(w, "{\"time\": \"1970-01-01T00:00:01Z\", \"value\": \"1.5\"}\n{\"time\": 2000000000, \"value\": 2.5}\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "not found")
+		}
+	}))
+	defer ts.Close()
+
+	testCases := []struct {
+		name      string
+		spec      *fhttp.FromHTTPOpSpec
+		validator url.Validator
+		want      []*executetest.Table
+		wantErr   error
+	}{
+		{
+			name: "cursor",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:         ts.URL + "/items",
+				JSONPath:    "$.data",
+				CursorPath:  "$.next",
+				CursorParam: "cursor",
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "id", Type: flux.TFloat},
+						{Label: "name", Type: flux.TString},
+						{Label: "tags", Type: flux.TString},
+						{Label: "ok", Type: flux.TBool},
+					},
+					Data: [][]interface{}{
+						{1.0, "a", nil, nil},
+						{2.0, nil, `["x"]`, nil},
+						{3.0, "c", nil, true},
+					},
+				},
+			},
+		},
+		{
+			name: "max pages",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:         ts.URL + "/items",
+				JSONPath:    "$.data[*]",
+				Columns:     map[string]string{"id": "int"},
+				CursorPath:  "$.next",
+				CursorParam: "cursor",
+				MaxPages:    1,
+			},
+			wantErr: errors.New(codes.Invalid, "http.from requested 1 pages but the response has more pages, increase maxPages to read them"),
+		},
+		{
+			name: "link header",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:      ts.URL + "/linked",
+				JSONPath: "$",
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{1.0},
+						{2.0},
+						{3.0},
+					},
+				},
+			},
+		},
+		{
+			name: "headers only sent to origin",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:      ts.URL + "/auth",
+				JSONPath: "$",
+				Headers:  map[string]string{"Authorization": "Bearer token"},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"Bearer token"},
+						{"Bearer token"},
+						{""},
+					},
+				},
+			},
+		},
+		{
+			name: "ndjson",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:      ts.URL + "/events",
+				JSONPath: "$",
+				Columns:  map[string]string{"time": "time", "value": "float"},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "time", Type: flux.TTime},
+						{Label: "value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1e9), 1.5},
+						{execute.Time(2e9), 2.5},
+					},
+				},
+			},
+		},
+		{
+			name: "not found",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:      ts.URL + "/missing",
+				JSONPath: "$",
+			},
+			wantErr: errors.New(codes.NotFound, "http.from request failed with status 404: not found"),
+		},
+		{
+			name: "invalid url",
+			spec: &fhttp.FromHTTPOpSpec{
+				URL:      ts.URL + "/items",
+				JSONPath: "$",
+			},
+			validator: denyValidator{},
+			wantErr:   errors.New(codes.Invalid, "no such host"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Method = http.MethodGet
+			if tc.spec.MaxPages == 0 {
+				tc.spec.MaxPages = fhttp.DefaultMaxPages
+			}
+			validator := tc.validator
+			if validator == nil {
+				validator = url.PassValidator{}
+			}
+			executetest.RunSourceHelper(t, context.Background(), tc.want, tc.wantErr, func(id execute.DatasetID) execute.Source {
+				src, err := fhttp.NewHTTPSource(tc.spec, ts.Client(), validator, id, memory.DefaultAllocator)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return src
+			})
+		})
+	}
+}
+
+func TestFromHTTPSource_MaxResponseSize(t *testing.T) {
+	defer func(size int64) { fhttp.MaxResponseSize = size }(fhttp.MaxResponseSize)
+	fhttp.MaxResponseSize = 16
+
+	// Every page is smaller than the limit, but together they are larger.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `</?page=next>; rel="next"`)
+		fmt.Fprint(w, `[1, 2, 3]`)
+	}))
+	defer ts.Close()
+
+	spec := &fhttp.FromHTTPOpSpec{
+		URL:      ts.URL,
+		Method:   http.MethodGet,
+		JSONPath: "$",
+		MaxPages: fhttp.DefaultMaxPages,
+	}
+	wantErr := errors.New(codes.ResourceExhausted, "http.from responses are larger than the limit of 16 bytes")
+	executetest.RunSourceHelper(t, context.Background(), nil, wantErr, func(id execute.DatasetID) execute.Source {
+		src, err := fhttp.NewHTTPSource(spec, ts.Client(), url.PassValidator{}, id, memory.DefaultAllocator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return src
+	})
+}
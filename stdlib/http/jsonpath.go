@@ -0,0 +1,143 @@
+package http
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// jsonPath is a parsed JSONPath expression.
+//
+// Only the subset of JSONPath that selects values by position is supported:
+// the root `$`, child members `.name` and `['name']`, array indexes `[n]`
+// (negative indexes count from the end) and wildcards `.*` and `[*]`.
+type jsonPath []pathStep
+
+// pathStep selects values from each value that the previous step selected.
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses a JSONPath expression.
+func parseJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, errors.Newf(codes.Invalid, "invalid JSONPath %q: must start with $", expr)
+	}
+	var (
+		path jsonPath
+		s    = expr[1:]
+	)
+	for len(s) > 0 {
+		var (
+			step pathStep
+			err  error
+		)
+		switch s[0] {
+		case '.':
+			step, s, err = parseMember(s[1:])
+		case '[':
+			step, s, err = parseSubscript(s[1:])
+		default:
+			err = errors.Newf(codes.Invalid, "unexpected character %q", s[0])
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, codes.Invalid, "invalid JSONPath %q", expr)
+		}
+		path = append(path, step)
+	}
+	return path, nil
+}
+
+// parseMember parses the name of a child member or a wildcard after a dot.
+func parseMember(s string) (pathStep, string, error) {
+	if strings.HasPrefix(s, "*") {
+		return pathStep{wildcard: true}, s[1:], nil
+	}
+	n := strings.IndexAny(s, ".[")
+	if n < 0 {
+		n = len(s)
+	}
+	if n == 0 {
+		if strings.HasPrefix(s, ".") {
+			return pathStep{}, "", errors.New(codes.Invalid, "recursive descent is not supported")
+		}
+		return pathStep{}, "", errors.New(codes.Invalid, "missing member name")
+	}
+	return pathStep{key: s[:n]}, s[n:], nil
+}
+
+// parseSubscript parses an index, a quoted member name or a wildcard between brackets.
+// A quoted member name ends at its closing quote, so it may contain brackets.
+func parseSubscript(s string) (pathStep, string, error) {
+	if len(s) > 0 && (s[0] == '\'' || s[0] == '"') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return pathStep{}, "", errors.New(codes.Invalid, "missing closing quote")
+		}
+		key, rest := s[1:end+1], s[end+2:]
+		if !strings.HasPrefix(rest, "]") {
+			return pathStep{}, "", errors.New(codes.Invalid, "missing ]")
+		}
+		return pathStep{key: key}, rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathStep{}, "", errors.New(codes.Invalid, "missing ]")
+	}
+	sub, rest := s[:end], s[end+1:]
+	if sub == "*" {
+		return pathStep{wildcard: true}, rest, nil
+	}
+	index, err := strconv.Atoi(sub)
+	if err != nil {
+		return pathStep{}, "", errors.Newf(codes.Invalid, "invalid subscript %q", sub)
+	}
+	return pathStep{index: index, isIndex: true}, rest, nil
+}
+
+// selectValues returns the values of the decoded JSON document that the path selects.
+// Members of objects that a wildcard selects are returned in the order of their names.
+func (p jsonPath) selectValues(doc interface{}) []interface{} {
+	vs := []interface{}{doc}
+	for _, step := range p {
+		var next []interface{}
+		for _, v := range vs {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					keys := make([]string, 0, len(v))
+					for k := range v {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, v[k])
+					}
+				} else if mv, ok := v[step.key]; ok && !step.isIndex {
+					next = append(next, mv)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, v...)
+				} else if step.isIndex {
+					i := step.index
+					if i < 0 {
+						i += len(v)
+					}
+					if i >= 0 && i < len(v) {
+						next = append(next, v[i])
+					}
+				}
+			}
+		}
+		vs = next
+	}
+	return vs
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONPath(t *testing.T) {
+	const doc = `{"data": {"items": [{"id": 1}, {"id": 2}, {"id": 3}], "my key": "v", "a[0]": "w"}, "next": "c"}`
+
+	testCases := []struct {
+		path    string
+		want    []interface{}
+		wantErr bool
+	}{
+		{path: "$.next", want: []interface{}{"c"}},
+		{path: "$['data']['my key']", want: []interface{}{"v"}},
+		{path: `$.data["a[0]"]`, want: []interface{}{"w"}},
+		{path: "$.data.items[1].id", want: []interface{}{2.0}},
+		{path: "$.data.items[-1].id", want: []interface{}{3.0}},
+		{path: "$.data.items[*].id", want: []interface{}{1.0, 2.0, 3.0}},
+		{path: "$.*", want: []interface{}{
+			map[string]interface{}{
+				"items":  []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}, map[string]interface{}{"id": 3.0}},
+				"my key": "v",
+				"a[0]":   "w",
+			},
+			"c",
+		}},
+		{path: "$.missing"},
+		{path: "$.data.items[5]"},
+		{path: "data", wantErr: true},
+		{path: "$..id", wantErr: true},
+		{path: "$.data[0", wantErr: true},
+		{path: "$.data[x]", wantErr: true},
+		{path: "$.data['x]", wantErr: true},
+		{path: "$.data['x'", wantErr: true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := parseJSONPath(tc.path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var v interface{}
+			if err := json.Unmarshal([]byte(doc), &v); err != nil {
+				t.Fatal(err)
+			}
+			if got := path.selectValues(v); !cmp.Equal(tc.want, got) {
+				t.Errorf("unexpected values -want/+got\n%s", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
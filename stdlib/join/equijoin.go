@@ -48,7 +48,24 @@ func (p *EquiJoinProcedureSpec) Copy() plan.ProcedureSpec {
 }
 
 func (p *EquiJoinProcedureSpec) Cost(inStats []plan.Statistics) (cost plan.Cost, outStats plan.Statistics) {
-	return plan.Cost{}, plan.Statistics{}
+	return joinCost(inStats)
+}
+
+// joinCost estimates that a join reads every row of both inputs and
+// that each row matches a single row on the other side, so the output
+// is as large as the larger input.
+func joinCost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	stats := plan.SumStatistics(inStats)
+	var out plan.Statistics
+	for _, s := range inStats {
+		if stats.Cardinality > 0 && s.Cardinality > out.Cardinality {
+			out.Cardinality = s.Cardinality
+		}
+		if stats.GroupCardinality > 0 && s.GroupCardinality > out.GroupCardinality {
+			out.GroupCardinality = s.GroupCardinality
+		}
+	}
+	return plan.Cost{CPU: stats.Cardinality}, out
 }
 
 func newEquiJoinProcedureSpec(spec *JoinProcedureSpec, cols []ColumnPair) *EquiJoinProcedureSpec {
@@ -52,7 +52,7 @@ func (p *SortMergeJoinProcedureSpec) RequiredAttributes() []plan.PhysicalAttribu
 }
 
 func (p *SortMergeJoinProcedureSpec) Cost(inStats []plan.Statistics) (cost plan.Cost, outStats plan.Statistics) {
-	return plan.Cost{}, plan.Statistics{}
+	return joinCost(inStats)
 }
 
 type SortMergeJoinPredicateRule struct{}
@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/csv"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/decode"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/segmentio/kafka-go"
+)
+
+// newMessageDecoder returns a function that decodes the value
+// of a message in the format into the builders of the cache.
+// Rows without a time have the time of the message.
+func newMessageDecoder(ctx context.Context, format string, cache execute.TableBuilderCache, alloc memory.Allocator) func(kafka.Message) error {
+	switch format {
+	case FormatJSON:
+		return func(msg kafka.Message) error {
+			return decode.JSON(msg.Value, values.ConvertTime(msg.Time), cache, decode.Options{})
+		}
+	case FormatCSV:
+		return func(msg kafka.Message) error {
+			return decodeCSV(ctx, msg, cache, alloc)
+		}
+	default:
+		return func(msg kafka.Message) error {
+			return decode.LineProtocol(msg.Value, values.ConvertTime(msg.Time), cache, decode.Options{})
+		}
+	}
+}
+
+// decodeCSV decodes the tables of a message that contains annotated CSV.
+// Tables with the same group key are appended to each other.
+func decodeCSV(ctx context.Context, msg kafka.Message, cache execute.TableBuilderCache, alloc memory.Allocator) error {
+	dec := csv.NewResultDecoder(csv.ResultDecoderConfig{
+		Allocator: alloc,
+		Context:   ctx,
+	})
+	result, err := dec.Decode(bytes.NewReader(msg.Value))
+	if err != nil {
+		return err
+	}
+	return result.Tables().Do(func(tbl flux.Table) error {
+		builder, _ := cache.TableBuilder(tbl.Key())
+		colMap, err := execute.AddNewTableCols(tbl, builder, nil)
+		if err != nil {
+			return err
+		}
+		return execute.AppendMappedTable(tbl, builder, colMap)
+	})
+}
@@ -0,0 +1,465 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// FromKafkaKind is the Kind for the FromKafka Flux function
+	FromKafkaKind = "fromKafka"
+
+	// DefaultGroupID is the group ID that identifies the reads to the brokers
+	// when no group ID is given.
+	DefaultGroupID = "flux"
+
+	// maxMessageBytes is the maximum size of a message that is read.
+	maxMessageBytes = 1e6
+)
+
+// Formats of the messages that kafka.from decodes.
+const (
+	FormatLineProtocol = "lineprotocol"
+	FormatJSON         = "json"
+	FormatCSV          = "csv"
+)
+
+var formats = []string{FormatLineProtocol, FormatJSON, FormatCSV}
+
+type FromKafkaOpSpec struct {
+	Brokers []string  `json:"brokers"`
+	Topic   string    `json:"topic"`
+	GroupID string    `json:"groupID"`
+	Start   flux.Time `json:"start"`
+	Stop    flux.Time `json:"stop"`
+	// StartOffset and StopOffset are -1 if they are not set.
+	StartOffset int64  `json:"startOffset"`
+	StopOffset  int64  `json:"stopOffset"`
+	Format      string `json:"format"`
+}
+
+func init() {
+	fromKafkaSignature := runtime.MustLookupBuiltinType("kafka", "from")
+	runtime.RegisterPackageValue("kafka", "from", flux.MustValue(flux.FunctionValue(FromKafkaKind, createFromKafkaOpSpec, fromKafkaSignature)))
+	plan.RegisterProcedureSpec(FromKafkaKind, newFromKafkaProcedure, FromKafkaKind)
+	execute.RegisterSource(FromKafkaKind, createFromKafkaSource)
+}
+
+// KafkaPartitionReader reads the messages of a single partition of a topic.
+// It is implemented by *kafka.Conn.
+type KafkaPartitionReader interface {
+	io.Closer
+	// ReadOffsets returns the first offset of the partition
+	// and the offset of the next message that is written to it.
+	ReadOffsets() (first, last int64, err error)
+	// ReadOffset returns the offset of the first message with
+	// a timestamp equal or greater than t, or -1 if there is none.
+	ReadOffset(t time.Time) (int64, error)
+	Seek(offset int64, whence int) (int64, error)
+	ReadMessage(maxBytes int) (kafka.Message, error)
+}
+
+// KafkaDialer connects to the partitions of a topic.
+type KafkaDialer interface {
+	// Partitions returns the IDs of the partitions of the topic.
+	Partitions(ctx context.Context, topic string) ([]int, error)
+	// DialPartition connects to the leader of a partition of the topic.
+	DialPartition(ctx context.Context, topic string, partition int) (KafkaPartitionReader, error)
+}
+
+// DefaultKafkaDialerFactory creates the KafkaDialer that kafka.from uses and is injectable for testing.
+var DefaultKafkaDialerFactory = func(brokers []string, groupID string) KafkaDialer {
+	return &brokerDialer{
+		brokers: brokers,
+		dialer:  &kafka.Dialer{ClientID: groupID},
+	}
+}
+
+// brokerDialer connects to the first of the brokers that is available.
+type brokerDialer struct {
+	brokers []string
+	dialer  *kafka.Dialer
+}
+
+func (d *brokerDialer) Partitions(ctx context.Context, topic string) ([]int, error) {
+	var conn *kafka.Conn
+	err := d.each(func(address string) (err error) {
+		conn, err = d.dialer.DialContext(ctx, "tcp", address)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(partitions))
+	for i, p := range partitions {
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
+func (d *brokerDialer) DialPartition(ctx context.Context, topic string, partition int) (KafkaPartitionReader, error) {
+	var conn *kafka.Conn
+	err := d.each(func(address string) (err error) {
+		conn, err = d.dialer.DialLeader(ctx, "tcp", address, topic, partition)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// each calls fn with the address of each broker until it succeeds.
+func (d *brokerDialer) each(fn func(address string) error) error {
+	var err error
+	for _, b := range d.brokers {
+		if err = fn(brokerAddress(b)); err == nil {
+			return nil
+		}
+	}
+	return errors.Wrap(err, codes.Unavailable, "could not connect to kafka brokers")
+}
+
+// brokerAddress returns the host and port of a broker
+// that is given either as an address or as a URL.
+func brokerAddress(broker string) string {
+	if strings.Contains(broker, "://") {
+		if u, err := url.Parse(broker); err == nil {
+			return u.Host
+		}
+	}
+	return broker
+}
+
+func createFromKafkaOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(FromKafkaOpSpec)
+
+	brokers, err := args.GetRequiredArray("brokers", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	if brokers.Len() < 1 {
+		return nil, errors.New(codes.Invalid, "at least one broker is required")
+	}
+	spec.Brokers = make([]string, brokers.Len())
+	for i := range spec.Brokers {
+		spec.Brokers[i] = brokers.Get(i).Str()
+	}
+
+	if spec.Topic, err = args.GetRequiredString("topic"); err != nil {
+		return nil, err
+	} else if len(spec.Topic) == 0 {
+		return nil, errors.New(codes.Invalid, "invalid topic name")
+	}
+
+	if groupID, ok, err := args.GetString("groupID"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.GroupID = groupID
+	} else {
+		spec.GroupID = DefaultGroupID
+	}
+
+	if spec.Start, _, err = args.GetTime("start"); err != nil {
+		return nil, err
+	}
+	if spec.Stop, _, err = args.GetTime("stop"); err != nil {
+		return nil, err
+	}
+
+	spec.StartOffset, spec.StopOffset = -1, -1
+	if offset, ok, err := args.GetInt("startOffset"); err != nil {
+		return nil, err
+	} else if ok {
+		if offset < 0 {
+			return nil, errors.New(codes.Invalid, "startOffset must not be negative")
+		}
+		spec.StartOffset = offset
+	}
+	if offset, ok, err := args.GetInt("stopOffset"); err != nil {
+		return nil, err
+	} else if ok {
+		if offset < 0 {
+			return nil, errors.New(codes.Invalid, "stopOffset must not be negative")
+		}
+		spec.StopOffset = offset
+	}
+
+	if format, ok, err := args.GetString("format"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Format = format
+	} else {
+		spec.Format = FormatLineProtocol
+	}
+	if !contains(formats, spec.Format) {
+		return nil, errors.Newf(codes.Invalid, "invalid format %s, must be one of %v", spec.Format, formats)
+	}
+
+	return spec, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, st := range ss {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FromKafkaOpSpec) Kind() flux.OperationKind {
+	return FromKafkaKind
+}
+
+type FromKafkaProcedureSpec struct {
+	plan.DefaultCost
+	Brokers []string
+	Topic   string
+	GroupID string
+	// Start and Stop are the bounds of the timestamps of the
+	// messages that are read. They are zero if they are not set.
+	Start time.Time
+	Stop  time.Time
+	// StartOffset and StopOffset are -1 if they are not set.
+	StartOffset int64
+	StopOffset  int64
+	Format      string
+}
+
+func newFromKafkaProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromKafkaOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	ps := &FromKafkaProcedureSpec{
+		Brokers:     spec.Brokers,
+		Topic:       spec.Topic,
+		GroupID:     spec.GroupID,
+		StartOffset: spec.StartOffset,
+		StopOffset:  spec.StopOffset,
+		Format:      spec.Format,
+	}
+	if !spec.Start.IsZero() {
+		ps.Start = spec.Start.Time(pa.Now())
+	}
+	if !spec.Stop.IsZero() {
+		ps.Stop = spec.Stop.Time(pa.Now())
+	}
+	return ps, nil
+}
+
+func (s *FromKafkaProcedureSpec) Kind() plan.ProcedureKind {
+	return FromKafkaKind
+}
+
+func (s *FromKafkaProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	ns.Brokers = append([]string(nil), s.Brokers...)
+	return &ns
+}
+
+func createFromKafkaSource(s plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := s.(*FromKafkaProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", s)
+	}
+
+	deps := flux.GetDependencies(a.Context())
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range spec.Brokers {
+		u, err := url.Parse(b)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "invalid kafka broker url: %v", err)
+		}
+		if err := validator.Validate(u); err != nil {
+			return nil, errors.Newf(codes.Invalid, "kafka broker url did not pass validation: %v", err)
+		}
+	}
+
+	dialer := DefaultKafkaDialerFactory(spec.Brokers, spec.GroupID)
+	return NewKafkaSource(spec, dialer, dsid, a.Allocator())
+}
+
+// NewKafkaSource creates a source that reads the messages of the topic from the dialer.
+func NewKafkaSource(spec *FromKafkaProcedureSpec, dialer KafkaDialer, dsid execute.DatasetID, alloc memory.Allocator) (execute.Source, error) {
+	if !contains(formats, spec.Format) {
+		return nil, errors.Newf(codes.Invalid, "unknown format: %v", spec.Format)
+	}
+	return execute.CreateSourceFromIterator(&kafkaIterator{
+		spec:   spec,
+		dialer: dialer,
+		alloc:  alloc,
+	}, dsid)
+}
+
+// kafkaIterator reads the messages of each partition of the topic that
+// are within the bounds and outputs the tables that they decode into.
+type kafkaIterator struct {
+	spec   *FromKafkaProcedureSpec
+	dialer KafkaDialer
+	alloc  memory.Allocator
+}
+
+func (k *kafkaIterator) Do(ctx context.Context, f func(flux.Table) error) error {
+	partitions, err := k.dialer.Partitions(ctx, k.spec.Topic)
+	if err != nil {
+		return err
+	}
+
+	cache := execute.NewTableBuilderCache(k.alloc)
+	cache.SetTriggerSpec(plan.DefaultTriggerSpec)
+	decode := newMessageDecoder(ctx, k.spec.Format, cache, k.alloc)
+	for _, partition := range partitions {
+		if err := k.readPartition(ctx, partition, decode); err != nil {
+			return errors.Wrapf(err, codes.Inherit, "error reading partition %d of topic %s", partition, k.spec.Topic)
+		}
+	}
+
+	return cache.ForEachBuilder(func(key flux.GroupKey, builder execute.TableBuilder) error {
+		tbl, err := builder.Table()
+		if err != nil {
+			return err
+		}
+		return f(tbl)
+	})
+}
+
+func (k *kafkaIterator) readPartition(ctx context.Context, partition int, decode func(kafka.Message) error) error {
+	conn, err := k.dialer.DialPartition(ctx, k.spec.Topic, partition)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The reads of a connection block until a message is available
+	// and are only interrupted by closing the connection.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	start, stop, err := k.bounds(conn)
+	if err != nil {
+		return err
+	}
+	if start >= stop {
+		return nil
+	}
+	if _, err := conn.Seek(start, kafka.SeekAbsolute); err != nil {
+		return err
+	}
+
+	for offset := start; offset < stop; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := conn.ReadMessage(maxMessageBytes)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+		}
+		offset = msg.Offset + 1
+		if msg.Offset >= stop {
+			break
+		}
+		if !k.inTimeRange(msg.Time) {
+			continue
+		}
+		if err := decode(msg); err != nil {
+			return errors.Wrapf(err, codes.Inherit, "could not decode message at offset %d", msg.Offset)
+		}
+	}
+	return nil
+}
+
+// inTimeRange reports whether a message with the timestamp t is within
+// the start and stop bounds. The timestamps of the messages of a partition
+// are not always in the order of their offsets, so the messages between the
+// offsets of the bounds may still be outside of them.
+func (k *kafkaIterator) inTimeRange(t time.Time) bool {
+	if !k.spec.Start.IsZero() && t.Before(k.spec.Start) {
+		return false
+	}
+	if !k.spec.Stop.IsZero() && !t.Before(k.spec.Stop) {
+		return false
+	}
+	return true
+}
+
+// bounds returns the offsets of the first message that is
+// read from the partition and of the message after the last.
+func (k *kafkaIterator) bounds(conn KafkaPartitionReader) (start, stop int64, err error) {
+	start, stop, err = conn.ReadOffsets()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// offsetAt returns the offset of the first message at or after t
+	// or the offset after the last message if there is no such message.
+	offsetAt := func(t time.Time) (int64, error) {
+		offset, err := conn.ReadOffset(t)
+		if err != nil {
+			return 0, err
+		} else if offset < 0 || offset > stop {
+			return stop, nil
+		}
+		return offset, nil
+	}
+
+	if k.spec.StopOffset >= 0 && k.spec.StopOffset < stop {
+		stop = k.spec.StopOffset
+	}
+	if !k.spec.Stop.IsZero() {
+		offset, err := offsetAt(k.spec.Stop)
+		if err != nil {
+			return 0, 0, err
+		}
+		if offset < stop {
+			stop = offset
+		}
+	}
+	if k.spec.StartOffset > start {
+		start = k.spec.StartOffset
+	}
+	if !k.spec.Start.IsZero() {
+		offset, err := offsetAt(k.spec.Start)
+		if err != nil {
+			return 0, 0, err
+		}
+		if offset > start {
+			start = offset
+		}
+	}
+	return start, stop, nil
+}
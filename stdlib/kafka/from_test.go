@@ -0,0 +1,400 @@
+package kafka_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/internal/operation"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/querytest"
+	fkafka "github.com/InfluxCommunity/flux/stdlib/kafka"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestFromKafka_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name:    "from no brokers",
+			Raw:     `import "kafka" kafka.from(brokers: [], topic: "events")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from invalid format",
+			Raw:     `import "kafka" kafka.from(brokers: ["127.0.0.1:9092"], topic: "events", format: "xml")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from negative offset",
+			Raw:     `import "kafka" kafka.from(brokers: ["127.0.0.1:9092"], topic: "events", startOffset: -1)`,
+			WantErr: true,
+		},
+		{
+			Name: "from defaults",
+			Raw:  `import "kafka" kafka.from(brokers: ["127.0.0.1:9092"], topic: "events")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromKafka0",
+						Spec: &fkafka.FromKafkaOpSpec{
+							Brokers:     []string{"127.0.0.1:9092"},
+							Topic:       "events",
+							GroupID:     fkafka.DefaultGroupID,
+							StartOffset: -1,
+							StopOffset:  -1,
+							Format:      fkafka.FormatLineProtocol,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "from range",
+			Raw: `import "kafka"
+kafka.from(brokers: ["127.0.0.1:9092"], topic: "events", groupID: "replay", start: -1h, stopOffset: 10, format: "json")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromKafka0",
+						Spec: &fkafka.FromKafkaOpSpec{
+							Brokers: []string{"127.0.0.1:9092"},
+							Topic:   "events",
+							GroupID: "replay",
+							Start: flux.Time{
+								Relative:   -time.Hour,
+								IsRelative: true,
+							},
+							StartOffset: -1,
+							StopOffset:  10,
+							Format:      fkafka.FormatJSON,
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+// brokerMock is a broker that has the messages of each partition of a topic in memory.
+type brokerMock struct {
+	partitions [][]kafka.Message
+}
+
+func (b *brokerMock) Partitions(ctx context.Context, topic string) ([]int, error) {
+	ids := make([]int, len(b.partitions))
+	for i := range ids {
+		ids[i] = i
+	}
+	return ids, nil
+}
+
+func (b *brokerMock) DialPartition(ctx context.Context, topic string, partition int) (fkafka.KafkaPartitionReader, error) {
+	return &partitionMock{msgs: b.partitions[partition]}, nil
+}
+
+// partitionMock reads the messages of a partition.
+// The offset of each message is its index.
+type partitionMock struct {
+	msgs   []kafka.Message
+	offset int64
+}
+
+func (p *partitionMock) Close() error { return nil }
+
+func (p *partitionMock) ReadOffsets() (first, last int64, err error) {
+	return 0, int64(len(p.msgs)), nil
+}
+
+func (p *partitionMock) ReadOffset(t time.Time) (int64, error) {
+	for i, msg := range p.msgs {
+		if !msg.Time.Before(t) {
+			return int64(i), nil
+		}
+	}
+	return -1, nil
+}
+
+func (p *partitionMock) Seek(offset int64, whence int) (int64, error) {
+	p.offset = offset
+	return offset, nil
+}
+
+func (p *partitionMock) ReadMessage(maxBytes int) (kafka.Message, error) {
+	if p.offset >= int64(len(p.msgs)) {
+		return kafka.Message{}, io.EOF
+	}
+	msg := p.msgs[p.offset]
+	msg.Offset = p.offset
+	p.offset++
+	return msg, nil
+}
+
+func message(sec int64, value string) kafka.Message {
+	return kafka.Message{Time: time.Unix(sec, 0).UTC(), Value: []byte(value)}
+}
+
+func TestFromKafkaSource_Run(t *testing.T) {
+	broker := &brokerMock{
+		partitions: [][]kafka.Message{
+			{
+				message(1, "cpu,host=a usage=1.5 1000000000\ncpu,host=b usage=2.5 1000000000"),
+				message(2, "cpu,host=a usage=3.5,count=2i 2000000000"),
+				message(3, "cpu,host=a usage=4.5"),
+			},
+			{
+				message(1, "cpu,host=b usage=5.5 1000000000"),
+			},
+		},
+	}
+	unorderedBroker := &brokerMock{
+		partitions: [][]kafka.Message{
+			{
+				message(2, "cpu,host=a usage=1.5"),
+				message(1, "cpu,host=a usage=2.5"),
+				message(3, "cpu,host=a usage=3.5"),
+			},
+		},
+	}
+	tagBroker := &brokerMock{
+		partitions: [][]kafka.Message{
+			{
+				message(1, "cpu,_field=f,_measurement=m,host=a usage=1.5 1000000000"),
+			},
+		},
+	}
+	jsonBroker := &brokerMock{
+		partitions: [][]kafka.Message{
+			{
+				message(1, `{"host": "a", "usage": 1.5}`),
+				message(2, `{"host": "b", "ok": true}{"_time": "1970-01-01T00:00:10Z", "usage": null}`),
+			},
+		},
+	}
+	csvBroker := &brokerMock{
+		partitions: [][]kafka.Message{
+			{
+				message(1, `#datatype,string,long,dateTime:RFC3339,string,double
+#group,false,false,false,true,false
+#default,_result,,,,
+,result,table,_time,host,_value
+,,0,1970-01-01T00:00:01Z,a,1.5
+`),
+				message(2, `#datatype,string,long,dateTime:RFC3339,string,double
+#group,false,false,false,true,false
+#default,_result,,,,
+,result,table,_time,host,_value
+,,0,1970-01-01T00:00:02Z,a,2.5
+,,1,1970-01-01T00:00:02Z,b,3.5
+`),
+			},
+		},
+	}
+
+	lpCols := []flux.ColMeta{
+		{Label: "_field", Type: flux.TString},
+		{Label: "_measurement", Type: flux.TString},
+		{Label: "host", Type: flux.TString},
+		{Label: "_time", Type: flux.TTime},
+	}
+	lpKey := []string{"_field", "_measurement", "host"}
+	withValue := func(typ flux.ColType) []flux.ColMeta {
+		return append(append([]flux.ColMeta(nil), lpCols...), flux.ColMeta{Label: "_value", Type: typ})
+	}
+
+	testCases := []struct {
+		name    string
+		broker  *brokerMock
+		spec    *fkafka.FromKafkaProcedureSpec
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name:   "line protocol",
+			broker: broker,
+			spec:   &fkafka.FromKafkaProcedureSpec{StartOffset: -1, StopOffset: -1, Format: fkafka.FormatLineProtocol},
+			want: []*executetest.Table{
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TInt),
+					Data: [][]interface{}{
+						{"count", "cpu", "a", execute.Time(2e9), int64(2)},
+					},
+				},
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TFloat),
+					Data: [][]interface{}{
+						{"usage", "cpu", "a", execute.Time(1e9), 1.5},
+						{"usage", "cpu", "a", execute.Time(2e9), 3.5},
+						{"usage", "cpu", "a", execute.Time(3e9), 4.5},
+					},
+				},
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TFloat),
+					Data: [][]interface{}{
+						{"usage", "cpu", "b", execute.Time(1e9), 2.5},
+						{"usage", "cpu", "b", execute.Time(1e9), 5.5},
+					},
+				},
+			},
+		},
+		{
+			name:   "offsets",
+			broker: broker,
+			spec:   &fkafka.FromKafkaProcedureSpec{StartOffset: 1, StopOffset: 2, Format: fkafka.FormatLineProtocol},
+			want: []*executetest.Table{
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TInt),
+					Data: [][]interface{}{
+						{"count", "cpu", "a", execute.Time(2e9), int64(2)},
+					},
+				},
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TFloat),
+					Data: [][]interface{}{
+						{"usage", "cpu", "a", execute.Time(2e9), 3.5},
+					},
+				},
+			},
+		},
+		{
+			name:   "timestamps",
+			broker: broker,
+			spec: &fkafka.FromKafkaProcedureSpec{
+				Start:       time.Unix(2, 0),
+				Stop:        time.Unix(3, 0),
+				StartOffset: -1,
+				StopOffset:  -1,
+				Format:      fkafka.FormatLineProtocol,
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TInt),
+					Data: [][]interface{}{
+						{"count", "cpu", "a", execute.Time(2e9), int64(2)},
+					},
+				},
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TFloat),
+					Data: [][]interface{}{
+						{"usage", "cpu", "a", execute.Time(2e9), 3.5},
+					},
+				},
+			},
+		},
+		{
+			name:   "unordered timestamps",
+			broker: unorderedBroker,
+			spec: &fkafka.FromKafkaProcedureSpec{
+				Start:       time.Unix(2, 0),
+				Stop:        time.Unix(3, 0),
+				StartOffset: -1,
+				StopOffset:  -1,
+				Format:      fkafka.FormatLineProtocol,
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TFloat),
+					Data: [][]interface{}{
+						{"usage", "cpu", "a", execute.Time(2e9), 1.5},
+					},
+				},
+			},
+		},
+		{
+			name:   "tags named like columns",
+			broker: tagBroker,
+			spec:   &fkafka.FromKafkaProcedureSpec{StartOffset: -1, StopOffset: -1, Format: fkafka.FormatLineProtocol},
+			want: []*executetest.Table{
+				{
+					KeyCols: lpKey,
+					ColMeta: withValue(flux.TFloat),
+					Data: [][]interface{}{
+						{"usage", "cpu", "a", execute.Time(1e9), 1.5},
+					},
+				},
+			},
+		},
+		{
+			name:   "json",
+			broker: jsonBroker,
+			spec:   &fkafka.FromKafkaProcedureSpec{StartOffset: -1, StopOffset: -1, Format: fkafka.FormatJSON},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "usage", Type: flux.TFloat},
+						{Label: "ok", Type: flux.TBool},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1e9), "a", 1.5, nil},
+						{execute.Time(2e9), "b", nil, true},
+						{execute.Time(10e9), nil, nil, nil},
+					},
+				},
+			},
+		},
+		{
+			name:   "csv",
+			broker: csvBroker,
+			spec:   &fkafka.FromKafkaProcedureSpec{StartOffset: -1, StopOffset: -1, Format: fkafka.FormatCSV},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1e9), "a", 1.5},
+						{execute.Time(2e9), "a", 2.5},
+					},
+				},
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(2e9), "b", 3.5},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Topic = "events"
+			executetest.RunSourceHelper(t, context.Background(), tc.want, tc.wantErr, func(id execute.DatasetID) execute.Source {
+				src, err := fkafka.NewKafkaSource(tc.spec, tc.broker, id, memory.DefaultAllocator)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return src
+			})
+		})
+	}
+}
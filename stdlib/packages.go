@@ -23,6 +23,7 @@ import (
 	_ "github.com/InfluxCommunity/flux/stdlib/contrib/qxip/hash"
 	_ "github.com/InfluxCommunity/flux/stdlib/contrib/qxip/iox"
 	_ "github.com/InfluxCommunity/flux/stdlib/contrib/qxip/logql"
+	_ "github.com/InfluxCommunity/flux/stdlib/contrib/qxip/tempo"
 	_ "github.com/InfluxCommunity/flux/stdlib/contrib/rhajek/bigpanda"
 	_ "github.com/InfluxCommunity/flux/stdlib/contrib/sranka/opsgenie"
 	_ "github.com/InfluxCommunity/flux/stdlib/contrib/sranka/sensu"
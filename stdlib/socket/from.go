@@ -1,11 +1,15 @@
 // Package socket implements a source that gets input from a socket connection and produces tables given a decoder.
-// This is a good candidate for streaming use cases. For now, it produces a single table for everything
-// that it receives from the start to the end of the connection.
+// By default, it produces a single table for everything that it receives from the start to the end of the connection.
+// When an interval is given with the line decoder, the source is unbounded: it produces a table for the lines
+// that it received at every interval and advances the watermark to the time of the interval, so that windows
+// are emitted while the connection is open.
 package socket
 
 import (
+	"bufio"
 	"context"
 	"io"
+	"math"
 	"net"
 	neturl "net/url"
 	"strings"
@@ -17,6 +21,7 @@ import (
 	"github.com/InfluxCommunity/flux/execute"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/internal/line"
+	"github.com/InfluxCommunity/flux/memory"
 	"github.com/InfluxCommunity/flux/plan"
 	"github.com/InfluxCommunity/flux/runtime"
 	"github.com/InfluxCommunity/flux/values"
@@ -25,8 +30,9 @@ import (
 const FromSocketKind = "fromSocket"
 
 type FromSocketOpSpec struct {
-	URL     string `json:"url"`
-	Decoder string `json:"decoder"`
+	URL     string        `json:"url"`
+	Decoder string        `json:"decoder"`
+	Every   flux.Duration `json:"every"`
 }
 
 func init() {
@@ -79,6 +85,17 @@ func createFromSocketOpSpec(args flux.Arguments, a *flux.Administration) (flux.O
 		return nil, errors.Newf(codes.Invalid, "invalid decoder %s, must be one of %v", spec.Decoder, decoders)
 	}
 
+	if every, ok, err := args.GetDuration("every"); err != nil {
+		return nil, err
+	} else if ok {
+		if !every.IsPositive() || !every.NanoOnly() {
+			return nil, errors.New(codes.Invalid, "every must be a positive duration without months")
+		} else if spec.Decoder != "line" {
+			return nil, errors.Newf(codes.Invalid, "every is only supported with the line decoder, got %s", spec.Decoder)
+		}
+		spec.Every = every
+	}
+
 	return spec, nil
 }
 
@@ -90,6 +107,7 @@ type FromSocketProcedureSpec struct {
 	plan.DefaultCost
 	URL     string
 	Decoder string
+	Every   flux.Duration
 }
 
 func newFromSocketProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
@@ -101,6 +119,7 @@ func newFromSocketProcedure(qs flux.OperationSpec, pa plan.Administration) (plan
 	return &FromSocketProcedureSpec{
 		URL:     spec.URL,
 		Decoder: spec.Decoder,
+		Every:   spec.Every,
 	}, nil
 }
 
@@ -112,9 +131,28 @@ func (s *FromSocketProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := new(FromSocketProcedureSpec)
 	ns.URL = s.URL
 	ns.Decoder = s.Decoder
+	ns.Every = s.Every
 	return ns
 }
 
+// Unbounded reports whether the source reads from the socket
+// until the query is cancelled.
+func (s *FromSocketProcedureSpec) Unbounded() bool {
+	return !s.Every.IsZero()
+}
+
+// TimeBounds gives an unbounded source all of time as its bounds,
+// so that it can be windowed without a range.
+func (s *FromSocketProcedureSpec) TimeBounds(predecessorBounds *plan.Bounds) *plan.Bounds {
+	if !s.Unbounded() {
+		return predecessorBounds
+	}
+	return &plan.Bounds{
+		Start: values.Time(math.MinInt64),
+		Stop:  values.Time(math.MaxInt64),
+	}
+}
+
 func createFromSocketSource(s plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
 	spec, ok := s.(*FromSocketProcedureSpec)
 	if !ok {
@@ -150,10 +188,12 @@ func createFromSocketSource(s plan.ProcedureSpec, dsid execute.DatasetID, a exec
 		return nil, errors.Wrap(err, codes.Inherit, "error in creating socket source")
 	}
 
-	return NewSocketSource(spec, conn, &nowTimeProvider{}, dsid)
+	return NewSocketSource(spec, conn, &nowTimeProvider{}, dsid, a.Allocator())
 }
 
-func NewSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.TimeProvider, dsid execute.DatasetID) (execute.Source, error) {
+// NewSocketSource returns a source that reads from rc. The streaming
+// source builds its tables with mem.
+func NewSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.TimeProvider, dsid execute.DatasetID, mem memory.Allocator) (execute.Source, error) {
 	var decoder flux.ResultDecoder
 	switch spec.Decoder {
 	case "csv":
@@ -169,6 +209,16 @@ func NewSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.Ti
 		return nil, errors.Newf(codes.Invalid, "unknown decoder type: %v", spec.Decoder)
 	}
 
+	if spec.Unbounded() {
+		return &streamingSocketSource{
+			d:     dsid,
+			rc:    rc,
+			tp:    tp,
+			every: spec.Every.Duration(),
+			mem:   mem,
+		}, nil
+	}
+
 	return &socketSource{
 		d:       dsid,
 		rc:      rc,
@@ -208,3 +258,128 @@ func (ss *socketSource) Run(ctx context.Context) {
 		t.Finish(ss.d, err)
 	}
 }
+
+// streamingSocketSource reads lines from the socket until the connection
+// is closed or the query is cancelled. It produces a table of the lines that
+// it read at every interval and then advances the watermark to the time of
+// the interval. Lines are timestamped when they are read, so no line that
+// is read later can belong to a window that the watermark has passed.
+type streamingSocketSource struct {
+	execute.ExecutionNode
+	d     execute.DatasetID
+	rc    io.ReadCloser
+	tp    line.TimeProvider
+	every time.Duration
+	mem   memory.Allocator
+	ts    execute.TransformationSet
+
+	times  []values.Time
+	values []string
+}
+
+func (ss *streamingSocketSource) AddTransformation(t execute.Transformation) {
+	ss.ts = append(ss.ts, t)
+}
+
+func (ss *streamingSocketSource) Run(ctx context.Context) {
+	err := ss.run(ctx)
+	ss.ts.Finish(ss.d, err)
+}
+
+func (ss *streamingSocketSource) run(ctx context.Context) error {
+	// Closing the connection stops the reader when the query is cancelled.
+	defer ss.rc.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	lines := make(chan string)
+	errC := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(ss.rc)
+		for {
+			s, err := r.ReadString('\n')
+			if err != nil {
+				// Like the line decoder, a line
+				// without a separator is dropped.
+				if err == io.EOF {
+					err = nil
+				}
+				errC <- err
+				return
+			}
+			select {
+			case lines <- strings.Trim(s, "\n"):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(ss.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case s := <-lines:
+			ss.times = append(ss.times, ss.tp.CurrentTime())
+			ss.values = append(ss.values, s)
+		case <-ticker.C:
+			if err := ss.flush(); err != nil {
+				return err
+			}
+		case err := <-errC:
+			if err != nil {
+				return errors.Wrap(err, codes.Inherit, "decode error")
+			}
+			// Every line is received before the reader stops,
+			// so the last lines are in the buffer.
+			return ss.flush()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// flush sends the lines that were read since the last flush
+// and advances the watermark to the current time.
+func (ss *streamingSocketSource) flush() error {
+	if len(ss.values) > 0 {
+		tbl, err := ss.table()
+		if err != nil {
+			return err
+		}
+		if err := ss.ts.Process(ss.d, tbl); err != nil {
+			return err
+		}
+		ss.times, ss.values = ss.times[:0], ss.values[:0]
+	}
+
+	now := ss.tp.CurrentTime()
+	if err := ss.ts.UpdateWatermark(ss.d, now); err != nil {
+		return err
+	}
+	return ss.ts.UpdateProcessingTime(ss.d, now)
+}
+
+// table builds a table with the same schema as the line decoder.
+func (ss *streamingSocketSource) table() (flux.Table, error) {
+	key := execute.NewGroupKey(nil, nil)
+	builder := execute.NewColListTableBuilder(key, ss.mem)
+	timeIdx, err := builder.AddCol(flux.ColMeta{Label: "_time", Type: flux.TTime})
+	if err != nil {
+		return nil, err
+	}
+	valueIdx, err := builder.AddCol(flux.ColMeta{Label: "_value", Type: flux.TString})
+	if err != nil {
+		return nil, err
+	}
+	for i := range ss.values {
+		if err := builder.AppendTime(timeIdx, ss.times[i]); err != nil {
+			return nil, err
+		}
+		if err := builder.AppendString(valueIdx, ss.values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Table()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/InfluxCommunity/flux/execute"
 	"github.com/InfluxCommunity/flux/execute/executetest"
 	"github.com/InfluxCommunity/flux/internal/operation"
+	"github.com/InfluxCommunity/flux/memory"
 	"github.com/InfluxCommunity/flux/mock"
 	"github.com/InfluxCommunity/flux/plan"
 	"github.com/InfluxCommunity/flux/querytest"
@@ -36,6 +37,29 @@ socket.from()`,
 socket.from(url: "url", decoder: "wrong")`,
 			WantErr: true,
 		},
+		{
+			Name: "from every with csv",
+			Raw: `import "socket"
+socket.from(url: "url", decoder: "csv", every: 1s)`,
+			WantErr: true,
+		},
+		{
+			Name: "from every",
+			Raw: `import "socket"
+socket.from(url: "url", decoder: "line", every: 1s)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromSocket0",
+						Spec: &socket.FromSocketOpSpec{
+							URL:     "url",
+							Decoder: "line",
+							Every:   flux.ConvertDuration(time.Second),
+						},
+					},
+				},
+			},
+		},
 		{
 			Name: "from ok",
 			Raw: `import "socket"
@@ -204,7 +228,7 @@ source
 			c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
 			c.SetTriggerSpec(plan.DefaultTriggerSpec)
 			r := io.NopCloser(bytes.NewReader([]byte(tc.input)))
-			ss, err := socket.NewSocketSource(tc.spec, r, &mock.AscendingTimeProvider{}, id)
+			ss, err := socket.NewSocketSource(tc.spec, r, &mock.AscendingTimeProvider{}, id, executetest.UnlimitedAllocator)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -232,3 +256,92 @@ source
 		})
 	}
 }
+
+func TestFromSocketSource_RunStreaming(t *testing.T) {
+	r, w := io.Pipe()
+	spec := &socket.FromSocketProcedureSpec{
+		Decoder: "line",
+		Every:   flux.ConvertDuration(time.Millisecond),
+	}
+	mem := &memory.ResourceAllocator{}
+	ss, err := socket.NewSocketSource(spec, r, &mock.AscendingTimeProvider{}, executetest.RandomDatasetID(), mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The source calls the transformation from its own goroutine,
+	// so the tables are sent to the test on a channel.
+	var (
+		last       execute.Time
+		watermarks int
+	)
+	tables := make(chan *executetest.Table, 10)
+	done := make(chan error, 1)
+	ss.AddTransformation(&mock.Transformation{
+		ProcessFn: func(id execute.DatasetID, tbl flux.Table) error {
+			cpy, err := executetest.ConvertTable(tbl)
+			if err != nil {
+				return err
+			}
+			last = cpy.Data[len(cpy.Data)-1][0].(execute.Time)
+			tables <- cpy
+			return nil
+		},
+		UpdateWatermarkFn: func(id execute.DatasetID, ts execute.Time) error {
+			// No line that was sent may be after the watermark.
+			if ts <= last {
+				t.Errorf("watermark %v is not after the last line at %v", ts, last)
+			}
+			watermarks++
+			return nil
+		},
+		FinishFn: func(id execute.DatasetID, err error) {
+			close(tables)
+			done <- err
+		},
+	})
+	go ss.Run(context.Background())
+
+	write := func(s string) {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for the first lines to be sent before the
+	// connection is closed so that the source sends them
+	// while the connection is open.
+	write("a\nb\n")
+	var got []*executetest.Table
+	for n := 0; n < 2; {
+		tbl := <-tables
+		got = append(got, tbl)
+		n += len(tbl.Data)
+	}
+	write("c\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for tbl := range tables {
+		got = append(got, tbl)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	for _, tbl := range got {
+		for _, row := range tbl.Data {
+			values = append(values, row[1].(string))
+		}
+	}
+	if want := []string{"a", "b", "c"}; !cmp.Equal(want, values) {
+		t.Errorf("unexpected values -want/+got\n%s", cmp.Diff(want, values))
+	}
+	if watermarks < len(got) {
+		t.Errorf("expected a watermark after every table, got %d watermarks for %d tables", watermarks, len(got))
+	}
+	if mem.TotalAllocated() == 0 {
+		t.Error("expected the tables to be built with the allocator of the source")
+	}
+}
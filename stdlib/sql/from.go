@@ -11,6 +11,7 @@ import (
 	"github.com/InfluxCommunity/flux/memory"
 	"github.com/InfluxCommunity/flux/plan"
 	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/values"
 	_ "github.com/lib/pq"
 	_ "github.com/vertica/vertica-sql-go"
 )
@@ -21,9 +22,11 @@ const FromSQLKind = "fromSQL"
 const layout = "2006-01-02 15:04:05.999999999"
 
 type FromSQLOpSpec struct {
-	DriverName     string `json:"driverName,omitempty"`
-	DataSourceName string `json:"dataSourceName,omitempty"`
-	Query          string `json:"query,omitempty"`
+	DriverName     string                 `json:"driverName,omitempty"`
+	DataSourceName string                 `json:"dataSourceName,omitempty"`
+	Query          string                 `json:"query,omitempty"`
+	Params         []interface{}          `json:"params,omitempty"`
+	NamedParams    map[string]interface{} `json:"namedParams,omitempty"`
 }
 
 func init() {
@@ -51,6 +54,48 @@ func createFromSQLOpSpec(args flux.Arguments, administration *flux.Administratio
 	} else {
 		spec.Query = query
 	}
+
+	if params, ok := args.Get("params"); ok {
+		arr := params.Array()
+		spec.Params = make([]interface{}, 0, arr.Len())
+		var err error
+		arr.Range(func(i int, v values.Value) {
+			if err != nil {
+				return
+			}
+			var p interface{}
+			if p, err = paramValue(v); err != nil {
+				err = errors.Wrapf(err, codes.Inherit, "params[%d]", i)
+				return
+			}
+			spec.Params = append(spec.Params, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if namedParams, ok, err := args.GetObject("namedParams"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.NamedParams = make(map[string]interface{}, namedParams.Len())
+		namedParams.Range(func(name string, v values.Value) {
+			if err != nil {
+				return
+			}
+			var p interface{}
+			if p, err = paramValue(v); err != nil {
+				err = errors.Wrapf(err, codes.Inherit, "namedParams.%s", name)
+				return
+			}
+			spec.NamedParams[name] = p
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if spec.Params != nil && spec.NamedParams != nil {
+		return nil, errors.New(codes.Invalid, "params and namedParams cannot be used together")
+	}
 	return spec, nil
 }
 
@@ -63,6 +108,8 @@ type FromSQLProcedureSpec struct {
 	DriverName     string
 	DataSourceName string
 	Query          string
+	Params         []interface{}
+	NamedParams    map[string]interface{}
 }
 
 func newFromSQLProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
@@ -75,6 +122,8 @@ func newFromSQLProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.Pr
 		DriverName:     spec.DriverName,
 		DataSourceName: spec.DataSourceName,
 		Query:          spec.Query,
+		Params:         spec.Params,
+		NamedParams:    spec.NamedParams,
 	}, nil
 }
 
@@ -87,6 +136,16 @@ func (s *FromSQLProcedureSpec) Copy() plan.ProcedureSpec {
 	ns.DriverName = s.DriverName
 	ns.DataSourceName = s.DataSourceName
 	ns.Query = s.Query
+	if s.Params != nil {
+		ns.Params = make([]interface{}, len(s.Params))
+		copy(ns.Params, s.Params)
+	}
+	if s.NamedParams != nil {
+		ns.NamedParams = make(map[string]interface{}, len(s.NamedParams))
+		for k, v := range s.NamedParams {
+			ns.NamedParams[k] = v
+		}
+	}
 	return ns
 }
 
@@ -135,6 +194,12 @@ func createFromSQLSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a ex
 		return nil, errors.Newf(codes.Invalid, "sql driver %s not supported", spec.DriverName)
 	}
 
+	// Rewrite the placeholders of the query for the driver.
+	query, args, err := bindParams(spec.DriverName, spec.Query, spec.Params, spec.NamedParams)
+	if err != nil {
+		return nil, err
+	}
+
 	readFn := func(ctx context.Context, rows *sql.Rows) (flux.Table, error) {
 		reader, err := newRowReader(rows)
 		if err != nil {
@@ -143,16 +208,18 @@ func createFromSQLSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a ex
 		}
 		return read(ctx, reader, a.Allocator())
 	}
-	iterator := &sqlIterator{spec: spec, id: dsid, read: readFn}
+	iterator := &sqlIterator{spec: spec, id: dsid, query: query, args: args, read: readFn}
 	return execute.CreateSourceFromIterator(iterator, dsid)
 }
 
 var _ execute.SourceIterator = (*sqlIterator)(nil)
 
 type sqlIterator struct {
-	spec *FromSQLProcedureSpec
-	id   execute.DatasetID
-	read func(ctx context.Context, rows *sql.Rows) (flux.Table, error)
+	spec  *FromSQLProcedureSpec
+	id    execute.DatasetID
+	query string
+	args  []interface{}
+	read  func(ctx context.Context, rows *sql.Rows) (flux.Table, error)
 }
 
 func (c *sqlIterator) connect(ctx context.Context) (*sql.DB, error) {
@@ -175,7 +242,7 @@ func (c *sqlIterator) Do(ctx context.Context, f func(flux.Table) error) error {
 	}
 	defer func() { _ = db.Close() }()
 
-	rows, err := db.QueryContext(ctx, c.spec.Query)
+	rows, err := db.QueryContext(ctx, c.query, c.args...)
 	if err != nil {
 		return errors.Wrap(err, codes.Invalid)
 	}
@@ -0,0 +1,224 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/semantic"
+	"github.com/InfluxCommunity/flux/values"
+)
+
+// paramValue converts a Flux value into a value
+// that can be bound to a query parameter.
+func paramValue(v values.Value) (interface{}, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+	switch v.Type().Nature() {
+	case semantic.Int:
+		return v.Int(), nil
+	case semantic.UInt:
+		return v.UInt(), nil
+	case semantic.Float:
+		return v.Float(), nil
+	case semantic.String:
+		return v.Str(), nil
+	case semantic.Bool:
+		return v.Bool(), nil
+	case semantic.Time:
+		return v.Time().Time(), nil
+	case semantic.Bytes:
+		return v.Bytes(), nil
+	default:
+		return nil, errors.Newf(codes.Invalid, "unsupported query parameter type %v", v.Type())
+	}
+}
+
+// placeholderFunc returns a function that formats
+// the nth positional placeholder for the driver.
+func placeholderFunc(driverName string) func(n int) string {
+	switch driverName {
+	case "postgres":
+		return func(n int) string { return fmt.Sprintf("$%d", n) }
+	case "mssql", "sqlserver":
+		return func(n int) string { return fmt.Sprintf("@p%d", n) }
+	default:
+		return func(n int) string { return "?" }
+	}
+}
+
+// hasBackslashEscapes reports whether the driver
+// escapes characters in string literals with a backslash.
+func hasBackslashEscapes(driverName string) bool {
+	switch driverName {
+	case "mysql", "clickhouse", "bigquery":
+		return true
+	default:
+		return false
+	}
+}
+
+// hasDollarQuotes reports whether the driver supports
+// dollar-quoted string literals such as $$text$$ or $tag$text$tag$.
+func hasDollarQuotes(driverName string) bool {
+	return driverName == "postgres"
+}
+
+// bindParams rewrites the placeholders of the query into the placeholders
+// of the driver and returns the arguments that are bound to them.
+// A query without params or namedParams is never rewritten.
+//
+// Positional parameters are written as ? in the query and named parameters
+// as :name. A named parameter may be used more than once. Placeholders in
+// string literals, quoted identifiers and comments are left as they are.
+// If a query with positional parameters has no ? placeholders, it is
+// assumed to use the placeholders of the driver and is not rewritten.
+//
+// In postgres, ? is also an operator of the jsonb type. A postgres query
+// that uses $n placeholders is not rewritten, so the operator can be used
+// together with params.
+func bindParams(driverName, query string, params []interface{}, namedParams map[string]interface{}) (string, []interface{}, error) {
+	if params == nil && namedParams == nil {
+		return query, nil, nil
+	}
+	if params != nil && namedParams != nil {
+		return "", nil, errors.New(codes.Invalid, "params and namedParams cannot be used together")
+	}
+
+	var (
+		placeholder = placeholderFunc(driverName)
+		backslash   = hasBackslashEscapes(driverName)
+		dollar      = hasDollarQuotes(driverName)
+		sb          strings.Builder
+		args        []interface{}
+		positional  int
+		// numbered is true if the query has $n placeholders.
+		numbered bool
+	)
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '$' && dollar && i+1 < len(query) && '0' <= query[i+1] && query[i+1] <= '9':
+			numbered = true
+			sb.WriteByte(c)
+			i++
+		case c == '$' && dollar:
+			end := skipDollarQuoted(query, i)
+			sb.WriteString(query[i:end])
+			i = end
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, backslash && c != '`')
+			sb.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			sb.WriteString(query[i:end])
+			i = end
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			// A type cast such as value::text.
+			sb.WriteString("::")
+			i += 2
+		case c == ':' && namedParams != nil && i+1 < len(query) && isIdentStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isIdentPart(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			v, ok := namedParams[name]
+			if !ok {
+				return "", nil, errors.Newf(codes.Invalid, "query parameter %q is not in namedParams", name)
+			}
+			args = append(args, v)
+			sb.WriteString(placeholder(len(args)))
+			i = end
+		case c == '?' && params != nil:
+			positional++
+			if positional <= len(params) {
+				args = append(args, params[positional-1])
+				sb.WriteString(placeholder(len(args)))
+			} else {
+				sb.WriteByte(c)
+			}
+			i++
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	if params != nil {
+		if positional == 0 || numbered {
+			// The query uses the placeholders of the driver.
+			return query, params, nil
+		} else if positional > len(params) {
+			return "", nil, errors.Newf(codes.Invalid, "query has more placeholders than the %d params", len(params))
+		} else if positional != len(params) {
+			return "", nil, errors.Newf(codes.Invalid, "query has %d placeholders but %d params were given", positional, len(params))
+		}
+	}
+	return sb.String(), args, nil
+}
+
+// skipQuoted returns the index after the quoted string or
+// identifier that starts at i. A quote is escaped by doubling
+// it or, if backslash is true, with a backslash.
+func skipQuoted(query string, i int, backslash bool) int {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// skipDollarQuoted returns the index after the dollar-quoted string
+// that starts at i. If there is no dollar quote at i, it returns
+// the index after the dollar sign.
+func skipDollarQuoted(query string, i int) int {
+	end := i + 1
+	for end < len(query) && query[end] != '$' {
+		if !isIdentPart(query[end]) || end == i+1 && !isIdentStart(query[end]) {
+			return i + 1
+		}
+		end++
+	}
+	if end >= len(query) {
+		return i + 1
+	}
+	delim := query[i : end+1]
+	if n := strings.Index(query[end+1:], delim); n >= 0 {
+		return end + 1 + n + len(delim)
+	}
+	return len(query)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9'
+}
@@ -0,0 +1,162 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/flux/values"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBindParams(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		driverName  string
+		query       string
+		params      []interface{}
+		namedParams map[string]interface{}
+		wantQuery   string
+		wantArgs    []interface{}
+		wantErr     string
+	}{
+		{
+			name:       "no params",
+			driverName: "postgres",
+			query:      "SELECT * FROM t WHERE a = '?'",
+			wantQuery:  "SELECT * FROM t WHERE a = '?'",
+		},
+		{
+			name:       "positional mysql",
+			driverName: "mysql",
+			query:      "SELECT * FROM t WHERE a = ? AND b = ?",
+			params:     []interface{}{int64(1), "x"},
+			wantQuery:  "SELECT * FROM t WHERE a = ? AND b = ?",
+			wantArgs:   []interface{}{int64(1), "x"},
+		},
+		{
+			name:       "positional postgres",
+			driverName: "postgres",
+			query:      "SELECT * FROM t WHERE a = ? AND b = '?' AND c = ?::text -- ?\n",
+			params:     []interface{}{int64(1), "x"},
+			wantQuery:  "SELECT * FROM t WHERE a = $1 AND b = '?' AND c = $2::text -- ?\n",
+			wantArgs:   []interface{}{int64(1), "x"},
+		},
+		{
+			name:       "positional sqlserver",
+			driverName: "sqlserver",
+			query:      `SELECT * FROM "t?" WHERE a = ? /* ? */ AND b = ?`,
+			params:     []interface{}{int64(1), "x"},
+			wantQuery:  `SELECT * FROM "t?" WHERE a = @p1 /* ? */ AND b = @p2`,
+			wantArgs:   []interface{}{int64(1), "x"},
+		},
+		{
+			name:       "driver placeholders",
+			driverName: "postgres",
+			query:      "SELECT * FROM t WHERE a = $1",
+			params:     []interface{}{int64(1)},
+			wantQuery:  "SELECT * FROM t WHERE a = $1",
+			wantArgs:   []interface{}{int64(1)},
+		},
+		{
+			name:       "dollar quoted postgres",
+			driverName: "postgres",
+			query:      "SELECT $$?$$, $tag$ :a ? $$ $tag$, a$b FROM t WHERE a = ?",
+			params:     []interface{}{int64(1)},
+			wantQuery:  "SELECT $$?$$, $tag$ :a ? $$ $tag$, a$b FROM t WHERE a = $1",
+			wantArgs:   []interface{}{int64(1)},
+		},
+		{
+			name:       "jsonb operator postgres",
+			driverName: "postgres",
+			query:      "SELECT * FROM t WHERE data ? 'k' AND data ?| array['a'] AND a = $1",
+			params:     []interface{}{int64(1)},
+			wantQuery:  "SELECT * FROM t WHERE data ? 'k' AND data ?| array['a'] AND a = $1",
+			wantArgs:   []interface{}{int64(1)},
+		},
+		{
+			name:        "named",
+			driverName:  "postgres",
+			query:       "SELECT * FROM t WHERE a = :a AND b = :b OR a = :a AND c = ':a'",
+			namedParams: map[string]interface{}{"a": int64(1), "b": "x", "unused": true},
+			wantQuery:   "SELECT * FROM t WHERE a = $1 AND b = $2 OR a = $3 AND c = ':a'",
+			wantArgs:    []interface{}{int64(1), "x", int64(1)},
+		},
+		{
+			name:        "named with backslash escapes",
+			driverName:  "mysql",
+			query:       `SELECT * FROM t WHERE a = 'it\'s :a' AND b = :a`,
+			namedParams: map[string]interface{}{"a": int64(1)},
+			wantQuery:   `SELECT * FROM t WHERE a = 'it\'s :a' AND b = ?`,
+			wantArgs:    []interface{}{int64(1)},
+		},
+		{
+			name:        "missing named param",
+			driverName:  "postgres",
+			query:       "SELECT * FROM t WHERE a = :a",
+			namedParams: map[string]interface{}{},
+			wantErr:     `query parameter "a" is not in namedParams`,
+		},
+		{
+			name:       "too many placeholders",
+			driverName: "mysql",
+			query:      "SELECT * FROM t WHERE a = ? AND b = ?",
+			params:     []interface{}{int64(1)},
+			wantErr:    "query has more placeholders than the 1 params",
+		},
+		{
+			name:       "too few placeholders",
+			driverName: "mysql",
+			query:      "SELECT * FROM t WHERE a = ?",
+			params:     []interface{}{int64(1), int64(2)},
+			wantErr:    "query has 1 placeholders but 2 params were given",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := bindParams(tt.driverName, tt.query, tt.params, tt.namedParams)
+			if err != nil {
+				if tt.wantErr == "" {
+					t.Fatal(err)
+				} else if want, got := tt.wantErr, err.Error(); want != got {
+					t.Fatalf("unexpected error -want/+got\n\t- %s\n\t+ %s", want, got)
+				}
+				return
+			} else if tt.wantErr != "" {
+				t.Fatal("expected error")
+			}
+			if want, got := tt.wantQuery, query; want != got {
+				t.Errorf("unexpected query -want/+got\n\t- %s\n\t+ %s", want, got)
+			}
+			if !cmp.Equal(tt.wantArgs, args) {
+				t.Errorf("unexpected args -want/+got\n%s", cmp.Diff(tt.wantArgs, args))
+			}
+		})
+	}
+}
+
+func TestParamValue(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		v    values.Value
+		want interface{}
+	}{
+		{v: values.NewInt(1), want: int64(1)},
+		{v: values.NewUInt(1), want: uint64(1)},
+		{v: values.NewFloat(1.5), want: 1.5},
+		{v: values.NewString("a"), want: "a"},
+		{v: values.NewBool(true), want: true},
+		{v: values.NewTime(values.ConvertTime(now)), want: now},
+		{v: values.NewBytes([]byte("a")), want: []byte("a")},
+	} {
+		got, err := paramValue(tt.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(tt.want, got) {
+			t.Errorf("unexpected value -want/+got\n%s", cmp.Diff(tt.want, got))
+		}
+	}
+
+	if _, err := paramValue(values.NewDuration(values.ConvertDurationNsecs(time.Second))); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -3,6 +3,7 @@ package sql
 import (
 	"database/sql"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/InfluxCommunity/flux"
@@ -11,6 +12,7 @@ import (
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/plan"
 	"github.com/InfluxCommunity/flux/runtime"
+	"github.com/InfluxCommunity/flux/semantic"
 	"github.com/InfluxCommunity/flux/values"
 )
 
@@ -20,10 +22,15 @@ const (
 )
 
 type ToSQLOpSpec struct {
-	DriverName     string `json:"driverName,omitempty"`
-	DataSourceName string `json:"dataSourcename,omitempty"`
-	Table          string `json:"table,omitempty"`
-	BatchSize      int    `json:"batchSize,omitempty"`
+	DriverName     string            `json:"driverName,omitempty"`
+	DataSourceName string            `json:"dataSourcename,omitempty"`
+	Table          string            `json:"table,omitempty"`
+	BatchSize      int               `json:"batchSize,omitempty"`
+	KeyColumns     []string          `json:"keyColumns,omitempty"`
+	OnConflict     string            `json:"onConflict,omitempty"`
+	ColumnTypes    map[string]string `json:"columnTypes,omitempty"`
+	// NoTransaction disables writing all rows in a single transaction.
+	NoTransaction bool `json:"noTransaction,omitempty"`
 }
 
 func init() {
@@ -71,9 +78,64 @@ func (o *ToSQLOpSpec) ReadArgs(args flux.Arguments) error {
 		o.BatchSize = int(b)
 	}
 
+	if keyColumns, ok, err := args.GetArray("keyColumns", semantic.String); err != nil {
+		return err
+	} else if ok {
+		o.KeyColumns = make([]string, 0, keyColumns.Len())
+		keyColumns.Range(func(i int, v values.Value) {
+			o.KeyColumns = append(o.KeyColumns, v.Str())
+		})
+	}
+
+	if onConflict, ok, err := args.GetString("onConflict"); err != nil {
+		return err
+	} else if ok {
+		if err := validateOnConflict(o.DriverName, onConflict, o.KeyColumns); err != nil {
+			return err
+		}
+		o.OnConflict = onConflict
+	}
+
+	if columnTypes, ok, err := args.GetDictionary("columnTypes"); err != nil {
+		return err
+	} else if ok {
+		o.ColumnTypes = make(map[string]string, columnTypes.Len())
+		columnTypes.Range(func(k, v values.Value) {
+			if err == nil {
+				err = validateColumnType(k.Str(), v.Str())
+			}
+			o.ColumnTypes[k.Str()] = v.Str()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if transaction, ok, err := args.GetBool("transaction"); err != nil {
+		return err
+	} else if ok {
+		if transaction && !supportsTx(o.DriverName) {
+			return errors.Newf(codes.Invalid, "driver %s does not support transactions", o.DriverName)
+		}
+		o.NoTransaction = !transaction
+	}
+
 	return err
 }
 
+// columnTypePattern matches a SQL type name of one or more words with
+// an optional precision and scale, such as DOUBLE PRECISION or
+// DECIMAL(10, 2). The types are written into the CREATE TABLE statement
+// as they are, so anything else is rejected.
+var columnTypePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*( [A-Za-z_][A-Za-z0-9_]*)*( ?\( *[0-9]+ *(, *[0-9]+ *)?\))?$`)
+
+func validateColumnType(label, typ string) error {
+	if !columnTypePattern.MatchString(typ) {
+		return errors.Newf(codes.Invalid, "invalid SQL type %q for column %q", typ, label)
+	}
+	return nil
+}
+
 func createToSQLOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
 	if err := a.AddParentFromArgs(args); err != nil {
 		return nil, err
@@ -106,8 +168,20 @@ func (o *ToSQLProcedureSpec) Copy() plan.ProcedureSpec {
 			DataSourceName: s.DataSourceName,
 			Table:          s.Table,
 			BatchSize:      s.BatchSize,
+			OnConflict:     s.OnConflict,
+			NoTransaction:  s.NoTransaction,
 		},
 	}
+	if s.KeyColumns != nil {
+		res.Spec.KeyColumns = make([]string, len(s.KeyColumns))
+		copy(res.Spec.KeyColumns, s.KeyColumns)
+	}
+	if s.ColumnTypes != nil {
+		res.Spec.ColumnTypes = make(map[string]string, len(s.ColumnTypes))
+		for k, v := range s.ColumnTypes {
+			res.Spec.ColumnTypes[k] = v
+		}
+	}
 	return res
 }
 
@@ -141,6 +215,8 @@ type ToSQLTransformation struct {
 	spec  *ToSQLProcedureSpec
 	db    *sql.DB
 	tx    *sql.Tx
+	// exec runs the statements in the transaction if there is one.
+	exec Execer
 }
 
 func (t *ToSQLTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
@@ -161,20 +237,21 @@ func NewToSQLTransformation(d execute.Dataset, deps flux.Dependencies, cache exe
 	if err != nil {
 		return nil, err
 	}
-	var tx *sql.Tx
-	if supportsTx(spec.Spec.DriverName) {
-		tx, err = db.Begin()
-		if err != nil {
-			return nil, err
-		}
-	}
-	return &ToSQLTransformation{
+	t := &ToSQLTransformation{
 		d:     d,
 		cache: cache,
 		spec:  spec,
 		db:    db,
-		tx:    tx,
-	}, nil
+		exec:  db,
+	}
+	if supportsTx(spec.Spec.DriverName) && !spec.Spec.NoTransaction {
+		t.tx, err = db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		t.exec = t.tx
+	}
+	return t, nil
 }
 
 type idxType struct {
@@ -188,7 +265,7 @@ func (t *ToSQLTransformation) Process(id execute.DatasetID, tbl flux.Table) (err
 		return err
 	}
 	for i := range valStrings {
-		if err := ExecuteQueries(t.tx, t.spec.Spec, colNames, &valStrings[i], &valArgs[i]); err != nil {
+		if err := ExecuteQueries(t.exec, t.spec.Spec, colNames, &valStrings[i], &valArgs[i]); err != nil {
 			return err
 		}
 	}
@@ -206,14 +283,16 @@ func (t *ToSQLTransformation) UpdateProcessingTime(id execute.DatasetID, pt exec
 
 func (t *ToSQLTransformation) Finish(id execute.DatasetID, err error) {
 	if supportsTx(t.spec.Spec.DriverName) {
-		var txErr error
-		if err == nil {
-			txErr = t.tx.Commit()
-		} else {
-			txErr = t.tx.Rollback()
-		}
-		if txErr != nil {
-			err = errors.Wrap(err, codes.Inherit, txErr)
+		if t.tx != nil {
+			var txErr error
+			if err == nil {
+				txErr = t.tx.Commit()
+			} else {
+				txErr = t.tx.Rollback()
+			}
+			if txErr != nil {
+				err = errors.Wrap(err, codes.Inherit, txErr)
+			}
 		}
 		if dbErr := t.db.Close(); dbErr != nil {
 			err = errors.Wrap(err, codes.Inherit, dbErr)
@@ -224,6 +303,11 @@ func (t *ToSQLTransformation) Finish(id execute.DatasetID, err error) {
 
 type translationFunc func(f flux.ColType, colname string) (string, error)
 
+// Execer runs SQL statements. It is implemented by *sql.DB and *sql.Tx.
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // quoteIdentFunc is used to quote identifiers like table and column names for a
 // given SQL dialect.
 type quoteIdentFunc func(name string) string
@@ -369,6 +453,12 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 		return nil, nil, nil, err
 	}
 
+	for _, k := range t.spec.Spec.KeyColumns {
+		if execute.ColIdx(k, cols) < 0 {
+			return nil, nil, nil, errors.Newf(codes.FailedPrecondition, "key column %q is not in the table", k)
+		}
+	}
+
 	labels := make(map[string]idxType, len(cols))
 	var questionMarks, newSQLTableCols []string
 	for i, col := range cols {
@@ -382,6 +472,11 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 			// handled within each driver's implementation.
 			// The expectation is the identifiers in these values are
 			// quoted/escaped making them safe for formatting into SQL below.
+			if typ, ok := t.spec.Spec.ColumnTypes[col.Label]; ok {
+				// The type was given explicitly.
+				newSQLTableCols = append(newSQLTableCols, quoteIdent(col.Label)+" "+typ)
+				break
+			}
 			v, err := translateColumn()(col.Type, col.Label)
 			if err != nil {
 				return nil, nil, nil, err
@@ -392,6 +487,8 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 		}
 	}
 
+	keyDef, engineDef := primaryKeyClause(t.spec.Spec.DriverName, t.spec.Spec.OnConflict, quoteIdent, t.spec.Spec.KeyColumns)
+
 	// Creates the placeholders for values in the query
 	// eg: (?,?)
 	valuePlaceHolders := fmt.Sprintf("(%s)", strings.Join(questionMarks, ","))
@@ -418,7 +515,7 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 					singleQuote(t.spec.Spec.Table),
 					quoteIdent(t.spec.Spec.Table),
 					// XXX: Items in `newSQLTableCols` should include _quoted column identifiers_, ref: influxdata/idpe#8689
-					strings.Join(newSQLTableCols, ","),
+					strings.Join(newSQLTableCols, ",")+keyDef,
 				)
 			} else if t.spec.Spec.DriverName == "hdb" { // SAP HANA does not support IF NOT EXIST
 				// wrap CREATE TABLE statement with HDB-specific "if not exists" SQLScript check
@@ -426,7 +523,7 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 					"CREATE TABLE %s (%s)",
 					hdbEscapeName(t.spec.Spec.Table, true),
 					// XXX: Items in `newSQLTableCols` should include _quoted column identifiers_, ref: influxdata/idpe#8689
-					strings.Join(newSQLTableCols, ","),
+					strings.Join(newSQLTableCols, ",")+keyDef,
 				)
 				// The table name we pass to `hdbAddIfNotExist` cannot be escaped
 				// using `hdbEscapeName` here since it needs to appear as both a
@@ -439,10 +536,10 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 					"CREATE TABLE IF NOT EXISTS %s (%s)",
 					quoteIdent(t.spec.Spec.Table),
 					// XXX: Items in `newSQLTableCols` should include _quoted column identifiers_, ref: influxdata/idpe#8689
-					strings.Join(newSQLTableCols, ","),
-				)
+					strings.Join(newSQLTableCols, ",")+keyDef,
+				) + engineDef
 			}
-			_, err = t.tx.Exec(q)
+			_, err = t.exec.Exec(q)
 			if err != nil {
 				return err
 			}
@@ -518,8 +615,13 @@ func CreateInsertComponents(t *ToSQLTransformation, tbl flux.Table) (colNames []
 }
 
 // ExecuteQueries runs the SQL statements required to insert the new rows.
-func ExecuteQueries(tx *sql.Tx, s *ToSQLOpSpec, colNames []string, valueStrings *[]string, valueArgs *[]interface{}) (err error) {
-	concatValueStrings := strings.Join(*valueStrings, ",")
+//
+// The rows are inserted with exec, which is a *sql.Tx when the rows
+// are written in a transaction. The transaction is rolled back if
+// the statement fails.
+func ExecuteQueries(exec Execer, s *ToSQLOpSpec, colNames []string, valueStrings *[]string, valueArgs *[]interface{}) (err error) {
+	rowStrings, args := dedupRows(s, colNames, *valueStrings, *valueArgs)
+	concatValueStrings := strings.Join(rowStrings, ",")
 
 	quoteIdent, err := getQuoteIdentFunc(s.DriverName)
 	if err != nil {
@@ -542,11 +644,7 @@ func ExecuteQueries(tx *sql.Tx, s *ToSQLOpSpec, colNames []string, valueStrings
 	// N.B. identifiers that will be string formatted into SQL statements must be
 	// quoted/escaped, ref: influxdata/idpe#8689
 	quotedTable := quoteIdent(s.Table)
-	quotedColNames := make([]string, len(colNames))
-	for idx, name := range colNames {
-		quotedColNames[idx] = quoteIdent(name)
-	}
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", quotedTable, strings.Join(quotedColNames, ","), concatValueStrings)
+	query := insertQuery(s, quoteIdent, colNames, concatValueStrings)
 
 	if isMssqlDriver(s.DriverName) && mssqlCheckParameter(s.DataSourceName, mssqlIdentityInsertEnabled) {
 		// XXX: identifiers that will be string formatted into SQL statements must be quoted, ref: influxdata/idpe#8689
@@ -556,13 +654,17 @@ func ExecuteQueries(tx *sql.Tx, s *ToSQLOpSpec, colNames []string, valueStrings
 			quotedTable,
 		)
 		epilogue := fmt.Sprintf("IF @tableHasIdentity = 1 BEGIN SET IDENTITY_INSERT %s OFF END", quotedTable)
-		query = strings.Join([]string{prologue, query, epilogue}, "; ")
+		query = strings.Join([]string{prologue, strings.TrimSuffix(query, ";"), epilogue}, "; ")
 	}
 	if s.DriverName != "sqlmock" {
-		_, err := tx.Exec(query, *valueArgs...)
+		_, err := exec.Exec(query, args...)
 		if err != nil {
 			// this err which is extremely helpful as it comes from the SQL driver should be
 			// bubbled up further up the stack so user can see the issue
+			tx, ok := exec.(*sql.Tx)
+			if !ok {
+				return err
+			}
 			if rbErr := tx.Rollback(); rbErr != nil {
 				return errors.Newf(codes.Aborted, "transaction failed (%s) while recovering from %s", err, rbErr)
 			}
@@ -331,3 +331,24 @@ func assertErrorIsNil(t *testing.T, got error) {
 		t.Errorf("expected error to be nil, got %v", got)
 	}
 }
+
+func TestValidateColumnType(t *testing.T) {
+	for _, tt := range []struct {
+		typ     string
+		wantErr bool
+	}{
+		{typ: "INT"},
+		{typ: "VARCHAR(64)"},
+		{typ: "DECIMAL(10, 2)"},
+		{typ: "DOUBLE PRECISION"},
+		{typ: "TIMESTAMP WITH TIME ZONE"},
+		{typ: "INT); DROP TABLE x; --", wantErr: true},
+		{typ: "INT DEFAULT 'a'", wantErr: true},
+		{typ: "VARCHAR(64) -- comment", wantErr: true},
+		{typ: "", wantErr: true},
+	} {
+		if err := validateColumnType("c", tt.typ); (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error for %q: %v", tt.typ, err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/internal/errors"
+)
+
+// Modes that sql.to uses to resolve rows that conflict
+// with existing rows on the key columns.
+const (
+	// OnConflictError inserts the rows and fails on a conflict.
+	OnConflictError = "error"
+	// OnConflictUpdate replaces the existing rows.
+	OnConflictUpdate = "update"
+	// OnConflictIgnore keeps the existing rows.
+	OnConflictIgnore = "ignore"
+)
+
+// validateOnConflict checks that the driver can resolve conflicts with the mode.
+func validateOnConflict(driverName, onConflict string, keyColumns []string) error {
+	switch onConflict {
+	case OnConflictError:
+		return nil
+	case OnConflictUpdate, OnConflictIgnore:
+	default:
+		return errors.Newf(codes.Invalid, "invalid onConflict %q, must be one of %q, %q or %q", onConflict, OnConflictError, OnConflictUpdate, OnConflictIgnore)
+	}
+	if len(keyColumns) == 0 {
+		return errors.Newf(codes.Invalid, "onConflict %q requires keyColumns", onConflict)
+	}
+
+	switch driverName {
+	case "postgres", "sqlite3", "mysql", "mssql", "sqlserver", "snowflake", "sqlmock":
+		return nil
+	case "clickhouse":
+		// Rows with the same key are replaced when the
+		// ReplacingMergeTree engine merges its parts.
+		if onConflict == OnConflictUpdate {
+			return nil
+		}
+	}
+	return errors.Newf(codes.Invalid, "onConflict %q is not supported for driver %s", onConflict, driverName)
+}
+
+// primaryKeyClause returns the clause that is added to the
+// CREATE TABLE statement to define the key columns.
+func primaryKeyClause(driverName, onConflict string, quoteIdent quoteIdentFunc, keyColumns []string) (columnDef, tableDef string) {
+	if len(keyColumns) == 0 {
+		return "", ""
+	}
+	keys := quoteIdents(quoteIdent, keyColumns)
+	if driverName == "clickhouse" {
+		// ClickHouse does not enforce keys. Only the ReplacingMergeTree
+		// engine replaces the rows with the same key, so the rows are
+		// kept as they are unless conflicts should be updated.
+		engine := "MergeTree"
+		if onConflict == OnConflictUpdate {
+			engine = "ReplacingMergeTree"
+		}
+		return "", fmt.Sprintf(" ENGINE = %s ORDER BY (%s)", engine, keys)
+	}
+	return fmt.Sprintf(",PRIMARY KEY (%s)", keys), ""
+}
+
+// insertQuery returns the statement that inserts the rows of valueStrings
+// and resolves conflicts on the key columns according to the spec.
+func insertQuery(s *ToSQLOpSpec, quoteIdent quoteIdentFunc, colNames []string, valueStrings string) string {
+	table := quoteIdent(s.Table)
+	cols := quoteIdents(quoteIdent, colNames)
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, cols, valueStrings)
+	if s.OnConflict == "" || s.OnConflict == OnConflictError {
+		return insert
+	}
+
+	isKey := make(map[string]bool, len(s.KeyColumns))
+	for _, k := range s.KeyColumns {
+		isKey[k] = true
+	}
+	var others []string
+	for _, c := range colNames {
+		if !isKey[c] {
+			others = append(others, c)
+		}
+	}
+	update := s.OnConflict == OnConflictUpdate && len(others) > 0
+
+	switch s.DriverName {
+	case "postgres", "sqlite3", "sqlmock":
+		conflict := fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", quoteIdents(quoteIdent, s.KeyColumns))
+		if update {
+			sets := make([]string, len(others))
+			for i, c := range others {
+				sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", quoteIdent(c), quoteIdent(c))
+			}
+			conflict = fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", quoteIdents(quoteIdent, s.KeyColumns), strings.Join(sets, ","))
+		}
+		return insert + conflict
+	case "mysql":
+		if !update {
+			return "INSERT IGNORE" + strings.TrimPrefix(insert, "INSERT")
+		}
+		sets := make([]string, len(others))
+		for i, c := range others {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", quoteIdent(c), quoteIdent(c))
+		}
+		return insert + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ",")
+	case "mssql", "sqlserver", "snowflake":
+		var source string
+		if s.DriverName == "snowflake" {
+			// Snowflake names the columns of VALUES column1, column2 and so on.
+			aliases := make([]string, len(colNames))
+			for i, c := range colNames {
+				aliases[i] = fmt.Sprintf("column%d AS %s", i+1, quoteIdent(c))
+			}
+			source = fmt.Sprintf("(SELECT %s FROM VALUES %s) AS source", strings.Join(aliases, ","), valueStrings)
+		} else {
+			source = fmt.Sprintf("(VALUES %s) AS source (%s)", valueStrings, cols)
+		}
+		on := make([]string, len(s.KeyColumns))
+		for i, k := range s.KeyColumns {
+			on[i] = fmt.Sprintf("target.%s = source.%s", quoteIdent(k), quoteIdent(k))
+		}
+		query := fmt.Sprintf("MERGE INTO %s AS target USING %s ON %s", table, source, strings.Join(on, " AND "))
+		if update {
+			sets := make([]string, len(others))
+			for i, c := range others {
+				sets[i] = fmt.Sprintf("%s = source.%s", quoteIdent(c), quoteIdent(c))
+			}
+			query += " WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ",")
+		}
+		sources := make([]string, len(colNames))
+		for i, c := range colNames {
+			sources[i] = "source." + quoteIdent(c)
+		}
+		query += fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", cols, strings.Join(sources, ","))
+		if s.DriverName != "snowflake" {
+			// SQL Server requires MERGE to be terminated.
+			query += ";"
+		}
+		return query
+	default:
+		// The table engine resolves the conflicts, see validateOnConflict.
+		return insert
+	}
+}
+
+// dedupRows removes the rows of a batch that have the same key as another
+// row of the batch. A single statement cannot resolve a conflict between
+// two of its own rows: postgres and sqlite fail to update a row twice and
+// MERGE inserts both rows. The rows are kept as if they had been written
+// one at a time, so the last row of a key is kept when conflicts are
+// updated and the first row is kept when they are ignored.
+//
+// Every row has a value in valueArgs for each of the columns and a
+// placeholder string in valueStrings.
+func dedupRows(s *ToSQLOpSpec, colNames []string, valueStrings []string, valueArgs []interface{}) ([]string, []interface{}) {
+	switch s.OnConflict {
+	case OnConflictUpdate, OnConflictIgnore:
+	default:
+		return valueStrings, valueArgs
+	}
+	switch s.DriverName {
+	case "postgres", "sqlite3", "sqlmock", "mssql", "sqlserver", "snowflake":
+	default:
+		// MySQL resolves the conflicts of the rows in order and
+		// ClickHouse replaces them when it merges its parts.
+		return valueStrings, valueArgs
+	}
+
+	n := len(colNames)
+	if n == 0 || len(valueArgs) != n*len(valueStrings) {
+		return valueStrings, valueArgs
+	}
+	keyIdxs := make([]int, 0, len(s.KeyColumns))
+	for _, k := range s.KeyColumns {
+		for j, c := range colNames {
+			if c == k {
+				keyIdxs = append(keyIdxs, j)
+				break
+			}
+		}
+	}
+
+	// rows holds the index of the row that is kept for each key.
+	rows := make(map[string]int, len(valueStrings))
+	keep := make([]int, 0, len(valueStrings))
+	var sb strings.Builder
+	for i := range valueStrings {
+		sb.Reset()
+		for _, j := range keyIdxs {
+			v := valueArgs[i*n+j]
+			_, _ = fmt.Fprintf(&sb, "%T:%v\x00", v, v)
+		}
+		key := sb.String()
+		if k, ok := rows[key]; ok {
+			if s.OnConflict == OnConflictUpdate {
+				keep[k] = i
+			}
+			continue
+		}
+		rows[key] = len(keep)
+		keep = append(keep, i)
+	}
+	if len(keep) == len(valueStrings) {
+		return valueStrings, valueArgs
+	}
+
+	args := make([]interface{}, 0, len(keep)*n)
+	for _, i := range keep {
+		args = append(args, valueArgs[i*n:(i+1)*n]...)
+	}
+	return valueStrings[:len(keep)], args
+}
+
+func quoteIdents(quoteIdent quoteIdentFunc, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return strings.Join(quoted, ",")
+}
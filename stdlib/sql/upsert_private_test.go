@@ -0,0 +1,199 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInsertQuery(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		driverName string
+		onConflict string
+		want       string
+	}{
+		{
+			name:       "no conflict",
+			driverName: "postgres",
+			want:       `INSERT INTO "t" ("id","v") VALUES (?,?),(?,?)`,
+		},
+		{
+			name:       "postgres update",
+			driverName: "postgres",
+			onConflict: OnConflictUpdate,
+			want:       `INSERT INTO "t" ("id","v") VALUES (?,?),(?,?) ON CONFLICT ("id") DO UPDATE SET "v" = EXCLUDED."v"`,
+		},
+		{
+			name:       "sqlite ignore",
+			driverName: "sqlite3",
+			onConflict: OnConflictIgnore,
+			want:       `INSERT INTO "t" ("id","v") VALUES (?,?),(?,?) ON CONFLICT ("id") DO NOTHING`,
+		},
+		{
+			name:       "mysql update",
+			driverName: "mysql",
+			onConflict: OnConflictUpdate,
+			want:       "INSERT INTO `t` (`id`,`v`) VALUES (?,?),(?,?) ON DUPLICATE KEY UPDATE `v` = VALUES(`v`)",
+		},
+		{
+			name:       "mysql ignore",
+			driverName: "mysql",
+			onConflict: OnConflictIgnore,
+			want:       "INSERT IGNORE INTO `t` (`id`,`v`) VALUES (?,?),(?,?)",
+		},
+		{
+			name:       "mssql update",
+			driverName: "sqlserver",
+			onConflict: OnConflictUpdate,
+			want: `MERGE INTO "t" AS target USING (VALUES (?,?),(?,?)) AS source ("id","v") ON target."id" = source."id"` +
+				` WHEN MATCHED THEN UPDATE SET "v" = source."v"` +
+				` WHEN NOT MATCHED THEN INSERT ("id","v") VALUES (source."id",source."v");`,
+		},
+		{
+			name:       "snowflake ignore",
+			driverName: "snowflake",
+			onConflict: OnConflictIgnore,
+			want: `MERGE INTO "t" AS target USING (SELECT column1 AS "id",column2 AS "v" FROM VALUES (?,?),(?,?)) AS source ON target."id" = source."id"` +
+				` WHEN NOT MATCHED THEN INSERT ("id","v") VALUES (source."id",source."v")`,
+		},
+		{
+			name:       "clickhouse update",
+			driverName: "clickhouse",
+			onConflict: OnConflictUpdate,
+			want:       `INSERT INTO "t" ("id","v") VALUES (?,?),(?,?)`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quoteIdent, err := getQuoteIdentFunc(tt.driverName)
+			assertErrorIsNil(t, err)
+			s := &ToSQLOpSpec{
+				DriverName: tt.driverName,
+				Table:      "t",
+				KeyColumns: []string{"id"},
+				OnConflict: tt.onConflict,
+			}
+			got := insertQuery(s, quoteIdent, []string{"id", "v"}, "(?,?),(?,?)")
+			if want := tt.want; want != got {
+				t.Errorf("unexpected query -want/+got\n\t- %s\n\t+ %s", want, got)
+			}
+		})
+	}
+}
+
+func TestValidateOnConflict(t *testing.T) {
+	for _, tt := range []struct {
+		driverName string
+		onConflict string
+		keyColumns []string
+		wantErr    string
+	}{
+		{driverName: "hdb", onConflict: OnConflictError},
+		{driverName: "postgres", onConflict: OnConflictUpdate, keyColumns: []string{"id"}},
+		{driverName: "clickhouse", onConflict: OnConflictUpdate, keyColumns: []string{"id"}},
+		{
+			driverName: "postgres",
+			onConflict: "replace",
+			wantErr:    `invalid onConflict "replace", must be one of "error", "update" or "ignore"`,
+		},
+		{
+			driverName: "postgres",
+			onConflict: OnConflictIgnore,
+			wantErr:    `onConflict "ignore" requires keyColumns`,
+		},
+		{
+			driverName: "clickhouse",
+			onConflict: OnConflictIgnore,
+			keyColumns: []string{"id"},
+			wantErr:    `onConflict "ignore" is not supported for driver clickhouse`,
+		},
+		{
+			driverName: "vertica",
+			onConflict: OnConflictUpdate,
+			keyColumns: []string{"id"},
+			wantErr:    `onConflict "update" is not supported for driver vertica`,
+		},
+	} {
+		err := validateOnConflict(tt.driverName, tt.onConflict, tt.keyColumns)
+		if tt.wantErr == "" {
+			assertErrorIsNil(t, err)
+			continue
+		}
+		assertErrorMsg(t, tt.wantErr, err)
+	}
+}
+
+func TestPrimaryKeyClause(t *testing.T) {
+	quoteIdent, err := getQuoteIdentFunc("postgres")
+	assertErrorIsNil(t, err)
+	columnDef, tableDef := primaryKeyClause("postgres", OnConflictUpdate, quoteIdent, []string{"id", "_time"})
+	if want, got := `,PRIMARY KEY ("id","_time")`, columnDef; want != got {
+		t.Errorf("unexpected column definition -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+	if tableDef != "" {
+		t.Errorf("unexpected table definition %q", tableDef)
+	}
+
+	quoteIdent, err = getQuoteIdentFunc("clickhouse")
+	assertErrorIsNil(t, err)
+	columnDef, tableDef = primaryKeyClause("clickhouse", OnConflictUpdate, quoteIdent, []string{"id"})
+	if columnDef != "" {
+		t.Errorf("unexpected column definition %q", columnDef)
+	}
+	if want, got := ` ENGINE = ReplacingMergeTree ORDER BY ("id")`, tableDef; want != got {
+		t.Errorf("unexpected table definition -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+
+	_, tableDef = primaryKeyClause("clickhouse", OnConflictError, quoteIdent, []string{"id"})
+	if want, got := ` ENGINE = MergeTree ORDER BY ("id")`, tableDef; want != got {
+		t.Errorf("unexpected table definition -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+}
+
+func TestDedupRows(t *testing.T) {
+	colNames := []string{"id", "v"}
+	valueStrings := []string{"(?,?)", "(?,?)", "(?,?)", "(?,?)"}
+	valueArgs := []interface{}{"a", int64(1), "b", int64(2), "a", int64(3), int64(1), int64(4)}
+
+	testCases := []struct {
+		name       string
+		driverName string
+		onConflict string
+		wantArgs   []interface{}
+	}{
+		{
+			name:       "update keeps last row",
+			driverName: "postgres",
+			onConflict: OnConflictUpdate,
+			wantArgs:   []interface{}{"a", int64(3), "b", int64(2), int64(1), int64(4)},
+		},
+		{
+			name:       "ignore keeps first row",
+			driverName: "sqlserver",
+			onConflict: OnConflictIgnore,
+			wantArgs:   []interface{}{"a", int64(1), "b", int64(2), int64(1), int64(4)},
+		},
+		{
+			name:       "error keeps all rows",
+			driverName: "postgres",
+			onConflict: OnConflictError,
+			wantArgs:   valueArgs,
+		},
+		{
+			name:       "mysql keeps all rows",
+			driverName: "mysql",
+			onConflict: OnConflictUpdate,
+			wantArgs:   valueArgs,
+		},
+	}
+	for _, tt := range testCases {
+		s := &ToSQLOpSpec{DriverName: tt.driverName, OnConflict: tt.onConflict, KeyColumns: []string{"id"}}
+		gotStrings, gotArgs := dedupRows(s, colNames, valueStrings, valueArgs)
+		if want, got := len(tt.wantArgs)/len(colNames), len(gotStrings); want != got {
+			t.Errorf("%s: unexpected number of rows: want %d, got %d", tt.name, want, got)
+		}
+		if !cmp.Equal(tt.wantArgs, gotArgs) {
+			t.Errorf("%s: unexpected args -want/+got\n%s", tt.name, cmp.Diff(tt.wantArgs, gotArgs))
+		}
+	}
+}
@@ -62,11 +62,20 @@ func (s *CountProcedureSpec) Copy() plan.ProcedureSpec {
 	}
 }
 
+// PassThroughAttribute implements the PassThroughAttributer interface used by
+// the planner. The counts of the partitions of parallel data are summed once
+// the partitions are merged, see PartitionRule.
+func (s *CountProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	return attrKey == plan.ParallelRunKey || s.SimpleAggregateConfig.PassThroughAttribute(attrKey)
+}
+
 func (s *CountProcedureSpec) AggregateMethod() string {
 	return CountKind
 }
 func (s *CountProcedureSpec) ReAggregateSpec() plan.ProcedureSpec {
-	return new(SumProcedureSpec)
+	return &SumProcedureSpec{
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
 }
 
 // TriggerSpec implements plan.TriggerAwareProcedureSpec
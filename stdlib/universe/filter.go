@@ -109,6 +109,11 @@ func (s *FilterProcedureSpec) PassThroughAttribute(attrKey string) bool {
 	return false
 }
 
+// RowWise implements resultcache.RowWiseProcedureSpec.
+func (s *FilterProcedureSpec) RowWise() bool {
+	return true
+}
+
 func (s *FilterProcedureSpec) Kind() plan.ProcedureKind {
 	return FilterKind
 }
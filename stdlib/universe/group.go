@@ -9,6 +9,7 @@ import (
 	"github.com/InfluxCommunity/flux/arrow"
 	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/spill"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/internal/execute/dataset"
 	"github.com/InfluxCommunity/flux/internal/execute/table"
@@ -191,9 +192,16 @@ type groupTransformation struct {
 }
 
 func NewGroupTransformation(ctx context.Context, spec *GroupProcedureSpec, id execute.DatasetID, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	// The buffered tables are spilled to disk when spilling is enabled.
+	// This only applies when the tables are buffered by this transformation
+	// since the group transformation below streams the grouped tables.
+	spiller := spill.New(ctx, mem)
 	t := &groupTransformation{
 		cache: table.BuilderCache{
 			New: func(key flux.GroupKey) table.Builder {
+				if spiller.Enabled() {
+					return spill.NewBuilder(key, spiller, mem)
+				}
 				return table.NewBufferedBuilder(key, mem)
 			},
 		},
@@ -224,7 +232,8 @@ func (t *groupTransformation) Process(id execute.DatasetID, tbl flux.Table) erro
 	if key, ok, err := t.getTableKey(tbl.Key(), tbl.Cols()); err != nil {
 		return err
 	} else if ok {
-		ab, _ := table.GetBufferedBuilder(key, &t.cache)
+		var ab groupTableBuilder
+		t.cache.Get(key, &ab)
 		return t.appendTable(ab, tbl)
 	}
 
@@ -285,7 +294,13 @@ func (t *groupTransformation) getTableKey(key flux.GroupKey, cols []flux.ColMeta
 	return execute.NewGroupKey(cols, vs), true, nil
 }
 
-func (t *groupTransformation) appendTable(ab *table.BufferedBuilder, tbl flux.Table) error {
+// groupTableBuilder is a builder in the cache of the group transformation.
+type groupTableBuilder interface {
+	table.Builder
+	AppendTable(tbl flux.Table) error
+}
+
+func (t *groupTransformation) appendTable(ab groupTableBuilder, tbl flux.Table) error {
 	// Read the table and append each of the columns.
 	return ab.AppendTable(tbl)
 }
@@ -390,7 +405,8 @@ func (t *groupTransformation) groupByRow(tbl flux.Table) error {
 			return err
 		}
 
-		ab, _ := table.GetBufferedBuilder(key, &t.cache)
+		var ab groupTableBuilder
+		t.cache.Get(key, &ab)
 		return t.appendTable(ab, tbl)
 	})
 }
@@ -77,6 +77,13 @@ func newLimitProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.Proc
 func (s *LimitProcedureSpec) Kind() plan.ProcedureKind {
 	return LimitKind
 }
+
+// Cost estimates that a limit keeps at most N rows of every table.
+func (s *LimitProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	stats := plan.SumStatistics(inStats)
+	return plan.Cost{CPU: stats.Cardinality}, plan.LimitStatistics(stats, s.N)
+}
+
 func (s *LimitProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := new(LimitProcedureSpec)
 	*ns = *s
@@ -89,6 +89,14 @@ func newMapProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.Proced
 	}, nil
 }
 
+func (s *MapProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	switch attrKey {
+	case plan.ParallelRunKey:
+		return true
+	}
+	return false
+}
+
 func (s *MapProcedureSpec) Kind() plan.ProcedureKind {
 	return MapKind
 }
@@ -32,6 +32,14 @@ type vectorizedMapProcedureSpec struct {
 	Fn interpreter.ResolvedFunction
 }
 
+func (v *vectorizedMapProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	switch attrKey {
+	case plan.ParallelRunKey:
+		return true
+	}
+	return false
+}
+
 func (v *vectorizedMapProcedureSpec) Kind() plan.ProcedureKind {
 	return vectorizedMapKind
 }
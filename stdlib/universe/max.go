@@ -64,6 +64,13 @@ func (s *MaxProcedureSpec) Copy() plan.ProcedureSpec {
 	return ns
 }
 
+// PassThroughAttribute implements the PassThroughAttributer interface used by
+// the planner. PartitionRule selects the maximum of the rows that the
+// partitions of parallel data select once they are merged.
+func (s *MaxProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	return attrKey == plan.ParallelRunKey || s.SelectorConfig.PassThroughAttribute(attrKey)
+}
+
 // TriggerSpec implements plan.TriggerAwareProcedureSpec
 func (s *MaxProcedureSpec) TriggerSpec() plan.TriggerSpec {
 	return plan.NarrowTransformationTriggerSpec{}
@@ -5,15 +5,22 @@ import (
 
 	"github.com/InfluxCommunity/flux"
 	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/arrow"
 	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
 	"github.com/InfluxCommunity/flux/internal/errors"
 	"github.com/InfluxCommunity/flux/plan"
 	"github.com/InfluxCommunity/flux/runtime"
 	arrowmath "github.com/apache/arrow/go/v7/arrow/math"
+	"github.com/apache/arrow/go/v7/arrow/memory"
 )
 
-const MeanKind = "mean"
+const (
+	MeanKind        = "mean"
+	PartialMeanKind = "partialMean"
+	FinalMeanKind   = "finalMean"
+)
 
 type MeanOpSpec struct {
 	execute.SimpleAggregateConfig
@@ -25,6 +32,8 @@ func init() {
 	runtime.RegisterPackageValue("universe", MeanKind, flux.MustValue(flux.FunctionValue(MeanKind, CreateMeanOpSpec, meanSignature)))
 	plan.RegisterProcedureSpec(MeanKind, newMeanProcedure, MeanKind)
 	execute.RegisterTransformation(MeanKind, createMeanTransformation)
+	execute.RegisterTransformation(PartialMeanKind, createPartialMeanTransformation)
+	execute.RegisterTransformation(FinalMeanKind, createFinalMeanTransformation)
 }
 func CreateMeanOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
 	if err := a.AddParentFromArgs(args); err != nil {
@@ -157,3 +166,168 @@ func (a *MeanAgg) ValueFloat() float64 {
 func (a *MeanAgg) IsNull() bool {
 	return a.count == 0
 }
+
+// PartialMeanProcedureSpec computes the sum and the count of the values
+// of each column so that the mean of several partitions of a table
+// can be computed by FinalMeanProcedureSpec.
+type PartialMeanProcedureSpec struct {
+	execute.SimpleAggregateConfig
+}
+
+func (s *PartialMeanProcedureSpec) Kind() plan.ProcedureKind {
+	return PartialMeanKind
+}
+
+func (s *PartialMeanProcedureSpec) Copy() plan.ProcedureSpec {
+	return &PartialMeanProcedureSpec{
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// PassThroughAttribute implements the PassThroughAttributer interface used by
+// the planner. The partial means of parallel data are combined by
+// FinalMeanProcedureSpec once the partitions are merged.
+func (s *PartialMeanProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	return attrKey == plan.ParallelRunKey || s.SimpleAggregateConfig.PassThroughAttribute(attrKey)
+}
+
+// FinalMeanProcedureSpec computes the mean of each column from the sums
+// and counts produced by PartialMeanProcedureSpec.
+type FinalMeanProcedureSpec struct {
+	execute.SimpleAggregateConfig
+}
+
+func (s *FinalMeanProcedureSpec) Kind() plan.ProcedureKind {
+	return FinalMeanKind
+}
+
+func (s *FinalMeanProcedureSpec) Copy() plan.ProcedureSpec {
+	return &FinalMeanProcedureSpec{
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// meanCountColumn is the name of the column that holds
+// the partial count of the values of a column.
+func meanCountColumn(label string) string {
+	return "_mean_count_" + label
+}
+
+func createPartialMeanTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*PartialMeanProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	tr := &meanPhaseTransformation{columns: s.Columns}
+	return execute.NewAggregateTransformation(id, tr, a.Allocator())
+}
+
+func createFinalMeanTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*FinalMeanProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	tr := &meanPhaseTransformation{columns: s.Columns, final: true}
+	return execute.NewAggregateTransformation(id, tr, a.Allocator())
+}
+
+// meanPhaseTransformation computes either the partial or the final phase
+// of a mean. The state is a MeanAgg for each of the columns.
+type meanPhaseTransformation struct {
+	columns []string
+	final   bool
+}
+
+func (t *meanPhaseTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	aggs, _ := state.([]*MeanAgg)
+	if aggs == nil {
+		aggs = make([]*MeanAgg, len(t.columns))
+		for i := range aggs {
+			aggs[i] = new(MeanAgg)
+		}
+	}
+
+	for i, label := range t.columns {
+		if chunk.Key().HasCol(label) {
+			return nil, false, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
+		}
+		idx := chunk.Index(label)
+		if idx < 0 {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+		}
+
+		if t.final {
+			if err := t.mergePartial(aggs[i], chunk, idx); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+
+		switch typ := chunk.Col(idx).Type; typ {
+		case flux.TInt:
+			aggs[i].DoInt(chunk.Ints(idx))
+		case flux.TUInt:
+			aggs[i].DoUInt(chunk.Uints(idx))
+		case flux.TFloat:
+			aggs[i].DoFloat(chunk.Floats(idx))
+		default:
+			return nil, false, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", typ)
+		}
+	}
+	return aggs, true, nil
+}
+
+// mergePartial adds the sums and counts of a partial mean to the aggregate.
+func (t *meanPhaseTransformation) mergePartial(agg *MeanAgg, chunk table.Chunk, idx int) error {
+	label := chunk.Col(idx).Label
+	countIdx := chunk.Index(meanCountColumn(label))
+	if countIdx < 0 {
+		return errors.Newf(codes.Internal, "partial mean count for column %q does not exist", label)
+	}
+	if chunk.Col(idx).Type != flux.TFloat || chunk.Col(countIdx).Type != flux.TInt {
+		return errors.Newf(codes.Internal, "invalid partial mean for column %q", label)
+	}
+
+	sums, counts := chunk.Floats(idx), chunk.Ints(countIdx)
+	for i := 0; i < chunk.Len(); i++ {
+		if sums.IsValid(i) && counts.IsValid(i) {
+			agg.sum += sums.Value(i)
+			agg.count += counts.Value(i)
+		}
+	}
+	return nil
+}
+
+func (t *meanPhaseTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	aggs := state.([]*MeanAgg)
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+2*len(aggs)),
+	}
+	buffer.Columns = append(buffer.Columns, key.Cols()...)
+	buffer.Values = make([]array.Array, len(key.Cols()), cap(buffer.Columns))
+	for j := range key.Cols() {
+		buffer.Values[j] = arrow.Repeat(key.Cols()[j].Type, key.Value(j), 1, mem)
+	}
+
+	for i, agg := range aggs {
+		label := t.columns[i]
+		buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: label, Type: flux.TFloat})
+		if t.final {
+			buffer.Values = append(buffer.Values, array.FloatRepeat(agg.ValueFloat(), agg.IsNull(), 1, mem))
+			continue
+		}
+		buffer.Values = append(buffer.Values, array.FloatRepeat(agg.sum, false, 1, mem))
+		buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: meanCountColumn(label), Type: flux.TInt})
+		buffer.Values = append(buffer.Values, array.IntRepeat(agg.count, false, 1, mem))
+	}
+
+	if err := buffer.Validate(); err != nil {
+		return err
+	}
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *meanPhaseTransformation) Close() error {
+	return nil
+}
@@ -21,6 +21,10 @@ const (
 type PartitionMergeProcedureSpec struct {
 	plan.DefaultCost
 	Factor int
+	// Regroup merges the tables with the same group key that are produced
+	// by different partitions into a single table. It is required when
+	// a group key may be found in more than one partition.
+	Regroup bool
 }
 
 func (o *PartitionMergeProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
@@ -37,6 +41,13 @@ func (o *PartitionMergeProcedureSpec) RequiredAttributes() []plan.PhysicalAttrib
 	}
 }
 
+func (o *PartitionMergeProcedureSpec) PlanDetails() string {
+	if o.Regroup {
+		return "Regroup: true"
+	}
+	return ""
+}
+
 func (o *PartitionMergeProcedureSpec) Kind() plan.ProcedureKind {
 	return ParallelMergeKind
 }
@@ -45,6 +56,7 @@ func (o *PartitionMergeProcedureSpec) Copy() plan.ProcedureSpec {
 	return &PartitionMergeProcedureSpec{
 		DefaultCost: o.DefaultCost,
 		Factor:      o.Factor,
+		Regroup:     o.Regroup,
 	}
 }
 
@@ -80,6 +92,12 @@ type PartitionMergeTransformation struct {
 	mu               sync.Mutex
 	predecessorState map[execute.DatasetID]*parallelPredecessorState
 	finished         bool
+
+	// builders holds a buffered table of each partition for each group
+	// key until all of the partitions have finished when regrouping.
+	builders *execute.GroupLookup
+	// partitions is the index of the partition of each predecessor.
+	partitions map[execute.DatasetID]int
 }
 
 type parallelPredecessorState struct {
@@ -97,20 +115,31 @@ func NewPartitionMergeTransformation(ctx context.Context, dataset *execute.Passt
 	span, ctx = opentracing.StartSpanFromContext(ctx, "PartitionMergeTransformation.Process")
 
 	predecessorState := make(map[execute.DatasetID]*parallelPredecessorState, len(predecessors))
-	for _, id := range predecessors {
+	partitions := make(map[execute.DatasetID]int, len(predecessors))
+	for i, id := range predecessors {
 		predecessorState[id] = new(parallelPredecessorState)
+		partitions[id] = i
 	}
 
-	return &PartitionMergeTransformation{
+	t := &PartitionMergeTransformation{
 		ctx:              ctx,
 		dataset:          dataset,
 		span:             span,
 		alloc:            alloc,
 		predecessorState: predecessorState,
-	}, nil
+		partitions:       partitions,
+	}
+	if spec.Regroup {
+		t.builders = execute.NewGroupLookup()
+	}
+	return t, nil
 }
 
 func (t *PartitionMergeTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	if t.builders != nil {
+		return t.regroup(id, tbl)
+	}
+
 	passthroughBuilder := table.NewBufferedBuilder(tbl.Key(), t.alloc)
 
 	err := tbl.Do(func(er flux.ColReader) error {
@@ -135,6 +164,72 @@ func (t *PartitionMergeTransformation) Process(id execute.DatasetID, tbl flux.Ta
 	return t.dataset.Process(out)
 }
 
+// regroup appends the table to the buffered table of its partition
+// with the same group key.
+func (t *PartitionMergeTransformation) regroup(id execute.DatasetID, tbl flux.Table) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.finished {
+		tbl.Done()
+		return nil
+	}
+
+	builders := t.builders.LookupOrCreate(tbl.Key(), func() interface{} {
+		return make([]*table.BufferedBuilder, len(t.partitions))
+	}).([]*table.BufferedBuilder)
+	i := t.partitions[id]
+	if builders[i] == nil {
+		builders[i] = table.NewBufferedBuilder(tbl.Key(), t.alloc)
+	}
+	return builders[i].AppendTable(tbl)
+}
+
+// flush sends the regrouped tables to the dataset unless an error
+// has already happened, in which case the tables are released.
+// The rows of a table are in the order of the partitions, so rows
+// that are partitioned by time stay in time order.
+func (t *PartitionMergeTransformation) flush(err error) error {
+	defer t.builders.Clear()
+	release := func(builders []*table.BufferedBuilder) {
+		for _, b := range builders {
+			if b != nil {
+				b.Release()
+			}
+		}
+	}
+	if err != nil {
+		_ = t.builders.Range(func(key flux.GroupKey, value interface{}) error {
+			release(value.([]*table.BufferedBuilder))
+			return nil
+		})
+		return err
+	}
+	return t.builders.Range(func(key flux.GroupKey, value interface{}) error {
+		builders := value.([]*table.BufferedBuilder)
+		merged := table.NewBufferedBuilder(key, t.alloc)
+		for i, b := range builders {
+			if b == nil {
+				continue
+			}
+			tbl, err := b.Table()
+			if err == nil {
+				err = merged.AppendTable(tbl)
+			}
+			if err != nil {
+				release(builders[i+1:])
+				merged.Release()
+				return err
+			}
+		}
+		out, err := merged.Table()
+		if err != nil {
+			return err
+		}
+		return t.dataset.Process(out)
+	})
+}
+
 func (t *PartitionMergeTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -199,6 +294,9 @@ func (t *PartitionMergeTransformation) Finish(id execute.DatasetID, err error) {
 	}
 
 	if t.finished {
+		if t.builders != nil {
+			err = t.flush(err)
+		}
 		t.dataset.Finish(err)
 	}
 }
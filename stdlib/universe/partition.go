@@ -0,0 +1,364 @@
+package universe
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/array"
+	"github.com/InfluxCommunity/flux/arrow"
+	"github.com/InfluxCommunity/flux/codes"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/table"
+	"github.com/InfluxCommunity/flux/internal/arrowutil"
+	"github.com/InfluxCommunity/flux/internal/errors"
+	"github.com/InfluxCommunity/flux/internal/feature"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/apache/arrow/go/v7/arrow/bitutil"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+const PartitionKind = "partition"
+
+// minRowsPerPartition is the estimated number of rows that a partition
+// must process for the work it saves to outweigh the cost of running
+// and merging another copy of the transformations.
+const minRowsPerPartition = 10000
+
+func init() {
+	plan.RegisterParallelizeRules(PartitionRule{})
+	execute.RegisterTransformation(PartitionKind, createPartitionTransformation)
+}
+
+// PartitionProcedureSpec splits the tables produced by a single
+// predecessor into Factor partitions that are processed in parallel.
+// Tables are partitioned by group key unless ByTime is set, in which case
+// the rows of each table are partitioned by the time range they fall in.
+type PartitionProcedureSpec struct {
+	plan.DefaultCost
+	Factor int
+	ByTime bool
+}
+
+func (s *PartitionProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
+	return plan.PhysicalAttributes{
+		plan.ParallelRunKey: plan.ParallelRunAttribute{Factor: s.Factor},
+	}
+}
+
+func (s *PartitionProcedureSpec) PlanDetails() string {
+	return fmt.Sprintf("PartitionFactor: %d, PartitionByTime: %v", s.Factor, s.ByTime)
+}
+
+func (s *PartitionProcedureSpec) Kind() plan.ProcedureKind {
+	return PartitionKind
+}
+
+func (s *PartitionProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createPartitionTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*PartitionProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+
+	opts := a.ParallelOpts()
+	tr := &partitionTransformation{
+		group:  opts.Group,
+		factor: opts.Factor,
+	}
+	if s.ByTime {
+		// Without bounds the time ranges are not known,
+		// so fall back to partitioning by group key.
+		tr.bounds = a.StreamContext().Bounds()
+	}
+	return execute.NewNarrowTransformation(id, tr, a.Allocator())
+}
+
+// partitionTransformation keeps the data that belongs
+// to one of the partitions of its predecessor.
+type partitionTransformation struct {
+	group  int
+	factor int
+	bounds *execute.Bounds
+}
+
+func (t *partitionTransformation) Process(chunk table.Chunk, d *execute.TransportDataset, mem memory.Allocator) error {
+	if t.factor < 2 {
+		chunk.Retain()
+		return d.Process(chunk)
+	}
+
+	if t.bounds != nil {
+		idx := chunk.Index(execute.DefaultTimeColLabel)
+		if idx >= 0 && chunk.Col(idx).Type == flux.TTime && !chunk.Key().HasCol(execute.DefaultTimeColLabel) {
+			return t.processByTime(chunk, idx, d, mem)
+		}
+	}
+
+	if partitionOfKey(chunk.Key(), t.factor) != t.group {
+		return nil
+	}
+	chunk.Retain()
+	return d.Process(chunk)
+}
+
+// processByTime keeps the rows of the chunk with a time
+// in the time range of the partition. Rows without a time
+// are kept by the first partition.
+func (t *partitionTransformation) processByTime(chunk table.Chunk, idx int, d *execute.TransportDataset, mem memory.Allocator) error {
+	ts := chunk.Ints(idx)
+	bitset := memory.NewResizableBuffer(mem)
+	bitset.Resize(chunk.Len())
+	defer bitset.Release()
+
+	n := 0
+	for i := 0; i < chunk.Len(); i++ {
+		keep := t.group == 0
+		if ts.IsValid(i) {
+			keep = t.partitionOfTime(execute.Time(ts.Value(i))) == t.group
+		}
+		bitutil.SetBitTo(bitset.Buf(), i, keep)
+		if keep {
+			n++
+		}
+	}
+
+	// The first partition forwards empty chunks so that
+	// a table that is empty in every partition is not lost.
+	if n == 0 && t.group != 0 {
+		return nil
+	} else if n == chunk.Len() {
+		chunk.Retain()
+		return d.Process(chunk)
+	}
+
+	vs := make([]array.Array, len(chunk.Cols()))
+	for j, col := range chunk.Cols() {
+		arr := chunk.Values(j)
+		if chunk.Key().HasCol(col.Label) {
+			vs[j] = arrow.Slice(arr, 0, int64(n))
+			continue
+		}
+		vs[j] = arrowutil.Filter(arr, bitset.Bytes(), mem)
+	}
+	return d.Process(table.ChunkFromBuffer(arrow.TableBuffer{
+		GroupKey: chunk.Key(),
+		Columns:  chunk.Cols(),
+		Values:   vs,
+	}))
+}
+
+// partitionOfTime splits the bounds into equal time ranges
+// and returns the partition whose time range contains the time.
+func (t *partitionTransformation) partitionOfTime(ts execute.Time) int {
+	width := (t.bounds.Stop - t.bounds.Start) / execute.Time(t.factor)
+	if width <= 0 || ts < t.bounds.Start {
+		return 0
+	}
+	if p := int((ts - t.bounds.Start) / width); p < t.factor {
+		return p
+	}
+	return t.factor - 1
+}
+
+func (t *partitionTransformation) Close() error {
+	return nil
+}
+
+// partitionOfKey returns the partition that a group key is assigned to.
+func partitionOfKey(key flux.GroupKey, factor int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key.String()))
+	return int(h.Sum32() % uint32(factor))
+}
+
+// statelessPartitionKinds are the kinds of the transformations that
+// process each row independently and can run on every partition.
+var statelessPartitionKinds = []plan.ProcedureKind{
+	FilterKind,
+	MapKind,
+	vectorizedMapKind,
+	SchemaMutationKind,
+}
+
+// aggregatePartitionKinds are the kinds of the aggregates that can be
+// computed for every partition and then merged. See splitAggregate.
+var aggregatePartitionKinds = []plan.ProcedureKind{
+	SumKind,
+	CountKind,
+	MinKind,
+	MaxKind,
+	MeanKind,
+}
+
+func isPartitionKind(kind plan.ProcedureKind, kinds []plan.ProcedureKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAggregate returns the partial aggregate that runs on every
+// partition and the final aggregate that combines the partial
+// aggregates once the partitions are merged.
+func splitAggregate(spec plan.ProcedureSpec) (partial, final plan.PhysicalProcedureSpec, ok bool) {
+	switch s := spec.(type) {
+	case *SumProcedureSpec:
+		return s.Copy().(plan.PhysicalProcedureSpec), s.ReAggregateSpec().(plan.PhysicalProcedureSpec), true
+	case *CountProcedureSpec:
+		return s.Copy().(plan.PhysicalProcedureSpec), s.ReAggregateSpec().(plan.PhysicalProcedureSpec), true
+	case *MinProcedureSpec:
+		return s.Copy().(plan.PhysicalProcedureSpec), s.Copy().(plan.PhysicalProcedureSpec), true
+	case *MaxProcedureSpec:
+		return s.Copy().(plan.PhysicalProcedureSpec), s.Copy().(plan.PhysicalProcedureSpec), true
+	case *MeanProcedureSpec:
+		return &PartialMeanProcedureSpec{SimpleAggregateConfig: s.SimpleAggregateConfig.Copy()},
+			&FinalMeanProcedureSpec{SimpleAggregateConfig: s.SimpleAggregateConfig.Copy()},
+			true
+	}
+	return nil, nil, false
+}
+
+// partitionFactor returns the number of partitions to split the source
+// into. The factor is reduced when the estimated cardinality of the source
+// is too low for every partition to process minRowsPerPartition rows.
+// Sources whose cardinality is not known are split into factor partitions.
+func partitionFactor(source plan.Node, factor int) int {
+	_, stats := plan.EstimateCost(source)
+	if stats.Cardinality <= 0 {
+		return factor
+	}
+	if n := stats.Cardinality / minRowsPerPartition; n < int64(factor) {
+		return int(n)
+	}
+	return factor
+}
+
+// PartitionRule splits a source into partitions so that the stateless
+// transformations that follow it run in parallel. When the
+// transformations are followed by a mergeable aggregate, the aggregate is
+// split into a partial aggregate that runs on every partition and a final
+// aggregate that runs once the partitions are merged.
+//
+//	from |> filter |> map |> sum
+//
+// becomes
+//
+//	from |> partition |> filter |> map |> sum |> partitionMerge |> sum
+//
+// The rule is enabled by the parallelPartitionFactor feature flag,
+// which sets the largest number of partitions. Fewer partitions are
+// used when the source is estimated to produce few rows.
+type PartitionRule struct{}
+
+func (PartitionRule) Name() string {
+	return "PartitionRule"
+}
+
+func (PartitionRule) Pattern() plan.Pattern {
+	kinds := make([]plan.ProcedureKind, 0, len(statelessPartitionKinds)+len(aggregatePartitionKinds))
+	kinds = append(kinds, statelessPartitionKinds...)
+	kinds = append(kinds, aggregatePartitionKinds...)
+	return plan.MultiSuccessorOneOf(kinds, plan.AnySingleSuccessor())
+}
+
+func (PartitionRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	factor := feature.ParallelPartitionFactor().Int(ctx)
+	if factor < 2 {
+		return node, false, nil
+	}
+
+	top, ok := node.(*plan.PhysicalPlanNode)
+	if !ok {
+		return node, false, nil
+	}
+
+	// Walk down from this node to the source through stateless
+	// transformations that are not shared with other branches.
+	var (
+		source plan.Node
+		bottom plan.Node = top
+	)
+	for {
+		pred := bottom.Predecessors()[0]
+		if len(pred.Predecessors()) == 0 {
+			source = pred
+			break
+		}
+		if !isPartitionKind(pred.Kind(), statelessPartitionKinds) ||
+			len(pred.Predecessors()) != 1 || len(pred.Successors()) != 1 {
+			return node, false, nil
+		}
+		bottom = pred
+	}
+	if len(source.Successors()) != 1 || plan.GetOutputAttribute(source, plan.ParallelRunKey) != nil {
+		return node, false, nil
+	}
+	if factor = partitionFactor(source, factor); factor < 2 {
+		return node, false, nil
+	}
+
+	// The tables of a group key must be merged back together when a
+	// group key may be found in more than one partition. That happens when
+	// the rows of a table are split by time or when a transformation can
+	// change the group key of a table.
+	byTime := feature.ParallelPartitionByTime().Enabled(ctx)
+	regroup := byTime
+	for n := bottom; n != top; n = n.Successors()[0] {
+		if n.Kind() != FilterKind {
+			regroup = true
+		}
+	}
+
+	spec := top.Spec.Copy().(plan.PhysicalProcedureSpec)
+	var final plan.PhysicalProcedureSpec
+	if isPartitionKind(top.Kind(), aggregatePartitionKinds) {
+		if spec, final, ok = splitAggregate(top.Spec); !ok {
+			return node, false, nil
+		}
+	} else if top.Kind() != FilterKind {
+		regroup = true
+	}
+
+	// Insert the partition between the source and the bottom of the chain.
+	partition := plan.CreateUniquePhysicalNode(ctx, "partition", &PartitionProcedureSpec{
+		Factor: factor,
+		ByTime: byTime,
+	})
+	partition.AddPredecessors(source)
+	source.Successors()[0] = partition
+	partition.AddSuccessors(bottom)
+	bottom.Predecessors()[0] = partition
+
+	// Replace the top of the chain with a copy that runs on every partition
+	// and merge the partitions after it. The planner attaches the successors
+	// of the top of the chain to the node that is returned.
+	parallel := plan.CreatePhysicalNode(top.ID(), spec)
+	parallel.Source = top.Source
+	parallel.AddPredecessors(top.Predecessors()...)
+	pred := top.Predecessors()[0]
+	pred.Successors()[plan.IndexOfNode(top, pred.Successors())] = parallel
+
+	merge := plan.CreateUniquePhysicalNode(ctx, "partitionMerge", &PartitionMergeProcedureSpec{
+		Factor:  factor,
+		Regroup: regroup,
+	})
+	parallel.AddSuccessors(merge)
+	merge.AddPredecessors(parallel)
+	if final == nil {
+		return merge, true, nil
+	}
+
+	finalNode := plan.CreateUniquePhysicalNode(ctx, string(final.Kind()), final)
+	finalNode.Source = top.Source
+	merge.AddSuccessors(finalNode)
+	finalNode.AddPredecessors(merge)
+	return finalNode, true, nil
+}
@@ -0,0 +1,27 @@
+package universe
+
+import (
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+// NewPartitionTransformation is exposed so the tests can create
+// the transformation for one of the partitions.
+func NewPartitionTransformation(id execute.DatasetID, group, factor int, bounds *execute.Bounds, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	tr := &partitionTransformation{
+		group:  group,
+		factor: factor,
+		bounds: bounds,
+	}
+	return execute.NewNarrowTransformation(id, tr, mem)
+}
+
+// NewMeanPhaseTransformation is exposed so the tests can compute
+// the partial and the final phase of a mean.
+func NewMeanPhaseTransformation(id execute.DatasetID, columns []string, final bool, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	tr := &meanPhaseTransformation{
+		columns: columns,
+		final:   final,
+	}
+	return execute.NewAggregateTransformation(id, tr, mem)
+}
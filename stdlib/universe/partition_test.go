@@ -0,0 +1,583 @@
+package universe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/internal/feature"
+	"github.com/InfluxCommunity/flux/interpreter"
+	"github.com/InfluxCommunity/flux/memory"
+	"github.com/InfluxCommunity/flux/plan"
+	"github.com/InfluxCommunity/flux/plan/plantest"
+	"github.com/InfluxCommunity/flux/stdlib/influxdata/influxdb"
+	"github.com/InfluxCommunity/flux/stdlib/universe"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPartitionRule(t *testing.T) {
+	partitioned := func(byTime bool) context.Context {
+		return feature.Inject(context.Background(), executetest.TestFlagger{
+			feature.ParallelPartitionFactor().Key(): 4,
+			feature.ParallelPartitionByTime().Key(): byTime,
+		})
+	}
+
+	from := &influxdb.FromProcedureSpec{
+		Bucket: influxdb.NameOrID{Name: "testbucket"},
+	}
+	filter := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn: executetest.FunctionExpression(t, `(r) => r._value > 0`),
+		},
+	}
+	drop := &universe.SchemaMutationProcedureSpec{
+		Mutations: []universe.SchemaMutation{
+			&universe.DropOpSpec{Columns: []string{"host"}},
+		},
+	}
+	sum := &universe.SumProcedureSpec{
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	}
+	count := &universe.CountProcedureSpec{
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	}
+	mean := &universe.MeanProcedureSpec{
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	}
+	spread := &universe.SpreadProcedureSpec{
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	}
+
+	tests := []plantest.RuleTestCase{
+		{
+			Name:    "Disabled",
+			Context: context.Background(),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("filter", filter),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			NoChange:       true,
+			SkipValidation: true,
+		},
+		{
+			Name:    "Stateless",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("filter", filter),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:    "StatelessChangesGroupKey",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("drop", drop),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("drop", drop),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4, Regroup: true}),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:    "Sum",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("sum", sum),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:    "CountByTime",
+			Context: partitioned(true),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("count", count),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4, ByTime: true}),
+					plan.CreatePhysicalNode("count", count),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4, Regroup: true}),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:    "Mean",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("mean", mean),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("mean", &universe.PartialMeanProcedureSpec{
+						SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+					}),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("finalMean", &universe.FinalMeanProcedureSpec{
+						SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+					}),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
+			},
+			SkipValidation: true,
+		},
+		{
+			// spread cannot be merged, so it runs once the
+			// partitions of the filter are merged.
+			Name:    "NonMergeableAggregate",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("spread", spread),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("spread", spread),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:    "SmallSource",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", &sizedSource{Rows: 15000}),
+					plan.CreatePhysicalNode("filter", filter),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			NoChange:       true,
+			SkipValidation: true,
+		},
+		{
+			Name:    "ReducedFactor",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", &sizedSource{Rows: 25000}),
+					plan.CreatePhysicalNode("filter", filter),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", &sizedSource{Rows: 25000}),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 2}),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 2}),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:    "SharedSource",
+			Context: partitioned(false),
+			Rules:   []plan.Rule{universe.PartitionRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("filter", filter),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{{0, 1}, {0, 2}},
+			},
+			NoChange:       true,
+			SkipValidation: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
+
+// sizedSource is a source that reports the number of rows it produces.
+type sizedSource struct {
+	Rows int64
+}
+
+func (s *sizedSource) Kind() plan.ProcedureKind {
+	return "sizedSource"
+}
+
+func (s *sizedSource) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func (s *sizedSource) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return plan.Cost{}, plan.Statistics{Cardinality: s.Rows}
+}
+
+func TestPartitionParallelAttribute(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		spec    plan.PhysicalProcedureSpec
+		wantErr bool
+	}{
+		{
+			name: "Sum",
+			spec: &universe.SumProcedureSpec{SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig},
+		},
+		{
+			name: "PartialMean",
+			spec: &universe.PartialMeanProcedureSpec{SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig},
+		},
+		{
+			name: "Min",
+			spec: &universe.MinProcedureSpec{SelectorConfig: execute.DefaultSelectorConfig},
+		},
+		{
+			name:    "Spread",
+			spec:    &universe.SpreadProcedureSpec{SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig},
+			wantErr: true,
+		},
+		{
+			name:    "First",
+			spec:    &universe.FirstProcedureSpec{SelectorConfig: execute.DefaultSelectorConfig},
+			wantErr: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			partition := plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4})
+			agg := plan.CreatePhysicalNode("agg", tc.spec)
+			merge := plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4})
+			partition.AddSuccessors(agg)
+			agg.AddPredecessors(partition)
+			agg.AddSuccessors(merge)
+			merge.AddPredecessors(agg)
+
+			// An aggregate that is not merged by PartitionRule
+			// must not accept the partitions of parallel data.
+			err := plan.CheckSuccessorsMustRequire(partition)
+			if tc.wantErr && err == nil {
+				t.Error("expected parallel data to be rejected")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestPartitionMergeTransformation_Regroup(t *testing.T) {
+	partition := func(times ...int64) *executetest.Table {
+		tbl := &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+		}
+		for _, ts := range times {
+			tbl.Data = append(tbl.Data, []interface{}{execute.Time(ts), "a", float64(ts)})
+		}
+		return tbl
+	}
+
+	parents := []execute.DatasetID{executetest.RandomDatasetID(), executetest.RandomDatasetID()}
+	d := execute.NewPassthroughDataset(executetest.RandomDatasetID())
+	tr, err := universe.NewPartitionMergeTransformation(context.Background(), d, memory.DefaultAllocator,
+		&universe.PartitionMergeProcedureSpec{Factor: 2, Regroup: true}, parents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := executetest.NewDataStore()
+	d.AddTransformation(store)
+
+	// The second partition finishes first, but its rows
+	// are after the rows of the first partition.
+	if err := tr.Process(parents[1], partition(10, 15)); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parents[1], nil)
+	if err := tr.Process(parents[0], partition(0, 5)); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parents[0], nil)
+
+	got, err := executetest.TablesFromCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected the partitions to be merged into a single table, got %d tables", len(got))
+	}
+	if want := partition(0, 5, 10, 15).Data; !cmp.Equal(want, got[0].Data) {
+		t.Errorf("unexpected rows -want/+got\n%s", cmp.Diff(want, got[0].Data))
+	}
+}
+
+func TestPartitionTransformation(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{
+			&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), "a", 1.0},
+					{execute.Time(5), "a", 2.0},
+					{execute.Time(10), "a", 3.0},
+					{execute.Time(15), "a", 4.0},
+				},
+			},
+		}
+	}
+	bounds := &execute.Bounds{Start: 0, Stop: 20}
+
+	testCases := []struct {
+		name   string
+		group  int
+		bounds *execute.Bounds
+		want   []*executetest.Table
+	}{
+		{
+			name:   "FirstTimeRange",
+			group:  0,
+			bounds: bounds,
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), "a", 1.0},
+					{execute.Time(5), "a", 2.0},
+				},
+			}},
+		},
+		{
+			name:   "SecondTimeRange",
+			group:  1,
+			bounds: bounds,
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(10), "a", 3.0},
+					{execute.Time(15), "a", 4.0},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				data(),
+				tc.want,
+				nil,
+				func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewPartitionTransformation(id, tc.group, 2, tc.bounds, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+
+	// Every table is kept by exactly one partition when partitioning by group key.
+	var got []*executetest.Table
+	for group := 0; group < 2; group++ {
+		store := executetest.NewDataStore()
+		tr, d, err := universe.NewPartitionTransformation(executetest.RandomDatasetID(), group, 2, nil, memory.DefaultAllocator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.AddTransformation(store)
+		parentID := executetest.RandomDatasetID()
+		for _, tbl := range data() {
+			if err := tr.Process(parentID, tbl); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tr.Finish(parentID, nil)
+
+		tables, err := executetest.TablesFromCache(store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tables...)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected the table to be kept by a single partition, got %d tables", len(got))
+	}
+}
+
+func TestMeanPhaseTransformation(t *testing.T) {
+	partial := []flux.Table{
+		&executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "_mean_count__value", Type: flux.TInt},
+			},
+			Data: [][]interface{}{
+				{"a", 3.0, int64(2)},
+				{"a", 7.0, int64(1)},
+				{"a", 0.0, int64(0)},
+			},
+		},
+	}
+	want := []*executetest.Table{{
+		KeyCols: []string{"t0"},
+		ColMeta: []flux.ColMeta{
+			{Label: "t0", Type: flux.TString},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{"a", 10.0 / 3},
+		},
+	}}
+	executetest.ProcessTestHelper2(
+		t,
+		partial,
+		want,
+		nil,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := universe.NewMeanPhaseTransformation(id, []string{"_value"}, true, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+
+	input := []flux.Table{
+		&executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TInt},
+			},
+			Data: [][]interface{}{
+				{"a", int64(1)},
+				{"a", nil},
+				{"a", int64(2)},
+			},
+		},
+	}
+	want = []*executetest.Table{{
+		KeyCols: []string{"t0"},
+		ColMeta: []flux.ColMeta{
+			{Label: "t0", Type: flux.TString},
+			{Label: "_value", Type: flux.TFloat},
+			{Label: "_mean_count__value", Type: flux.TInt},
+		},
+		Data: [][]interface{}{
+			{"a", 3.0, int64(2)},
+		},
+	}}
+	executetest.ProcessTestHelper2(
+		t,
+		input,
+		want,
+		nil,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := universe.NewMeanPhaseTransformation(id, []string{"_value"}, false, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+}
@@ -79,6 +79,11 @@ func (s *RangeProcedureSpec) TimeBounds(predecessorBounds *plan.Bounds) *plan.Bo
 	return bounds
 }
 
+// TimeRangeColumns implements resultcache.TimeRangeProcedureSpec.
+func (s *RangeProcedureSpec) TimeRangeColumns() (timeCol, startCol, stopCol string, ok bool) {
+	return s.TimeColumn, s.StartColumn, s.StopColumn, true
+}
+
 func (s *RangeProcedureSpec) PassThroughAttribute(attrKey string) bool {
 	switch attrKey {
 	case plan.CollationKey:
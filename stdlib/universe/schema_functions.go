@@ -370,10 +370,31 @@ type SchemaMutationProcedureSpec struct {
 	Mutations []SchemaMutation
 }
 
+func (s *SchemaMutationProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	switch attrKey {
+	case plan.ParallelRunKey:
+		return true
+	}
+	return false
+}
+
 func (s *SchemaMutationProcedureSpec) Kind() plan.ProcedureKind {
 	return SchemaMutationKind
 }
 
+// RowWise implements resultcache.RowWiseProcedureSpec.
+// Only dropping and keeping columns leaves the time columns as they are.
+func (s *SchemaMutationProcedureSpec) RowWise() bool {
+	for _, m := range s.Mutations {
+		switch m.(type) {
+		case *DropOpSpec, *KeepOpSpec:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (s *SchemaMutationProcedureSpec) Copy() plan.ProcedureSpec {
 	newMutations := make([]SchemaMutation, len(s.Mutations))
 	for i, m := range s.Mutations {
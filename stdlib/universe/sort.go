@@ -10,6 +10,7 @@ import (
 	"github.com/InfluxCommunity/flux/arrow"
 	"github.com/InfluxCommunity/flux/codes"
 	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/spill"
 	"github.com/InfluxCommunity/flux/execute/table"
 	"github.com/InfluxCommunity/flux/internal/arrowutil"
 	"github.com/InfluxCommunity/flux/internal/errors"
@@ -97,6 +98,13 @@ func (s *SortProcedureSpec) Copy() plan.ProcedureSpec {
 	return &ns
 }
 
+// Cost estimates the cost of sorting the rows. The rows
+// are not changed so the statistics are passed through.
+func (s *SortProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	stats := plan.SumStatistics(inStats)
+	return plan.SortCost(stats), stats
+}
+
 func (s *SortProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
 	return plan.PhysicalAttributes{
 		plan.CollationKey: &plan.CollationAttr{
@@ -116,21 +124,29 @@ func createSortTransformation(id execute.DatasetID, mode execute.AccumulationMod
 	if !ok {
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
 	}
-	return NewSortTransformation(id, s, a.Allocator())
+	return newSortTransformation(id, s, a.Allocator(), spill.New(a.Context(), a.Allocator()))
 }
 
 type sortTransformation struct {
 	execute.ExecutionNode
 	d       *execute.PassthroughDataset
 	mem     memory.Allocator
+	spiller *spill.Spiller
 	cols    []string
 	compare arrowutil.CompareFunc
 }
 
 func NewSortTransformation(id execute.DatasetID, spec *SortProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	return newSortTransformation(id, spec, mem, nil)
+}
+
+// newSortTransformation constructs a sort transformation that spills
+// sorted runs to disk with the Spiller when it is not nil.
+func newSortTransformation(id execute.DatasetID, spec *SortProcedureSpec, mem memory.Allocator, spiller *spill.Spiller) (execute.Transformation, execute.Dataset, error) {
 	t := &sortTransformation{
 		d:       execute.NewPassthroughDataset(id),
 		mem:     mem,
+		spiller: spiller,
 		cols:    spec.Columns,
 		compare: arrowutil.Compare,
 	}
@@ -148,15 +164,32 @@ func (s *sortTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 		key:      tbl.Key(),
 		sortCols: sortCols,
 		compare:  s.compare,
+		spiller:  s.spiller,
 	}
+	var buffered int64
 	if err := tbl.Do(func(cr flux.ColReader) error {
-		return s.processView(mh, cr)
+		if err := s.processView(mh, cr); err != nil {
+			return err
+		}
+		if !s.spiller.Enabled() {
+			return nil
+		}
+		// Merge the buffered views into a sorted run on disk
+		// when the memory limit is close.
+		buffered += spill.Size(cr)
+		if s.spiller.ShouldSpill(buffered) {
+			buffered = 0
+			return mh.Spill(s.mem)
+		}
+		return nil
 	}); err != nil {
+		mh.Release()
 		return err
 	}
 
 	out, err := mh.Table(-1, s.mem)
 	if err != nil {
+		mh.Release()
 		return err
 	}
 	return s.d.Process(out)
@@ -265,12 +298,16 @@ type sortTableMergeHeapItem struct {
 	cr        flux.ColReader
 	indices   *array.Int
 	i, offset int
+
+	// run is the spilled run that the buffers are read from.
+	// The buffers of a run are already sorted.
+	run *spill.RunReader
 }
 
 func (s *sortTableMergeHeapItem) Next() bool {
 	s.i++
 	if s.i >= s.cr.Len() {
-		return false
+		return s.nextBuffer()
 	}
 	s.offset = s.i
 	if s.indices != nil {
@@ -279,6 +316,21 @@ func (s *sortTableMergeHeapItem) Next() bool {
 	return true
 }
 
+// nextBuffer reads the next buffer of the run.
+func (s *sortTableMergeHeapItem) nextBuffer() bool {
+	if s.run == nil {
+		return false
+	}
+	cr, ok := s.run.Next()
+	if !ok {
+		return false
+	}
+	s.cr.Release()
+	s.cr = cr
+	s.i, s.offset = 0, 0
+	return true
+}
+
 func (s *sortTableMergeHeapItem) Release() {
 	if s.indices != nil {
 		s.indices.Release()
@@ -288,6 +340,10 @@ func (s *sortTableMergeHeapItem) Release() {
 		s.cr.Release()
 		s.cr = nil
 	}
+	if s.run != nil {
+		s.run.Release()
+		s.run = nil
+	}
 }
 
 type sortTableMergeHeap struct {
@@ -296,6 +352,11 @@ type sortTableMergeHeap struct {
 	items    []*sortTableMergeHeapItem
 	sortCols []int
 	compare  arrowutil.CompareFunc
+
+	// spiller and runs hold the sorted runs that were spilled
+	// to disk when the spiller is not nil.
+	spiller *spill.Spiller
+	runs    *spill.RunSet
 }
 
 func (s *sortTableMergeHeap) Len() int {
@@ -336,18 +397,144 @@ func (s *sortTableMergeHeap) ValueLen() int {
 	return n
 }
 
+// Spill merges the items in memory into a sorted run on disk.
+func (s *sortTableMergeHeap) Spill(mem memory.Allocator) error {
+	if s.ValueLen() == 0 {
+		return nil
+	}
+	w, err := s.spiller.NewRun(s.key, s.cols)
+	if err != nil {
+		return err
+	}
+	if err := s.merge(-1, mem, w.Write); err != nil {
+		w.Release()
+		return err
+	}
+	r, err := w.Finish()
+	if err != nil {
+		return err
+	}
+	if s.runs == nil {
+		s.runs = s.spiller.NewRunSet(func(runs []*spill.RunReader) (*spill.RunReader, error) {
+			return s.mergeRuns(runs, mem)
+		})
+	}
+	return s.runs.Add(r)
+}
+
+// mergeRuns merges sorted runs into a single sorted run.
+func (s *sortTableMergeHeap) mergeRuns(runs []*spill.RunReader, mem memory.Allocator) (*spill.RunReader, error) {
+	mh := &sortTableMergeHeap{
+		cols:     s.cols,
+		key:      s.key,
+		sortCols: s.sortCols,
+		compare:  s.compare,
+	}
+	defer mh.Release()
+	if err := mh.addRuns(runs); err != nil {
+		return nil, err
+	}
+	w, err := s.spiller.NewRun(s.key, s.cols)
+	if err != nil {
+		return nil, err
+	}
+	if err := mh.merge(-1, mem, w.Write); err != nil {
+		w.Release()
+		return nil, err
+	}
+	// Reading a run may have stopped early because of an error.
+	for _, r := range runs {
+		if err := r.Err(); err != nil {
+			w.Release()
+			return nil, err
+		}
+	}
+	return w.Finish()
+}
+
+// addRuns adds the first buffer of each run to the items.
+// The remaining buffers are read as the items are merged.
+func (s *sortTableMergeHeap) addRuns(runs []*spill.RunReader) error {
+	for _, r := range runs {
+		cr, ok := r.Next()
+		if !ok {
+			if err := r.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		s.items = append(s.items, &sortTableMergeHeapItem{cr: cr, run: r})
+	}
+	return nil
+}
+
+// Release releases the items and the spilled runs.
+func (s *sortTableMergeHeap) Release() {
+	for _, item := range s.items {
+		item.Release()
+	}
+	s.items = s.items[:0]
+	s.runs.Release()
+}
+
 func (s *sortTableMergeHeap) Table(limit int, mem memory.Allocator) (flux.Table, error) {
+	// Add the spilled runs to the items that are merged.
+	// Runs are merged first if there are too many to read at once.
+	if err := s.runs.Reduce(); err != nil {
+		return nil, err
+	}
+	runs := s.runs.Take()
+	if err := s.addRuns(runs); err != nil {
+		for _, r := range runs {
+			r.Release()
+		}
+		return nil, err
+	}
+
 	if s.ValueLen() == 0 {
 		// Degenerate case where there are no rows to merge sort.
 		for len(s.items) > 0 {
 			s.Pop()
 		}
+		for _, r := range runs {
+			r.Release()
+		}
 		return execute.NewEmptyTable(s.key, s.cols), nil
 	}
 
-	// Construct the buffered builder that will contain the full table.
-	builder := table.NewBufferedBuilder(s.key, mem)
+	// Construct the builder that will contain the full table.
+	// If the runs do not fit in memory, the output is spilled too.
+	var builder sortTableBuilder = table.NewBufferedBuilder(s.key, mem)
+	if s.spiller.Enabled() {
+		builder = spill.NewBuilder(s.key, s.spiller, mem)
+	}
+	if err := s.merge(limit, mem, func(buffer flux.ColReader) error {
+		return builder.AppendBuffer(buffer)
+	}); err != nil {
+		builder.Release()
+		return nil, err
+	}
+
+	// Reading a run may have stopped early because of an error.
+	for _, r := range runs {
+		if err := r.Err(); err != nil {
+			builder.Release()
+			return nil, err
+		}
+		r.Release()
+	}
+	return builder.Table()
+}
+
+// sortTableBuilder is the builder for the output of a sort.
+type sortTableBuilder interface {
+	table.Builder
+	AppendBuffer(cr flux.ColReader) error
+}
 
+// merge merges the items up to the limit and passes the merged
+// buffers to fn. A negative limit merges all of the items.
+func (s *sortTableMergeHeap) merge(limit int, mem memory.Allocator, fn func(buffer flux.ColReader) error) error {
 	// Initialize the heap now that we have all of the data.
 	heap.Init(s)
 
@@ -395,11 +582,11 @@ func (s *sortTableMergeHeap) Table(limit int, mem memory.Allocator) (flux.Table,
 		if limit > 0 {
 			limit -= buffer.Len()
 		}
-		if err := builder.AppendBuffer(&buffer); err != nil {
-			buffer.Release()
-			return nil, err
-		}
+		err := fn(&buffer)
 		buffer.Release()
+		if err != nil {
+			return err
+		}
 	}
 
 	// Release the remaining items and clear the items.
@@ -408,9 +595,7 @@ func (s *sortTableMergeHeap) Table(limit int, mem memory.Allocator) (flux.Table,
 		item.Release()
 	}
 	s.items = s.items[:0]
-
-	// Determine the next buffer size.
-	return builder.Table()
+	return nil
 }
 
 func (s *sortTableMergeHeap) NextBuffer(builders []array.Builder, keys []array.Array, n int, mem memory.Allocator) arrow.TableBuffer {
@@ -445,7 +630,13 @@ func (s *sortTableMergeHeap) NextBuffer(builders []array.Builder, keys []array.A
 	}
 
 	// Initialize the key buffers if they need to be.
+	// The buffers of spilled runs are read as they are merged
+	// so a later buffer may be larger than the first one.
 	for i := range keys {
+		if keys[i] != nil && keys[i].Len() < n {
+			keys[i].Release()
+			keys[i] = nil
+		}
 		if keys[i] == nil {
 			keys[i] = arrow.Repeat(s.key.Cols()[i].Type, s.key.Value(i), n, mem)
 		}
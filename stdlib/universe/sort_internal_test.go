@@ -0,0 +1,13 @@
+package universe
+
+import (
+	"github.com/InfluxCommunity/flux/execute"
+	"github.com/InfluxCommunity/flux/execute/spill"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+)
+
+// NewSpillingSortTransformation is exposed so the tests can
+// spill the sorted runs of the sort transformation to disk.
+func NewSpillingSortTransformation(id execute.DatasetID, spec *SortProcedureSpec, mem memory.Allocator, spiller *spill.Spiller) (execute.Transformation, execute.Dataset, error) {
+	return newSortTransformation(id, spec, mem, spiller)
+}
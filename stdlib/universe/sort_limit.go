@@ -31,6 +31,14 @@ func (s *SortLimitProcedureSpec) Kind() plan.ProcedureKind {
 	return SortLimitKind
 }
 
+// Cost estimates that a sort limit compares every row and
+// only keeps N rows of every table.
+func (s *SortLimitProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	stats := plan.SumStatistics(inStats)
+	out := plan.LimitStatistics(stats, s.N)
+	return plan.Cost{CPU: stats.Cardinality, MEM: out.Cardinality}, out
+}
+
 func (s *SortLimitProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := *s
 	ns.SortProcedureSpec = s.SortProcedureSpec.Copy().(*SortProcedureSpec)
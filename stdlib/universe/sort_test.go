@@ -1,11 +1,16 @@
 package universe_test
 
 import (
+	"context"
+	"os"
 	"testing"
 
 	"github.com/InfluxCommunity/flux"
+	"github.com/InfluxCommunity/flux/dependencies/filesystem"
 	"github.com/InfluxCommunity/flux/execute"
 	"github.com/InfluxCommunity/flux/execute/executetest"
+	"github.com/InfluxCommunity/flux/execute/spill"
+	"github.com/InfluxCommunity/flux/execute/table"
 	"github.com/InfluxCommunity/flux/memory"
 	"github.com/InfluxCommunity/flux/stdlib/universe"
 )
@@ -384,3 +389,91 @@ func TestSort_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestSort_Spill(t *testing.T) {
+	dir := t.TempDir()
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	// A tiny threshold and minimum run size spill every buffer that is
+	// processed, and merging two runs at a time merges the runs in levels.
+	ctx = spill.Inject(ctx, spill.Config{Dir: dir, Threshold: 1e-9, MinRunSize: 1, MaxMergeRuns: 2})
+
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "t0", Type: flux.TString},
+		{Label: "_value", Type: flux.TFloat},
+	}
+	key := []string{"t0"}
+	var buffers []flux.ColReader
+	for _, data := range [][][]interface{}{
+		{
+			{execute.Time(1), "a", 3.0},
+			{execute.Time(2), "a", nil},
+			{execute.Time(3), "a", 1.0},
+		},
+		{
+			{execute.Time(4), "a", 2.0},
+			{execute.Time(5), "a", 6.0},
+		},
+		{
+			{execute.Time(6), "a", 5.0},
+			{execute.Time(7), "a", 4.0},
+		},
+	} {
+		tbl := &executetest.Table{KeyCols: key, ColMeta: cols, Data: data}
+		if err := tbl.Do(func(cr flux.ColReader) error {
+			cr.Retain()
+			buffers = append(buffers, cr)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	in := &table.BufferedTable{
+		GroupKey: buffers[0].Key(),
+		Columns:  buffers[0].Cols(),
+		Buffers:  buffers,
+	}
+
+	want := []*executetest.Table{{
+		KeyCols: key,
+		ColMeta: cols,
+		Data: [][]interface{}{
+			{execute.Time(2), "a", nil},
+			{execute.Time(3), "a", 1.0},
+			{execute.Time(4), "a", 2.0},
+			{execute.Time(1), "a", 3.0},
+			{execute.Time(7), "a", 4.0},
+			{execute.Time(6), "a", 5.0},
+			{execute.Time(5), "a", 6.0},
+		},
+	}}
+	executetest.ProcessTestHelper2(
+		t,
+		[]flux.Table{in},
+		want,
+		nil,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			limit := int64(1 << 30)
+			mem := &memory.ResourceAllocator{Limit: &limit}
+			spiller := spill.New(ctx, mem)
+			if !spiller.Enabled() {
+				t.Fatal("expected spilling to be enabled")
+			}
+			tr, d, err := universe.NewSpillingSortTransformation(id, &universe.SortProcedureSpec{
+				Columns: []string{"_value"},
+			}, mem, spiller)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		t.Errorf("spill file was not removed: %s", f.Name())
+	}
+}
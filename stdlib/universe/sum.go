@@ -64,6 +64,13 @@ func (s *SumProcedureSpec) Copy() plan.ProcedureSpec {
 	}
 }
 
+// PassThroughAttribute implements the PassThroughAttributer interface used by
+// the planner. The sums of the partitions of parallel data are added up by
+// the sum that PartitionRule places after the partitions are merged.
+func (s *SumProcedureSpec) PassThroughAttribute(attrKey string) bool {
+	return attrKey == plan.ParallelRunKey || s.SimpleAggregateConfig.PassThroughAttribute(attrKey)
+}
+
 // TriggerSpec implements plan.TriggerAwareProcedureSpec
 func (s *SumProcedureSpec) TriggerSpec() plan.TriggerSpec {
 	return plan.NarrowTransformationTriggerSpec{}
@@ -73,7 +80,9 @@ func (s *SumProcedureSpec) AggregateMethod() string {
 	return SumKind
 }
 func (s *SumProcedureSpec) ReAggregateSpec() plan.ProcedureSpec {
-	return new(SumProcedureSpec)
+	return &SumProcedureSpec{
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
 }
 
 type SumAgg struct{}
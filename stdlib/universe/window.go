@@ -466,9 +466,11 @@ func (WindowTriggerPhysicalRule) Pattern() plan.Pattern {
 // Rewrite modifies a window's trigger spec so long as it doesn't have any
 // window descendents that occur earlier in the plan and as long as none
 // of its descendents merge multiple streams together like union and join.
+// Windows over an unbounded source keep the watermark trigger so that each
+// window is emitted once the watermark passes its stop time.
 func (WindowTriggerPhysicalRule) Rewrite(ctx context.Context, window plan.Node) (plan.Node, bool, error) {
 	// This rule's pattern ensures us only one predecessor
-	if !hasValidPredecessors(window.Predecessors()[0]) {
+	if !hasValidPredecessors(window.Predecessors()[0]) || plan.IsUnbounded(window) {
 		return window, false, nil
 	}
 	// This rule's pattern ensures us a physical operator
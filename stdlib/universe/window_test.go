@@ -925,6 +925,28 @@ func mockPred(id string) plan.Node {
 	return plan.CreatePhysicalNode(plan.NodeID(id), &universe.FilterProcedureSpec{})
 }
 
+// streamingSourceProcedureSpec is a source that
+// produces data until the query is cancelled.
+type streamingSourceProcedureSpec struct {
+	plan.DefaultCost
+}
+
+func (s *streamingSourceProcedureSpec) Kind() plan.ProcedureKind {
+	return "streaming"
+}
+
+func (s *streamingSourceProcedureSpec) Copy() plan.ProcedureSpec {
+	return &streamingSourceProcedureSpec{}
+}
+
+func (s *streamingSourceProcedureSpec) Unbounded() bool {
+	return true
+}
+
+func streamingOp(id string) plan.Node {
+	return plan.CreatePhysicalNode(plan.NodeID(id), &streamingSourceProcedureSpec{})
+}
+
 func TestWindowRewriteRule(t *testing.T) {
 	testcases := []struct {
 		name string
@@ -936,6 +958,7 @@ func TestWindowRewriteRule(t *testing.T) {
 		//    w: window transformation
 		//    r: range transformation
 		//    b: bounded source
+		//    s: streaming source
 		//    W: window transformation with narrow trigger spec
 		{
 			name: "bounded source",
@@ -976,6 +999,25 @@ func TestWindowRewriteRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "streaming source",
+			// w       w
+			// |       |
+			// r  ==>  r
+			// |       |
+			// s       s
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					streamingOp("0"),
+					rangeOp("1"),
+					windowOp("2"),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+		},
 		{
 			name: "dependent window",
 			// w       w